@@ -5060,6 +5060,779 @@ func (x *fastReflection_EventValidatorPayout) ProtoMethods() *protoiface.Methods
 	}
 }
 
+var (
+	md_EventX402Revenue            protoreflect.MessageDescriptor
+	fd_EventX402Revenue_provider   protoreflect.FieldDescriptor
+	fd_EventX402Revenue_service    protoreflect.FieldDescriptor
+	fd_EventX402Revenue_requests   protoreflect.FieldDescriptor
+	fd_EventX402Revenue_revenue    protoreflect.FieldDescriptor
+	fd_EventX402Revenue_reserve    protoreflect.FieldDescriptor
+	fd_EventX402Revenue_period_end protoreflect.FieldDescriptor
+	fd_EventX402Revenue_height     protoreflect.FieldDescriptor
+)
+
+func init() {
+	file_arkeo_arkeo_events_proto_init()
+	md_EventX402Revenue = File_arkeo_arkeo_events_proto.Messages().ByName("EventX402Revenue")
+	fd_EventX402Revenue_provider = md_EventX402Revenue.Fields().ByName("provider")
+	fd_EventX402Revenue_service = md_EventX402Revenue.Fields().ByName("service")
+	fd_EventX402Revenue_requests = md_EventX402Revenue.Fields().ByName("requests")
+	fd_EventX402Revenue_revenue = md_EventX402Revenue.Fields().ByName("revenue")
+	fd_EventX402Revenue_reserve = md_EventX402Revenue.Fields().ByName("reserve")
+	fd_EventX402Revenue_period_end = md_EventX402Revenue.Fields().ByName("period_end")
+	fd_EventX402Revenue_height = md_EventX402Revenue.Fields().ByName("height")
+}
+
+var _ protoreflect.Message = (*fastReflection_EventX402Revenue)(nil)
+
+type fastReflection_EventX402Revenue EventX402Revenue
+
+func (x *EventX402Revenue) ProtoReflect() protoreflect.Message {
+	return (*fastReflection_EventX402Revenue)(x)
+}
+
+func (x *EventX402Revenue) slowProtoReflect() protoreflect.Message {
+	mi := &file_arkeo_arkeo_events_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+var _fastReflection_EventX402Revenue_messageType fastReflection_EventX402Revenue_messageType
+var _ protoreflect.MessageType = fastReflection_EventX402Revenue_messageType{}
+
+type fastReflection_EventX402Revenue_messageType struct{}
+
+func (x fastReflection_EventX402Revenue_messageType) Zero() protoreflect.Message {
+	return (*fastReflection_EventX402Revenue)(nil)
+}
+func (x fastReflection_EventX402Revenue_messageType) New() protoreflect.Message {
+	return new(fastReflection_EventX402Revenue)
+}
+func (x fastReflection_EventX402Revenue_messageType) Descriptor() protoreflect.MessageDescriptor {
+	return md_EventX402Revenue
+}
+
+// Descriptor returns message descriptor, which contains only the protobuf
+// type information for the message.
+func (x *fastReflection_EventX402Revenue) Descriptor() protoreflect.MessageDescriptor {
+	return md_EventX402Revenue
+}
+
+// Type returns the message type, which encapsulates both Go and protobuf
+// type information. If the Go type information is not needed,
+// it is recommended that the message descriptor be used instead.
+func (x *fastReflection_EventX402Revenue) Type() protoreflect.MessageType {
+	return _fastReflection_EventX402Revenue_messageType
+}
+
+// New returns a newly allocated and mutable empty message.
+func (x *fastReflection_EventX402Revenue) New() protoreflect.Message {
+	return new(fastReflection_EventX402Revenue)
+}
+
+// Interface unwraps the message reflection interface and
+// returns the underlying ProtoMessage interface.
+func (x *fastReflection_EventX402Revenue) Interface() protoreflect.ProtoMessage {
+	return (*EventX402Revenue)(x)
+}
+
+// Range iterates over every populated field in an undefined order,
+// calling f for each field descriptor and value encountered.
+// Range returns immediately if f returns false.
+// While iterating, mutating operations may only be performed
+// on the current field descriptor.
+func (x *fastReflection_EventX402Revenue) Range(f func(protoreflect.FieldDescriptor, protoreflect.Value) bool) {
+	if len(x.Provider) != 0 {
+		value := protoreflect.ValueOfBytes(x.Provider)
+		if !f(fd_EventX402Revenue_provider, value) {
+			return
+		}
+	}
+	if x.Service != "" {
+		value := protoreflect.ValueOfString(x.Service)
+		if !f(fd_EventX402Revenue_service, value) {
+			return
+		}
+	}
+	if x.Requests != int64(0) {
+		value := protoreflect.ValueOfInt64(x.Requests)
+		if !f(fd_EventX402Revenue_requests, value) {
+			return
+		}
+	}
+	if x.Revenue != nil {
+		value := protoreflect.ValueOfMessage(x.Revenue.ProtoReflect())
+		if !f(fd_EventX402Revenue_revenue, value) {
+			return
+		}
+	}
+	if x.Reserve != "" {
+		value := protoreflect.ValueOfString(x.Reserve)
+		if !f(fd_EventX402Revenue_reserve, value) {
+			return
+		}
+	}
+	if x.PeriodEnd != int64(0) {
+		value := protoreflect.ValueOfInt64(x.PeriodEnd)
+		if !f(fd_EventX402Revenue_period_end, value) {
+			return
+		}
+	}
+	if x.Height != int64(0) {
+		value := protoreflect.ValueOfInt64(x.Height)
+		if !f(fd_EventX402Revenue_height, value) {
+			return
+		}
+	}
+}
+
+// Has reports whether a field is populated.
+//
+// Some fields have the property of nullability where it is possible to
+// distinguish between the default value of a field and whether the field
+// was explicitly populated with the default value. Singular message fields,
+// member fields of a oneof, and proto2 scalar fields are nullable. Such
+// fields are populated only if explicitly set.
+//
+// In other cases (aside from the nullable cases above),
+// a proto3 scalar field is populated if it contains a non-zero value, and
+// a repeated field is populated if it is non-empty.
+func (x *fastReflection_EventX402Revenue) Has(fd protoreflect.FieldDescriptor) bool {
+	switch fd.FullName() {
+	case "arkeo.arkeo.EventX402Revenue.provider":
+		return len(x.Provider) != 0
+	case "arkeo.arkeo.EventX402Revenue.service":
+		return x.Service != ""
+	case "arkeo.arkeo.EventX402Revenue.requests":
+		return x.Requests != int64(0)
+	case "arkeo.arkeo.EventX402Revenue.revenue":
+		return x.Revenue != nil
+	case "arkeo.arkeo.EventX402Revenue.reserve":
+		return x.Reserve != ""
+	case "arkeo.arkeo.EventX402Revenue.period_end":
+		return x.PeriodEnd != int64(0)
+	case "arkeo.arkeo.EventX402Revenue.height":
+		return x.Height != int64(0)
+	default:
+		if fd.IsExtension() {
+			panic(fmt.Errorf("proto3 declared messages do not support extensions: arkeo.arkeo.EventX402Revenue"))
+		}
+		panic(fmt.Errorf("message arkeo.arkeo.EventX402Revenue does not contain field %s", fd.FullName()))
+	}
+}
+
+// Clear clears the field such that a subsequent Has call reports false.
+//
+// Clearing an extension field clears both the extension type and value
+// associated with the given field number.
+//
+// Clear is a mutating operation and unsafe for concurrent use.
+func (x *fastReflection_EventX402Revenue) Clear(fd protoreflect.FieldDescriptor) {
+	switch fd.FullName() {
+	case "arkeo.arkeo.EventX402Revenue.provider":
+		x.Provider = nil
+	case "arkeo.arkeo.EventX402Revenue.service":
+		x.Service = ""
+	case "arkeo.arkeo.EventX402Revenue.requests":
+		x.Requests = int64(0)
+	case "arkeo.arkeo.EventX402Revenue.revenue":
+		x.Revenue = nil
+	case "arkeo.arkeo.EventX402Revenue.reserve":
+		x.Reserve = ""
+	case "arkeo.arkeo.EventX402Revenue.period_end":
+		x.PeriodEnd = int64(0)
+	case "arkeo.arkeo.EventX402Revenue.height":
+		x.Height = int64(0)
+	default:
+		if fd.IsExtension() {
+			panic(fmt.Errorf("proto3 declared messages do not support extensions: arkeo.arkeo.EventX402Revenue"))
+		}
+		panic(fmt.Errorf("message arkeo.arkeo.EventX402Revenue does not contain field %s", fd.FullName()))
+	}
+}
+
+// Get retrieves the value for a field.
+//
+// For unpopulated scalars, it returns the default value, where
+// the default value of a bytes scalar is guaranteed to be a copy.
+// For unpopulated composite types, it returns an empty, read-only view
+// of the value; to obtain a mutable reference, use Mutable.
+func (x *fastReflection_EventX402Revenue) Get(descriptor protoreflect.FieldDescriptor) protoreflect.Value {
+	switch descriptor.FullName() {
+	case "arkeo.arkeo.EventX402Revenue.provider":
+		value := x.Provider
+		return protoreflect.ValueOfBytes(value)
+	case "arkeo.arkeo.EventX402Revenue.service":
+		value := x.Service
+		return protoreflect.ValueOfString(value)
+	case "arkeo.arkeo.EventX402Revenue.requests":
+		value := x.Requests
+		return protoreflect.ValueOfInt64(value)
+	case "arkeo.arkeo.EventX402Revenue.revenue":
+		value := x.Revenue
+		return protoreflect.ValueOfMessage(value.ProtoReflect())
+	case "arkeo.arkeo.EventX402Revenue.reserve":
+		value := x.Reserve
+		return protoreflect.ValueOfString(value)
+	case "arkeo.arkeo.EventX402Revenue.period_end":
+		value := x.PeriodEnd
+		return protoreflect.ValueOfInt64(value)
+	case "arkeo.arkeo.EventX402Revenue.height":
+		value := x.Height
+		return protoreflect.ValueOfInt64(value)
+	default:
+		if descriptor.IsExtension() {
+			panic(fmt.Errorf("proto3 declared messages do not support extensions: arkeo.arkeo.EventX402Revenue"))
+		}
+		panic(fmt.Errorf("message arkeo.arkeo.EventX402Revenue does not contain field %s", descriptor.FullName()))
+	}
+}
+
+// Set stores the value for a field.
+//
+// For a field belonging to a oneof, it implicitly clears any other field
+// that may be currently set within the same oneof.
+// For extension fields, it implicitly stores the provided ExtensionType.
+// When setting a composite type, it is unspecified whether the stored value
+// aliases the source's memory in any way. If the composite value is an
+// empty, read-only value, then it panics.
+//
+// Set is a mutating operation and unsafe for concurrent use.
+func (x *fastReflection_EventX402Revenue) Set(fd protoreflect.FieldDescriptor, value protoreflect.Value) {
+	switch fd.FullName() {
+	case "arkeo.arkeo.EventX402Revenue.provider":
+		x.Provider = value.Bytes()
+	case "arkeo.arkeo.EventX402Revenue.service":
+		x.Service = value.Interface().(string)
+	case "arkeo.arkeo.EventX402Revenue.requests":
+		x.Requests = value.Int()
+	case "arkeo.arkeo.EventX402Revenue.revenue":
+		x.Revenue = value.Message().Interface().(*v1beta1.Coin)
+	case "arkeo.arkeo.EventX402Revenue.reserve":
+		x.Reserve = value.Interface().(string)
+	case "arkeo.arkeo.EventX402Revenue.period_end":
+		x.PeriodEnd = value.Int()
+	case "arkeo.arkeo.EventX402Revenue.height":
+		x.Height = value.Int()
+	default:
+		if fd.IsExtension() {
+			panic(fmt.Errorf("proto3 declared messages do not support extensions: arkeo.arkeo.EventX402Revenue"))
+		}
+		panic(fmt.Errorf("message arkeo.arkeo.EventX402Revenue does not contain field %s", fd.FullName()))
+	}
+}
+
+// Mutable returns a mutable reference to a composite type.
+//
+// If the field is unpopulated, it may allocate a composite value.
+// For a field belonging to a oneof, it implicitly clears any other field
+// that may be currently set within the same oneof.
+// For extension fields, it implicitly stores the provided ExtensionType
+// if not already stored.
+// It panics if the field does not contain a composite type.
+//
+// Mutable is a mutating operation and unsafe for concurrent use.
+func (x *fastReflection_EventX402Revenue) Mutable(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	switch fd.FullName() {
+	case "arkeo.arkeo.EventX402Revenue.revenue":
+		if x.Revenue == nil {
+			x.Revenue = new(v1beta1.Coin)
+		}
+		return protoreflect.ValueOfMessage(x.Revenue.ProtoReflect())
+	case "arkeo.arkeo.EventX402Revenue.provider":
+		panic(fmt.Errorf("field provider of message arkeo.arkeo.EventX402Revenue is not mutable"))
+	case "arkeo.arkeo.EventX402Revenue.service":
+		panic(fmt.Errorf("field service of message arkeo.arkeo.EventX402Revenue is not mutable"))
+	case "arkeo.arkeo.EventX402Revenue.requests":
+		panic(fmt.Errorf("field requests of message arkeo.arkeo.EventX402Revenue is not mutable"))
+	case "arkeo.arkeo.EventX402Revenue.reserve":
+		panic(fmt.Errorf("field reserve of message arkeo.arkeo.EventX402Revenue is not mutable"))
+	case "arkeo.arkeo.EventX402Revenue.period_end":
+		panic(fmt.Errorf("field period_end of message arkeo.arkeo.EventX402Revenue is not mutable"))
+	case "arkeo.arkeo.EventX402Revenue.height":
+		panic(fmt.Errorf("field height of message arkeo.arkeo.EventX402Revenue is not mutable"))
+	default:
+		if fd.IsExtension() {
+			panic(fmt.Errorf("proto3 declared messages do not support extensions: arkeo.arkeo.EventX402Revenue"))
+		}
+		panic(fmt.Errorf("message arkeo.arkeo.EventX402Revenue does not contain field %s", fd.FullName()))
+	}
+}
+
+// NewField returns a new value that is assignable to the field
+// for the given descriptor. For scalars, this returns the default value.
+// For lists, maps, and messages, this returns a new, empty, mutable value.
+func (x *fastReflection_EventX402Revenue) NewField(fd protoreflect.FieldDescriptor) protoreflect.Value {
+	switch fd.FullName() {
+	case "arkeo.arkeo.EventX402Revenue.provider":
+		return protoreflect.ValueOfBytes(nil)
+	case "arkeo.arkeo.EventX402Revenue.service":
+		return protoreflect.ValueOfString("")
+	case "arkeo.arkeo.EventX402Revenue.requests":
+		return protoreflect.ValueOfInt64(int64(0))
+	case "arkeo.arkeo.EventX402Revenue.revenue":
+		m := new(v1beta1.Coin)
+		return protoreflect.ValueOfMessage(m.ProtoReflect())
+	case "arkeo.arkeo.EventX402Revenue.reserve":
+		return protoreflect.ValueOfString("")
+	case "arkeo.arkeo.EventX402Revenue.period_end":
+		return protoreflect.ValueOfInt64(int64(0))
+	case "arkeo.arkeo.EventX402Revenue.height":
+		return protoreflect.ValueOfInt64(int64(0))
+	default:
+		if fd.IsExtension() {
+			panic(fmt.Errorf("proto3 declared messages do not support extensions: arkeo.arkeo.EventX402Revenue"))
+		}
+		panic(fmt.Errorf("message arkeo.arkeo.EventX402Revenue does not contain field %s", fd.FullName()))
+	}
+}
+
+// WhichOneof reports which field within the oneof is populated,
+// returning nil if none are populated.
+// It panics if the oneof descriptor does not belong to this message.
+func (x *fastReflection_EventX402Revenue) WhichOneof(d protoreflect.OneofDescriptor) protoreflect.FieldDescriptor {
+	switch d.FullName() {
+	default:
+		panic(fmt.Errorf("%s is not a oneof field in arkeo.arkeo.EventX402Revenue", d.FullName()))
+	}
+	panic("unreachable")
+}
+
+// GetUnknown retrieves the entire list of unknown fields.
+// The caller may only mutate the contents of the RawFields
+// if the mutated bytes are stored back into the message with SetUnknown.
+func (x *fastReflection_EventX402Revenue) GetUnknown() protoreflect.RawFields {
+	return x.unknownFields
+}
+
+// SetUnknown stores an entire list of unknown fields.
+// The raw fields must be syntactically valid according to the wire format.
+// An implementation may panic if this is not the case.
+// Once stored, the caller must not mutate the content of the RawFields.
+// An empty RawFields may be passed to clear the fields.
+//
+// SetUnknown is a mutating operation and unsafe for concurrent use.
+func (x *fastReflection_EventX402Revenue) SetUnknown(fields protoreflect.RawFields) {
+	x.unknownFields = fields
+}
+
+// IsValid reports whether the message is valid.
+//
+// An invalid message is an empty, read-only value.
+//
+// An invalid message often corresponds to a nil pointer of the concrete
+// message type, but the details are implementation dependent.
+// Validity is not part of the protobuf data model, and may not
+// be preserved in marshaling or other operations.
+func (x *fastReflection_EventX402Revenue) IsValid() bool {
+	return x != nil
+}
+
+// ProtoMethods returns optional fastReflectionFeature-path implementations of various operations.
+// This method may return nil.
+//
+// The returned methods type is identical to
+// "google.golang.org/protobuf/runtime/protoiface".Methods.
+// Consult the protoiface package documentation for details.
+func (x *fastReflection_EventX402Revenue) ProtoMethods() *protoiface.Methods {
+	size := func(input protoiface.SizeInput) protoiface.SizeOutput {
+		x := input.Message.Interface().(*EventX402Revenue)
+		if x == nil {
+			return protoiface.SizeOutput{
+				NoUnkeyedLiterals: input.NoUnkeyedLiterals,
+				Size:              0,
+			}
+		}
+		options := runtime.SizeInputToOptions(input)
+		_ = options
+		var n int
+		var l int
+		_ = l
+		l = len(x.Provider)
+		if l > 0 {
+			n += 1 + l + runtime.Sov(uint64(l))
+		}
+		l = len(x.Service)
+		if l > 0 {
+			n += 1 + l + runtime.Sov(uint64(l))
+		}
+		if x.Requests != 0 {
+			n += 1 + runtime.Sov(uint64(x.Requests))
+		}
+		if x.Revenue != nil {
+			l = options.Size(x.Revenue)
+			n += 1 + l + runtime.Sov(uint64(l))
+		}
+		l = len(x.Reserve)
+		if l > 0 {
+			n += 1 + l + runtime.Sov(uint64(l))
+		}
+		if x.PeriodEnd != 0 {
+			n += 1 + runtime.Sov(uint64(x.PeriodEnd))
+		}
+		if x.Height != 0 {
+			n += 1 + runtime.Sov(uint64(x.Height))
+		}
+		if x.unknownFields != nil {
+			n += len(x.unknownFields)
+		}
+		return protoiface.SizeOutput{
+			NoUnkeyedLiterals: input.NoUnkeyedLiterals,
+			Size:              n,
+		}
+	}
+
+	marshal := func(input protoiface.MarshalInput) (protoiface.MarshalOutput, error) {
+		x := input.Message.Interface().(*EventX402Revenue)
+		if x == nil {
+			return protoiface.MarshalOutput{
+				NoUnkeyedLiterals: input.NoUnkeyedLiterals,
+				Buf:               input.Buf,
+			}, nil
+		}
+		options := runtime.MarshalInputToOptions(input)
+		_ = options
+		size := options.Size(x)
+		dAtA := make([]byte, size)
+		i := len(dAtA)
+		_ = i
+		var l int
+		_ = l
+		if x.unknownFields != nil {
+			i -= len(x.unknownFields)
+			copy(dAtA[i:], x.unknownFields)
+		}
+		if x.Height != 0 {
+			i = runtime.EncodeVarint(dAtA, i, uint64(x.Height))
+			i--
+			dAtA[i] = 0x38
+		}
+		if x.PeriodEnd != 0 {
+			i = runtime.EncodeVarint(dAtA, i, uint64(x.PeriodEnd))
+			i--
+			dAtA[i] = 0x30
+		}
+		if len(x.Reserve) > 0 {
+			i -= len(x.Reserve)
+			copy(dAtA[i:], x.Reserve)
+			i = runtime.EncodeVarint(dAtA, i, uint64(len(x.Reserve)))
+			i--
+			dAtA[i] = 0x2a
+		}
+		if x.Revenue != nil {
+			encoded, err := options.Marshal(x.Revenue)
+			if err != nil {
+				return protoiface.MarshalOutput{
+					NoUnkeyedLiterals: input.NoUnkeyedLiterals,
+					Buf:               input.Buf,
+				}, err
+			}
+			i -= len(encoded)
+			copy(dAtA[i:], encoded)
+			i = runtime.EncodeVarint(dAtA, i, uint64(len(encoded)))
+			i--
+			dAtA[i] = 0x22
+		}
+		if x.Requests != 0 {
+			i = runtime.EncodeVarint(dAtA, i, uint64(x.Requests))
+			i--
+			dAtA[i] = 0x18
+		}
+		if len(x.Service) > 0 {
+			i -= len(x.Service)
+			copy(dAtA[i:], x.Service)
+			i = runtime.EncodeVarint(dAtA, i, uint64(len(x.Service)))
+			i--
+			dAtA[i] = 0x12
+		}
+		if len(x.Provider) > 0 {
+			i -= len(x.Provider)
+			copy(dAtA[i:], x.Provider)
+			i = runtime.EncodeVarint(dAtA, i, uint64(len(x.Provider)))
+			i--
+			dAtA[i] = 0xa
+		}
+		if input.Buf != nil {
+			input.Buf = append(input.Buf, dAtA...)
+		} else {
+			input.Buf = dAtA
+		}
+		return protoiface.MarshalOutput{
+			NoUnkeyedLiterals: input.NoUnkeyedLiterals,
+			Buf:               input.Buf,
+		}, nil
+	}
+	unmarshal := func(input protoiface.UnmarshalInput) (protoiface.UnmarshalOutput, error) {
+		x := input.Message.Interface().(*EventX402Revenue)
+		if x == nil {
+			return protoiface.UnmarshalOutput{
+				NoUnkeyedLiterals: input.NoUnkeyedLiterals,
+				Flags:             input.Flags,
+			}, nil
+		}
+		options := runtime.UnmarshalInputToOptions(input)
+		_ = options
+		dAtA := input.Buf
+		l := len(dAtA)
+		iNdEx := 0
+		for iNdEx < l {
+			preIndex := iNdEx
+			var wire uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrIntOverflow
+				}
+				if iNdEx >= l {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				wire |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			fieldNum := int32(wire >> 3)
+			wireType := int(wire & 0x7)
+			if wireType == 4 {
+				return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, fmt.Errorf("proto: EventX402Revenue: wiretype end group for non-group")
+			}
+			if fieldNum <= 0 {
+				return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, fmt.Errorf("proto: EventX402Revenue: illegal tag %d (wire type %d)", fieldNum, wire)
+			}
+			switch fieldNum {
+			case 1:
+				if wireType != 2 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, fmt.Errorf("proto: wrong wireType = %d for field Provider", wireType)
+				}
+				var byteLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrIntOverflow
+					}
+					if iNdEx >= l {
+						return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					byteLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if byteLen < 0 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrInvalidLength
+				}
+				postIndex := iNdEx + byteLen
+				if postIndex < 0 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrInvalidLength
+				}
+				if postIndex > l {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, io.ErrUnexpectedEOF
+				}
+				x.Provider = append(x.Provider[:0], dAtA[iNdEx:postIndex]...)
+				if x.Provider == nil {
+					x.Provider = []byte{}
+				}
+				iNdEx = postIndex
+			case 2:
+				if wireType != 2 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, fmt.Errorf("proto: wrong wireType = %d for field Service", wireType)
+				}
+				var stringLen uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrIntOverflow
+					}
+					if iNdEx >= l {
+						return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					stringLen |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				intStringLen := int(stringLen)
+				if intStringLen < 0 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrInvalidLength
+				}
+				postIndex := iNdEx + intStringLen
+				if postIndex < 0 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrInvalidLength
+				}
+				if postIndex > l {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, io.ErrUnexpectedEOF
+				}
+				x.Service = string(dAtA[iNdEx:postIndex])
+				iNdEx = postIndex
+			case 3:
+				if wireType != 0 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, fmt.Errorf("proto: wrong wireType = %d for field Requests", wireType)
+				}
+				x.Requests = 0
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrIntOverflow
+					}
+					if iNdEx >= l {
+						return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					x.Requests |= int64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+			case 4:
+				if wireType != 2 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, fmt.Errorf("proto: wrong wireType = %d for field Revenue", wireType)
+				}
+				var msglen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrIntOverflow
+					}
+					if iNdEx >= l {
+						return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					msglen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if msglen < 0 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrInvalidLength
+				}
+				postIndex := iNdEx + msglen
+				if postIndex < 0 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrInvalidLength
+				}
+				if postIndex > l {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, io.ErrUnexpectedEOF
+				}
+				if x.Revenue == nil {
+					x.Revenue = &v1beta1.Coin{}
+				}
+				if err := options.Unmarshal(dAtA[iNdEx:postIndex], x.Revenue); err != nil {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, err
+				}
+				iNdEx = postIndex
+			case 5:
+				if wireType != 2 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, fmt.Errorf("proto: wrong wireType = %d for field Reserve", wireType)
+				}
+				var stringLen uint64
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrIntOverflow
+					}
+					if iNdEx >= l {
+						return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					stringLen |= uint64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				intStringLen := int(stringLen)
+				if intStringLen < 0 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrInvalidLength
+				}
+				postIndex := iNdEx + intStringLen
+				if postIndex < 0 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrInvalidLength
+				}
+				if postIndex > l {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, io.ErrUnexpectedEOF
+				}
+				x.Reserve = string(dAtA[iNdEx:postIndex])
+				iNdEx = postIndex
+			case 6:
+				if wireType != 0 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, fmt.Errorf("proto: wrong wireType = %d for field PeriodEnd", wireType)
+				}
+				x.PeriodEnd = 0
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrIntOverflow
+					}
+					if iNdEx >= l {
+						return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					x.PeriodEnd |= int64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+			case 7:
+				if wireType != 0 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, fmt.Errorf("proto: wrong wireType = %d for field Height", wireType)
+				}
+				x.Height = 0
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrIntOverflow
+					}
+					if iNdEx >= l {
+						return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					x.Height |= int64(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+			default:
+				iNdEx = preIndex
+				skippy, err := runtime.Skip(dAtA[iNdEx:])
+				if err != nil {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, err
+				}
+				if (skippy < 0) || (iNdEx+skippy) < 0 {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, runtime.ErrInvalidLength
+				}
+				if (iNdEx + skippy) > l {
+					return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, io.ErrUnexpectedEOF
+				}
+				if !options.DiscardUnknown {
+					x.unknownFields = append(x.unknownFields, dAtA[iNdEx:iNdEx+skippy]...)
+				}
+				iNdEx += skippy
+			}
+		}
+
+		if iNdEx > l {
+			return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, io.ErrUnexpectedEOF
+		}
+		return protoiface.UnmarshalOutput{NoUnkeyedLiterals: input.NoUnkeyedLiterals, Flags: input.Flags}, nil
+	}
+	return &protoiface.Methods{
+		NoUnkeyedLiterals: struct{}{},
+		Flags:             protoiface.SupportMarshalDeterministic | protoiface.SupportUnmarshalDiscardUnknown,
+		Size:              size,
+		Marshal:           marshal,
+		Unmarshal:         unmarshal,
+		Merge:             nil,
+		CheckInitialized:  nil,
+	}
+}
+
 // Code generated by protoc-gen-go. DO NOT EDIT.
 // versions:
 // 	protoc-gen-go v1.27.0
@@ -5611,6 +6384,89 @@ func (x *EventValidatorPayout) GetReward() string {
 	return ""
 }
 
+type EventX402Revenue struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Provider  []byte        `protobuf:"bytes,1,opt,name=provider,proto3" json:"provider,omitempty"`
+	Service   string        `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	Requests  int64         `protobuf:"varint,3,opt,name=requests,proto3" json:"requests,omitempty"`
+	Revenue   *v1beta1.Coin `protobuf:"bytes,4,opt,name=revenue,proto3" json:"revenue,omitempty"`
+	Reserve   string        `protobuf:"bytes,5,opt,name=reserve,proto3" json:"reserve,omitempty"`
+	PeriodEnd int64         `protobuf:"varint,6,opt,name=period_end,json=periodEnd,proto3" json:"period_end,omitempty"`
+	Height    int64         `protobuf:"varint,7,opt,name=height,proto3" json:"height,omitempty"`
+}
+
+func (x *EventX402Revenue) Reset() {
+	*x = EventX402Revenue{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_arkeo_arkeo_events_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *EventX402Revenue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EventX402Revenue) ProtoMessage() {}
+
+// Deprecated: Use EventX402Revenue.ProtoReflect.Descriptor instead.
+func (*EventX402Revenue) Descriptor() ([]byte, []int) {
+	return file_arkeo_arkeo_events_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *EventX402Revenue) GetProvider() []byte {
+	if x != nil {
+		return x.Provider
+	}
+	return nil
+}
+
+func (x *EventX402Revenue) GetService() string {
+	if x != nil {
+		return x.Service
+	}
+	return ""
+}
+
+func (x *EventX402Revenue) GetRequests() int64 {
+	if x != nil {
+		return x.Requests
+	}
+	return 0
+}
+
+func (x *EventX402Revenue) GetRevenue() *v1beta1.Coin {
+	if x != nil {
+		return x.Revenue
+	}
+	return nil
+}
+
+func (x *EventX402Revenue) GetReserve() string {
+	if x != nil {
+		return x.Reserve
+	}
+	return ""
+}
+
+func (x *EventX402Revenue) GetPeriodEnd() int64 {
+	if x != nil {
+		return x.PeriodEnd
+	}
+	return 0
+}
+
+func (x *EventX402Revenue) GetHeight() int64 {
+	if x != nil {
+		return x.Height
+	}
+	return 0
+}
+
 var File_arkeo_arkeo_events_proto protoreflect.FileDescriptor
 
 var file_arkeo_arkeo_events_proto_rawDesc = []byte{
@@ -5793,16 +6649,37 @@ var file_arkeo_arkeo_events_proto_rawDesc = []byte{
 	0x2b, 0xc8, 0xde, 0x1f, 0x00, 0xda, 0xde, 0x1f, 0x15, 0x63, 0x6f, 0x73, 0x6d, 0x6f, 0x73, 0x73,
 	0x64, 0x6b, 0x2e, 0x69, 0x6f, 0x2f, 0x6d, 0x61, 0x74, 0x68, 0x2e, 0x49, 0x6e, 0x74, 0xd2, 0xb4,
 	0x2d, 0x0a, 0x63, 0x6f, 0x73, 0x6d, 0x6f, 0x73, 0x2e, 0x49, 0x6e, 0x74, 0x52, 0x06, 0x72, 0x65,
-	0x77, 0x61, 0x72, 0x64, 0x42, 0x89, 0x01, 0x0a, 0x0f, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x72, 0x6b,
-	0x65, 0x6f, 0x2e, 0x61, 0x72, 0x6b, 0x65, 0x6f, 0x42, 0x0b, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73,
-	0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x1c, 0x63, 0x6f, 0x73, 0x6d, 0x6f, 0x73, 0x73,
-	0x64, 0x6b, 0x2e, 0x69, 0x6f, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x61, 0x72, 0x6b, 0x65, 0x6f, 0x2f,
-	0x61, 0x72, 0x6b, 0x65, 0x6f, 0xa2, 0x02, 0x03, 0x41, 0x41, 0x58, 0xaa, 0x02, 0x0b, 0x41, 0x72,
-	0x6b, 0x65, 0x6f, 0x2e, 0x41, 0x72, 0x6b, 0x65, 0x6f, 0xca, 0x02, 0x0b, 0x41, 0x72, 0x6b, 0x65,
-	0x6f, 0x5c, 0x41, 0x72, 0x6b, 0x65, 0x6f, 0xe2, 0x02, 0x17, 0x41, 0x72, 0x6b, 0x65, 0x6f, 0x5c,
-	0x41, 0x72, 0x6b, 0x65, 0x6f, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61, 0x74,
-	0x61, 0xea, 0x02, 0x0c, 0x41, 0x72, 0x6b, 0x65, 0x6f, 0x3a, 0x3a, 0x41, 0x72, 0x6b, 0x65, 0x6f,
-	0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x77, 0x61, 0x72, 0x64, 0x22, 0xce, 0x02, 0x0a, 0x10, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x58, 0x34,
+	0x30, 0x32, 0x52, 0x65, 0x76, 0x65, 0x6e, 0x75, 0x65, 0x12, 0x4b, 0x0a, 0x08, 0x70, 0x72, 0x6f,
+	0x76, 0x69, 0x64, 0x65, 0x72, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x42, 0x2f, 0xfa, 0xde, 0x1f,
+	0x2b, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x61, 0x72, 0x6b, 0x65,
+	0x6f, 0x6e, 0x65, 0x74, 0x77, 0x6f, 0x72, 0x6b, 0x2f, 0x61, 0x72, 0x6b, 0x65, 0x6f, 0x2f, 0x63,
+	0x6f, 0x6d, 0x6d, 0x6f, 0x6e, 0x2e, 0x50, 0x75, 0x62, 0x4b, 0x65, 0x79, 0x52, 0x08, 0x70, 0x72,
+	0x6f, 0x76, 0x69, 0x64, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x12, 0x1a, 0x0a, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x08, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x73, 0x12, 0x39, 0x0a, 0x07,
+	0x72, 0x65, 0x76, 0x65, 0x6e, 0x75, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e,
+	0x63, 0x6f, 0x73, 0x6d, 0x6f, 0x73, 0x2e, 0x62, 0x61, 0x73, 0x65, 0x2e, 0x76, 0x31, 0x62, 0x65,
+	0x74, 0x61, 0x31, 0x2e, 0x43, 0x6f, 0x69, 0x6e, 0x42, 0x04, 0xc8, 0xde, 0x1f, 0x00, 0x52, 0x07,
+	0x72, 0x65, 0x76, 0x65, 0x6e, 0x75, 0x65, 0x12, 0x45, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x65, 0x72,
+	0x76, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x42, 0x2b, 0xc8, 0xde, 0x1f, 0x00, 0xda, 0xde,
+	0x1f, 0x15, 0x63, 0x6f, 0x73, 0x6d, 0x6f, 0x73, 0x73, 0x64, 0x6b, 0x2e, 0x69, 0x6f, 0x2f, 0x6d,
+	0x61, 0x74, 0x68, 0x2e, 0x49, 0x6e, 0x74, 0xd2, 0xb4, 0x2d, 0x0a, 0x63, 0x6f, 0x73, 0x6d, 0x6f,
+	0x73, 0x2e, 0x49, 0x6e, 0x74, 0x52, 0x07, 0x72, 0x65, 0x73, 0x65, 0x72, 0x76, 0x65, 0x12, 0x1d,
+	0x0a, 0x0a, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x5f, 0x65, 0x6e, 0x64, 0x18, 0x06, 0x20, 0x01,
+	0x28, 0x03, 0x52, 0x09, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x45, 0x6e, 0x64, 0x12, 0x16, 0x0a,
+	0x06, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x03, 0x52, 0x06, 0x68,
+	0x65, 0x69, 0x67, 0x68, 0x74, 0x42, 0x89, 0x01, 0x0a, 0x0f, 0x63, 0x6f, 0x6d, 0x2e, 0x61, 0x72,
+	0x6b, 0x65, 0x6f, 0x2e, 0x61, 0x72, 0x6b, 0x65, 0x6f, 0x42, 0x0b, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x73, 0x50, 0x72, 0x6f, 0x74, 0x6f, 0x50, 0x01, 0x5a, 0x1c, 0x63, 0x6f, 0x73, 0x6d, 0x6f, 0x73,
+	0x73, 0x64, 0x6b, 0x2e, 0x69, 0x6f, 0x2f, 0x61, 0x70, 0x69, 0x2f, 0x61, 0x72, 0x6b, 0x65, 0x6f,
+	0x2f, 0x61, 0x72, 0x6b, 0x65, 0x6f, 0xa2, 0x02, 0x03, 0x41, 0x41, 0x58, 0xaa, 0x02, 0x0b, 0x41,
+	0x72, 0x6b, 0x65, 0x6f, 0x2e, 0x41, 0x72, 0x6b, 0x65, 0x6f, 0xca, 0x02, 0x0b, 0x41, 0x72, 0x6b,
+	0x65, 0x6f, 0x5c, 0x41, 0x72, 0x6b, 0x65, 0x6f, 0xe2, 0x02, 0x17, 0x41, 0x72, 0x6b, 0x65, 0x6f,
+	0x5c, 0x41, 0x72, 0x6b, 0x65, 0x6f, 0x5c, 0x47, 0x50, 0x42, 0x4d, 0x65, 0x74, 0x61, 0x64, 0x61,
+	0x74, 0x61, 0xea, 0x02, 0x0c, 0x41, 0x72, 0x6b, 0x65, 0x6f, 0x3a, 0x3a, 0x41, 0x72, 0x6b, 0x65,
+	0x6f, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -5817,7 +6694,7 @@ func file_arkeo_arkeo_events_proto_rawDescGZIP() []byte {
 	return file_arkeo_arkeo_events_proto_rawDescData
 }
 
-var file_arkeo_arkeo_events_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_arkeo_arkeo_events_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_arkeo_arkeo_events_proto_goTypes = []interface{}{
 	(*EventBondProvider)(nil),    // 0: arkeo.arkeo.EventBondProvider
 	(*EventModProvider)(nil),     // 1: arkeo.arkeo.EventModProvider
@@ -5825,24 +6702,26 @@ var file_arkeo_arkeo_events_proto_goTypes = []interface{}{
 	(*EventSettleContract)(nil),  // 3: arkeo.arkeo.EventSettleContract
 	(*EventCloseContract)(nil),   // 4: arkeo.arkeo.EventCloseContract
 	(*EventValidatorPayout)(nil), // 5: arkeo.arkeo.EventValidatorPayout
-	(ProviderStatus)(0),          // 6: arkeo.arkeo.ProviderStatus
-	(*v1beta1.Coin)(nil),         // 7: cosmos.base.v1beta1.Coin
-	(ContractType)(0),            // 8: arkeo.arkeo.ContractType
-	(ContractAuthorization)(0),   // 9: arkeo.arkeo.ContractAuthorization
+	(*EventX402Revenue)(nil),     // 6: arkeo.arkeo.EventX402Revenue
+	(ProviderStatus)(0),          // 7: arkeo.arkeo.ProviderStatus
+	(*v1beta1.Coin)(nil),         // 8: cosmos.base.v1beta1.Coin
+	(ContractType)(0),            // 9: arkeo.arkeo.ContractType
+	(ContractAuthorization)(0),   // 10: arkeo.arkeo.ContractAuthorization
 }
 var file_arkeo_arkeo_events_proto_depIdxs = []int32{
-	6, // 0: arkeo.arkeo.EventModProvider.status:type_name -> arkeo.arkeo.ProviderStatus
-	7, // 1: arkeo.arkeo.EventModProvider.subscription_rate:type_name -> cosmos.base.v1beta1.Coin
-	7, // 2: arkeo.arkeo.EventModProvider.pay_as_you_go_rate:type_name -> cosmos.base.v1beta1.Coin
-	8, // 3: arkeo.arkeo.EventOpenContract.type:type_name -> arkeo.arkeo.ContractType
-	7, // 4: arkeo.arkeo.EventOpenContract.rate:type_name -> cosmos.base.v1beta1.Coin
-	9, // 5: arkeo.arkeo.EventOpenContract.authorization:type_name -> arkeo.arkeo.ContractAuthorization
-	8, // 6: arkeo.arkeo.EventSettleContract.type:type_name -> arkeo.arkeo.ContractType
-	7, // [7:7] is the sub-list for method output_type
-	7, // [7:7] is the sub-list for method input_type
-	7, // [7:7] is the sub-list for extension type_name
-	7, // [7:7] is the sub-list for extension extendee
-	0, // [0:7] is the sub-list for field type_name
+	7,  // 0: arkeo.arkeo.EventModProvider.status:type_name -> arkeo.arkeo.ProviderStatus
+	8,  // 1: arkeo.arkeo.EventModProvider.subscription_rate:type_name -> cosmos.base.v1beta1.Coin
+	8,  // 2: arkeo.arkeo.EventModProvider.pay_as_you_go_rate:type_name -> cosmos.base.v1beta1.Coin
+	9,  // 3: arkeo.arkeo.EventOpenContract.type:type_name -> arkeo.arkeo.ContractType
+	8,  // 4: arkeo.arkeo.EventOpenContract.rate:type_name -> cosmos.base.v1beta1.Coin
+	10, // 5: arkeo.arkeo.EventOpenContract.authorization:type_name -> arkeo.arkeo.ContractAuthorization
+	9,  // 6: arkeo.arkeo.EventSettleContract.type:type_name -> arkeo.arkeo.ContractType
+	8,  // 7: arkeo.arkeo.EventX402Revenue.revenue:type_name -> cosmos.base.v1beta1.Coin
+	8,  // [8:8] is the sub-list for method output_type
+	8,  // [8:8] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
 }
 
 func init() { file_arkeo_arkeo_events_proto_init() }
@@ -5924,6 +6803,18 @@ func file_arkeo_arkeo_events_proto_init() {
 				return nil
 			}
 		}
+		file_arkeo_arkeo_events_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*EventX402Revenue); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -5931,7 +6822,7 @@ func file_arkeo_arkeo_events_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_arkeo_arkeo_events_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   0,
 		},
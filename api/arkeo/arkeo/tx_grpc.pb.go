@@ -25,6 +25,8 @@ type MsgClient interface {
 	ClaimContractIncome(ctx context.Context, in *MsgClaimContractIncome, opts ...grpc.CallOption) (*MsgClaimContractIncomeResponse, error)
 	// this line is used by starport scaffolding # proto/tx/rpc
 	SetVersion(ctx context.Context, in *MsgSetVersion, opts ...grpc.CallOption) (*MsgSetVersionResponse, error)
+	// ReportX402Revenue reports provider income from x402 agent payments.
+	ReportX402Revenue(ctx context.Context, in *MsgReportX402Revenue, opts ...grpc.CallOption) (*MsgReportX402RevenueResponse, error)
 }
 
 type msgClient struct {
@@ -89,6 +91,15 @@ func (c *msgClient) SetVersion(ctx context.Context, in *MsgSetVersion, opts ...g
 	return out, nil
 }
 
+func (c *msgClient) ReportX402Revenue(ctx context.Context, in *MsgReportX402Revenue, opts ...grpc.CallOption) (*MsgReportX402RevenueResponse, error) {
+	out := new(MsgReportX402RevenueResponse)
+	err := c.cc.Invoke(ctx, "/arkeo.arkeo.Msg/ReportX402Revenue", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MsgServer is the server API for Msg service.
 // All implementations must embed UnimplementedMsgServer
 // for forward compatibility
@@ -100,6 +111,8 @@ type MsgServer interface {
 	ClaimContractIncome(context.Context, *MsgClaimContractIncome) (*MsgClaimContractIncomeResponse, error)
 	// this line is used by starport scaffolding # proto/tx/rpc
 	SetVersion(context.Context, *MsgSetVersion) (*MsgSetVersionResponse, error)
+	// ReportX402Revenue reports provider income from x402 agent payments.
+	ReportX402Revenue(context.Context, *MsgReportX402Revenue) (*MsgReportX402RevenueResponse, error)
 	mustEmbedUnimplementedMsgServer()
 }
 
@@ -125,6 +138,9 @@ func (UnimplementedMsgServer) ClaimContractIncome(context.Context, *MsgClaimCont
 func (UnimplementedMsgServer) SetVersion(context.Context, *MsgSetVersion) (*MsgSetVersionResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetVersion not implemented")
 }
+func (UnimplementedMsgServer) ReportX402Revenue(context.Context, *MsgReportX402Revenue) (*MsgReportX402RevenueResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportX402Revenue not implemented")
+}
 func (UnimplementedMsgServer) mustEmbedUnimplementedMsgServer() {}
 
 // UnsafeMsgServer may be embedded to opt out of forward compatibility for this service.
@@ -246,6 +262,24 @@ func _Msg_SetVersion_Handler(srv interface{}, ctx context.Context, dec func(inte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Msg_ReportX402Revenue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MsgReportX402Revenue)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MsgServer).ReportX402Revenue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/arkeo.arkeo.Msg/ReportX402Revenue",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MsgServer).ReportX402Revenue(ctx, req.(*MsgReportX402Revenue))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // Msg_ServiceDesc is the grpc.ServiceDesc for Msg service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -277,6 +311,10 @@ var Msg_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "SetVersion",
 			Handler:    _Msg_SetVersion_Handler,
 		},
+		{
+			MethodName: "ReportX402Revenue",
+			Handler:    _Msg_ReportX402Revenue_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "arkeo/arkeo/tx.proto",
@@ -32,5 +32,9 @@ func main() {
 		fmt.Println(err)
 		return
 	}
-	proxy.Run()
+	proxy.ConfigFile = *configPath
+	if err := proxy.Run(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 }
@@ -44,6 +44,7 @@ type IDataStorage interface {
 	InsertModProviderEvent(ctx context.Context, providerID int64, evt types.ModProviderEvent, txID string, height int64) (*Entity, error)
 	UpsertIndexerStatus(ctx context.Context, height int64) (*Entity, error)
 	InsertGenericEvent(ctx context.Context, eventType, txID string, height int64, attrJSON []byte) (*Entity, error)
+	InsertX402RevenueEvent(ctx context.Context, providerID int64, evt atypes.EventX402Revenue, txID string, height int64) (*Entity, error)
 }
 
 var _ IDataStorage = &DirectoryDB{}
@@ -150,3 +150,12 @@ func (s *MockDataStorage) InsertGenericEvent(ctx context.Context, eventType stri
 	//nolint:forcetypeassert
 	return args.Get(0).(*Entity), args.Error(1)
 }
+
+func (s *MockDataStorage) InsertX402RevenueEvent(ctx context.Context, providerID int64, evt atypes.EventX402Revenue, txID string, height int64) (*Entity, error) {
+	args := s.Called(ctx, providerID, evt, txID, height)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	//nolint:forcetypeassert
+	return args.Get(0).(*Entity), args.Error(1)
+}
@@ -62,6 +62,12 @@ var sqlGetNetworkStatsByService = `
 		INNER JOIN providers p6 ON c5.provider_id = p6.id
 		WHERE (p6.status = 'ONLINE'::text)
 		AND (p6.service = $1)
+	) + (
+		SELECT COALESCE(sum(xre1.requests), 0) AS coalesce
+		FROM x402_revenue_events xre1
+		INNER JOIN providers p8 ON xre1.provider_id = p8.id
+		WHERE (p8.status = 'ONLINE'::text)
+		AND (p8.service = $1)
 	) as total_queries,
 	(
 		SELECT COALESCE(sum(cse2.paid), 0) AS coalesce
@@ -70,5 +76,12 @@ var sqlGetNetworkStatsByService = `
 		INNER JOIN providers p7 ON c6.provider_id = p7.id
 		WHERE (p7.status = 'ONLINE'::text)
 		AND (p7.service = $1)
+	) + (
+		SELECT COALESCE(sum(xre2.revenue - xre2.reserve), 0) AS coalesce
+		FROM x402_revenue_events xre2
+		INNER JOIN providers p9 ON xre2.provider_id = p9.id
+		WHERE (p9.status = 'ONLINE'::text)
+		AND (p9.service = $1)
+		AND (xre2.revenue_denom = 'uarkeo')
 	) as total_paid
 	`
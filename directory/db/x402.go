@@ -0,0 +1,31 @@
+package db
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	atypes "github.com/arkeonetwork/arkeo/x/arkeo/types"
+)
+
+func (d *DirectoryDB) InsertX402RevenueEvent(ctx context.Context, providerID int64, evt atypes.EventX402Revenue, txID string, height int64) (*Entity, error) {
+	if evt.Reserve.IsNil() {
+		return nil, errors.New("nil Reserve")
+	}
+	conn, err := d.getConnection(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error obtaining db connection")
+	}
+	defer conn.Release()
+
+	return insert(ctx, conn, sqlInsertX402RevenueEvent,
+		providerID,
+		txID,
+		height,
+		evt.Requests,
+		evt.Revenue.Denom,
+		evt.Revenue.Amount.String(),
+		evt.Reserve.String(),
+		evt.PeriodEnd,
+	)
+}
@@ -0,0 +1,18 @@
+package db
+
+var sqlInsertX402RevenueEvent = `
+	INSERT INTO x402_revenue_events (
+		provider_id,
+		txid,
+		height,
+		requests,
+		revenue_denom,
+		revenue,
+		reserve,
+		period_end
+	)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	ON CONFLICT ON CONSTRAINT x402_revenue_evts_txid_provider_key DO UPDATE SET
+		updated = now()
+	returning id, created, updated
+`
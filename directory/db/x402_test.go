@@ -0,0 +1,41 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"cosmossdk.io/math"
+	cosmostypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/pashagolub/pgxmock/v2"
+	"github.com/stretchr/testify/assert"
+
+	arkeotypes "github.com/arkeonetwork/arkeo/x/arkeo/types"
+)
+
+func TestInsertX402RevenueEvent(t *testing.T) {
+	m, db := getMockDirectoryDBForTest(t)
+	defer m.Close()
+	testTime := time.Now()
+	txID := arkeotypes.GetRandomTxID()
+	evt := arkeotypes.EventX402Revenue{
+		Provider:  arkeotypes.GetRandomPubKey(),
+		Service:   "mock",
+		Requests:  42,
+		Revenue:   cosmostypes.NewCoin("uarkeo", math.NewInt(1000)),
+		Reserve:   math.NewInt(100),
+		PeriodEnd: 1700000000,
+		Height:    1024,
+	}
+	m.ExpectQuery("INSERT INTO x402_revenue_events.*").
+		WithArgs(int64(1), txID, evt.Height, evt.Requests, "uarkeo", "1000", "100", evt.PeriodEnd).
+		WillReturnRows(
+			pgxmock.NewRows([]string{"id", "created", "updated"}).
+				AddRow(int64(1), testTime, testTime))
+
+	entity, err := db.InsertX402RevenueEvent(context.Background(), int64(1), evt, txID, evt.Height)
+	assert.Nil(t, err)
+	assert.NotNil(t, entity)
+	assert.Equal(t, int64(1), entity.ID)
+	assert.Nil(t, m.ExpectationsWereMet())
+}
@@ -255,6 +255,14 @@ func (s *Service) handleAbciEvent(event abcitypes.Event, transaction tmtypes.Tx,
 		if err := s.handleContractSettlementEvent(ctx, eventSettleContract, txID, height); err != nil {
 			return err
 		}
+	case atypes.EventTypeX402Revenue:
+		eventX402Revenue, err := parseEventToConcreteType[atypes.EventX402Revenue](event)
+		if err != nil {
+			return err
+		}
+		if err := s.handleX402RevenueEvent(ctx, eventX402Revenue, txID, height); err != nil {
+			return err
+		}
 	case atypes.EventTypeValidatorPayout:
 		// Intentionally ignored: writing these to `validator_payout_events` is very high-volume
 		// and isn't currently used by the directory/indexer.
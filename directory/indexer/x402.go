@@ -0,0 +1,22 @@
+package indexer
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+
+	atypes "github.com/arkeonetwork/arkeo/x/arkeo/types"
+)
+
+func (s *Service) handleX402RevenueEvent(ctx context.Context, evt atypes.EventX402Revenue, txID string, height int64) error {
+	provider, err := s.db.FindProvider(ctx, evt.Provider.String(), evt.Service)
+	if err != nil {
+		return errors.Wrapf(err, "error finding provider %s for service %s", evt.Provider.String(), evt.Service)
+	}
+
+	if _, err = s.db.InsertX402RevenueEvent(ctx, provider.ID, evt, txID, height); err != nil {
+		return errors.Wrapf(err, "error inserting x402 revenue event")
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package indexer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"cosmossdk.io/math"
+	cosmostypes "github.com/cosmos/cosmos-sdk/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/arkeonetwork/arkeo/common/logging"
+	"github.com/arkeonetwork/arkeo/directory/db"
+	arkeotypes "github.com/arkeonetwork/arkeo/x/arkeo/types"
+)
+
+func TestHandleX402RevenueEvent(t *testing.T) {
+	mockDb := new(db.MockDataStorage)
+	s := Service{
+		params:         ServiceParams{},
+		db:             mockDb,
+		done:           make(chan struct{}),
+		wg:             &sync.WaitGroup{},
+		logger:         logging.WithoutFields(),
+		tmClient:       nil,
+		blockFillQueue: make(chan db.BlockGap),
+	}
+	testPubKey := arkeotypes.GetRandomPubKey()
+	txID := arkeotypes.GetRandomTxID()
+	evt := arkeotypes.EventX402Revenue{
+		Provider:  testPubKey,
+		Service:   "mock",
+		Requests:  42,
+		Revenue:   cosmostypes.NewCoin("uarkeo", math.NewInt(1000)),
+		Reserve:   math.NewInt(100),
+		PeriodEnd: 1700000000,
+		Height:    1024,
+	}
+
+	mockFindProvider := mockDb.On("FindProvider", mock.Anything, testPubKey.String(), "mock").
+		Return(nil, fmt.Errorf("fail to find provider"))
+	assert.NotNil(t, s.handleX402RevenueEvent(context.Background(), evt, txID, evt.Height))
+	mockFindProvider.Unset()
+
+	mockDb.On("FindProvider", mock.Anything, testPubKey.String(), "mock").
+		Return(&db.ArkeoProvider{Entity: db.Entity{ID: 1}}, nil)
+	mockDb.On("InsertX402RevenueEvent", mock.Anything, int64(1), evt, txID, evt.Height).
+		Return(&db.Entity{ID: 2}, nil)
+	assert.Nil(t, s.handleX402RevenueEvent(context.Background(), evt, txID, evt.Height))
+	mockDb.AssertExpectations(t)
+}
@@ -0,0 +1,27 @@
+create table x402_revenue_events
+(
+    id            bigserial                 not null
+        constraint x402_revenue_events_pk
+            primary key,
+    created       timestamptz default now() not null,
+    updated       timestamptz default now() not null,
+    provider_id   bigint                    not null
+        constraint x402_revenue_events_providers_id_fk
+            references providers,
+    txid          text                      not null,
+    height        bigint                    not null,
+    requests      bigint                    not null,
+    revenue_denom text                      not null,
+    revenue       numeric                   not null,
+    reserve       numeric                   not null,
+    period_end    bigint                    not null
+);
+
+alter table x402_revenue_events add constraint x402_revenue_evts_txid_provider_key unique (txid, provider_id);
+
+create index x402_revenue_events_provider_id_idx on x402_revenue_events (provider_id);
+
+{{ template "views/network_stats_v_v1.sql" . }}
+---- create above / drop below ----
+{{ template "views/network_stats_v.sql" . }}
+drop table x402_revenue_events;
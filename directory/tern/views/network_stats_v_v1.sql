@@ -0,0 +1,23 @@
+/*
+ network_stats_v including the x402 revenue reported by providers: reported
+ requests count as queries and the revenue, net of the reserve tax, as paid
+ income when it is in uarkeo
+ */
+
+create or replace view network_stats_v as
+(
+select (select count(1) from contracts)                                 as total_contracts,
+       (select count(1) from contracts c where c.settlement_height = 0)     as open_contracts,
+       (SELECT percentile_cont(0.5) within group (order by duration) -- percentile_disc
+        from contracts c
+        where c.settlement_height = 0)                                      as median_open_contract_length,
+       (SELECT percentile_cont(0.5) within group (order by rate_amount)
+        from contracts c
+        where c.settlement_height = 0)                                      as median_open_contract_rate,
+       (select count(1) from providers where status = 'ONLINE')         as total_online_providers,
+       (select coalesce(sum(nonce), 0) from contract_settlement_events) +   -- nonce here is serviced request count
+       (select coalesce(sum(requests), 0) from x402_revenue_events)     as total_queries,
+       (select coalesce(sum(paid), 0) from contract_settlement_events) +
+       (select coalesce(sum(revenue - reserve), 0)
+        from x402_revenue_events
+        where revenue_denom = 'uarkeo')                                     as total_paid );
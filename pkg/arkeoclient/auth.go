@@ -0,0 +1,94 @@
+package arkeoclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/cometbft/cometbft/libs/log"
+
+	"github.com/arkeonetwork/arkeo/sentinel"
+)
+
+// ContractAuth signs the arkauth header of requests paid by a pay-as-you-go
+// or subscription contract. Every request uses the next nonce, nonces are
+// persisted so they keep increasing across restarts.
+type ContractAuth struct {
+	manager *sentinel.ArkeoAuthManager
+}
+
+// NewContractAuth creates the contract auth of the client key derived from
+// mnemonic. Nonces are kept in the LevelDB folder nonceStoreLocation, in
+// memory when it is empty.
+func NewContractAuth(contractID uint64, chainID, mnemonic, nonceStoreLocation string) (*ContractAuth, error) {
+	if contractID == 0 || chainID == "" || mnemonic == "" {
+		return nil, fmt.Errorf("contract auth needs a contract id, chain id and mnemonic")
+	}
+	store, err := sentinel.NewNonceStore(nonceStoreLocation)
+	if err != nil {
+		return nil, err
+	}
+	manager, err := sentinel.NewArkeoAuthManager(contractID, chainID, mnemonic, store, log.NewNopLogger())
+	if err != nil {
+		_ = store.Close()
+		return nil, err
+	}
+	return &ContractAuth{manager: manager}, nil
+}
+
+// ContractID returns the contract paying the requests
+func (a *ContractAuth) ContractID() uint64 {
+	return a.manager.GetContractId()
+}
+
+// Nonce returns the last nonce used
+func (a *ContractAuth) Nonce() int64 {
+	return a.manager.GetNonce()
+}
+
+// Next signs the arkauth value of the next request
+func (a *ContractAuth) Next() (string, error) {
+	return a.manager.GenerateAuthHeader()
+}
+
+// NextFor signs the arkauth value of a request worth several, such as a
+// JSON-RPC batch, that sentinels count once per call
+func (a *ContractAuth) NextFor(requests int64) (string, error) {
+	return a.manager.GenerateAuthHeaderFor(requests)
+}
+
+// Sync moves the nonce past the last one the sentinel at baseURL claimed for
+// the contract, e.g. after the nonce store was lost
+func (a *ContractAuth) Sync(ctx context.Context, client *http.Client, baseURL string) error {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	u := fmt.Sprintf("%s/claim/%d", strings.TrimSuffix(baseURL, "/"), a.ContractID())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to fetch claim: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to fetch claim: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var claim sentinel.Claim
+	if err := json.NewDecoder(resp.Body).Decode(&claim); err != nil {
+		return fmt.Errorf("failed to decode claim: %w", err)
+	}
+	return a.manager.SetNonce(claim.Nonce)
+}
+
+// Close closes the nonce store
+func (a *ContractAuth) Close() error {
+	return a.manager.Close()
+}
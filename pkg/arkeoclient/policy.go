@@ -0,0 +1,95 @@
+package arkeoclient
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/arkeonetwork/arkeo/sentinel"
+)
+
+// ErrNoRequirement is returned when no offered requirement is acceptable
+var ErrNoRequirement = errors.New("no acceptable payment requirement")
+
+// Policy picks the requirement to pay among those the signer supports. The
+// requirements are in the order the provider offered them.
+type Policy interface {
+	Choose(accepts []sentinel.PaymentRequirements) (sentinel.PaymentRequirements, error)
+}
+
+// PolicyFunc adapts a function to a Policy
+type PolicyFunc func(accepts []sentinel.PaymentRequirements) (sentinel.PaymentRequirements, error)
+
+// Choose calls f
+func (f PolicyFunc) Choose(accepts []sentinel.PaymentRequirements) (sentinel.PaymentRequirements, error) {
+	return f(accepts)
+}
+
+// First pays the first requirement, the provider's preference
+func First() Policy {
+	return PolicyFunc(func(accepts []sentinel.PaymentRequirements) (sentinel.PaymentRequirements, error) {
+		if len(accepts) == 0 {
+			return sentinel.PaymentRequirements{}, ErrNoRequirement
+		}
+		return accepts[0], nil
+	})
+}
+
+// PreferNetworks pays on the first of networks the provider accepts, other
+// networks are refused
+func PreferNetworks(networks ...string) Policy {
+	return PolicyFunc(func(accepts []sentinel.PaymentRequirements) (sentinel.PaymentRequirements, error) {
+		for _, network := range networks {
+			for _, requirements := range accepts {
+				if requirements.Network == network {
+					return requirements, nil
+				}
+			}
+		}
+		return sentinel.PaymentRequirements{}, fmt.Errorf("%w on %v", ErrNoRequirement, networks)
+	})
+}
+
+// Cheapest pays the lowest amount. Amounts are compared in atomic units, mix
+// it with PreferNetworks or MaxAmount when the assets differ in value.
+func Cheapest() Policy {
+	return PolicyFunc(func(accepts []sentinel.PaymentRequirements) (sentinel.PaymentRequirements, error) {
+		var best *sentinel.PaymentRequirements
+		var lowest *big.Int
+		for i := range accepts {
+			amount, ok := new(big.Int).SetString(accepts[i].Amount, 10)
+			if !ok {
+				continue
+			}
+			if lowest == nil || amount.Cmp(lowest) < 0 {
+				best, lowest = &accepts[i], amount
+			}
+		}
+		if best == nil {
+			return sentinel.PaymentRequirements{}, ErrNoRequirement
+		}
+		return *best, nil
+	})
+}
+
+// MaxAmount only lets next choose among requirements on the networks of
+// limits, for at most their limit in atomic units
+func MaxAmount(limits map[string]string, next Policy) Policy {
+	return PolicyFunc(func(accepts []sentinel.PaymentRequirements) (sentinel.PaymentRequirements, error) {
+		allowed := make([]sentinel.PaymentRequirements, 0, len(accepts))
+		for _, requirements := range accepts {
+			limit, ok := new(big.Int).SetString(limits[requirements.Network], 10)
+			if !ok {
+				continue
+			}
+			amount, ok := new(big.Int).SetString(requirements.Amount, 10)
+			if ok && amount.Cmp(limit) <= 0 {
+				allowed = append(allowed, requirements)
+			}
+		}
+		if len(allowed) == 0 {
+			return sentinel.PaymentRequirements{}, fmt.Errorf("%w within the amount limits", ErrNoRequirement)
+		}
+		return next.Choose(allowed)
+	})
+}
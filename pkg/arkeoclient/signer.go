@@ -0,0 +1,114 @@
+package arkeoclient
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+
+	"github.com/arkeonetwork/arkeo/sentinel"
+)
+
+// PaymentSigner signs x402 payments. Supports reports whether the signer can
+// pay a requirement, Sign returns the X-PAYMENT header paying it.
+type PaymentSigner interface {
+	Supports(requirements sentinel.PaymentRequirements) bool
+	Sign(ctx context.Context, requirements sentinel.PaymentRequirements) (string, error)
+}
+
+// EVMSigner pays "exact" requirements on EVM networks with an EIP-3009
+// TransferWithAuthorization signed by its key
+type EVMSigner struct {
+	key *ecdsa.PrivateKey
+
+	// Validity is how long a signed authorization can be settled, capped by
+	// the maxTimeoutSeconds of the requirement (default 60s)
+	Validity time.Duration
+}
+
+// NewEVMSigner creates a signer from a hex encoded secp256k1 private key
+func NewEVMSigner(hexKey string) (*EVMSigner, error) {
+	key, err := crypto.HexToECDSA(strings.TrimPrefix(hexKey, "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid evm private key: %w", err)
+	}
+	return NewEVMSignerFromKey(key), nil
+}
+
+// NewEVMSignerFromKey creates a signer from a private key
+func NewEVMSignerFromKey(key *ecdsa.PrivateKey) *EVMSigner {
+	return &EVMSigner{key: key, Validity: time.Minute}
+}
+
+// Address returns the payer address of the signer
+func (s *EVMSigner) Address() string {
+	return crypto.PubkeyToAddress(s.key.PublicKey).Hex()
+}
+
+// Supports accepts "exact" requirements on "eip155:<id>" networks
+func (s *EVMSigner) Supports(requirements sentinel.PaymentRequirements) bool {
+	return requirements.Scheme == sentinel.X402SchemeExact && strings.HasPrefix(requirements.Network, "eip155:")
+}
+
+// Sign authorizes the transfer of the required amount to the provider
+func (s *EVMSigner) Sign(ctx context.Context, requirements sentinel.PaymentRequirements) (string, error) {
+	if !s.Supports(requirements) {
+		return "", fmt.Errorf("evm signer cannot pay %s on %s", requirements.Scheme, requirements.Network)
+	}
+	nonce := make([]byte, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	validity := s.Validity
+	if timeout := time.Duration(requirements.MaxTimeoutSeconds) * time.Second; timeout > 0 && (validity <= 0 || timeout < validity) {
+		validity = timeout
+	}
+	now := time.Now()
+	auth := sentinel.EIP3009Authorization{
+		From:        s.Address(),
+		To:          requirements.PayTo,
+		Value:       requirements.Amount,
+		ValidAfter:  strconv.FormatInt(now.Add(-time.Minute).Unix(), 10),
+		ValidBefore: strconv.FormatInt(now.Add(validity).Unix(), 10),
+		Nonce:       hexutil.Encode(nonce),
+	}
+
+	hash, err := sentinel.EIP3009TypedDataHash(auth, requirements)
+	if err != nil {
+		return "", err
+	}
+	sig, err := crypto.Sign(hash, s.key)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign authorization: %w", err)
+	}
+	sig[crypto.RecoveryIDOffset] += 27
+
+	return encodePayment(requirements, sentinel.ExactEVMPayload{Signature: hexutil.Encode(sig), Authorization: auth})
+}
+
+// encodePayment builds the base64 X-PAYMENT header of a scheme payload
+func encodePayment(requirements sentinel.PaymentRequirements, payload interface{}) (string, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payment payload: %w", err)
+	}
+	buf, err := json.Marshal(sentinel.PaymentPayload{
+		X402Version: sentinel.X402Version,
+		Scheme:      requirements.Scheme,
+		Network:     requirements.Network,
+		Accepted:    &requirements,
+		Payload:     raw,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal payment: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(buf), nil
+}
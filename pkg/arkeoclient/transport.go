@@ -0,0 +1,204 @@
+// Package arkeoclient is a client for Arkeo sentinels. Its Transport pays for
+// requests with x402 when a sentinel answers 402 Payment Required, and signs
+// the arkauth header of requests paid by an Arkeo contract.
+package arkeoclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/arkeonetwork/arkeo/sentinel"
+)
+
+// maxPaymentRequiredSize bounds the 402 body read to choose a requirement
+const maxPaymentRequiredSize = 1 << 20
+
+// Transport is an http.RoundTripper paying for requests to sentinels.
+//
+// With a Signer, a 402 answer is parsed, a requirement chosen by Policy among
+// those the signer supports, and the request sent again with the signed
+// X-PAYMENT header. With Auth, every request carries the arkauth header of the
+// next contract nonce, JSON-RPC batches moving it by their number of calls; a
+// request refused for a stale nonce is sent again once the nonce is synced
+// with the sentinel.
+type Transport struct {
+	// Base sends the requests (http.DefaultTransport if nil)
+	Base http.RoundTripper
+
+	// Signer pays x402 requirements, without it 402 answers are returned as is
+	Signer PaymentSigner
+
+	// Policy chooses the requirement to pay (First if nil)
+	Policy Policy
+
+	// MaxSpend is sent as X-PAYMENT-MAX-SPEND with paid requests, e.g.
+	// "1000000/day", so the sentinel refuses payments over the budget
+	MaxSpend string
+
+	// Auth signs arkauth for contract access (optional)
+	Auth *ContractAuth
+}
+
+// NewClient returns an http.Client sending its requests through t
+func NewClient(t *Transport) *http.Client {
+	return &http.Client{Transport: t}
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+	return http.DefaultTransport
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := replayableBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := t.send(req, body, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusBadRequest && t.Auth != nil {
+		resp, err = t.resyncNonce(req, body, resp)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusPaymentRequired || t.Signer == nil || req.Header.Get("X-PAYMENT") != "" {
+		return resp, nil
+	}
+
+	required, err := readPaymentRequired(resp)
+	if err != nil {
+		return nil, err
+	}
+	requirements, err := t.choose(required.Accepts)
+	if err != nil {
+		return nil, err
+	}
+	payment, err := t.Signer.Sign(req.Context(), requirements)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign payment: %w", err)
+	}
+	return t.send(req, body, payment)
+}
+
+// send sends a copy of req with a fresh arkauth and the payment, if any
+func (t *Transport) send(req *http.Request, body []byte, payment string) (*http.Response, error) {
+	out := req.Clone(req.Context())
+	if body != nil {
+		out.Body = io.NopCloser(bytes.NewReader(body))
+		out.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+	}
+	if t.Auth != nil {
+		auth, err := t.Auth.NextFor(jsonRPCBatchSize(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to sign arkauth: %w", err)
+		}
+		out.Header.Set(sentinel.QueryArkAuth, auth)
+	}
+	if payment != "" {
+		out.Header.Set("X-PAYMENT", payment)
+		if t.MaxSpend != "" && out.Header.Get(sentinel.X402MaxSpendHeader) == "" {
+			out.Header.Set(sentinel.X402MaxSpendHeader, t.MaxSpend)
+		}
+	}
+	return t.base().RoundTrip(out)
+}
+
+// resyncNonce sends req again after a "bad nonce" refusal, once the nonce is
+// moved past the one the sentinel last claimed
+func (t *Transport) resyncNonce(req *http.Request, body []byte, resp *http.Response) (*http.Response, error) {
+	msg, err := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(string(msg), "bad nonce") {
+		resp.Body = io.NopCloser(bytes.NewReader(msg))
+		return resp, nil
+	}
+	baseURL := fmt.Sprintf("%s://%s", req.URL.Scheme, req.URL.Host)
+	if err := t.Auth.Sync(req.Context(), &http.Client{Transport: t.base()}, baseURL); err != nil {
+		return nil, err
+	}
+	return t.send(req, body, "")
+}
+
+// choose applies the policy to the requirements the signer supports
+func (t *Transport) choose(accepts []sentinel.PaymentRequirements) (sentinel.PaymentRequirements, error) {
+	supported := make([]sentinel.PaymentRequirements, 0, len(accepts))
+	for _, requirements := range accepts {
+		if t.Signer.Supports(requirements) {
+			supported = append(supported, requirements)
+		}
+	}
+	if len(supported) == 0 {
+		return sentinel.PaymentRequirements{}, fmt.Errorf("%w: the signer supports none of the %d offered", ErrNoRequirement, len(accepts))
+	}
+	policy := t.Policy
+	if policy == nil {
+		policy = First()
+	}
+	return policy.Choose(supported)
+}
+
+// replayableBody reads the body of req so it can be sent more than once
+func replayableBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	return body, nil
+}
+
+// jsonRPCBatchSize returns the calls of a JSON-RPC batch body, 1 for any
+// other body
+func jsonRPCBatchSize(body []byte) int64 {
+	body = bytes.TrimSpace(body)
+	if len(body) == 0 || body[0] != '[' {
+		return 1
+	}
+	var batch []json.RawMessage
+	if err := json.Unmarshal(body, &batch); err != nil || len(batch) == 0 {
+		return 1
+	}
+	return int64(len(batch))
+}
+
+// readPaymentRequired decodes and closes a 402 answer
+func readPaymentRequired(resp *http.Response) (sentinel.PaymentRequiredResponse, error) {
+	defer resp.Body.Close()
+	var required sentinel.PaymentRequiredResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxPaymentRequiredSize)).Decode(&required); err != nil {
+		return required, fmt.Errorf("failed to decode payment requirements: %w", err)
+	}
+	return required, nil
+}
+
+// Receipt returns the x402 receipt of a paid response, if any
+func Receipt(resp *http.Response) (*sentinel.X402PaymentResponse, error) {
+	header := resp.Header.Get(sentinel.X402PaymentResponseHeader)
+	if header == "" {
+		return nil, nil
+	}
+	receipt, err := sentinel.DecodeX402PaymentResponse(header)
+	if err != nil {
+		return nil, err
+	}
+	return &receipt, nil
+}
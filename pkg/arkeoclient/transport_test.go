@@ -0,0 +1,155 @@
+package arkeoclient
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+func testRequirements(network, amount string) sentinel.PaymentRequirements {
+	return sentinel.PaymentRequirements{
+		Scheme:            sentinel.X402SchemeExact,
+		Network:           network,
+		Amount:            amount,
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             "0x1234567890abcdef1234567890abcdef12345678",
+		MaxTimeoutSeconds: 60,
+		Extra:             map[string]interface{}{"name": "USDC", "version": "2"},
+	}
+}
+
+func TestTransport_X402(t *testing.T) {
+	paymentStore, err := sentinel.NewX402PaymentStore("")
+	require.NoError(t, err)
+	defer paymentStore.Close()
+	handler := sentinel.NewX402Handler("")
+	handler.Verifier = sentinel.NewLocalVerifier()
+	handler.Store = paymentStore
+	handler.Accepts = []sentinel.PaymentRequirements{
+		testRequirements("eip155:84532", "2000"),
+		testRequirements("eip155:11155111", "1000"),
+	}
+	var bodies []string
+	server := httptest.NewServer(handler.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		_, _ = w.Write([]byte(`{"result":"0x1"}`))
+	}), "eth"))
+	defer server.Close()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := NewEVMSignerFromKey(key)
+	client := NewClient(&Transport{Signer: signer, Policy: Cheapest()})
+
+	resp, err := client.Post(server.URL+"/x402/eth/", "application/json", strings.NewReader(`{"method":"eth_blockNumber"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	receipt, err := Receipt(resp)
+	require.NoError(t, err)
+	require.True(t, receipt.Success)
+	require.Equal(t, "eip155:11155111", receipt.Network)
+	require.Equal(t, "1000", receipt.Amount)
+	require.Equal(t, []string{`{"method":"eth_blockNumber"}`}, bodies)
+
+	// without a signer the 402 is returned
+	resp, err = http.Get(server.URL + "/x402/eth/")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusPaymentRequired, resp.StatusCode)
+
+	// the policy refuses every requirement
+	client = NewClient(&Transport{Signer: signer, Policy: MaxAmount(map[string]string{"eip155:84532": "1000"}, First())})
+	_, err = client.Get(server.URL + "/x402/eth/")
+	require.ErrorIs(t, err, ErrNoRequirement)
+}
+
+func TestPolicies(t *testing.T) {
+	accepts := []sentinel.PaymentRequirements{
+		testRequirements("eip155:8453", "1000"),
+		testRequirements("eip155:1", "500"),
+		testRequirements("arkeo:arkeo-main-v1", "850000"),
+	}
+	choose := func(policy Policy) string {
+		requirements, err := policy.Choose(accepts)
+		require.NoError(t, err)
+		return requirements.Network
+	}
+	require.Equal(t, "eip155:8453", choose(First()))
+	require.Equal(t, "eip155:1", choose(Cheapest()))
+	require.Equal(t, "arkeo:arkeo-main-v1", choose(PreferNetworks("eip155:10", "arkeo:arkeo-main-v1")))
+	require.Equal(t, "eip155:8453", choose(MaxAmount(map[string]string{"eip155:8453": "1000", "eip155:1": "100"}, Cheapest())))
+
+	_, err := PreferNetworks("eip155:10").Choose(accepts)
+	require.ErrorIs(t, err, ErrNoRequirement)
+}
+
+func TestTransport_ContractAuth(t *testing.T) {
+	var mu sync.Mutex
+	claimed := int64(41)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if r.URL.Path == "/claim/7" {
+			fmt.Fprintf(w, `{"contract_id":7,"nonce":%d}`, claimed)
+			return
+		}
+		parts := strings.Split(r.Header.Get(sentinel.QueryArkAuth), ":")
+		require.Len(t, parts, 4)
+		require.Equal(t, "7", parts[0])
+		nonce, err := strconv.ParseInt(parts[1], 10, 64)
+		require.NoError(t, err)
+		if nonce <= claimed {
+			http.Error(w, fmt.Sprintf("bad nonce (%d/%d)", nonce, claimed), http.StatusBadRequest)
+			return
+		}
+		claimed = nonce
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	auth, err := NewContractAuth(7, "arkeo", testMnemonic, dir)
+	require.NoError(t, err)
+	client := NewClient(&Transport{Auth: auth})
+
+	// the first nonce is stale, the transport catches up with the claim
+	resp, err := client.Get(server.URL + "/eth-mainnet-fullnode")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int64(42), auth.Nonce())
+
+	resp, err = client.Get(server.URL + "/eth-mainnet-fullnode")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// a batch pays one nonce per call
+	batch := `[{"id":1,"method":"eth_chainId"},{"id":2,"method":"eth_blockNumber"},{"id":3,"method":"net_version"}]`
+	resp, err = client.Post(server.URL+"/eth-mainnet-fullnode", "application/json", strings.NewReader(batch))
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, int64(46), auth.Nonce())
+	require.NoError(t, auth.Close())
+
+	// nonces survive a restart
+	auth, err = NewContractAuth(7, "arkeo", testMnemonic, dir)
+	require.NoError(t, err)
+	defer auth.Close()
+	require.Equal(t, int64(46), auth.Nonce())
+}
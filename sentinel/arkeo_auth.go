@@ -58,10 +58,19 @@ func NewArkeoAuthManager(contractId uint64, chainId string, mnemonic string, non
 }
 
 func (am *ArkeoAuthManager) GenerateAuthHeader() (string, error) {
+	return am.GenerateAuthHeaderFor(1)
+}
+
+// GenerateAuthHeaderFor signs the header of a request worth several, such as
+// a JSON-RPC batch, moving the nonce forward by that many
+func (am *ArkeoAuthManager) GenerateAuthHeaderFor(requests int64) (string, error) {
+	if requests < 1 {
+		requests = 1
+	}
 	am.mu.Lock()
 	defer am.mu.Unlock()
 
-	am.nonce++
+	am.nonce += requests
 
 	// Persist new nonce
 	if am.nonceStore != nil {
@@ -88,6 +97,24 @@ func (am *ArkeoAuthManager) GenerateAuthHeader() (string, error) {
 	return authString, nil
 }
 
+// SetNonce moves the nonce forward to nonce, e.g. the nonce the provider last
+// claimed, so the next header uses nonce+1. Lower nonces are ignored.
+func (am *ArkeoAuthManager) SetNonce(nonce int64) error {
+	am.mu.Lock()
+	defer am.mu.Unlock()
+
+	if nonce <= am.nonce {
+		return nil
+	}
+	am.nonce = nonce
+	if am.nonceStore != nil {
+		if err := am.nonceStore.Set(am.contractId, am.nonce); err != nil {
+			return fmt.Errorf("failed to persist nonce: %w", err)
+		}
+	}
+	return nil
+}
+
 func (am *ArkeoAuthManager) GetNonce() int64 {
 	am.mu.Lock()
 	defer am.mu.Unlock()
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"text/tabwriter"
@@ -15,6 +16,28 @@ import (
 type TLSConfiguration struct {
 	Cert string `json:"tls_certificate,omitempty"`
 	Key  string `json:"tls_key,omitempty"`
+
+	// HTTPSPort is the port HTTPS is served on (default 443)
+	HTTPSPort string `json:"https_port,omitempty" yaml:"https_port,omitempty"`
+
+	// HTTPPort is the port redirecting to HTTPS and answering the ACME
+	// HTTP-01 challenges (default port)
+	HTTPPort string `json:"http_port,omitempty" yaml:"http_port,omitempty"`
+
+	// ACME obtains and renews the certificate instead of Cert and Key
+	ACME ACMEConfig `json:"acme,omitempty" yaml:"acme,omitempty"`
+}
+
+// ACMEConfig obtains certificates from an ACME certificate authority such as
+// Let's Encrypt or ZeroSSL, and renews them before they expire
+type ACMEConfig struct {
+	Enabled      bool     `json:"enabled" yaml:"enabled"`
+	Domains      []string `json:"domains,omitempty" yaml:"domains,omitempty"`             // host names certificates are issued for
+	Email        string   `json:"email,omitempty" yaml:"email,omitempty"`                 // contact for expiry and account notices (optional)
+	CacheDir     string   `json:"cache_dir,omitempty" yaml:"cache_dir,omitempty"`         // directory certificates and the account key are kept in (default ~/.arkeo/acme)
+	DirectoryURL string   `json:"directory_url,omitempty" yaml:"directory_url,omitempty"` // "letsencrypt" (default), "zerossl" or the directory URL of another authority
+	EABKeyID     string   `json:"eab_key_id,omitempty" yaml:"eab_key_id,omitempty"`       // external account binding key id, required by ZeroSSL
+	EABHMACKey   string   `json:"-" yaml:"eab_hmac_key,omitempty"`                        // external account binding key, base64url encoded
 }
 
 type ServiceConfig struct {
@@ -24,6 +47,103 @@ type ServiceConfig struct {
 	RpcUrl  string `json:"rpc_url" yaml:"rpc_url,omitempty"`
 	RpcUser string `json:"rpc_user,omitempty" yaml:"rpc_user,omitempty"`
 	RpcPass string `json:"rpc_pass,omitempty" yaml:"rpc_pass,omitempty"`
+
+	// Upstreams are more nodes serving the service, next to rpc_url
+	Upstreams []UpstreamConfig `json:"upstreams,omitempty" yaml:"upstreams,omitempty"`
+
+	// HealthCheck is the probe of the upstreams: a JSON-RPC method such as
+	// "eth_blockNumber", or a path such as "/status" that is fetched with GET.
+	// It is inferred from the service name when empty.
+	HealthCheck string `json:"health_check,omitempty" yaml:"health_check,omitempty"`
+
+	// MaxLag overrides upstream_health.max_lag for the service
+	MaxLag int `json:"max_lag,omitempty" yaml:"max_lag,omitempty"`
+
+	// JSONRPC filters the JSON-RPC requests to the service
+	JSONRPC JSONRPCConfig `json:"jsonrpc,omitempty" yaml:"jsonrpc,omitempty"`
+
+	// FinalityDepth is how many blocks behind the best height a block must be
+	// before answers about it are cached (default 64 on EVM chains, 6 on
+	// Bitcoin chains, 1 on Cosmos chains)
+	FinalityDepth uint64 `json:"finality_depth,omitempty" yaml:"finality_depth,omitempty"`
+
+	// Timeout is how many seconds a request to the service may take, from
+	// reading its body to writing the last byte of the answer (default
+	// server.write_timeout). Long eth_getLogs or trace_* calls and streamed
+	// answers need more than the default.
+	Timeout uint64 `json:"timeout,omitempty" yaml:"timeout,omitempty"`
+
+	// MaxBodySize is the largest request body in bytes (default 1 MiB)
+	MaxBodySize int64 `json:"max_body_size,omitempty" yaml:"max_body_size,omitempty"`
+
+	// FlushIntervalMs is how many milliseconds answers are buffered before
+	// they are flushed to the client, -1 flushes after every write. Server-sent
+	// events and chunked answers are always flushed right away.
+	FlushIntervalMs int64 `json:"flush_interval_ms,omitempty" yaml:"flush_interval_ms,omitempty"`
+
+	// WebSocket limits the WebSocket connections to the service
+	WebSocket WebSocketConfig `json:"websocket,omitempty" yaml:"websocket,omitempty"`
+}
+
+// WebSocketConfig limits the WebSocket connections to a service. They are
+// not bound by the request timeout. Zero values use the defaults.
+type WebSocketConfig struct {
+	MaxConnections uint64 `json:"max_connections,omitempty" yaml:"max_connections,omitempty"`   // connections open at once (default unlimited)
+	MaxMessageSize int64  `json:"max_message_size,omitempty" yaml:"max_message_size,omitempty"` // largest message in bytes, in either direction (default 1 MiB)
+	IdleTimeout    uint64 `json:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"`         // seconds without a message before the connection is closed (default 300)
+	MaxDuration    uint64 `json:"max_duration,omitempty" yaml:"max_duration,omitempty"`         // seconds a connection may stay open (default unlimited)
+}
+
+// ServerConfig sets the timeouts of the sentinel HTTP servers. Zero values
+// use the defaults.
+type ServerConfig struct {
+	ReadHeaderTimeout uint64 `json:"read_header_timeout,omitempty" yaml:"read_header_timeout,omitempty"` // seconds to read the request headers (default 10)
+	ReadTimeout       uint64 `json:"read_timeout,omitempty" yaml:"read_timeout,omitempty"`               // seconds to read a request to the routes other than the services (default 30)
+	WriteTimeout      uint64 `json:"write_timeout,omitempty" yaml:"write_timeout,omitempty"`             // seconds to serve a request, unless its service sets a timeout (default 60)
+	IdleTimeout       uint64 `json:"idle_timeout,omitempty" yaml:"idle_timeout,omitempty"`               // seconds a keep-alive connection waits for the next request (default 120)
+	MaxHeaderBytes    int    `json:"max_header_bytes,omitempty" yaml:"max_header_bytes,omitempty"`       // largest request headers in bytes (default 1 MiB)
+}
+
+// ResponseCacheConfig caches the answers to requests for finalized data, such
+// as old blocks or mined transaction receipts, so they are served without
+// asking the upstreams again. Zero values use the defaults.
+type ResponseCacheConfig struct {
+	Enabled       bool   `json:"enabled" yaml:"enabled"`
+	MaxEntries    int    `json:"max_entries,omitempty" yaml:"max_entries,omitempty"`       // answers held in memory (default 10000)
+	MaxBytes      int64  `json:"max_bytes,omitempty" yaml:"max_bytes,omitempty"`           // bytes held in memory (default 64MiB)
+	MaxEntrySize  int    `json:"max_entry_size,omitempty" yaml:"max_entry_size,omitempty"` // largest answer cached (default 1MiB)
+	StoreLocation string `json:"store_location,omitempty" yaml:"store_location,omitempty"` // LevelDB path answers evicted from memory spill to, none if empty
+	StoreTTL      uint64 `json:"store_ttl,omitempty" yaml:"store_ttl,omitempty"`           // seconds spilled answers are kept (default 604800)
+}
+
+// JSONRPCConfig filters the JSON-RPC requests to a service. Methods match
+// exactly or by prefix with a trailing "*", e.g. "debug_*".
+type JSONRPCConfig struct {
+	Allow    []string `json:"allow,omitempty" yaml:"allow,omitempty"`         // methods served, all but the denied ones when empty
+	Deny     []string `json:"deny,omitempty" yaml:"deny,omitempty"`           // methods refused
+	MaxBatch int      `json:"max_batch,omitempty" yaml:"max_batch,omitempty"` // calls in a batch (default 100, -1 unlimited)
+	Disabled bool     `json:"disabled,omitempty" yaml:"disabled,omitempty"`   // forward the requests without inspecting them
+}
+
+// UpstreamConfig is a node serving a service
+type UpstreamConfig struct {
+	Name    string `json:"name,omitempty" yaml:"name,omitempty"` // reported in /metadata.json instead of the url
+	RpcUrl  string `json:"rpc_url" yaml:"rpc_url"`
+	RpcUser string `json:"rpc_user,omitempty" yaml:"rpc_user,omitempty"`
+	RpcPass string `json:"rpc_pass,omitempty" yaml:"rpc_pass,omitempty"`
+	Weight  int    `json:"weight,omitempty" yaml:"weight,omitempty"` // share of the requests relative to the other upstreams (default 1)
+}
+
+// UpstreamHealthConfig configures the health checks of the upstreams and the
+// ejection of failing ones. Zero values use the defaults.
+type UpstreamHealthConfig struct {
+	Interval     uint64 `json:"interval,omitempty" yaml:"interval,omitempty"`           // seconds between active probes (default 15)
+	Timeout      uint64 `json:"timeout,omitempty" yaml:"timeout,omitempty"`             // seconds before a probe fails (default 5)
+	EjectAfter   int    `json:"eject_after,omitempty" yaml:"eject_after,omitempty"`     // consecutive failed requests that eject an upstream (default 3)
+	EjectSeconds uint64 `json:"eject_seconds,omitempty" yaml:"eject_seconds,omitempty"` // seconds an upstream stays ejected (default 30)
+	Retries      int    `json:"retries,omitempty" yaml:"retries,omitempty"`             // other upstreams tried by an idempotent request (default 1, -1 disables)
+	MaxLag       int    `json:"max_lag,omitempty" yaml:"max_lag,omitempty"`             // blocks an upstream can be behind the best height and still serve (default 5, -1 disables)
+	Disabled     bool   `json:"disabled,omitempty" yaml:"disabled,omitempty"`           // turns the active probes off, failures still eject
 }
 
 type Configuration struct {
@@ -46,14 +166,217 @@ type Configuration struct {
 	ArkeoAuthChainId            string           `json:"arkeo_auth_chain_id,omitempty"`    // Chain ID for auth
 	ArkeoAuthMnemonic           string           `json:"arkeo_auth_mnemonic,omitempty"`    // Mnemonic phrase for signing
 	ArkeoAuthNonceStore         string           `json:"arkeo_auth_nonce_store,omitempty"` // LevelDB path for nonce storage
-	
+
 	// x402 AI Agent Payment Configuration
-	X402Enabled         bool   `json:"x402_enabled,omitempty" yaml:"x402_enabled,omitempty"`                   // Enable x402 payments
-	X402ProviderAddress string `json:"x402_provider_address,omitempty" yaml:"x402_provider_address,omitempty"` // Address to receive payments
-	X402FacilitatorURL  string `json:"x402_facilitator_url,omitempty" yaml:"x402_facilitator_url,omitempty"`   // x402 facilitator URL
-	X402PriceUSDC       string `json:"x402_price_usdc,omitempty" yaml:"x402_price_usdc,omitempty"`             // Price per request in USDC (atomic units)
-	X402PriceARKEO      string `json:"x402_price_arkeo,omitempty" yaml:"x402_price_arkeo,omitempty"`           // Price per request in ARKEO (atomic units)
-	X402ARKEODiscount   int    `json:"x402_arkeo_discount,omitempty" yaml:"x402_arkeo_discount,omitempty"`     // Discount % for ARKEO payments
+	X402Enabled              bool   `json:"x402_enabled,omitempty" yaml:"x402_enabled,omitempty"`                               // Enable x402 payments (legacy, implies production mode)
+	X402Mode                 string `json:"x402_mode,omitempty" yaml:"x402_mode,omitempty"`                                     // x402 mode: "production", "dev" or "off"
+	X402ProviderAddress      string `json:"x402_provider_address,omitempty" yaml:"x402_provider_address,omitempty"`             // Address to receive payments
+	X402FacilitatorURL       string `json:"x402_facilitator_url,omitempty" yaml:"x402_facilitator_url,omitempty"`               // x402 facilitator URL
+	X402Verifier             string `json:"x402_verifier,omitempty" yaml:"x402_verifier,omitempty"`                             // Payment verifier: "facilitator" (default) or "local"
+	X402PaymentStoreLocation string `json:"x402_payment_store_location,omitempty" yaml:"x402_payment_store_location,omitempty"` // LevelDB path for x402 replay protection
+	X402ReceiptStoreLocation string `json:"x402_receipt_store_location,omitempty" yaml:"x402_receipt_store_location,omitempty"` // LevelDB path for x402 payment outcomes
+	X402PriceUSDC            string `json:"x402_price_usdc,omitempty" yaml:"x402_price_usdc,omitempty"`                         // Price per request in USDC (atomic units)
+	X402PriceARKEO           string `json:"x402_price_arkeo,omitempty" yaml:"x402_price_arkeo,omitempty"`                       // Price per request in ARKEO (atomic units)
+	X402ARKEODiscount        int    `json:"x402_arkeo_discount,omitempty" yaml:"x402_arkeo_discount,omitempty"`                 // Discount % for ARKEO payments
+
+	// x402 pricing table keyed by service name; requests to services that are
+	// not listed cost one compute unit
+	X402Pricing map[string]X402ServicePricing `json:"x402_pricing,omitempty" yaml:"x402_pricing,omitempty"`
+
+	// x402 payment options; when empty the legacy USDC/ARKEO options are used
+	X402Accepts []X402AcceptConfig `json:"x402_accepts,omitempty" yaml:"x402_accepts,omitempty"`
+
+	// x402 prepaid sessions
+	X402Sessions X402SessionConfig `json:"x402_sessions,omitempty" yaml:"x402_sessions,omitempty"`
+
+	// x402 WebSocket budgets
+	X402WebSocket X402WebSocketConfig `json:"x402_websocket,omitempty" yaml:"x402_websocket,omitempty"`
+
+	// x402 revenue reported on chain
+	X402Revenue X402RevenueConfig `json:"x402_revenue,omitempty" yaml:"x402_revenue,omitempty"`
+
+	// x402 rate limits and deny list
+	X402Limits X402LimitsConfig `json:"x402_limits,omitempty" yaml:"x402_limits,omitempty"`
+
+	// x402 spend tracking and per payer spend caps
+	X402Spend X402SpendConfig `json:"x402_spend,omitempty" yaml:"x402_spend,omitempty"`
+
+	// health checks and failover of the service upstreams
+	UpstreamHealth UpstreamHealthConfig `json:"upstream_health,omitempty" yaml:"upstream_health,omitempty"`
+
+	// cache of the answers to requests for finalized data
+	ResponseCache ResponseCacheConfig `json:"response_cache,omitempty" yaml:"response_cache,omitempty"`
+
+	// Prometheus metrics
+	Metrics MetricsConfig `json:"metrics,omitempty" yaml:"metrics,omitempty"`
+
+	// log levels, format and access logs
+	Logging LoggingConfig `json:"logging,omitempty" yaml:"logging,omitempty"`
+
+	// seconds in-flight requests are given to finish on shutdown (default 30)
+	ShutdownTimeout uint64 `json:"shutdown_timeout,omitempty" yaml:"shutdown_timeout,omitempty"`
+
+	// admin routes, such as the configuration reload
+	Admin AdminConfig `json:"admin,omitempty" yaml:"admin,omitempty"`
+
+	// timeouts of the HTTP servers
+	Server ServerConfig `json:"server,omitempty" yaml:"server,omitempty"`
+}
+
+// AdminConfig serves the admin routes on an address apart from the public
+// port. Without Listen there are no admin routes.
+type AdminConfig struct {
+	Listen string `json:"listen,omitempty" yaml:"listen,omitempty"` // e.g. "127.0.0.1:9103"
+	Token  string `json:"-" yaml:"token,omitempty"`                 // bearer token the admin routes require (optional)
+
+	// ClientCA serves the admin routes over mutual TLS, requiring client
+	// certificates signed by this PEM bundle. The server certificate is Cert
+	// and Key, or else the one of the public port.
+	ClientCA string `json:"client_ca,omitempty" yaml:"client_ca,omitempty"`
+	Cert     string `json:"cert,omitempty" yaml:"cert,omitempty"`
+	Key      string `json:"key,omitempty" yaml:"key,omitempty"`
+}
+
+// LoggingConfig configures the sentinel logs. Secrets such as arkauth,
+// X-PAYMENT, mnemonics and rpc_url credentials are redacted from them.
+type LoggingConfig struct {
+	Level   string            `json:"level,omitempty" yaml:"level,omitempty"`     // debug, info (default), error or none
+	Format  string            `json:"format,omitempty" yaml:"format,omitempty"`   // plain (default) or json
+	Modules map[string]string `json:"modules,omitempty" yaml:"modules,omitempty"` // level per subsystem, e.g. upstreams: debug
+
+	// AccessLog writes a JSON line per request, in Elastic Common Schema
+	AccessLog AccessLogConfig `json:"access_log,omitempty" yaml:"access_log,omitempty"`
+}
+
+// AccessLogConfig configures the access logs
+type AccessLogConfig struct {
+	Enabled bool   `json:"enabled,omitempty" yaml:"enabled,omitempty"`
+	Output  string `json:"output,omitempty" yaml:"output,omitempty"` // stdout (default), stderr or a file path
+}
+
+// MetricsConfig serves Prometheus metrics on /metrics, on Listen and on the
+// admin routes. They are kept off the public port unless Public is set.
+type MetricsConfig struct {
+	Disabled bool   `json:"disabled,omitempty" yaml:"disabled,omitempty"` // no /metrics route
+	Listen   string `json:"listen,omitempty" yaml:"listen,omitempty"`     // address serving /metrics apart from the public port, e.g. "127.0.0.1:9102"
+	Public   bool   `json:"public,omitempty" yaml:"public,omitempty"`     // also serve /metrics on the public port
+}
+
+// X402SpendConfig tracks what every payer spent per day and month and caps it
+type X402SpendConfig struct {
+	StoreLocation string                  `json:"store_location,omitempty" yaml:"store_location,omitempty"` // LevelDB path, next to the claim store if empty
+	Caps          map[string]X402SpendCap `json:"caps,omitempty" yaml:"caps,omitempty"`                     // CAIP-2 network -> spend caps of a payer on that network
+}
+
+// X402SpendCap caps what a payer spends in the asset of a network, in atomic
+// units. Empty caps are not enforced.
+type X402SpendCap struct {
+	Daily   string `json:"daily,omitempty" yaml:"daily,omitempty"`     // per UTC day
+	Monthly string `json:"monthly,omitempty" yaml:"monthly,omitempty"` // per UTC month
+}
+
+// GetX402SpendStoreLocation returns the spend store path, by default next to
+// the claim store. It is empty, in memory, when neither is set.
+func (c Configuration) GetX402SpendStoreLocation() string {
+	if c.X402Spend.StoreLocation != "" || c.ClaimStoreLocation == "" {
+		return c.X402Spend.StoreLocation
+	}
+	return filepath.Join(filepath.Dir(filepath.Clean(c.ClaimStoreLocation)), "x402_spend")
+}
+
+// X402LimitsConfig bounds what a single agent can ask of the upstream nodes
+// through the x402 routes, paying or not. Zero disables a limit.
+type X402LimitsConfig struct {
+	PayerRequestsPerMinute int      `json:"payer_requests_per_minute,omitempty" yaml:"payer_requests_per_minute,omitempty"` // paid requests per payer address
+	IPRequestsPerMinute    int      `json:"ip_requests_per_minute,omitempty" yaml:"ip_requests_per_minute,omitempty"`       // requests per client IP, including 402 quotes
+	PayerMaxConcurrent     int      `json:"payer_max_concurrent,omitempty" yaml:"payer_max_concurrent,omitempty"`           // in-flight requests and WebSockets per payer address
+	Deny                   []string `json:"deny,omitempty" yaml:"deny,omitempty"`                                           // payer addresses, IPs or CIDRs refused outright
+	TrustedProxies         []string `json:"trusted_proxies,omitempty" yaml:"trusted_proxies,omitempty"`                     // IPs or CIDRs of the proxies whose X-Real-Ip and X-Forwarded-For are believed
+}
+
+// X402WebSocketConfig limits the budgets bought when upgrading an x402 route
+// to a WebSocket. A budget is a number of frames, counted in both directions,
+// or a number of seconds.
+type X402WebSocketConfig struct {
+	DefaultFrames  uint64 `json:"default_frames,omitempty" yaml:"default_frames,omitempty"`     // frames bought when the upgrade names no budget (default 1000)
+	MaxFrames      uint64 `json:"max_frames,omitempty" yaml:"max_frames,omitempty"`             // largest frame budget (default 1000000)
+	MaxSeconds     uint64 `json:"max_seconds,omitempty" yaml:"max_seconds,omitempty"`           // longest time budget (default 86400)
+	UnitsPerSecond uint64 `json:"units_per_second,omitempty" yaml:"units_per_second,omitempty"` // compute units charged per second of a time budget (default 1)
+}
+
+// X402RevenueConfig credits settled x402 payments to a provider ledger that is
+// periodically reported on chain with MsgReportX402Revenue
+type X402RevenueConfig struct {
+	Enabled        bool   `json:"enabled" yaml:"enabled"`
+	StoreLocation  string `json:"store_location,omitempty" yaml:"store_location,omitempty"`   // LevelDB path for the ledger, in memory if empty
+	Mnemonic       string `json:"-" yaml:"mnemonic,omitempty"`                                // mnemonic of provider_pubkey, signs the reports
+	ReportInterval uint64 `json:"report_interval,omitempty" yaml:"report_interval,omitempty"` // seconds between reports (default 3600)
+	Fee            string `json:"fee,omitempty" yaml:"fee,omitempty"`                         // fee of a report tx (default 200uarkeo)
+	Gas            uint64 `json:"gas,omitempty" yaml:"gas,omitempty"`                         // gas limit of a report tx (default 200000)
+}
+
+// X402SessionConfig configures prepaid x402 sessions: a bundle of requests or
+// of time bought with one payment and spent with a bearer token
+type X402SessionConfig struct {
+	Enabled        bool   `json:"enabled" yaml:"enabled"`
+	StoreLocation  string `json:"store_location,omitempty" yaml:"store_location,omitempty"`     // LevelDB path for sessions, in memory if empty
+	Secret         string `json:"-" yaml:"secret,omitempty"`                                    // token signing key, generated and kept in the store if empty
+	MaxRequests    uint64 `json:"max_requests,omitempty" yaml:"max_requests,omitempty"`         // largest request bundle (default 100000)
+	MaxSeconds     uint64 `json:"max_seconds,omitempty" yaml:"max_seconds,omitempty"`           // longest session (default 86400)
+	UnitsPerSecond uint64 `json:"units_per_second,omitempty" yaml:"units_per_second,omitempty"` // compute units charged per second of a time session (default 1)
+}
+
+// X402AcceptConfig is a payment option advertised to x402 clients. Fields
+// left empty on a known network (USDC on Ethereum, Base and their testnets,
+// ARKEO on Arkeo) are filled from built-in presets.
+type X402AcceptConfig struct {
+	Scheme   string            `json:"scheme,omitempty" yaml:"scheme,omitempty"`     // payment scheme, "exact" (default) or "upto"
+	Network  string            `json:"network" yaml:"network"`                       // CAIP-2 network, e.g. "eip155:84532"
+	Asset    string            `json:"asset,omitempty" yaml:"asset,omitempty"`       // token contract or denom
+	Decimals int               `json:"decimals,omitempty" yaml:"decimals,omitempty"` // decimals of the asset
+	PayTo    string            `json:"pay_to,omitempty" yaml:"pay_to,omitempty"`     // recipient, x402_provider_address if unset
+	Price    string            `json:"price" yaml:"price"`                           // price of one compute unit in atomic units
+	Extra    map[string]string `json:"extra,omitempty" yaml:"extra,omitempty"`       // scheme specific extras (EIP-712 name and version)
+}
+
+// X402ServicePricing prices the requests of a service in compute units. A
+// request is charged its compute units times the per request asset price.
+type X402ServicePricing struct {
+	ComputeUnits uint64            `json:"compute_units,omitempty" yaml:"compute_units,omitempty"` // default compute units of a request (1 if unset)
+	Methods      map[string]uint64 `json:"methods,omitempty" yaml:"methods,omitempty"`             // JSON-RPC method -> compute units
+	Paths        []X402PathPricing `json:"paths,omitempty" yaml:"paths,omitempty"`                 // REST path pattern -> compute units, first match wins
+
+	// metered ("upto") payments
+	BytesPerUnit     uint64 `json:"bytes_per_unit,omitempty" yaml:"bytes_per_unit,omitempty"`         // response bytes charged as one extra compute unit (0 to not charge bytes)
+	MaxComputeUnits  uint64 `json:"max_compute_units,omitempty" yaml:"max_compute_units,omitempty"`   // most compute units a metered request is charged
+	MaxResponseBytes int64  `json:"max_response_bytes,omitempty" yaml:"max_response_bytes,omitempty"` // largest metered response buffered before it is charged (default 16MiB)
+}
+
+// X402PathPricing prices REST paths matching Pattern. Patterns use path.Match
+// syntax, a pattern ending in "*" also matches every path below its prefix.
+type X402PathPricing struct {
+	Pattern      string `json:"pattern" yaml:"pattern"`
+	ComputeUnits uint64 `json:"compute_units" yaml:"compute_units"`
+}
+
+// x402 modes
+const (
+	X402ModeProduction = "production" // payments are verified, the sentinel refuses to start without a verifier
+	X402ModeDev        = "dev"        // any payment is accepted, for local development only
+	X402ModeOff        = "off"        // x402 routes are disabled
+)
+
+// GetX402Mode returns the effective x402 mode. When no mode is set the legacy
+// x402_enabled flag selects production mode.
+func (c Configuration) GetX402Mode() string {
+	mode := strings.ToLower(strings.TrimSpace(c.X402Mode))
+	if mode == "" {
+		if c.X402Enabled {
+			return X402ModeProduction
+		}
+		return X402ModeOff
+	}
+	return mode
 }
 
 // Simple helper function to read an environment or return a default value
@@ -116,7 +439,7 @@ func NewTLSConfiguration() TLSConfiguration {
 }
 
 func (c TLSConfiguration) HasTLS() bool {
-	return len(c.Cert) > 0 && len(c.Key) > 0
+	return (len(c.Cert) > 0 && len(c.Key) > 0) || c.ACME.Enabled
 }
 
 func NewConfiguration() Configuration {
@@ -151,6 +474,9 @@ func (c Configuration) Print() {
 	fmt.Fprintln(writer, "Port\t", c.Port)
 	fmt.Fprintln(writer, "TLS Certificate\t", c.TLS.Cert)
 	fmt.Fprintln(writer, "TLS Key\t", c.TLS.Key)
+	if c.TLS.ACME.Enabled {
+		fmt.Fprintln(writer, "ACME Domains\t", strings.Join(c.TLS.ACME.Domains, ","))
+	}
 	fmt.Fprintln(writer, "Source Chain\t", c.SourceChain)
 	fmt.Fprintln(writer, "Event Stream Host\t", c.EventStreamHost)
 	fmt.Fprintln(writer, "Provider PubKey\t", c.ProviderPubKey)
@@ -166,6 +492,12 @@ func (c Configuration) Print() {
 		fmt.Fprintln(writer, "Arkeo Auth Nonce Store\t", c.ArkeoAuthNonceStore)
 	}
 
+	fmt.Fprintln(writer, "x402 Mode\t", c.GetX402Mode())
+	if c.X402Revenue.Enabled {
+		fmt.Fprintln(writer, "x402 Revenue Store Location\t", c.X402Revenue.StoreLocation)
+		fmt.Fprintln(writer, "x402 Revenue Configured\t", c.X402Revenue.Mnemonic != "")
+	}
+
 	writer.Flush()
 }
 
@@ -237,6 +569,28 @@ func LoadConfigurationFromFile(filename string) (Configuration, error) {
 	cfg.ArkeoAuthChainId = overrideString("ArkeoAuthChainId", cfg.ArkeoAuthChainId)
 	cfg.ArkeoAuthMnemonic = overrideString("ArkeoAuthMnemonic", cfg.ArkeoAuthMnemonic)
 	cfg.ArkeoAuthNonceStore = overrideString("ArkeoAuthNonceStore", cfg.ArkeoAuthNonceStore)
+	cfg.X402Mode = overrideString("X402_MODE", cfg.X402Mode)
+	cfg.X402PaymentStoreLocation = overrideString("X402_PAYMENT_STORE_LOCATION", cfg.X402PaymentStoreLocation)
+	cfg.X402ReceiptStoreLocation = overrideString("X402_RECEIPT_STORE_LOCATION", cfg.X402ReceiptStoreLocation)
+	cfg.X402Spend.StoreLocation = overrideString("X402_SPEND_STORE_LOCATION", cfg.X402Spend.StoreLocation)
+	cfg.X402Sessions.StoreLocation = overrideString("X402_SESSION_STORE_LOCATION", cfg.X402Sessions.StoreLocation)
+	cfg.X402Sessions.Secret = overrideString("X402_SESSION_SECRET", cfg.X402Sessions.Secret)
+	cfg.X402Revenue.StoreLocation = overrideString("X402_REVENUE_STORE_LOCATION", cfg.X402Revenue.StoreLocation)
+	cfg.X402Revenue.Mnemonic = overrideString("X402_REVENUE_MNEMONIC", cfg.X402Revenue.Mnemonic)
+	cfg.ResponseCache.StoreLocation = overrideString("RESPONSE_CACHE_STORE_LOCATION", cfg.ResponseCache.StoreLocation)
+	cfg.Metrics.Listen = overrideString("METRICS_LISTEN", cfg.Metrics.Listen)
+	cfg.Logging.Level = overrideString("LOG_LEVEL", cfg.Logging.Level)
+	cfg.Logging.Format = overrideString("LOG_FORMAT", cfg.Logging.Format)
+	cfg.ShutdownTimeout = overrideUint64("SHUTDOWN_TIMEOUT", cfg.ShutdownTimeout)
+	cfg.Admin.Listen = overrideString("ADMIN_LISTEN", cfg.Admin.Listen)
+	cfg.Admin.Token = overrideString("ADMIN_TOKEN", cfg.Admin.Token)
+	cfg.TLS.HTTPSPort = overrideString("TLS_HTTPS_PORT", cfg.TLS.HTTPSPort)
+	cfg.TLS.HTTPPort = overrideString("TLS_HTTP_PORT", cfg.TLS.HTTPPort)
+	cfg.TLS.ACME.Email = overrideString("ACME_EMAIL", cfg.TLS.ACME.Email)
+	cfg.TLS.ACME.EABKeyID = overrideString("ACME_EAB_KEY_ID", cfg.TLS.ACME.EABKeyID)
+	cfg.TLS.ACME.EABHMACKey = overrideString("ACME_EAB_HMAC_KEY", cfg.TLS.ACME.EABHMACKey)
+	cfg.Server.ReadTimeout = overrideUint64("SERVER_READ_TIMEOUT", cfg.Server.ReadTimeout)
+	cfg.Server.WriteTimeout = overrideUint64("SERVER_WRITE_TIMEOUT", cfg.Server.WriteTimeout)
 
 	return cfg, nil
 }
@@ -35,3 +35,10 @@ func TestConfiguration(t *testing.T) {
 	require.Equal(t, config.ContractConfigStoreLocation, "configy")
 	require.Equal(t, config.ProviderConfigStoreLocation, "providy")
 }
+
+func TestGetX402Mode(t *testing.T) {
+	require.Equal(t, X402ModeOff, Configuration{}.GetX402Mode())
+	require.Equal(t, X402ModeProduction, Configuration{X402Enabled: true}.GetX402Mode())
+	require.Equal(t, X402ModeDev, Configuration{X402Enabled: true, X402Mode: "Dev"}.GetX402Mode())
+	require.Equal(t, X402ModeOff, Configuration{X402Mode: "off"}.GetX402Mode())
+}
@@ -5,9 +5,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
-	"os/signal"
 	"strings"
-	"syscall"
 
 	"cosmossdk.io/errors"
 	"github.com/gogo/protobuf/proto"
@@ -67,8 +65,9 @@ func NewTendermintClient(baseURL string, authManager *ArkeoAuthManager) (*tmclie
 	return client, nil
 }
 
-func (p Proxy) EventListener(host string, authManager *ArkeoAuthManager) {
-	logger := p.logger
+// EventListener indexes the chain events until ctx is done
+func (p *Proxy) EventListener(ctx context.Context, host string, authManager *ArkeoAuthManager) {
+	logger := p.logger.With("module", LogModuleEvents)
 
 	logger.Info("starting realtime indexing using /websocket")
 
@@ -85,6 +84,8 @@ func (p Proxy) EventListener(host string, authManager *ArkeoAuthManager) {
 		if err != nil {
 			panic(fmt.Sprintf("error creating tm client for %s: %+v", host, err))
 		}
+		// the client logs its url, arkauth included
+		client.SetLogger(logger)
 		if err = client.Start(); err != nil {
 			panic(fmt.Sprintf("error starting ws client: %s: %+v", host, err))
 		}
@@ -155,14 +156,11 @@ func (p Proxy) EventListener(host string, authManager *ArkeoAuthManager) {
 		}
 	}
 
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-
 	for {
 		select {
 		case result := <-eventChan:
 			dispatchEvents(result)
-		case <-quit:
+		case <-ctx.Done():
 			return
 		}
 	}
@@ -406,8 +404,7 @@ func (p Proxy) handleModProviderEvent(result tmCoreTypes.ResultEvent) {
 		return
 	}
 
-	p.logger.Error(fmt.Sprintf("evt.Provider: %s", evt.Provider))
-	p.logger.Error(fmt.Sprintf("service.String(): %s", service.String()))
+	p.logger.Debug("mod provider event", "pubkey", evt.Provider.String(), "service", service.String())
 
 	providerConfig, err := p.ProviderConfigStore.Get(evt.Provider, service.String())
 	if err != nil {
@@ -196,7 +196,7 @@ func TestHandleHandleContractSettlementEvent(t *testing.T) {
 		Spender:    inputContract.Client,
 		Nonce:      10,
 	}
-	_, err = proxy.paidTier(arkAuth, "")
+	_, err = proxy.paidTier(arkAuth, "", 1)
 	require.NoError(t, err)
 
 	// confirm our claim exists in the claim store
@@ -0,0 +1,341 @@
+package sentinel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+// JSON-RPC 2.0 error codes
+const (
+	JSONRPCParseError     = -32700
+	JSONRPCInvalidRequest = -32600
+	JSONRPCMethodNotFound = -32601
+)
+
+const defaultJSONRPCMaxBatch = 100
+
+// jsonRPCNullID is the id of errors about requests whose id is unknown
+var jsonRPCNullID = json.RawMessage("null")
+
+type jsonRPCContextKey struct{}
+
+// JSONRPCCall is a call of a JSON-RPC request
+type JSONRPCCall struct {
+	JSONRPC string          `json:"jsonrpc,omitempty"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+
+	// err is set for calls that are not valid JSON-RPC
+	err *JSONRPCError
+}
+
+// JSONRPCRequest is a single or batch JSON-RPC request
+type JSONRPCRequest struct {
+	Batch bool
+	Calls []JSONRPCCall
+}
+
+// Methods returns the method of every call
+func (r *JSONRPCRequest) Methods() []string {
+	methods := make([]string, 0, len(r.Calls))
+	for _, call := range r.Calls {
+		methods = append(methods, call.Method)
+	}
+	return methods
+}
+
+// JSONRPCError is the error object of a JSON-RPC response
+type JSONRPCError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *JSONRPCError) Error() string {
+	return fmt.Sprintf("json-rpc error %d: %s", e.Code, e.Message)
+}
+
+// JSONRPCResponse is a JSON-RPC error response
+type JSONRPCResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Error   *JSONRPCError   `json:"error"`
+}
+
+// ParseJSONRPCRequest parses a single or batch JSON-RPC request. Invalid
+// calls of a batch are kept with their error, an error is returned when the
+// body is not JSON or not a request at all.
+func ParseJSONRPCRequest(body []byte) (*JSONRPCRequest, *JSONRPCError) {
+	body = bytes.TrimSpace(body)
+	if !json.Valid(body) {
+		return nil, &JSONRPCError{Code: JSONRPCParseError, Message: "parse error"}
+	}
+	switch {
+	case len(body) > 0 && body[0] == '[':
+		var raw []json.RawMessage
+		if err := json.Unmarshal(body, &raw); err != nil {
+			return nil, &JSONRPCError{Code: JSONRPCParseError, Message: "parse error"}
+		}
+		if len(raw) == 0 {
+			return nil, &JSONRPCError{Code: JSONRPCInvalidRequest, Message: "invalid request: empty batch"}
+		}
+		request := &JSONRPCRequest{Batch: true, Calls: make([]JSONRPCCall, 0, len(raw))}
+		for _, element := range raw {
+			request.Calls = append(request.Calls, parseJSONRPCCall(element))
+		}
+		return request, nil
+	case len(body) > 0 && body[0] == '{':
+		call := parseJSONRPCCall(body)
+		if call.err != nil {
+			return nil, call.err
+		}
+		return &JSONRPCRequest{Calls: []JSONRPCCall{call}}, nil
+	}
+	return nil, &JSONRPCError{Code: JSONRPCInvalidRequest, Message: "invalid request"}
+}
+
+func parseJSONRPCCall(raw json.RawMessage) JSONRPCCall {
+	var call JSONRPCCall
+	if err := json.Unmarshal(raw, &call); err != nil {
+		call.err = &JSONRPCError{Code: JSONRPCInvalidRequest, Message: "invalid request"}
+		return call
+	}
+	if call.Method == "" {
+		call.err = &JSONRPCError{Code: JSONRPCInvalidRequest, Message: "invalid request: missing method"}
+	}
+	return call
+}
+
+// JSONRPCPolicy is the method allow and deny lists and the batch limit of a
+// service
+type JSONRPCPolicy struct {
+	allow    []string
+	deny     []string
+	maxBatch int
+}
+
+// NewJSONRPCPolicy creates the policy of a service from its configuration
+func NewJSONRPCPolicy(config conf.JSONRPCConfig) *JSONRPCPolicy {
+	maxBatch := config.MaxBatch
+	if maxBatch == 0 {
+		maxBatch = defaultJSONRPCMaxBatch
+	}
+	return &JSONRPCPolicy{allow: config.Allow, deny: config.Deny, maxBatch: maxBatch}
+}
+
+// Allowed reports whether a method can be called
+func (p *JSONRPCPolicy) Allowed(method string) bool {
+	for _, pattern := range p.deny {
+		if matchJSONRPCMethod(pattern, method) {
+			return false
+		}
+	}
+	if len(p.allow) == 0 {
+		return true
+	}
+	for _, pattern := range p.allow {
+		if matchJSONRPCMethod(pattern, method) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchJSONRPCMethod(pattern, method string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(method, prefix)
+	}
+	return pattern == method
+}
+
+// Check refuses requests with more calls than the batch limit, invalid calls
+// or calls to methods that are not allowed. A batch is refused as a whole:
+// the invalid or denied calls get their error, the others are not sent.
+func (p *JSONRPCPolicy) Check(request *JSONRPCRequest) (int, interface{}) {
+	if p.maxBatch > 0 && len(request.Calls) > p.maxBatch {
+		return http.StatusRequestEntityTooLarge, JSONRPCResponse{
+			JSONRPC: "2.0",
+			ID:      jsonRPCNullID,
+			Error: &JSONRPCError{
+				Code:    JSONRPCInvalidRequest,
+				Message: fmt.Sprintf("batch of %d calls exceeds the limit of %d", len(request.Calls), p.maxBatch),
+			},
+		}
+	}
+
+	status := 0
+	errs := make([]*JSONRPCError, len(request.Calls))
+	for i, call := range request.Calls {
+		switch {
+		case call.err != nil:
+			errs[i] = call.err
+			status = http.StatusBadRequest
+		case !p.Allowed(call.Method):
+			errs[i] = &JSONRPCError{Code: JSONRPCMethodNotFound, Message: fmt.Sprintf("method %s is not allowed", call.Method)}
+			if status == 0 {
+				status = http.StatusForbidden
+			}
+		}
+	}
+	if status == 0 {
+		return 0, nil
+	}
+	if !request.Batch {
+		return status, JSONRPCResponse{JSONRPC: "2.0", ID: jsonRPCID(request.Calls[0].ID), Error: errs[0]}
+	}
+	responses := make([]JSONRPCResponse, 0, len(request.Calls))
+	for i, call := range request.Calls {
+		err := errs[i]
+		if err == nil {
+			err = &JSONRPCError{Code: JSONRPCInvalidRequest, Message: "batch refused"}
+		}
+		responses = append(responses, JSONRPCResponse{JSONRPC: "2.0", ID: jsonRPCID(call.ID), Error: err})
+	}
+	return status, responses
+}
+
+func jsonRPCID(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return jsonRPCNullID
+	}
+	return id
+}
+
+// isJSONRPCProbe reports whether the upstreams probed with probe speak
+// JSON-RPC
+func isJSONRPCProbe(probe string) bool {
+	return !strings.HasPrefix(probe, "/") || probe == UpstreamProbeCosmos
+}
+
+// loadJSONRPCPolicies builds the policy of the configured services speaking
+// JSON-RPC, or with allow or deny lists. Services that turned the inspection
+// off have a nil policy.
+func loadJSONRPCPolicies(config conf.Configuration) map[string]*JSONRPCPolicy {
+	policies := make(map[string]*JSONRPCPolicy)
+	for _, svc := range config.Services {
+		probe := svc.HealthCheck
+		if probe == "" {
+			probe = defaultUpstreamProbe(svc.Name)
+		}
+		switch {
+		case svc.JSONRPC.Disabled:
+			policies[svc.Name] = nil
+		case isJSONRPCProbe(probe), len(svc.JSONRPC.Allow) > 0, len(svc.JSONRPC.Deny) > 0, svc.JSONRPC.MaxBatch != 0:
+			policies[svc.Name] = NewJSONRPCPolicy(svc.JSONRPC)
+		}
+	}
+	return policies
+}
+
+// jsonRPCPolicy returns the policy of a service, nil when its requests are
+// not inspected
+func (p *Proxy) jsonRPCPolicy(service string) *JSONRPCPolicy {
+	p.serviceMu.RLock()
+	policy, ok := p.jsonRPCPolicies[service]
+	p.serviceMu.RUnlock()
+	if ok {
+		return policy
+	}
+	if pool := p.upstreamPool(service); pool != nil && isJSONRPCProbe(pool.Probe) {
+		return NewJSONRPCPolicy(conf.JSONRPCConfig{})
+	}
+	return nil
+}
+
+// inspectJSONRPC parses the JSON-RPC requests to the services speaking it,
+// answers malformed and refused requests with JSON-RPC errors, and keeps the
+// parsed request in the context so each call of a batch is counted
+func (p *Proxy) inspectJSONRPC(serviceName func(r *http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Body == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		service := serviceName(r)
+		policy := p.jsonRPCPolicy(service)
+		if policy == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// the body limit of the proxied requests
+		limit := p.maxBodySize(service)
+		body, err := io.ReadAll(io.LimitReader(r.Body, limit+1))
+		if err != nil {
+			writeJSONRPCError(w, http.StatusBadRequest, &JSONRPCError{Code: JSONRPCInvalidRequest, Message: "failed to read request"})
+			return
+		}
+		if int64(len(body)) > limit {
+			writeJSONRPCError(w, http.StatusRequestEntityTooLarge, &JSONRPCError{Code: JSONRPCInvalidRequest, Message: "request too large"})
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		request, rpcErr := ParseJSONRPCRequest(body)
+		if rpcErr != nil {
+			p.logger.Info("json-rpc request refused", "service", service, "error", rpcErr)
+			writeJSONRPCError(w, http.StatusBadRequest, rpcErr)
+			return
+		}
+		if status, answer := policy.Check(request); answer != nil {
+			p.logger.Info("json-rpc request refused", "service", service, "calls", len(request.Calls), "status", status)
+			writeJSONRPC(w, status, answer)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), jsonRPCContextKey{}, request)))
+	})
+}
+
+// JSONRPCFromContext returns the JSON-RPC request parsed by the inspection
+func JSONRPCFromContext(ctx context.Context) (*JSONRPCRequest, bool) {
+	request, ok := ctx.Value(jsonRPCContextKey{}).(*JSONRPCRequest)
+	return request, ok
+}
+
+// jsonRPCCalls returns how many requests a request is worth: the calls of a
+// JSON-RPC batch, 1 otherwise
+func jsonRPCCalls(r *http.Request) int64 {
+	if request, ok := JSONRPCFromContext(r.Context()); ok && len(request.Calls) > 0 {
+		return int64(len(request.Calls))
+	}
+	return 1
+}
+
+// requestServiceName returns the service of a proxied request, from the
+// arkservice header or the first path segment
+func requestServiceName(r *http.Request) string {
+	if service := r.Header.Get(ServiceHeader); service != "" {
+		return service
+	}
+	parts := strings.Split(r.URL.Path, "/")
+	if len(parts) > 1 {
+		return parts[1]
+	}
+	return ""
+}
+
+// x402ServiceName returns the service of an /x402/{service}/... request
+func x402ServiceName(r *http.Request) string {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) >= 2 {
+		return parts[1]
+	}
+	return ""
+}
+
+func writeJSONRPCError(w http.ResponseWriter, status int, err *JSONRPCError) {
+	writeJSONRPC(w, status, JSONRPCResponse{JSONRPC: "2.0", ID: jsonRPCNullID, Error: err})
+}
+
+func writeJSONRPC(w http.ResponseWriter, status int, answer interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(answer)
+}
@@ -0,0 +1,108 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+func TestParseJSONRPCRequest(t *testing.T) {
+	request, err := ParseJSONRPCRequest([]byte(` {"jsonrpc":"2.0","id":7,"method":"eth_chainId"} `))
+	require.Nil(t, err)
+	require.False(t, request.Batch)
+	require.Equal(t, []string{"eth_chainId"}, request.Methods())
+
+	request, err = ParseJSONRPCRequest([]byte(`[{"id":1,"method":"eth_chainId"},{"id":2,"method":"getblockcount"},3]`))
+	require.Nil(t, err)
+	require.True(t, request.Batch)
+	require.Len(t, request.Calls, 3)
+	require.Nil(t, request.Calls[1].err)
+	require.Equal(t, JSONRPCInvalidRequest, request.Calls[2].err.Code)
+
+	for body, code := range map[string]int{
+		`{"id":1,"method":`: JSONRPCParseError,
+		`[]`:                JSONRPCInvalidRequest,
+		`{"id":1}`:          JSONRPCInvalidRequest,
+		`"eth_chainId"`:     JSONRPCInvalidRequest,
+	} {
+		_, err := ParseJSONRPCRequest([]byte(body))
+		require.NotNil(t, err, body)
+		require.Equal(t, code, err.Code, body)
+	}
+}
+
+func TestJSONRPCPolicy(t *testing.T) {
+	policy := NewJSONRPCPolicy(conf.JSONRPCConfig{Deny: []string{"debug_*", "admin_*"}, MaxBatch: 2})
+	require.True(t, policy.Allowed("eth_call"))
+	require.False(t, policy.Allowed("debug_traceTransaction"))
+
+	policy = NewJSONRPCPolicy(conf.JSONRPCConfig{Allow: []string{"eth_*", "net_version"}, Deny: []string{"eth_sign"}})
+	require.True(t, policy.Allowed("eth_getLogs"))
+	require.True(t, policy.Allowed("net_version"))
+	require.False(t, policy.Allowed("eth_sign"))
+	require.False(t, policy.Allowed("web3_clientVersion"))
+}
+
+func TestInspectJSONRPC(t *testing.T) {
+	proxy := &Proxy{
+		logger:  log.NewNopLogger(),
+		proxies: map[string]*url.URL{"eth-mainnet-fullnode": {Scheme: "http", Host: "eth"}, "gaia-mainnet-rest": {Scheme: "http", Host: "gaia"}},
+		jsonRPCPolicies: loadJSONRPCPolicies(conf.Configuration{Services: []conf.ServiceConfig{
+			{Name: "eth-mainnet-fullnode", JSONRPC: conf.JSONRPCConfig{Deny: []string{"debug_*"}, MaxBatch: 3}},
+		}}),
+	}
+	var calls int64
+	var units uint64
+	handler := proxy.inspectJSONRPC(requestServiceName, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls = jsonRPCCalls(r)
+		units = NewX402Pricing(nil).ComputeUnits(requestServiceName(r), r)
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func(service, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/"+service, strings.NewReader(body)))
+		return w
+	}
+
+	// batches are counted per call
+	w := send("eth-mainnet-fullnode", `[{"id":1,"method":"eth_chainId"},{"id":2,"method":"eth_blockNumber"}]`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, int64(2), calls)
+	require.Equal(t, uint64(2), units)
+
+	// denied methods refuse the whole batch
+	w = send("eth-mainnet-fullnode", `[{"id":1,"method":"eth_chainId"},{"id":"a","method":"debug_traceTransaction"}]`)
+	require.Equal(t, http.StatusForbidden, w.Code)
+	var responses []JSONRPCResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &responses))
+	require.Len(t, responses, 2)
+	require.Equal(t, `"a"`, string(responses[1].ID))
+	require.Equal(t, JSONRPCMethodNotFound, responses[1].Error.Code)
+	require.Equal(t, JSONRPCInvalidRequest, responses[0].Error.Code)
+
+	// batch cap
+	w = send("eth-mainnet-fullnode", `[{"method":"a"},{"method":"b"},{"method":"c"},{"method":"d"}]`)
+	require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+
+	// malformed requests get a JSON-RPC error
+	w = send("eth-mainnet-fullnode", `{"jsonrpc":"2.0",`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var response JSONRPCResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, JSONRPCParseError, response.Error.Code)
+	require.Equal(t, "null", string(response.ID))
+
+	// REST services are not inspected
+	calls = 0
+	w = send("gaia-mainnet-rest", `{"tx_bytes":"..."}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, int64(1), calls)
+}
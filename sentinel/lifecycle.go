@@ -0,0 +1,246 @@
+package sentinel
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+const (
+	// RoutesAdminReload reloads the configuration file
+	RoutesAdminReload = "/admin/reload"
+
+	// RoutesAdminX402Uncollected lists the x402 payments accepted by the local
+	// verifier whose authorization was not submitted on chain yet
+	RoutesAdminX402Uncollected = "/admin/x402/uncollected"
+
+	// RoutesAdminX402Collected marks x402 payments as submitted on chain
+	RoutesAdminX402Collected = "/admin/x402/collected"
+
+	// defaultShutdownTimeout is how long in-flight requests are given to
+	// finish on shutdown
+	defaultShutdownTimeout = 30 * time.Second
+)
+
+// currentConfig returns the configuration, services, pricing and rate limits
+// as last reloaded
+func (p *Proxy) currentConfig() conf.Configuration {
+	p.configMu.RLock()
+	defer p.configMu.RUnlock()
+	return p.Config
+}
+
+// serviceConfig returns the configuration of a service
+func (p *Proxy) serviceConfig(service string) (conf.ServiceConfig, bool) {
+	p.configMu.RLock()
+	defer p.configMu.RUnlock()
+	for _, svc := range p.Config.Services {
+		if svc.Name == service {
+			return svc, true
+		}
+	}
+	return conf.ServiceConfig{}, false
+}
+
+// ReloadFromFile reloads the configuration file the sentinel was started
+// with
+func (p *Proxy) ReloadFromFile() error {
+	if p.ConfigFile == "" {
+		return fmt.Errorf("no configuration file to reload")
+	}
+	config, err := conf.LoadConfigurationFromFile(p.ConfigFile)
+	if err != nil {
+		return fmt.Errorf("failed to load %s: %w", p.ConfigFile, err)
+	}
+	return p.Reload(config)
+}
+
+// Reload applies the services, the free tier rate limit and the x402
+// pricing and limits of config while serving. Requests in flight finish with
+// the upstreams they started with. The certificate files are read again.
+// The other settings, such as the ports and stores, need a restart.
+func (p *Proxy) Reload(config conf.Configuration) error {
+	if len(config.Services) == 0 {
+		return fmt.Errorf("configuration has no services")
+	}
+	var accepts []PaymentRequirements
+	if p.x402 != nil {
+		// check everything before swapping anything
+		if _, err := NewX402Limits(config.X402Limits); err != nil {
+			return err
+		}
+		if len(config.X402Accepts) > 0 {
+			var err error
+			accepts, err = resolveX402Accepts(config.X402Accepts, config.X402ProviderAddress, p.x402.ArkeoAddress)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	p.serviceMu.RLock()
+	serviceIDs := p.serviceIDs
+	p.serviceMu.RUnlock()
+	proxies := loadProxies(config, p.logger, serviceIDs)
+	p.swapUpstreams(proxies, loadUpstreams(config, p.logger, proxies))
+
+	p.serviceMu.Lock()
+	p.jsonRPCPolicies = loadJSONRPCPolicies(config)
+	p.serviceMu.Unlock()
+
+	p.configMu.Lock()
+	freeTierChanged := p.Config.FreeTierRateLimit != config.FreeTierRateLimit
+	p.Config.Services = config.Services
+	p.Config.FreeTierRateLimit = config.FreeTierRateLimit
+	p.Config.X402Pricing = config.X402Pricing
+	p.Config.X402PriceUSDC = config.X402PriceUSDC
+	p.Config.X402PriceARKEO = config.X402PriceARKEO
+	p.Config.X402Accepts = config.X402Accepts
+	p.Config.X402Limits = config.X402Limits
+	p.Metadata = NewMetadata(p.Config)
+	p.configMu.Unlock()
+	if freeTierChanged {
+		resetFreeTierLimiters()
+	}
+
+	if p.x402 != nil {
+		p.x402.Pricing.Update(config.X402Pricing)
+		p.x402.SetPrices(config.X402PriceUSDC, config.X402PriceARKEO, accepts)
+		if err := p.x402.Limits.Update(config.X402Limits); err != nil {
+			return err
+		}
+	}
+
+	p.reloadCertificates()
+
+	p.logger.Info("configuration reloaded", "services", len(config.Services))
+	return nil
+}
+
+// swapUpstreams replaces the proxies and upstream pools of the services.
+// Pools with the same upstreams are kept, with their health.
+func (p *Proxy) swapUpstreams(proxies map[string]*url.URL, upstreams map[string]*UpstreamPool) {
+	p.proxyMu.Lock()
+	defer p.proxyMu.Unlock()
+	for name, pool := range p.upstreams {
+		if next, ok := upstreams[name]; ok && pool.sameUpstreams(next) {
+			upstreams[name] = pool
+		}
+	}
+	p.proxies = proxies
+	p.upstreams = upstreams
+}
+
+// reloadOnSignal reloads the configuration file on SIGHUP until ctx is done
+func (p *Proxy) reloadOnSignal(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			if err := p.ReloadFromFile(); err != nil {
+				p.logger.Error("failed to reload configuration", "error", err)
+			}
+		}
+	}
+}
+
+// getAdminRouter returns the admin routes, served apart from the public port
+func (p *Proxy) getAdminRouter() *mux.Router {
+	router := mux.NewRouter()
+	router.Handle(RoutesAdminReload, p.adminAuth(http.HandlerFunc(p.handleReload))).Methods(http.MethodPost)
+	router.Handle(RoutesAdminX402Uncollected, p.adminAuth(http.HandlerFunc(p.handleX402Uncollected))).Methods(http.MethodGet)
+	router.Handle(RoutesAdminX402Collected, p.adminAuth(http.HandlerFunc(p.handleX402Collected))).Methods(http.MethodPost)
+	if !p.Config.Metrics.Disabled {
+		router.Handle(RoutesMetrics, p.adminAuth(p.handleMetrics())).Methods(http.MethodGet)
+	}
+	return router
+}
+
+// adminAuth requires the admin token, when configured, as a bearer token
+func (p *Proxy) adminAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := p.Config.Admin.Token
+		if token != "" {
+			given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				respondWithError(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// handleReload reloads the configuration file
+func (p *Proxy) handleReload(w http.ResponseWriter, r *http.Request) {
+	if err := p.ReloadFromFile(); err != nil {
+		p.logger.Error("failed to reload configuration", "error", err)
+		respondWithError(w, fmt.Sprintf("failed to reload configuration: %s", err), http.StatusInternalServerError)
+		return
+	}
+	respondWithJSON(w, http.StatusOK, map[string]int{"services": len(p.currentConfig().Services)})
+}
+
+// shutdownTimeout returns how long in-flight requests are given on shutdown
+func (p *Proxy) shutdownTimeout() time.Duration {
+	if p.Config.ShutdownTimeout == 0 {
+		return defaultShutdownTimeout
+	}
+	return time.Duration(p.Config.ShutdownTimeout) * time.Second
+}
+
+// shutdownServers stops the servers from accepting connections and waits
+// for their requests in flight, until the shutdown timeout
+func (p *Proxy) shutdownServers(servers []*http.Server) {
+	ctx, cancel := context.WithTimeout(context.Background(), p.shutdownTimeout())
+	defer cancel()
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			p.logger.Error("failed to drain server", "addr", server.Addr, "error", err)
+		}
+	}
+}
+
+// Close closes the stores of the sentinel
+func (p *Proxy) Close() error {
+	var errs []error
+	if p.ClaimStore != nil {
+		errs = append(errs, p.ClaimStore.Close())
+	}
+	if p.ContractConfigStore != nil {
+		errs = append(errs, p.ContractConfigStore.Close())
+	}
+	if p.ProviderConfigStore != nil {
+		errs = append(errs, p.ProviderConfigStore.Close())
+	}
+	if p.authManager != nil {
+		// closes the nonce store
+		errs = append(errs, p.authManager.Close())
+	}
+	if p.cache != nil {
+		errs = append(errs, p.cache.Close())
+	}
+	if p.x402 != nil {
+		errs = append(errs, p.x402.Close())
+	}
+	if p.accessLog != nil {
+		errs = append(errs, p.accessLog.Close())
+	}
+	return errors.Join(errs...)
+}
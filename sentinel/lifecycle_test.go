@@ -0,0 +1,190 @@
+package sentinel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+func TestReload(t *testing.T) {
+	config := conf.Configuration{
+		FreeTierRateLimit: 10,
+		Services: []conf.ServiceConfig{
+			{Name: "eth-mainnet-fullnode", RpcUrl: "http://eth-1:8545"},
+			{Name: "btc-mainnet-fullnode", RpcUrl: "http://btc-1:8332"},
+		},
+	}
+	serviceIDs := map[string]int32{"eth-mainnet-fullnode": 1, "btc-mainnet-fullnode": 2}
+	proxies := loadProxies(config, log.NewNopLogger(), serviceIDs)
+	limits, err := NewX402Limits(conf.X402LimitsConfig{IPRequestsPerMinute: 1})
+	require.NoError(t, err)
+	proxy := &Proxy{
+		Config:     config,
+		logger:     log.NewNopLogger(),
+		proxies:    proxies,
+		upstreams:  loadUpstreams(config, log.NewNopLogger(), proxies),
+		serviceIDs: serviceIDs,
+		x402: &X402Handler{
+			AcceptUSDC:          true,
+			PricePerRequestUSDC: "1000",
+			Pricing:             NewX402Pricing(nil),
+			Limits:              limits,
+		},
+	}
+	eth := proxy.upstreamPool("eth-mainnet-fullnode")
+	btc := proxy.upstreamPool("btc-mainnet-fullnode")
+	require.NoError(t, proxy.x402.Limits.AllowIP("1.2.3.4"))
+	require.Error(t, proxy.x402.Limits.AllowIP("1.2.3.4"))
+
+	reloaded := conf.Configuration{
+		FreeTierRateLimit: 20,
+		X402PriceUSDC:     "2000",
+		X402Pricing:       map[string]conf.X402ServicePricing{"eth-mainnet-fullnode": {ComputeUnits: 5}},
+		X402Limits:        conf.X402LimitsConfig{IPRequestsPerMinute: 2, Deny: []string{"10.0.0.0/8"}},
+		Services: []conf.ServiceConfig{
+			{Name: "eth-mainnet-fullnode", RpcUrl: "http://eth-1:8545"},
+			{Name: "btc-mainnet-fullnode", RpcUrl: "http://btc-2:8332"},
+		},
+	}
+	require.NoError(t, proxy.Reload(reloaded))
+
+	// unchanged upstreams keep their pool and health, changed ones are swapped
+	require.Same(t, eth, proxy.upstreamPool("eth-mainnet-fullnode"))
+	require.NotSame(t, btc, proxy.upstreamPool("btc-mainnet-fullnode"))
+	require.Equal(t, "btc-2:8332", proxy.upstreamPool("btc-mainnet-fullnode").upstreams[0].URL.Host)
+
+	require.Equal(t, 20, proxy.currentConfig().FreeTierRateLimit)
+	require.Equal(t, 20, proxy.Metadata.Configuration.FreeTierRateLimit)
+	require.Equal(t, uint64(5), proxy.x402.Pricing.DefaultUnits("eth-mainnet-fullnode"))
+	require.Equal(t, "2000", proxy.x402.paymentOptions()[0].Amount)
+	require.Error(t, proxy.x402.Limits.AllowIP("10.1.1.1"))
+	require.NoError(t, proxy.x402.Limits.AllowIP("1.2.3.4"))
+
+	// a bad configuration changes nothing
+	require.Error(t, proxy.Reload(conf.Configuration{}))
+	bad := reloaded
+	bad.X402Limits = conf.X402LimitsConfig{Deny: []string{"10.0.0.0/99"}}
+	require.Error(t, proxy.Reload(bad))
+	require.Error(t, proxy.x402.Limits.AllowIP("10.1.1.1"))
+}
+
+func TestReloadMetadata(t *testing.T) {
+	config := conf.Configuration{
+		Services: []conf.ServiceConfig{{Name: "eth-mainnet-fullnode", RpcUrl: "http://eth-1:8545"}},
+	}
+	serviceIDs := map[string]int32{"eth-mainnet-fullnode": 1}
+	proxies := loadProxies(config, log.NewNopLogger(), serviceIDs)
+	proxy := &Proxy{
+		Config:     config,
+		Metadata:   NewMetadata(config),
+		logger:     log.NewNopLogger(),
+		proxies:    proxies,
+		upstreams:  loadUpstreams(config, log.NewNopLogger(), proxies),
+		serviceIDs: serviceIDs,
+	}
+
+	// metadata is served from one snapshot while reloads replace it
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			reloaded := config
+			reloaded.Moniker = "reloaded"
+			require.NoError(t, proxy.Reload(reloaded))
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		w := httptest.NewRecorder()
+		proxy.handleMetadata(w, httptest.NewRequest(http.MethodGet, RoutesMetaData, nil))
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+	<-done
+}
+
+func TestAdminReload(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sentinel.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+x402_price_usdc: "3000"
+services:
+  - name: eth-mainnet-fullnode
+    rpc_url: http://eth-2:8545
+`), 0o600))
+
+	proxy := &Proxy{
+		Config:     conf.Configuration{Admin: conf.AdminConfig{Token: "s3cret"}},
+		ConfigFile: file,
+		logger:     log.NewNopLogger(),
+		serviceIDs: map[string]int32{"eth-mainnet-fullnode": 1},
+	}
+	router := proxy.getAdminRouter()
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, RoutesAdminReload, nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, RoutesAdminReload, nil)
+	r.Header.Set("Authorization", "Bearer s3cret")
+	router.ServeHTTP(w, r)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"services":1}`, w.Body.String())
+	require.Equal(t, "3000", proxy.currentConfig().X402PriceUSDC)
+	require.Equal(t, "eth-2:8545", proxy.upstreamPool("eth-mainnet-fullnode").upstreams[0].URL.Host)
+}
+
+func TestClose(t *testing.T) {
+	claims, err := NewClaimStore(t.TempDir())
+	require.NoError(t, err)
+	contracts, err := NewContractConfigurationStore(t.TempDir())
+	require.NoError(t, err)
+	providers, err := NewProviderConfigurationStore(t.TempDir())
+	require.NoError(t, err)
+	payments, err := NewX402PaymentStore(t.TempDir())
+	require.NoError(t, err)
+	proxy := &Proxy{
+		ClaimStore:          claims,
+		ContractConfigStore: contracts,
+		ProviderConfigStore: providers,
+		x402:                &X402Handler{Store: payments},
+	}
+	require.NoError(t, proxy.Close())
+	require.Error(t, claims.Set(NewClaim(1, nil, 1, "")))
+}
+
+func TestReloadFreeTierRateLimit(t *testing.T) {
+	config := conf.Configuration{
+		FreeTierRateLimit: 1,
+		Services:          []conf.ServiceConfig{{Name: "eth-mainnet-fullnode", RpcUrl: "http://eth-1:8545"}},
+	}
+	proxy := &Proxy{
+		Config:     config,
+		logger:     log.NewNopLogger(),
+		MemStore:   NewMemStore("", nil, log.NewNopLogger()),
+		serviceIDs: map[string]int32{"eth-mainnet-fullnode": 1},
+	}
+	// the router is built once, before any reload
+	handler := proxy.auth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func() int {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/eth-mainnet-fullnode", nil)
+		r.Header.Set("X-Real-Ip", "198.51.100.23")
+		handler.ServeHTTP(w, r)
+		return w.Code
+	}
+	require.Equal(t, http.StatusOK, serve())
+	require.Equal(t, http.StatusTooManyRequests, serve())
+
+	config.FreeTierRateLimit = 3
+	require.NoError(t, proxy.Reload(config))
+	for i := 0; i < 3; i++ {
+		require.Equal(t, http.StatusOK, serve())
+	}
+	require.Equal(t, http.StatusTooManyRequests, serve())
+}
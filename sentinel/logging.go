@@ -0,0 +1,499 @@
+package sentinel
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/cosmos/go-bip39"
+	"github.com/rs/zerolog"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+const (
+	// RequestIDHeader carries the id of a request, it is kept when sent by
+	// the client and forwarded to the upstream
+	RequestIDHeader = "X-Request-ID"
+
+	// maxRequestIDLength bounds the request ids accepted from clients
+	maxRequestIDLength = 128
+
+	// redacted replaces the secrets in the logs
+	redacted = "REDACTED"
+)
+
+// log subsystems, their level is set with logging.modules
+const (
+	LogModuleUpstreams = "upstreams"
+	LogModuleEvents    = "events"
+	LogModuleMemStore  = "memstore"
+	LogModuleX402      = "x402"
+	LogModuleHTTP      = "http"
+	LogModuleStore     = "store" // the LevelDB stores
+)
+
+// log formats
+const (
+	LogFormatPlain = "plain"
+	LogFormatJSON  = "json"
+)
+
+// NewLogger returns the sentinel logger writing to w, with the level and
+// format of config. A subsystem gets its own level through
+// logger.With("module", name). Secrets are redacted from every entry.
+func NewLogger(config conf.LoggingConfig, w io.Writer) (log.Logger, error) {
+	var base log.Logger
+	switch strings.ToLower(config.Format) {
+	case "", LogFormatPlain:
+		base = log.NewTMLogger(log.NewSyncWriter(w))
+	case LogFormatJSON:
+		base = log.NewTMJSONLogger(log.NewSyncWriter(w))
+	default:
+		return nil, fmt.Errorf("unknown log format %q", config.Format)
+	}
+
+	level := config.Level
+	if level == "" {
+		level = "info"
+	}
+	option, err := log.AllowLevel(strings.ToLower(level))
+	if err != nil {
+		return nil, err
+	}
+	options := []log.Option{option}
+	for module, moduleLevel := range config.Modules {
+		if module == LogModuleStore {
+			continue
+		}
+		option, err := allowModule(module, moduleLevel)
+		if err != nil {
+			return nil, err
+		}
+		options = append(options, option)
+	}
+
+	storeLevel := level
+	if moduleLevel, ok := config.Modules[LogModuleStore]; ok {
+		storeLevel = moduleLevel
+	}
+	if err := setStoreLogLevel(storeLevel); err != nil {
+		return nil, err
+	}
+
+	return redactingLogger{next: log.NewFilter(base, options...)}, nil
+}
+
+// allowModule sets the level of a subsystem
+func allowModule(module, level string) (log.Option, error) {
+	switch strings.ToLower(level) {
+	case "debug":
+		return log.AllowDebugWith("module", module), nil
+	case "info":
+		return log.AllowInfoWith("module", module), nil
+	case "error":
+		return log.AllowErrorWith("module", module), nil
+	case "none":
+		return log.AllowNoneWith("module", module), nil
+	}
+	return nil, fmt.Errorf("unknown log level %q for module %s (use debug, info, error or none)", level, module)
+}
+
+// setStoreLogLevel sets the level of the LevelDB stores, which log with
+// zerolog
+func setStoreLogLevel(level string) error {
+	switch strings.ToLower(level) {
+	case "debug":
+		zerolog.SetGlobalLevel(zerolog.DebugLevel)
+	case "info":
+		zerolog.SetGlobalLevel(zerolog.InfoLevel)
+	case "error":
+		zerolog.SetGlobalLevel(zerolog.ErrorLevel)
+	case "none":
+		zerolog.SetGlobalLevel(zerolog.Disabled)
+	default:
+		return fmt.Errorf("unknown log level %q for module %s (use debug, info, error or none)", level, LogModuleStore)
+	}
+	return nil
+}
+
+// redactingLogger removes the secrets from the values it logs
+type redactingLogger struct {
+	next log.Logger
+}
+
+func (l redactingLogger) Debug(msg string, keyvals ...interface{}) {
+	l.next.Debug(RedactString(msg), redactKeyvals(keyvals)...)
+}
+
+func (l redactingLogger) Info(msg string, keyvals ...interface{}) {
+	l.next.Info(RedactString(msg), redactKeyvals(keyvals)...)
+}
+
+func (l redactingLogger) Error(msg string, keyvals ...interface{}) {
+	l.next.Error(RedactString(msg), redactKeyvals(keyvals)...)
+}
+
+func (l redactingLogger) With(keyvals ...interface{}) log.Logger {
+	return redactingLogger{next: l.next.With(redactKeyvals(keyvals)...)}
+}
+
+// sensitiveLogKeys are the keys, lower cased without separators, whose
+// values are never logged
+var sensitiveLogKeys = map[string]bool{
+	"arkauth":            true,
+	"arkcontract":        true,
+	"auth":               true,
+	"xpayment":           true,
+	"authorization":      true,
+	"proxyauthorization": true,
+	"cookie":             true,
+	"setcookie":          true,
+	"rpcpass":            true,
+	"secret":             true,
+	"signature":          true,
+}
+
+// isSensitiveLogKey tells if the value of key is a secret
+func isSensitiveLogKey(key string) bool {
+	key = strings.ToLower(key)
+	key = strings.NewReplacer("-", "", "_", "", " ", "").Replace(key)
+	if sensitiveLogKeys[key] {
+		return true
+	}
+	for _, part := range []string{"mnemonic", "password", "passphrase", "privkey", "privatekey"} {
+		if strings.Contains(key, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactKeyvals returns a copy of keyvals with the secrets redacted
+func redactKeyvals(keyvals []interface{}) []interface{} {
+	out := make([]interface{}, len(keyvals))
+	for i := 0; i < len(keyvals); i++ {
+		if i%2 == 1 {
+			if key, ok := keyvals[i-1].(string); ok && isSensitiveLogKey(key) {
+				out[i] = redacted
+				continue
+			}
+		}
+		out[i] = redactValue(keyvals[i])
+	}
+	return out
+}
+
+// redactValue redacts a logged value, the types that may hold secrets are
+// turned into strings
+func redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil, bool, int, int32, int64, uint, uint32, uint64, float64, time.Time, time.Duration:
+		return v
+	case string:
+		return RedactString(v)
+	case []byte:
+		return RedactString(string(v))
+	case *url.URL:
+		if v == nil {
+			return v
+		}
+		return RedactURL(v.String())
+	case url.URL:
+		return RedactURL(v.String())
+	case http.Header:
+		return RedactHeader(v)
+	case error:
+		return errors.New(RedactString(v.Error()))
+	case fmt.Stringer:
+		return RedactString(v.String())
+	}
+	return RedactString(fmt.Sprintf("%+v", value))
+}
+
+var (
+	// urlUserinfo matches the credentials of a URL, as in rpc_url
+	urlUserinfo = regexp.MustCompile(`([A-Za-z][A-Za-z0-9+.\-]*://)[^/@\s"']+@`)
+
+	// secretParams matches the secrets sent as query parameters
+	secretParams = regexp.MustCompile(`(?i)\b((?:arkauth|arkcontract|x-payment|mnemonic|password|rpc_pass)=)[^&\s"']+`)
+
+	// secretFields matches the secrets of JSON documents
+	secretFields = regexp.MustCompile(`(?i)("(?:arkauth|arkcontract|x-payment|mnemonic|password|rpc_pass)"\s*:\s*")[^"]*`)
+
+	// mnemonicWords matches 12 or more short lower case words, which may
+	// hold a BIP-39 mnemonic
+	mnemonicWords = regexp.MustCompile(`\b(?:[a-z]{3,8} ){11,}[a-z]{3,8}\b`)
+)
+
+// mnemonicLength is the fewest words of a BIP-39 mnemonic
+const mnemonicLength = 12
+
+// redactMnemonics redacts the runs of 12 or more words of the BIP-39
+// wordlist in s, other text is kept
+func redactMnemonics(s string) string {
+	return mnemonicWords.ReplaceAllStringFunc(s, func(match string) string {
+		words := strings.Split(match, " ")
+		out := make([]string, 0, len(words))
+		start := 0
+		flush := func(end int) {
+			if end-start >= mnemonicLength {
+				out = append(out, redacted)
+			} else {
+				out = append(out, words[start:end]...)
+			}
+		}
+		for i, word := range words {
+			if _, ok := bip39.ReverseWordMap[word]; !ok {
+				flush(i)
+				out = append(out, word)
+				start = i + 1
+			}
+		}
+		flush(len(words))
+		return strings.Join(out, " ")
+	})
+}
+
+// RedactString removes URL credentials, arkauth, arkcontract and X-PAYMENT
+// values and mnemonics from s
+func RedactString(s string) string {
+	if s == "" {
+		return s
+	}
+	s = urlUserinfo.ReplaceAllString(s, "${1}"+redacted+"@")
+	s = secretParams.ReplaceAllString(s, "${1}"+redacted)
+	s = secretFields.ReplaceAllString(s, "${1}"+redacted)
+	return redactMnemonics(s)
+}
+
+// RedactURL removes the credentials and the secret query parameters of a
+// URL
+func RedactURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return RedactString(raw)
+	}
+	if u.User != nil {
+		u.User = url.User(redacted)
+	}
+	if u.RawQuery != "" {
+		u.RawQuery = RedactQuery(u.RawQuery)
+	}
+	return u.String()
+}
+
+// RedactQuery redacts the secret parameters of a raw query
+func RedactQuery(raw string) string {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		return RedactString(raw)
+	}
+	for key := range values {
+		if isSensitiveLogKey(key) {
+			values[key] = []string{redacted}
+		}
+	}
+	return values.Encode()
+}
+
+// RedactHeader returns a copy of h with the secret headers redacted
+func RedactHeader(h http.Header) http.Header {
+	out := make(http.Header, len(h))
+	for name, values := range h {
+		if isSensitiveLogKey(name) {
+			out[name] = []string{redacted}
+			continue
+		}
+		out[name] = values
+	}
+	return out
+}
+
+type requestIDContextKey struct{}
+
+// RequestID returns the id of the request being served, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey{}).(string)
+	return id
+}
+
+// newRequestID returns a random request id
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b[:])
+}
+
+// validRequestID tells if a request id sent by a client can be kept: short
+// and printable, so it can't forge log lines
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// withRequestID gives every request an id: the one sent in X-Request-ID,
+// or a new one. It is set on the request, so it reaches the upstream, and
+// on the response.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		r.Header.Set(RequestIDHeader, id)
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDContextKey{}, id)))
+	})
+}
+
+// requestLogger returns the logger of a request, with its id
+func (p *Proxy) requestLogger(r *http.Request) log.Logger {
+	if id := RequestID(r.Context()); id != "" {
+		return p.logger.With("request_id", id)
+	}
+	return p.logger
+}
+
+// accessLogEntry is an access log line, in Elastic Common Schema
+type accessLogEntry struct {
+	Timestamp  string            `json:"@timestamp"`
+	ECSVersion string            `json:"ecs.version"`
+	Duration   int64             `json:"event.duration"` // nanoseconds
+	RequestID  string            `json:"http.request.id,omitempty"`
+	Method     string            `json:"http.request.method"`
+	Status     int               `json:"http.response.status_code"`
+	Bytes      int64             `json:"http.response.body.bytes"`
+	Path       string            `json:"url.path"`
+	Query      string            `json:"url.query,omitempty"`
+	ClientIP   string            `json:"client.ip,omitempty"`
+	UserAgent  string            `json:"user_agent.original,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+}
+
+// ecsVersion is the Elastic Common Schema version of the access logs
+const ecsVersion = "8.11.0"
+
+// AccessLog writes the access log lines
+type AccessLog struct {
+	mu     sync.Mutex
+	out    io.Writer
+	closer io.Closer
+}
+
+// NewAccessLog opens the access log output: stdout, stderr or a file the
+// lines are appended to
+func NewAccessLog(output string) (*AccessLog, error) {
+	switch output {
+	case "", "stdout":
+		return &AccessLog{out: os.Stdout}, nil
+	case "stderr":
+		return &AccessLog{out: os.Stderr}, nil
+	}
+	file, err := os.OpenFile(output, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open access log: %w", err)
+	}
+	return &AccessLog{out: file, closer: file}, nil
+}
+
+// Write writes an entry as a JSON line
+func (a *AccessLog) Write(entry accessLogEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	_, err = a.out.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the access log file, if any
+func (a *AccessLog) Close() error {
+	if a.closer == nil {
+		return nil
+	}
+	return a.closer.Close()
+}
+
+// logRequests logs every request once served: a line of the access log
+// when enabled, a debug line of the http module otherwise
+func (p *Proxy) logRequests(next http.Handler) http.Handler {
+	logger := p.logger.With("module", LogModuleHTTP)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		// the handlers may rewrite the url, e.g. remove arkauth
+		path, query := r.URL.Path, r.URL.RawQuery
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		duration := time.Since(start)
+
+		if p.accessLog == nil {
+			logger.Debug("request handled",
+				"request_id", RequestID(r.Context()),
+				"method", r.Method,
+				"path", path,
+				"status", status,
+				"remote", p.getRemoteAddr(r),
+				"duration", duration,
+			)
+			return
+		}
+
+		entry := accessLogEntry{
+			Timestamp:  start.UTC().Format(time.RFC3339Nano),
+			ECSVersion: ecsVersion,
+			Duration:   duration.Nanoseconds(),
+			RequestID:  RequestID(r.Context()),
+			Method:     r.Method,
+			Status:     status,
+			Bytes:      recorder.bytes,
+			Path:       path,
+			Query:      RedactQuery(query),
+			ClientIP:   p.getRemoteAddr(r),
+			UserAgent:  r.UserAgent(),
+		}
+		labels := map[string]string{}
+		if service := requestServiceName(r); p.upstreamPool(service) != nil {
+			labels["service"] = service
+		}
+		if tier := w.Header().Get("tier"); tier != "" {
+			labels["tier"] = tier
+		}
+		if cache := w.Header().Get(CacheHeader); cache != "" {
+			labels["cache"] = cache
+		}
+		if len(labels) > 0 {
+			entry.Labels = labels
+		}
+		if err := p.accessLog.Write(entry); err != nil {
+			logger.Error("failed to write access log", "error", err)
+		}
+	})
+}
@@ -0,0 +1,274 @@
+package sentinel
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	// RoutesMetrics serves the Prometheus metrics
+	RoutesMetrics = "/metrics"
+
+	// metricsChainInterval is how often the chain height is fetched to
+	// measure the event stream lag
+	metricsChainInterval = 30 * time.Second
+)
+
+// request tiers
+const (
+	TierFree = "free"
+	TierPaid = "paid"
+	TierX402 = "x402"
+	TierNone = "none" // refused before the tier was known
+)
+
+var (
+	// metricsRegistry holds the sentinel metrics, apart from the default
+	// registry the chain modules may use
+	metricsRegistry = prometheus.NewRegistry()
+
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sentinel_requests_total",
+		Help: "Proxied requests by service, tier and HTTP status.",
+	}, []string{"service", "tier", "status"})
+
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sentinel_request_duration_seconds",
+		Help:    "Time to serve proxied requests, payment and upstream included.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "tier"})
+
+	upstreamDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sentinel_upstream_duration_seconds",
+		Help:    "Time until an upstream answered the headers of a request, per attempt.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"service", "upstream"})
+
+	upstreamFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sentinel_upstream_failures_total",
+		Help: "Requests an upstream failed or answered unavailable, per attempt.",
+	}, []string{"service", "upstream"})
+
+	rateLimitedTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sentinel_rate_limited_total",
+		Help: "Requests refused by a rate limit or deny list, by tier and reason.",
+	}, []string{"tier", "reason"})
+
+	claimsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sentinel_claims",
+		Help: "Claims in the claim store, by whether they were claimed on chain.",
+	}, []string{"claimed"})
+
+	unclaimedNonces = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sentinel_unclaimed_nonces",
+		Help: "Sum of the nonces of the claims not yet claimed on chain.",
+	})
+
+	x402VerifyDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sentinel_x402_verify_duration_seconds",
+		Help:    "Time to verify x402 payments, by network.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"network"})
+
+	x402VerifyFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sentinel_x402_verify_failures_total",
+		Help: "x402 payments refused at verification, by network and reason.",
+	}, []string{"network", "reason"})
+
+	x402SettleDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "sentinel_x402_settle_duration_seconds",
+		Help:    "Time to settle x402 payments, by network.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"network"})
+
+	x402SettleFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "sentinel_x402_settle_failures_total",
+		Help: "x402 payments that failed to settle, by network and reason.",
+	}, []string{"network", "reason"})
+
+	chainHeight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sentinel_chain_height",
+		Help: "Latest Arkeo block height reported by source_chain.",
+	})
+
+	eventStreamHeight = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sentinel_event_stream_height",
+		Help: "Latest Arkeo block height received from the event stream.",
+	})
+
+	eventStreamLag = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "sentinel_event_stream_lag_blocks",
+		Help: "Blocks the event stream is behind source_chain.",
+	})
+
+	webSocketConnections = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "sentinel_websocket_connections",
+		Help: "Open WebSocket connections relayed to the upstreams, by service.",
+	}, []string{"service"})
+)
+
+func init() {
+	metricsRegistry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		requestsTotal, requestDuration, upstreamDuration, upstreamFailures, rateLimitedTotal,
+		claimsGauge, unclaimedNonces,
+		x402VerifyDuration, x402VerifyFailures, x402SettleDuration, x402SettleFailures,
+		chainHeight, eventStreamHeight, eventStreamLag,
+		webSocketConnections,
+	)
+}
+
+// statusRecorder keeps the status and size of a response. It still flushes
+// and hijacks, so streams and WebSockets pass through.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.bytes += int64(n)
+	return n, err
+}
+
+func (s *statusRecorder) Flush() {
+	if flusher, ok := s.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the connection deadlines
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := s.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	if s.status == 0 {
+		s.status = http.StatusSwitchingProtocols
+	}
+	return hijacker.Hijack()
+}
+
+// instrument counts the requests of the proxied routes. The tier is the one
+// set by the auth middleware, or tier when given. Services that are not
+// served are counted as "unknown" to bound the labels.
+func (p *Proxy) instrument(tier string, serviceName func(r *http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		service := serviceName(r)
+		if p.upstreamPool(service) == nil {
+			service = "unknown"
+		}
+		recorder := &statusRecorder{ResponseWriter: w}
+		next.ServeHTTP(recorder, r)
+
+		requestTier := tier
+		if requestTier == "" {
+			requestTier = w.Header().Get("tier")
+		}
+		if requestTier == "" {
+			requestTier = TierNone
+		}
+		status := recorder.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		requestsTotal.WithLabelValues(service, requestTier, strconv.Itoa(status)).Inc()
+		requestDuration.WithLabelValues(service, requestTier).Observe(time.Since(start).Seconds())
+	})
+}
+
+// handleMetrics serves the metrics, the store gauges are read on scrape
+func (p *Proxy) handleMetrics() http.Handler {
+	metrics := promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.collectStoreMetrics()
+		metrics.ServeHTTP(w, r)
+	})
+}
+
+// collectStoreMetrics sets the claim and event stream gauges
+func (p *Proxy) collectStoreMetrics() {
+	if p.ClaimStore != nil {
+		var claimed, unclaimed int
+		var nonces int64
+		for _, claim := range p.ClaimStore.List() {
+			if claim.Claimed {
+				claimed++
+				continue
+			}
+			unclaimed++
+			nonces += claim.Nonce
+		}
+		claimsGauge.WithLabelValues("true").Set(float64(claimed))
+		claimsGauge.WithLabelValues("false").Set(float64(unclaimed))
+		unclaimedNonces.Set(float64(nonces))
+	}
+	if p.MemStore != nil {
+		eventStreamHeight.Set(float64(p.MemStore.GetHeight()))
+	}
+}
+
+// observeRateLimited counts a request refused by a limit
+func observeRateLimited(tier, reason string) {
+	rateLimitedTotal.WithLabelValues(tier, reason).Inc()
+}
+
+// watchEventStream fetches the chain height from source_chain until ctx is
+// done, to measure how far behind the event stream is
+func (p *Proxy) watchEventStream(ctx context.Context) {
+	source, err := url.Parse(p.Config.SourceChain)
+	if err != nil || source.Host == "" {
+		return
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	ticker := time.NewTicker(metricsChainInterval)
+	defer ticker.Stop()
+	for {
+		height, err := probeUpstream(ctx, client, source, UpstreamProbeCosmosREST)
+		if err != nil {
+			p.logger.Debug("failed to fetch chain height", "error", err)
+		} else {
+			current := p.MemStore.GetHeight()
+			chainHeight.Set(float64(height))
+			eventStreamHeight.Set(float64(current))
+			if lag := int64(height) - current; lag > 0 {
+				eventStreamLag.Set(float64(lag))
+			} else {
+				eventStreamLag.Set(0)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
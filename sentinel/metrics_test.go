@@ -0,0 +1,98 @@
+package sentinel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+	"github.com/arkeonetwork/arkeo/x/arkeo/types"
+)
+
+func TestInstrument(t *testing.T) {
+	proxy := &Proxy{
+		logger:  log.NewNopLogger(),
+		proxies: map[string]*url.URL{"eth-mainnet-fullnode": {Scheme: "http", Host: "eth"}},
+	}
+	handler := proxy.instrument("", requestServiceName, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("free") != "" {
+			w.Header().Set("tier", TierFree)
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	untiered := requestsTotal.WithLabelValues("eth-mainnet-fullnode", TierNone, "200")
+	limited := requestsTotal.WithLabelValues("eth-mainnet-fullnode", TierFree, "429")
+	unknown := requestsTotal.WithLabelValues("unknown", TierNone, "200")
+	before := []float64{testutil.ToFloat64(untiered), testutil.ToFloat64(limited), testutil.ToFloat64(unknown)}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/eth-mainnet-fullnode", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/eth-mainnet-fullnode?free=1", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/no-such-service-42", nil))
+
+	require.Equal(t, before[0]+1, testutil.ToFloat64(untiered))
+	require.Equal(t, before[1]+1, testutil.ToFloat64(limited))
+	require.Equal(t, before[2]+1, testutil.ToFloat64(unknown))
+}
+
+func TestHandleMetrics(t *testing.T) {
+	claims, err := NewClaimStore("")
+	require.NoError(t, err)
+	pk := types.GetRandomPubKey()
+	require.NoError(t, claims.Set(NewClaim(1, pk, 30, "sig")))
+	require.NoError(t, claims.Set(NewClaim(2, pk, 12, "sig")))
+	claimed := NewClaim(3, pk, 5, "sig")
+	claimed.Claimed = true
+	require.NoError(t, claims.Set(claimed))
+
+	proxy := &Proxy{
+		logger:     log.NewNopLogger(),
+		ClaimStore: claims,
+		MemStore:   NewMemStore("", nil, log.NewNopLogger()),
+	}
+	proxy.MemStore.SetHeight(77)
+
+	w := httptest.NewRecorder()
+	proxy.handleMetrics().ServeHTTP(w, httptest.NewRequest(http.MethodGet, RoutesMetrics, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	require.Contains(t, body, `sentinel_claims{claimed="false"} 2`)
+	require.Contains(t, body, `sentinel_claims{claimed="true"} 1`)
+	require.Contains(t, body, "sentinel_unclaimed_nonces 42")
+	require.Contains(t, body, "sentinel_event_stream_height 77")
+}
+
+func TestMetricsRoutes(t *testing.T) {
+	served := func(router *mux.Router) bool {
+		var match mux.RouteMatch
+		if !router.Match(httptest.NewRequest(http.MethodGet, RoutesMetrics, nil), &match) {
+			return false
+		}
+		template, _ := match.Route.GetPathTemplate()
+		return template == RoutesMetrics
+	}
+
+	// kept off the public port by default
+	proxy := &Proxy{logger: log.NewNopLogger(), Config: conf.Configuration{Admin: conf.AdminConfig{Token: "s3cret"}}}
+	require.False(t, served(proxy.getRouter()))
+	require.True(t, served(proxy.getAdminRouter()))
+
+	w := httptest.NewRecorder()
+	proxy.getAdminRouter().ServeHTTP(w, httptest.NewRequest(http.MethodGet, RoutesMetrics, nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	proxy.Config.Metrics.Public = true
+	require.True(t, served(proxy.getRouter()))
+
+	proxy.Config.Metrics.Disabled = true
+	require.False(t, served(proxy.getRouter()))
+	require.False(t, served(proxy.getAdminRouter()))
+}
@@ -90,3 +90,8 @@ func (ps *ProviderConfigurationStore) Set(config ProviderConfiguration) error {
 func (p *ProviderConfigurationStore) Remove(pubKey common.PubKey, service string) error {
 	return p.db.Delete([]byte(pubKey.String()+service), nil)
 }
+
+// Close underlying db
+func (p *ProviderConfigurationStore) Close() error {
+	return p.db.Close()
+}
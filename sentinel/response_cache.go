@@ -0,0 +1,711 @@
+package sentinel
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+const (
+	// CacheHeader tells whether an answer came from the response cache
+	CacheHeader = "X-Cache"
+	CacheHit    = "HIT"
+	CacheMiss   = "MISS"
+
+	defaultResponseCacheMaxEntries   = 10000
+	defaultResponseCacheMaxBytes     = 64 << 20
+	defaultResponseCacheMaxEntrySize = 1 << 20
+	defaultResponseCacheStoreTTL     = 7 * 24 * 3600
+
+	// default finality depths, in blocks
+	defaultEVMFinalityDepth     = 64
+	defaultBitcoinFinalityDepth = 6
+	defaultCosmosFinalityDepth  = 1
+
+	responseCachePrefix = "response/"
+)
+
+var (
+	// evmBlockParams are the EVM methods naming a block by number, with the
+	// position of the block param. Tags such as "latest" are not cached.
+	evmBlockParams = map[string]int{
+		"eth_getBlockByNumber":                    0,
+		"eth_getBlockReceipts":                    0,
+		"eth_getBlockTransactionCountByNumber":    0,
+		"eth_getTransactionByBlockNumberAndIndex": 0,
+		"eth_getUncleByBlockNumberAndIndex":       0,
+		"eth_getUncleCountByBlockNumber":          0,
+		"eth_getBalance":                          1,
+		"eth_getCode":                             1,
+		"eth_getTransactionCount":                 1,
+		"eth_call":                                1,
+		"eth_getStorageAt":                        2,
+		"eth_getProof":                            2,
+	}
+
+	// evmResultBlocks are the EVM methods whose result names its block
+	evmResultBlocks = map[string]string{
+		"eth_getBlockByHash":                    "number",
+		"eth_getTransactionByHash":              "blockNumber",
+		"eth_getTransactionByBlockHashAndIndex": "blockNumber",
+		"eth_getTransactionReceipt":             "blockNumber",
+	}
+
+	// evmConstants are the EVM methods whose result never changes
+	evmConstants = map[string]bool{"eth_chainId": true, "net_version": true}
+
+	// bitcoinResultBlocks are the Bitcoin methods whose result names its
+	// block, their confirmations are brought up to date when served
+	bitcoinResultBlocks = map[string]bool{"getblock": true, "getblockheader": true, "getblockstats": true}
+
+	// cometHeightMethods are the CometBFT RPC methods of a block height,
+	// called with JSON-RPC or GET
+	cometHeightMethods = map[string]bool{
+		"block": true, "block_results": true, "commit": true, "header": true, "validators": true,
+	}
+)
+
+// ResponseCache holds the answers to requests for finalized data, keyed by
+// the normalized request. The least recently used answers are evicted past
+// MaxEntries or MaxBytes, to the LevelDB store when one is configured.
+type ResponseCache struct {
+	logger  zerolog.Logger
+	config  conf.ResponseCacheConfig
+	db      *leveldb.DB
+	mu      sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+	bytes   int64
+	hits    uint64
+	misses  uint64
+}
+
+// ResponseCacheEntry is a cached answer. JSON-RPC answers are kept without
+// their id, which is the one of the request when served.
+type ResponseCacheEntry struct {
+	Key         string `json:"key"`
+	Body        []byte `json:"body"`
+	ContentType string `json:"content_type,omitempty"`
+	JSONRPC     bool   `json:"jsonrpc,omitempty"`
+	Height      uint64 `json:"height,omitempty"`
+	CreatedAt   int64  `json:"created_at"`
+
+	// Confirmations is set for answers whose result counts the
+	// confirmations of its block
+	Confirmations bool `json:"confirmations,omitempty"`
+}
+
+func (e *ResponseCacheEntry) size() int64 {
+	return int64(len(e.Key) + len(e.Body) + len(e.ContentType))
+}
+
+// ResponseCacheStats is reported in /metadata.json
+type ResponseCacheStats struct {
+	Entries int    `json:"entries"`
+	Bytes   int64  `json:"bytes"`
+	Hits    uint64 `json:"hits"`
+	Misses  uint64 `json:"misses"`
+}
+
+// withResponseCacheDefaults fills unset cache settings with defaults
+func withResponseCacheDefaults(config conf.ResponseCacheConfig) conf.ResponseCacheConfig {
+	if config.MaxEntries <= 0 {
+		config.MaxEntries = defaultResponseCacheMaxEntries
+	}
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = defaultResponseCacheMaxBytes
+	}
+	if config.MaxEntrySize <= 0 {
+		config.MaxEntrySize = defaultResponseCacheMaxEntrySize
+	}
+	if config.StoreTTL == 0 {
+		config.StoreTTL = defaultResponseCacheStoreTTL
+	}
+	return config
+}
+
+// NewResponseCache creates a cache held in memory, spilling to LevelDB when
+// config.StoreLocation is set
+func NewResponseCache(config conf.ResponseCacheConfig) (*ResponseCache, error) {
+	cache := &ResponseCache{
+		logger:  log.With().Str("module", "response-cache").Logger(),
+		config:  withResponseCacheDefaults(config),
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+	if config.StoreLocation != "" {
+		db, err := leveldb.OpenFile(config.StoreLocation, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fail to open level db %s: %w", config.StoreLocation, err)
+		}
+		cache.db = db
+	}
+	return cache, nil
+}
+
+// Get returns the answer cached under key, from memory or else from the store
+func (c *ResponseCache) Get(key string) (ResponseCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[key]; ok {
+		c.order.MoveToFront(elem)
+		c.hits++
+		return *elem.Value.(*ResponseCacheEntry), true
+	}
+	if c.db != nil {
+		buf, err := c.db.Get([]byte(responseCachePrefix+key), nil)
+		if err == nil {
+			var entry ResponseCacheEntry
+			if err := json.Unmarshal(buf, &entry); err != nil {
+				c.logger.Error().Err(err).Msg("fail to unmarshal cached response")
+			} else {
+				c.add(&entry)
+				c.hits++
+				return entry, true
+			}
+		}
+	}
+	c.misses++
+	return ResponseCacheEntry{}, false
+}
+
+// Set caches an answer, answers over MaxEntrySize are ignored
+func (c *ResponseCache) Set(entry ResponseCacheEntry) {
+	if len(entry.Body) > c.config.MaxEntrySize {
+		return
+	}
+	if entry.CreatedAt == 0 {
+		entry.CreatedAt = time.Now().Unix()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.add(&entry)
+}
+
+// add keeps an entry in memory and evicts the least recently used ones past
+// the limits. The caller holds the lock.
+func (c *ResponseCache) add(entry *ResponseCacheEntry) {
+	if elem, ok := c.entries[entry.Key]; ok {
+		c.bytes -= elem.Value.(*ResponseCacheEntry).size()
+		c.order.Remove(elem)
+	}
+	c.entries[entry.Key] = c.order.PushFront(entry)
+	c.bytes += entry.size()
+	for c.order.Len() > c.config.MaxEntries || c.bytes > c.config.MaxBytes {
+		elem := c.order.Back()
+		if elem == nil {
+			break
+		}
+		evicted := elem.Value.(*ResponseCacheEntry)
+		c.order.Remove(elem)
+		delete(c.entries, evicted.Key)
+		c.bytes -= evicted.size()
+		c.spill(evicted)
+	}
+}
+
+// spill writes an entry evicted from memory to the store
+func (c *ResponseCache) spill(entry *ResponseCacheEntry) {
+	if c.db == nil {
+		return
+	}
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		c.logger.Error().Err(err).Msg("fail to marshal cached response")
+		return
+	}
+	if err := c.db.Put([]byte(responseCachePrefix+entry.Key), buf, nil); err != nil {
+		c.logger.Error().Err(err).Msg("fail to spill cached response")
+	}
+}
+
+// Prune removes the answers spilled to the store before the given time and
+// returns how many
+func (c *ResponseCache) Prune(before time.Time) (int, error) {
+	if c.db == nil {
+		return 0, nil
+	}
+	iterator := c.db.NewIterator(util.BytesPrefix([]byte(responseCachePrefix)), nil)
+	defer iterator.Release()
+	batch := new(leveldb.Batch)
+	for iterator.Next() {
+		var entry ResponseCacheEntry
+		if err := json.Unmarshal(iterator.Value(), &entry); err != nil || entry.CreatedAt < before.Unix() {
+			batch.Delete(append([]byte(nil), iterator.Key()...))
+		}
+	}
+	if err := iterator.Error(); err != nil {
+		return 0, err
+	}
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+	if err := c.db.Write(batch, nil); err != nil {
+		c.logger.Error().Err(err).Msg("fail to prune cached responses")
+		return 0, err
+	}
+	return batch.Len(), nil
+}
+
+// Stats returns the size of the memory cache and how often it served
+func (c *ResponseCache) Stats() ResponseCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return ResponseCacheStats{
+		Entries: c.order.Len(),
+		Bytes:   c.bytes,
+		Hits:    c.hits,
+		Misses:  c.misses,
+	}
+}
+
+// Close closes the store
+func (c *ResponseCache) Close() error {
+	if c.db == nil {
+		return nil
+	}
+	return c.db.Close()
+}
+
+// finality tells whether a block is deep enough below the best height of the
+// upstreams to be final
+type finality struct {
+	best  uint64
+	depth uint64
+}
+
+func (f finality) final(height uint64) bool {
+	return f.best > 0 && height+f.depth <= f.best
+}
+
+// cacheable is a request whose answer can be cached
+type cacheable struct {
+	key  string
+	call *JSONRPCCall // nil for GET requests
+
+	// height is the final block the request names
+	height uint64
+
+	// resultHeight returns the block of an answer when the request names
+	// none, the answer is cached when that block is final
+	resultHeight func(result json.RawMessage) (uint64, bool)
+
+	// envelope is set for GET answers wrapped in a JSON-RPC response
+	envelope bool
+
+	confirmations bool
+}
+
+// cacheableRequest returns how the answer to r can be cached, nil when it
+// can not. service is the service of the request, path its path below the
+// service.
+func cacheableRequest(kind, service, path string, r *http.Request, f finality) *cacheable {
+	switch r.Method {
+	case http.MethodGet:
+		return cacheableGet(kind, service, path, r.URL.Query(), f)
+	case http.MethodPost:
+		request, ok := JSONRPCFromContext(r.Context())
+		if !ok || request.Batch || len(request.Calls) != 1 {
+			return nil
+		}
+		call := request.Calls[0]
+		if len(call.ID) == 0 || string(call.ID) == "null" {
+			return nil
+		}
+		c := cacheableCall(kind, call, f)
+		if c != nil {
+			c.call = &call
+			c.key = responseCacheKey(service, call.Method, canonicalParams(call.Params))
+		}
+		return c
+	}
+	return nil
+}
+
+// cacheableCall returns how the answer to a JSON-RPC call can be cached
+func cacheableCall(kind string, call JSONRPCCall, f finality) *cacheable {
+	switch kind {
+	case UpstreamProbeEVM:
+		if evmConstants[call.Method] {
+			return &cacheable{}
+		}
+		if field, ok := evmResultBlocks[call.Method]; ok {
+			return &cacheable{resultHeight: jsonHeight(field)}
+		}
+		if i, ok := evmBlockParams[call.Method]; ok {
+			var params []json.RawMessage
+			if json.Unmarshal(call.Params, &params) != nil || i >= len(params) {
+				return nil
+			}
+			var tag string
+			if json.Unmarshal(params[i], &tag) != nil || !strings.HasPrefix(strings.ToLower(tag), "0x") {
+				return nil
+			}
+			height, err := parseQuantity(tag)
+			if err != nil || !f.final(height) {
+				return nil
+			}
+			return &cacheable{height: height}
+		}
+	case UpstreamProbeBitcoin:
+		if call.Method == "getblockhash" {
+			var params []uint64
+			if json.Unmarshal(call.Params, &params) != nil || len(params) == 0 || !f.final(params[0]) {
+				return nil
+			}
+			return &cacheable{height: params[0]}
+		}
+		if bitcoinResultBlocks[call.Method] {
+			return &cacheable{resultHeight: jsonHeight("height"), confirmations: true}
+		}
+	case UpstreamProbeCosmos:
+		if call.Method == "tx" {
+			return &cacheable{resultHeight: jsonHeight("height")}
+		}
+		if cometHeightMethods[call.Method] {
+			height, ok := cometHeightParam(call.Params)
+			if !ok || !f.final(height) {
+				return nil
+			}
+			return &cacheable{height: height}
+		}
+	}
+	return nil
+}
+
+// cacheableGet returns how the answer to a GET request can be cached
+func cacheableGet(kind, service, path string, query url.Values, f finality) *cacheable {
+	path = strings.TrimSuffix(path, "/")
+	key := responseCacheKey(service, path, query.Encode())
+	switch kind {
+	case UpstreamProbeCosmos:
+		method := strings.TrimPrefix(path, "/")
+		if method == "tx" && query.Get("hash") != "" {
+			return &cacheable{key: key, envelope: true, resultHeight: jsonHeight("height")}
+		}
+		if cometHeightMethods[method] {
+			height, err := parseHeight(strings.Trim(query.Get("height"), `"`))
+			if err != nil || !f.final(height) {
+				return nil
+			}
+			return &cacheable{key: key, envelope: true, height: height}
+		}
+	case UpstreamProbeCosmosREST:
+		if rest := strings.TrimPrefix(path, "/cosmos/base/tendermint/v1beta1/blocks/"); rest != path {
+			height, err := parseHeight(rest)
+			if err != nil || !f.final(height) {
+				return nil
+			}
+			return &cacheable{key: key, height: height}
+		}
+		if rest := strings.TrimPrefix(path, "/cosmos/tx/v1beta1/txs/"); rest != path && rest != "" && !strings.Contains(rest, "/") {
+			return &cacheable{key: key, resultHeight: jsonHeight("tx_response", "height")}
+		}
+	}
+	return nil
+}
+
+// responseCacheKey hashes the parts of a normalized request
+func responseCacheKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// canonicalParams normalizes JSON-RPC params: objects are sorted by key and
+// hex strings lower cased
+func canonicalParams(params json.RawMessage) string {
+	if len(params) == 0 {
+		return "null"
+	}
+	decoder := json.NewDecoder(bytes.NewReader(params))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return string(params)
+	}
+	buf, err := json.Marshal(lowerHex(value))
+	if err != nil {
+		return string(params)
+	}
+	return string(buf)
+}
+
+func lowerHex(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X") {
+			return strings.ToLower(v)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = lowerHex(v[i])
+		}
+	case map[string]interface{}:
+		for k := range v {
+			v[k] = lowerHex(v[k])
+		}
+	}
+	return value
+}
+
+// parseQuantity parses a hex quantity such as "0x1b4"
+func parseQuantity(value string) (uint64, error) {
+	return strconv.ParseUint(strings.TrimPrefix(strings.TrimPrefix(value, "0x"), "0X"), 16, 64)
+}
+
+// cometHeightParam returns the height param of a CometBFT call, given by name
+// or by position
+func cometHeightParam(params json.RawMessage) (uint64, bool) {
+	var raw json.RawMessage
+	var named struct {
+		Height json.RawMessage `json:"height"`
+	}
+	var positional []json.RawMessage
+	switch {
+	case json.Unmarshal(params, &named) == nil && len(named.Height) > 0:
+		raw = named.Height
+	case json.Unmarshal(params, &positional) == nil && len(positional) > 0:
+		raw = positional[0]
+	default:
+		return 0, false
+	}
+	height, err := parseHeight(strings.Trim(string(raw), `"`))
+	return height, err == nil
+}
+
+// jsonHeight returns a function reading the block height at path in a JSON
+// object, as a number, a decimal string or a hex quantity
+func jsonHeight(path ...string) func(json.RawMessage) (uint64, bool) {
+	return func(value json.RawMessage) (uint64, bool) {
+		for _, field := range path {
+			var object map[string]json.RawMessage
+			if json.Unmarshal(value, &object) != nil {
+				return 0, false
+			}
+			value = object[field]
+		}
+		var height string
+		if json.Unmarshal(value, &height) != nil {
+			height = string(value)
+		}
+		if strings.HasPrefix(height, "0x") {
+			n, err := parseQuantity(height)
+			return n, err == nil
+		}
+		n, err := parseHeight(height)
+		return n, err == nil
+	}
+}
+
+// finalityDepth returns the finality depth of a service
+func (p *Proxy) finalityDepth(service, kind string) uint64 {
+	if svc, ok := p.serviceConfig(service); ok && svc.FinalityDepth > 0 {
+		return svc.FinalityDepth
+	}
+	switch kind {
+	case UpstreamProbeEVM:
+		return defaultEVMFinalityDepth
+	case UpstreamProbeBitcoin:
+		return defaultBitcoinFinalityDepth
+	}
+	return defaultCosmosFinalityDepth
+}
+
+// cacheKind returns the probe naming the rules of a service, the probe of
+// its pool or else the one inferred from its name
+func cacheKind(pool *UpstreamPool) string {
+	switch pool.Probe {
+	case UpstreamProbeEVM, UpstreamProbeBitcoin, UpstreamProbeCosmos, UpstreamProbeCosmosREST:
+		return pool.Probe
+	}
+	return defaultUpstreamProbe(pool.Service)
+}
+
+// serveCached answers r from the response cache when it holds the answer,
+// otherwise serves it with next and caches the answer once final. path is
+// the request path below the service.
+func (p *Proxy) serveCached(w http.ResponseWriter, r *http.Request, pool *UpstreamPool, path string, next http.Handler) {
+	if p.cache == nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+	kind := cacheKind(pool)
+	status, _ := pool.SyncStatus()
+	f := finality{best: status.Height, depth: p.finalityDepth(pool.Service, kind)}
+	c := cacheableRequest(kind, pool.Service, path, r, f)
+	if c == nil {
+		next.ServeHTTP(w, r)
+		return
+	}
+
+	if entry, ok := p.cache.Get(c.key); ok {
+		body, err := cachedAnswer(entry, c.call, f.best)
+		if err == nil {
+			if entry.ContentType != "" {
+				w.Header().Set("Content-Type", entry.ContentType)
+			}
+			w.Header().Set(CacheHeader, CacheHit)
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write(body)
+			return
+		}
+		p.logger.Error("failed to serve cached response", "service", pool.Service, "error", err)
+	}
+
+	// let the transport decompress the answer so it is cached as served
+	r.Header.Del("Accept-Encoding")
+	w.Header().Set(CacheHeader, CacheMiss)
+	capture := &cacheWriter{ResponseWriter: w, limit: p.cache.config.MaxEntrySize}
+	next.ServeHTTP(capture, r)
+	if capture.status != http.StatusOK || capture.overflow || w.Header().Get("Content-Encoding") != "" {
+		return
+	}
+	entry, ok := c.entry(capture.body.Bytes(), f)
+	if !ok {
+		return
+	}
+	entry.ContentType = w.Header().Get("Content-Type")
+	p.cache.Set(entry)
+}
+
+// entry builds the cache entry of a final answer
+func (c *cacheable) entry(body []byte, f finality) (ResponseCacheEntry, bool) {
+	entry := ResponseCacheEntry{Key: c.key, Body: body, JSONRPC: c.call != nil, Height: c.height, Confirmations: c.confirmations}
+	result := json.RawMessage(body)
+	if c.call != nil || c.envelope {
+		var envelope map[string]json.RawMessage
+		if json.Unmarshal(body, &envelope) != nil {
+			return entry, false
+		}
+		if e, ok := envelope["error"]; ok && string(e) != "null" {
+			return entry, false
+		}
+		result = envelope["result"]
+		if len(result) == 0 || string(result) == "null" {
+			return entry, false
+		}
+		if c.call != nil {
+			delete(envelope, "id")
+			buf, err := json.Marshal(envelope)
+			if err != nil {
+				return entry, false
+			}
+			entry.Body = buf
+		}
+	}
+	if c.resultHeight != nil {
+		height, ok := c.resultHeight(result)
+		if !ok || !f.final(height) {
+			return entry, false
+		}
+		entry.Height = height
+	}
+	return entry, true
+}
+
+// cachedAnswer returns the body serving a cached entry: JSON-RPC answers get
+// the id of the call, and confirmations counted from the best height
+func cachedAnswer(entry ResponseCacheEntry, call *JSONRPCCall, best uint64) ([]byte, error) {
+	if !entry.JSONRPC || call == nil {
+		return entry.Body, nil
+	}
+	var envelope map[string]json.RawMessage
+	if err := json.Unmarshal(entry.Body, &envelope); err != nil {
+		return nil, err
+	}
+	envelope["id"] = call.ID
+	if entry.Confirmations && best >= entry.Height {
+		var result map[string]json.RawMessage
+		if err := json.Unmarshal(envelope["result"], &result); err == nil {
+			if _, ok := result["confirmations"]; ok {
+				result["confirmations"] = json.RawMessage(strconv.FormatUint(best-entry.Height+1, 10))
+				if buf, err := json.Marshal(result); err == nil {
+					envelope["result"] = buf
+				}
+			}
+		}
+	}
+	return json.Marshal(envelope)
+}
+
+// cacheWriter keeps a copy of the answer it writes, up to limit bytes
+type cacheWriter struct {
+	http.ResponseWriter
+	limit    int
+	status   int
+	body     bytes.Buffer
+	overflow bool
+}
+
+func (c *cacheWriter) WriteHeader(status int) {
+	if c.status == 0 {
+		c.status = status
+	}
+	c.ResponseWriter.WriteHeader(status)
+}
+
+func (c *cacheWriter) Write(b []byte) (int, error) {
+	if c.status == 0 {
+		c.status = http.StatusOK
+	}
+	if !c.overflow {
+		if c.body.Len()+len(b) > c.limit {
+			c.overflow = true
+			c.body.Reset()
+		} else {
+			c.body.Write(b)
+		}
+	}
+	return c.ResponseWriter.Write(b)
+}
+
+func (c *cacheWriter) Flush() {
+	if flusher, ok := c.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (c *cacheWriter) Unwrap() http.ResponseWriter {
+	return c.ResponseWriter
+}
+
+// pruneResponseCache removes the answers spilled to the store past their
+// retention until ctx is done
+func (p *Proxy) pruneResponseCache(ctx context.Context) {
+	if p.cache == nil || p.cache.db == nil {
+		return
+	}
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			ttl := time.Duration(p.cache.config.StoreTTL) * time.Second
+			pruned, err := p.cache.Prune(time.Now().Add(-ttl))
+			if err != nil {
+				p.logger.Error("failed to prune cached responses", "error", err)
+			} else if pruned > 0 {
+				p.logger.Info("pruned cached responses", "count", pruned)
+			}
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package sentinel
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+func TestResponseCacheSpill(t *testing.T) {
+	cache, err := NewResponseCache(conf.ResponseCacheConfig{MaxEntries: 2, StoreLocation: t.TempDir()})
+	require.NoError(t, err)
+	defer cache.Close()
+
+	for _, key := range []string{"a", "b", "c"} {
+		cache.Set(ResponseCacheEntry{Key: key, Body: []byte(key)})
+	}
+	require.Equal(t, 2, cache.Stats().Entries)
+
+	// "a" was evicted to the store
+	entry, ok := cache.Get("a")
+	require.True(t, ok)
+	require.Equal(t, "a", string(entry.Body))
+	_, ok = cache.Get("d")
+	require.False(t, ok)
+	stats := cache.Stats()
+	require.Equal(t, uint64(1), stats.Hits)
+	require.Equal(t, uint64(1), stats.Misses)
+
+	// "b" was spilled when "a" came back
+	pruned, err := cache.Prune(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, pruned)
+
+	// answers over the entry size are not cached
+	small, err := NewResponseCache(conf.ResponseCacheConfig{MaxEntrySize: 4})
+	require.NoError(t, err)
+	small.Set(ResponseCacheEntry{Key: "big", Body: []byte("12345")})
+	_, ok = small.Get("big")
+	require.False(t, ok)
+}
+
+func TestCacheableRequest(t *testing.T) {
+	f := finality{best: 1000, depth: 64}
+	call := func(kind, body string) *cacheable {
+		request, rpcErr := ParseJSONRPCRequest([]byte(body))
+		require.Nil(t, rpcErr)
+		r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		r = r.WithContext(context.WithValue(r.Context(), jsonRPCContextKey{}, request))
+		return cacheableRequest(kind, "svc", "/", r, f)
+	}
+
+	c := call(UpstreamProbeEVM, `{"id":1,"method":"eth_getBlockByNumber","params":["0x64",false]}`)
+	require.NotNil(t, c)
+	require.Equal(t, uint64(100), c.height)
+	require.Equal(t, c.key, call(UpstreamProbeEVM, `{"id":"x","method":"eth_getBlockByNumber","params":["0X64",false]}`).key)
+	require.Nil(t, call(UpstreamProbeEVM, `{"id":1,"method":"eth_getBlockByNumber","params":["latest",false]}`))
+	require.Nil(t, call(UpstreamProbeEVM, `{"id":1,"method":"eth_getBlockByNumber","params":["0x3b0",false]}`))
+	require.Nil(t, call(UpstreamProbeEVM, `[{"id":1,"method":"eth_chainId"}]`))
+	require.Nil(t, call(UpstreamProbeEVM, `{"method":"eth_chainId"}`))
+	require.Nil(t, call(UpstreamProbeEVM, `{"id":1,"method":"eth_sendRawTransaction","params":["0x00"]}`))
+
+	c = call(UpstreamProbeEVM, `{"id":1,"method":"eth_getTransactionReceipt","params":["0xAB"]}`)
+	require.NotNil(t, c)
+	_, ok := c.entry([]byte(`{"jsonrpc":"2.0","id":1,"result":null}`), f)
+	require.False(t, ok)
+	_, ok = c.entry([]byte(`{"jsonrpc":"2.0","id":1,"result":{"blockNumber":"0x3e7"}}`), f)
+	require.False(t, ok)
+	entry, ok := c.entry([]byte(`{"jsonrpc":"2.0","id":1,"result":{"blockNumber":"0x10"}}`), f)
+	require.True(t, ok)
+	require.Equal(t, uint64(16), entry.Height)
+	require.NotContains(t, string(entry.Body), `"id"`)
+
+	require.NotNil(t, call(UpstreamProbeBitcoin, `{"id":1,"method":"getblockhash","params":[900]}`))
+	require.Nil(t, call(UpstreamProbeBitcoin, `{"id":1,"method":"getblockhash","params":[990]}`))
+	require.NotNil(t, call(UpstreamProbeCosmos, `{"id":1,"method":"block","params":{"height":"5"}}`))
+
+	get := func(kind, target string) *cacheable {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		return cacheableRequest(kind, "svc", r.URL.Path, r, f)
+	}
+	require.NotNil(t, get(UpstreamProbeCosmos, "/block?height=10"))
+	require.Nil(t, get(UpstreamProbeCosmos, "/block"))
+	require.Nil(t, get(UpstreamProbeCosmos, "/status"))
+	require.NotNil(t, get(UpstreamProbeCosmosREST, "/cosmos/base/tendermint/v1beta1/blocks/10"))
+	require.Nil(t, get(UpstreamProbeCosmosREST, "/cosmos/base/tendermint/v1beta1/blocks/latest"))
+	c = get(UpstreamProbeCosmosREST, "/cosmos/tx/v1beta1/txs/ABCD")
+	require.NotNil(t, c)
+	_, ok = c.entry([]byte(`{"tx_response":{"height":"20"}}`), f)
+	require.True(t, ok)
+}
+
+func TestServeCached(t *testing.T) {
+	var calls int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		var call JSONRPCCall
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&call))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"result":{"hash":"00ab","height":90,"confirmations":11},"error":null,"id":` + string(call.ID) + `}`))
+	}))
+	defer upstream.Close()
+
+	pool := NewUpstreamPool("btc-mainnet-fullnode", "", conf.UpstreamHealthConfig{}, log.NewNopLogger())
+	pool.Add("node", mustParseURL(t, upstream.URL), 1)
+	pool.record(pool.upstreams[0], 100, nil)
+	cache, err := NewResponseCache(conf.ResponseCacheConfig{})
+	require.NoError(t, err)
+	proxy := &Proxy{
+		logger:    log.NewNopLogger(),
+		proxies:   map[string]*url.URL{"btc-mainnet-fullnode": pool.upstreams[0].URL},
+		upstreams: map[string]*UpstreamPool{"btc-mainnet-fullnode": pool},
+		cache:     cache,
+	}
+	handler := proxy.inspectJSONRPC(requestServiceName, http.HandlerFunc(proxy.handleRequestAndRedirect))
+	send := func(id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		body := `{"jsonrpc":"1.0","id":` + id + `,"method":"getblock","params":["00AB"]}`
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/btc-mainnet-fullnode", strings.NewReader(body)))
+		return w
+	}
+
+	w := send(`"first"`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, CacheMiss, w.Header().Get(CacheHeader))
+
+	pool.record(pool.upstreams[0], 105, nil)
+	w = send("2")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, CacheHit, w.Header().Get(CacheHeader))
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	var response struct {
+		ID     json.RawMessage            `json:"id"`
+		Error  json.RawMessage            `json:"error"`
+		Result map[string]json.RawMessage `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	require.Equal(t, "2", string(response.ID))
+	require.Equal(t, "null", string(response.Error))
+	require.Equal(t, "16", string(response.Result["confirmations"]))
+}
@@ -1,27 +1,27 @@
 package sentinel
 
 import (
-	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"os"
+	"os/signal"
 	"path"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/cometbft/cometbft/libs/log"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
 	"github.com/gorilla/websocket"
-	"github.com/koding/websocketproxy"
-	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme/autocert"
 	"golang.org/x/sync/errgroup"
 	"sync"
 
@@ -32,6 +32,7 @@ import (
 type Proxy struct {
 	Metadata            Metadata
 	Config              conf.Configuration
+	ConfigFile          string // reloaded on SIGHUP or /admin/reload
 	MemStore            *MemStore
 	ClaimStore          *ClaimStore
 	ContractConfigStore *ContractConfigurationStore
@@ -39,21 +40,34 @@ type Proxy struct {
 	logger              log.Logger
 	proxies             map[string]*url.URL
 	proxyMu             sync.RWMutex
+	upstreams           map[string]*UpstreamPool
 	serviceIDs          map[string]int32
+	serviceInfo         map[string]registryService
+	jsonRPCPolicies     map[string]*JSONRPCPolicy
 	authManager         *ArkeoAuthManager
 	serviceMu           sync.RWMutex
+	x402                *X402Handler
+	x402Revenue         *X402RevenueReporter
+	cache               *ResponseCache
+	accessLog           *AccessLog
+	configMu            sync.RWMutex // guards the reloaded fields of Config and Metadata
+	webSockets          webSocketConns
+	certificates        *CertificateFiles // certificate of the public port, unless ACME
+	adminCertificates   *CertificateFiles // certificate of the admin routes, when apart
+	acme                *autocert.Manager
 }
 
 func NewProxy(config conf.Configuration) (*Proxy, error) {
-	logger := log.NewTMLogger(log.NewSyncWriter(os.Stdout))
+	logger, err := NewLogger(config.Logging, os.Stdout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logger: %w", err)
+	}
 
 	if len(config.Services) == 0 {
 		logger.Error("FATAL: Configuration file appears empty or did not load! No services found in config.Services.")
 		return nil, fmt.Errorf("configuration YAML was not loaded: no services configured")
 	}
 
-	logger.Error("DEBUG:FUNCTION NewProxy")
-
 	claimStore, err := NewClaimStore(config.ClaimStoreLocation)
 	if err != nil {
 		logger.Error(fmt.Sprintf("failed to create claim store with error: %s", err))
@@ -70,18 +84,9 @@ func NewProxy(config conf.Configuration) (*Proxy, error) {
 		return nil, fmt.Errorf("failed to create provider config store with error: %s", err)
 	}
 
-	serviceIDs := loadServiceRegistry(config, logger)
+	serviceIDs, serviceInfo := loadServiceRegistry(config, logger)
 	proxies := loadProxies(config, logger, serviceIDs)
 
-	fmt.Println("DEBUG: Proxies loaded at startup:")
-	for name, uri := range proxies {
-		if uri == nil {
-			//fmt.Printf("  %s -> [NOT CONFIGURED]\n", name)
-		} else {
-			fmt.Printf("  %s -> %s\n", name, uri.String())
-		}
-	}
-
 	// Initialize auth manager if configured
 	var authManager *ArkeoAuthManager
 	if config.ArkeoAuthContractId > 0 && config.ArkeoAuthMnemonic != "" {
@@ -109,53 +114,63 @@ func NewProxy(config conf.Configuration) (*Proxy, error) {
 		)
 	}
 
-	return &Proxy{
+	proxy := &Proxy{
 		Metadata:            NewMetadata(config),
 		Config:              config,
-		MemStore:            NewMemStore(config.HubProviderURI, authManager, logger),
+		MemStore:            NewMemStore(config.HubProviderURI, authManager, logger.With("module", LogModuleMemStore)),
 		ClaimStore:          claimStore,
 		ContractConfigStore: contractConfigStore,
 		proxies:             proxies, // <-- use the local variable here
 		proxyMu:             sync.RWMutex{},
+		upstreams:           loadUpstreams(config, logger, proxies),
 		logger:              logger,
 		ProviderConfigStore: providerConfigStore,
 		serviceIDs:          serviceIDs,
+		serviceInfo:         serviceInfo,
+		jsonRPCPolicies:     loadJSONRPCPolicies(config),
 		authManager:         authManager,
 		serviceMu:           sync.RWMutex{},
-	}, nil
-}
-
-func loadProxies(config conf.Configuration, logger log.Logger, serviceIDs map[string]int32) map[string]*url.URL {
+	}
 
-	logger.Error("DEBUG:FUNCTION: loadProxies")
+	if config.ResponseCache.Enabled {
+		proxy.cache, err = NewResponseCache(config.ResponseCache)
+		if err != nil {
+			logger.Error(fmt.Sprintf("failed to create response cache: %s", err))
+			return nil, fmt.Errorf("failed to create response cache: %s", err)
+		}
+	}
 
-	proxies := make(map[string]*url.URL)
-	serviceMap := make(map[string]conf.ServiceConfig)
+	if config.Logging.AccessLog.Enabled {
+		proxy.accessLog, err = NewAccessLog(config.Logging.AccessLog.Output)
+		if err != nil {
+			logger.Error(fmt.Sprintf("failed to create access log: %s", err))
+			return nil, fmt.Errorf("failed to create access log: %s", err)
+		}
+	}
 
-	logger.Error("DEBUG: Loading config services from YAML")
-	for i, svc := range config.Services {
-		logger.Error("DEBUG: YAML Config Service",
-			"idx", i,
-			"Name", svc.Name,
-			"RpcUrl", svc.RpcUrl,
-			"RpcUser", svc.RpcUser,
-			"RpcPassSet", svc.RpcPass != "")
+	if err := proxy.InitX402(); err != nil {
+		logger.Error(fmt.Sprintf("failed to initialize x402: %s", err))
+		return nil, fmt.Errorf("failed to initialize x402: %s", err)
 	}
 
-	// Populate serviceMap and print its contents
+	return proxy, nil
+}
+
+func loadProxies(config conf.Configuration, logger log.Logger, serviceIDs map[string]int32) map[string]*url.URL {
+	proxies := make(map[string]*url.URL)
+	serviceMap := make(map[string]conf.ServiceConfig)
 	for _, svc := range config.Services {
 		serviceMap[svc.Name] = svc
 	}
-	logger.Error("DEBUG: Listing all serviceMap keys")
-	for k, v := range serviceMap {
-		logger.Error("DEBUG: serviceMap entry", "name", k, "RpcUrl", v.RpcUrl, "RpcUser", v.RpcUser, "RpcPassSet", v.RpcPass != "")
-	}
 
 	for serviceName := range serviceIDs {
-		//logger.Error("DEBUG: Checking serviceName", "serviceName", serviceName)
 		if svc, ok := serviceMap[serviceName]; ok {
+			if svc.RpcUrl == "" && len(svc.Upstreams) > 0 {
+				// without rpc_url the first upstream is the primary one
+				first := svc.Upstreams[0]
+				svc.RpcUrl, svc.RpcUser, svc.RpcPass = first.RpcUrl, first.RpcUser, first.RpcPass
+			}
 			var fullURL string
-			logger.Error("DEBUG: Found serviceMap", "serviceName", serviceName, "RpcUrl", svc.RpcUrl)
 			if strings.HasPrefix(svc.RpcUrl, "https://") {
 				if svc.RpcUser != "" && svc.RpcPass != "" {
 					rpcURL := strings.TrimPrefix(svc.RpcUrl, "https://")
@@ -170,14 +185,12 @@ func loadProxies(config conf.Configuration, logger log.Logger, serviceIDs map[st
 				} else {
 					fullURL = svc.RpcUrl
 				}
-				logger.Error("DEBUG: Insecure endpoint", "serviceName", serviceName, "url", fullURL)
+				logger.Debug("insecure endpoint", "service", serviceName, "url", fullURL)
 			}
 
 			parsed := common.MustParseURL(fullURL)
 			if parsed == nil {
-				logger.Error("DEBUG: Could not parse URL for service", "serviceName", serviceName, "url", fullURL)
-			} else {
-				logger.Error("DEBUG: Loaded proxy", "serviceName", serviceName, "proxyUrl", parsed.String())
+				logger.Error("could not parse url for service", "service", serviceName, "url", fullURL)
 			}
 			proxies[serviceName] = parsed
 			continue
@@ -188,32 +201,34 @@ func loadProxies(config conf.Configuration, logger log.Logger, serviceIDs map[st
 		if envOk {
 			parsed := common.MustParseURL(env)
 			if parsed == nil {
-				logger.Error("DEBUG: Could not parse ENV URL for service", "serviceName", serviceName, "env", env)
-			} else {
-				logger.Error("DEBUG: Service uses ENV", "serviceName", serviceName, "envKey", envKey, "envUrl", parsed.String())
+				logger.Error("could not parse env url for service", "service", serviceName, "env", envKey, "url", env)
 			}
 			proxies[serviceName] = parsed
 		} else {
-			//logger.Error("DEBUG:MISS: Service not configured in YAML or ENV", "serviceName", serviceName)
 			proxies[serviceName] = nil
 		}
 	}
 
-	//logger.Error("DEBUG: Final proxies loaded")
 	for svc, u := range proxies {
-		if u == nil {
-			//logger.Error("DEBUG: proxy not configured", "service", svc)
-		} else {
-			logger.Error("DEBUG: proxy loaded", "service", svc, "uri", u.String())
+		if u != nil {
+			logger.Debug("proxy loaded", "service", svc, "uri", u)
 		}
 	}
 	return proxies
 }
 
+// registryService is the description of a service in the chain registry
+type registryService struct {
+	Description string
+	Type        string
+}
+
 // loadServiceRegistry attempts to fetch the on-chain service registry via REST
-// (defaulting to the legacy static list on failure).
-func loadServiceRegistry(config conf.Configuration, logger log.Logger) map[string]int32 {
+// (defaulting to the legacy static list on failure). It returns the service
+// ids and, when the chain was reached, their descriptions.
+func loadServiceRegistry(config conf.Configuration, logger log.Logger) (map[string]int32, map[string]registryService) {
 	registry := make(map[string]int32)
+	descriptions := make(map[string]registryService)
 
 	// Attempt to pull from REST gateway if available.
 	if config.HubProviderURI != "" {
@@ -223,21 +238,24 @@ func loadServiceRegistry(config conf.Configuration, logger log.Logger) map[strin
 			defer resp.Body.Close()
 			var payload struct {
 				Services []struct {
-					ServiceId int32  `json:"service_id"`
-					Name      string `json:"name"`
+					ServiceId   int32  `json:"service_id"`
+					Name        string `json:"name"`
+					Description string `json:"description"`
+					ServiceType string `json:"service_type"`
 				} `json:"services"`
 			}
 			if err := json.NewDecoder(resp.Body).Decode(&payload); err == nil {
 				for _, svc := range payload.Services {
 					registry[strings.ToLower(svc.Name)] = svc.ServiceId
+					descriptions[strings.ToLower(svc.Name)] = registryService{Description: svc.Description, Type: svc.ServiceType}
 				}
 			} else {
-				logger.Error("DEBUG: failed to decode registry response", "err", err)
+				logger.Error("failed to decode registry response", "error", err)
 			}
 		} else if err != nil {
-			logger.Error("DEBUG: failed to fetch registry", "err", err)
+			logger.Error("failed to fetch registry", "error", err)
 		} else {
-			logger.Error("DEBUG: registry fetch non-200", "status", resp.StatusCode)
+			logger.Error("registry fetch non-200", "status", resp.StatusCode)
 		}
 	}
 
@@ -248,7 +266,7 @@ func loadServiceRegistry(config conf.Configuration, logger log.Logger) map[strin
 		}
 	}
 
-	return registry
+	return registry, descriptions
 }
 
 // refreshServiceRegistry updates the in-memory registry periodically.
@@ -260,36 +278,30 @@ func (p *Proxy) refreshServiceRegistry(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			reg := loadServiceRegistry(p.Config, p.logger)
+			config := p.currentConfig()
+			reg, descriptions := loadServiceRegistry(config, p.logger)
 			if len(reg) == 0 {
 				continue
 			}
 			p.serviceMu.Lock()
 			p.serviceIDs = reg
+			if len(descriptions) > 0 {
+				p.serviceInfo = descriptions
+			}
 			p.serviceMu.Unlock()
 
 			// rebuild proxies to include any new services (using existing config/env)
-			newProxies := loadProxies(p.Config, p.logger, reg)
-			p.proxyMu.Lock()
-			p.proxies = newProxies
-			p.proxyMu.Unlock()
+			newProxies := loadProxies(config, p.logger, reg)
+			p.swapUpstreams(newProxies, loadUpstreams(config, p.logger, newProxies))
 
-			p.logger.Info("DEBUG: refreshed service registry", "count", len(reg))
+			p.logger.Debug("refreshed service registry", "count", len(reg))
 		}
 	}
 }
 
 // Given a request, send it to the appropriate url
 func (p *Proxy) handleRequestAndRedirect(w http.ResponseWriter, r *http.Request) {
-	// Limit the Size of incoming requests
-
-	p.logger.Info("DEBUG:TRACE: handleRequestAndRedirect called",
-		"path", r.URL.Path,
-		"method", r.Method,
-		"query", r.URL.RawQuery,
-	)
-
-	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // TODO: Check
+	logger := p.requestLogger(r)
 
 	// remove arkauth query arg
 	values := r.URL.Query()
@@ -305,70 +317,64 @@ func (p *Proxy) handleRequestAndRedirect(w http.ResponseWriter, r *http.Request)
 		serviceName = parts[1]
 	}
 
-	// Print all proxies and their URIs (for live debugging)
-	p.proxyMu.RLock()
-	for k, v := range p.proxies {
-		if v == nil {
-			//p.logger.Info("DEBUG:TRACE: Current proxy state", "service", k, "uri", "[NOT CONFIGURED]")
-		} else {
-			p.logger.Info("DEBUG:TRACE: Current proxy state", "service", k, "uri", v.String())
-		}
-	}
-	p.proxyMu.RUnlock()
-
-	p.proxyMu.RLock()
-	uri, exists := p.proxies[serviceName]
-	p.proxyMu.RUnlock()
-	if !exists || uri == nil {
-		p.logger.Error("DEBUG:TRACE: Service proxy not found or nil", "serviceName", serviceName)
+	pool := p.upstreamPool(serviceName)
+	if pool == nil {
+		logger.Debug("service not found", "service", serviceName)
 		respondWithError(w, "could not find service", http.StatusBadRequest)
 		return
 	}
+	logger.Debug("service selected", "service", serviceName, "upstreams", pool.Len())
 
-	p.logger.Info("DEBUG: Service selected",
-		"serviceName", serviceName,
-		"target_uri", uri.String(),
-	)
-
-	r.URL.Scheme = uri.Scheme
-	r.URL.Host = uri.Host
-	r.URL.User = uri.User
-	if pulledFromPath {
-		parts[1] = uri.Path // replace the service name with uri path (if exists)
-		r.URL.Path = path.Join(parts...)
-	}
+	// point the request at an upstream of the service
+	rewrite := func(req *http.Request, uri *url.URL) {
+		setUpstreamURL(req, uri)
+		if pulledFromPath {
+			// replace the service name with uri path (if exists)
+			rest := append([]string{"", uri.Path}, parts[2:]...)
+			req.URL.Path = path.Join(rest...)
+			req.URL.RawPath = ""
+		}
 
-	// Sanitize URL
-	// ensure a path always has a "/" prefix
-	if len(r.URL.Path) > 1 && !strings.HasPrefix(r.URL.Path, "/") {
-		r.URL.Path = fmt.Sprintf("/%s", r.URL.Path)
+		// Sanitize URL
+		// ensure a path always has a "/" prefix
+		if len(req.URL.Path) > 1 && !strings.HasPrefix(req.URL.Path, "/") {
+			req.URL.Path = fmt.Sprintf("/%s", req.URL.Path)
+		}
 	}
 
-	// check for the WebSocket upgrade header
+	// check for the WebSocket upgrade header, WebSockets have limits of
+	// their own rather than the request timeout
 	if websocket.IsWebSocketUpgrade(r) {
-		p.logger.Info("[TRACE] WebSocket upgrade detected", "url", r.URL.String())
-		fmt.Println(">>>>>>> Entering websocket....")
-		wsProxyURL := *r.URL
-		wsProxyURL.Scheme = "ws" // use the WebSocket scheme
-		wsProxy := websocketproxy.NewProxy(&wsProxyURL)
-		wsProxy.ServeHTTP(w, r)
+		p.serveWebSocket(w, r, pool, rewrite)
 		return
 	}
 
-	// Serve a reverse proxy for a given url
-	// create the reverse proxy
-	proxy := common.NewSingleHostReverseProxy(r.URL)
+	// Limit the size and duration of incoming requests
+	svc, _ := p.serviceConfig(serviceName)
+	r, cancel := p.limitRequest(w, r, svc)
+	defer cancel()
+
+	// Serve a reverse proxy for the upstreams of the service, idempotent
+	// requests failing on one upstream are retried on another. Streamed
+	// answers are flushed as they come.
+	proxy := &httputil.ReverseProxy{
+		Director:      func(req *http.Request) {},
+		Transport:     pool.Transport(rewrite),
+		FlushInterval: flushInterval(svc),
+	}
 	proxy.ModifyResponse = func(resp *http.Response) error {
-		p.logger.Info("DEBUG:PROXY: Upstream response", "status", resp.StatusCode, "url", resp.Request.URL.String())
+		logger.Debug("upstream response", "service", serviceName, "status", resp.StatusCode, "url", resp.Request.URL)
 		return nil
 	}
 	proxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
-		p.logger.Error("DEBUG:PROXY ERROR: ", "err", err, "target", r.URL.String(), "serviceName", serviceName)
-		http.Error(rw, "Proxy error: "+err.Error(), http.StatusBadGateway)
+		logger.Error("proxy error", "service", serviceName, "error", err)
+		http.Error(rw, "Proxy error: "+err.Error(), proxyErrorStatus(err))
 	}
-	p.logger.Info("DEBUG: Outgoing Proxy URL", "url", r.URL.String(), "method", r.Method)
-	proxy.ServeHTTP(w, r)
-	p.logger.Info("DEBUG:TRACE: Proxy call completed", "url", r.URL.String())
+	servicePath := r.URL.Path
+	if pulledFromPath {
+		servicePath = "/" + strings.Join(parts[2:], "/")
+	}
+	p.serveCached(w, r, pool, servicePath, proxy)
 }
 
 func (p *Proxy) handleMetadata(w http.ResponseWriter, r *http.Request) {
@@ -392,14 +398,21 @@ func (p *Proxy) handleMetadata(w http.ResponseWriter, r *http.Request) {
 		EventStreamHost   string        `json:"event_stream_host"`
 		ProviderPubKey    string        `json:"provider_pubkey"`
 		FreeTierRateLimit int           `json:"free_tier_rate_limit"`
+		X402Mode          string        `json:"x402_mode"`
 		Services          []serviceInfo `json:"services"`
 	}
 	type metadataResponse struct {
-		Version string     `json:"version"`
-		Config  configInfo `json:"config"`
+		Version       string                       `json:"version"`
+		Config        configInfo                   `json:"config"`
+		Upstreams     map[string][]UpstreamStatus  `json:"upstreams,omitempty"`
+		SyncStatus    map[string]ServiceSyncStatus `json:"sync_status,omitempty"`
+		ResponseCache *ResponseCacheStats          `json:"response_cache,omitempty"`
 	}
 
-	cfg := p.Metadata.Configuration // use the canonical config as source
+	p.configMu.RLock()
+	metadata := p.Metadata // Reload replaces it under the write lock
+	p.configMu.RUnlock()
+	cfg := metadata.Configuration // use the canonical config as source
 
 	// Build the services array from config.Services
 	services := make([]serviceInfo, 0, len(cfg.Services))
@@ -422,12 +435,19 @@ func (p *Proxy) handleMetadata(w http.ResponseWriter, r *http.Request) {
 		EventStreamHost:   cfg.EventStreamHost,
 		ProviderPubKey:    cfg.ProviderPubKey.String(),
 		FreeTierRateLimit: cfg.FreeTierRateLimit,
+		X402Mode:          cfg.GetX402Mode(),
 		Services:          services,
 	}
 
 	resp := metadataResponse{
-		Version: p.Metadata.Version,
-		Config:  config,
+		Version:    metadata.Version,
+		Config:     config,
+		Upstreams:  p.upstreamStatus(),
+		SyncStatus: p.syncStatus(),
+	}
+	if p.cache != nil {
+		stats := p.cache.Stats()
+		resp.ResponseCache = &stats
 	}
 
 	d, _ := json.Marshal(resp)
@@ -458,19 +478,7 @@ func (p *Proxy) handleContract(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	p.logger.Info("DEBUG: Incoming Headers")
-	for name, values := range r.Header {
-		for _, value := range values {
-			p.logger.Info("Header", "name", name, "value", value)
-		}
-	}
-	if r.Method == http.MethodPost {
-		bodyBytes, _ := io.ReadAll(r.Body)
-		r.Body = io.NopCloser(bytes.NewBuffer(bodyBytes)) // Reset so the proxy can read again
-		p.logger.Info("[DEBUG] Request Body", "body", string(bodyBytes))
-	}
-	p.logger.Info("DEBUG:URL Query", "rawquery", r.URL.RawQuery)
-	p.logger.Info("DEBUG:QueryContract", "QueryContract", QueryContract)
+	logger := p.requestLogger(r)
 
 	// check authorization
 	var auth ContractAuth
@@ -484,41 +492,34 @@ func (p *Proxy) handleContract(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	p.logger.Info("DEBUG:raw", "raw", raw)
-
 	if len(raw) > 0 {
-
 		auth, err = parseContractAuth(raw)
 		if err != nil {
-			p.logger.Error("fail to parse contract auth", "error", err, "auth", raw[0])
+			logger.Error("fail to parse contract auth", "error", err)
 			respondWithError(w, fmt.Sprintf("bad contract auth: %s", err), http.StatusBadRequest)
 			return
 		}
 
-		p.logger.Info("DEBUG:parsed auth", "auth", auth)
-
 		contract, err := p.MemStore.Get(contractConf.Key())
 		if err != nil {
-			p.logger.Error("fail to fetch contract", "error", err, "id", contractConf.Key())
+			logger.Error("fail to fetch contract", "error", err, "id", contractConf.Key())
 			respondWithError(w, fmt.Sprintf("missing contract: %s", err), http.StatusNotFound)
 			return
 		}
 
-		p.logger.Info("DEBUG:fetched contract", "contract", contract)
-
 		if err := auth.Validate(contractConf.LastTimeStamp, contract.Client); err != nil {
-			p.logger.Error("fail to validate contract auth", "error", err, "auth", auth.String())
+			logger.Error("fail to validate contract auth", "error", err, "contract_id", auth.ContractId)
 			respondWithError(w, fmt.Sprintf("bad contract auth: %s", err), http.StatusBadRequest)
 			return
 		}
 		contractConf.LastTimeStamp = auth.Timestamp
 		if err := p.ContractConfigStore.Set(contractConf); err != nil {
-			p.logger.Error("fail to save contract config", "error", err, "auth", auth.String())
+			logger.Error("fail to save contract config", "error", err, "contract_id", auth.ContractId)
 			respondWithError(w, fmt.Sprintf("fail to save contract config: %s", err), http.StatusBadRequest)
 			return
 		}
 	} else {
-		p.logger.Error("missing contract auth")
+		logger.Error("missing contract auth")
 		respondWithError(w, fmt.Sprintf("missing contract auth: %s", err), http.StatusBadRequest)
 		return
 	}
@@ -694,96 +695,145 @@ func (p *Proxy) handleClaim(w http.ResponseWriter, r *http.Request) {
 		respondWithError(w, fmt.Sprintf("fetch contract error: %s", err), http.StatusBadRequest)
 		return
 	}
-	p.logger.Info(fmt.Sprintf("claim data %v", claim))
+	p.logger.Debug("claim data", "claim", claim)
 
 	d, _ := json.Marshal(claim)
 	_, _ = w.Write(d)
 }
 
-func (p *Proxy) Run() {
+// Run serves the sentinel until SIGINT or SIGTERM, or until a server fails.
+// On shutdown the requests in flight are drained and the stores closed.
+// SIGHUP reloads the configuration file.
+func (p *Proxy) Run() error {
 	p.logger.Info("Starting Sentinel (reverse proxy)....")
 	p.Config.Print()
 
-	go p.EventListener(p.Config.EventStreamHost, p.authManager)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
 	router := p.getRouter()
 
-	// Configure Logrus
-	logrus.SetFormatter(&logrus.TextFormatter{})
-	logrus.SetOutput(os.Stdout)
-	logrus.SetLevel(logrus.InfoLevel)
+	// certificates are read before anything runs, so a bad one stops the start
+	if err := p.loadTLS(); err != nil {
+		return errors.Join(err, p.Close())
+	}
+	adminTLS, err := p.adminTLSConfig()
+	if err != nil {
+		return errors.Join(err, p.Close())
+	}
 
-	// Periodically refresh registry in background.
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// background jobs, stopped before the stores are closed
 	var g errgroup.Group
+	g.Go(func() error {
+		p.EventListener(ctx, p.Config.EventStreamHost, p.authManager)
+		return nil
+	})
+	g.Go(func() error {
+		p.reloadOnSignal(ctx)
+		return nil
+	})
 	g.Go(func() error {
 		p.refreshServiceRegistry(ctx)
 		return nil
 	})
+	g.Go(func() error {
+		p.pruneX402Payments(ctx)
+		return nil
+	})
+	g.Go(func() error {
+		p.checkUpstreams(ctx)
+		return nil
+	})
+	g.Go(func() error {
+		p.pruneResponseCache(ctx)
+		return nil
+	})
+	g.Go(func() error {
+		p.watchCertificates(ctx)
+		return nil
+	})
+	if !p.Config.Metrics.Disabled {
+		g.Go(func() error {
+			p.watchEventStream(ctx)
+			return nil
+		})
+	}
+	if p.x402Revenue != nil {
+		g.Go(func() error {
+			p.x402Revenue.Run(ctx)
+			return nil
+		})
+	}
 
-	// Add the Logrus middleware to the router
-	loggingRouter := p.logrusMiddleware(router)
+	// give every request an id and log it once served
+	loggingRouter := withRequestID(p.logRequests(router))
 
-	// Check if TLS certificates are configured
-	if p.Config.TLS.HasTLS() {
-		// Start a goroutine that listens to on port 80 and redirects HTTP to HTTPS
+	var servers []*http.Server
+	errs := make(chan error, 4)
+	serve := func(server *http.Server, listen func() error) {
+		servers = append(servers, server)
 		go func() {
-			redirectServer := &http.Server{
-				Addr: fmt.Sprintf(":%s", p.Config.Port),
-				Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-					http.Redirect(w, r, "https://"+r.Host+r.URL.String(), http.StatusMovedPermanently)
-				}),
-				ReadTimeout:  5 * time.Second,
-				WriteTimeout: 5 * time.Second,
-				IdleTimeout:  5 * time.Second,
-			}
-			if err := redirectServer.ListenAndServe(); err != nil {
-				panic(err)
+			if err := listen(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- fmt.Errorf("server %s stopped: %w", server.Addr, err)
 			}
 		}()
+	}
 
-		// Start HTTPS server on port 443
-		server := &http.Server{
-			Addr:              ":443",
-			Handler:           loggingRouter,
-			ReadTimeout:       5 * time.Second, // TODO: updated it to use config
-			ReadHeaderTimeout: 5 * time.Second,
-			WriteTimeout:      5 * time.Second,
-			IdleTimeout:       120 * time.Second,
-			TLSConfig: &tls.Config{
-				// Policies
-				MinVersion: tls.VersionTLS13,
-				CipherSuites: []uint16{
-					tls.TLS_AES_128_GCM_SHA256,
-					tls.TLS_AES_256_GCM_SHA384,
-					tls.TLS_CHACHA20_POLY1305_SHA256,
-				},
-			},
-			MaxHeaderBytes: 1 << 20,
-		}
-		if err := server.ListenAndServeTLS(p.Config.TLS.Cert, p.Config.TLS.Key); err != nil {
-			panic(err)
+	if !p.Config.Metrics.Disabled && p.Config.Metrics.Listen != "" {
+		// metrics apart from the public port
+		metricsServer := p.newServer(p.Config.Metrics.Listen, p.handleMetrics())
+		serve(metricsServer, metricsServer.ListenAndServe)
+	}
+	if p.Config.Admin.Listen != "" {
+		adminServer := p.newServer(p.Config.Admin.Listen, p.getAdminRouter())
+		if adminTLS != nil {
+			// mutual TLS, only clients with a certificate of the client CA
+			adminServer.TLSConfig = adminTLS
+			serve(adminServer, func() error {
+				return adminServer.ListenAndServeTLS("", "")
+			})
+		} else {
+			serve(adminServer, adminServer.ListenAndServe)
 		}
+	}
+
+	// Check if TLS certificates or ACME are configured
+	if p.Config.TLS.HasTLS() {
+		// Listen on the HTTP port and redirect HTTP to HTTPS
+		redirectServer := p.newServer(fmt.Sprintf(":%s", p.httpPort()), p.redirectToHTTPS())
+		serve(redirectServer, redirectServer.ListenAndServe)
+
+		// Start HTTPS server on the HTTPS port, the certificates are
+		// reloaded or renewed while serving
+		server := p.newServer(fmt.Sprintf(":%s", p.httpsPort()), loggingRouter)
+		server.TLSConfig = p.tlsConfig()
+		serve(server, func() error {
+			return server.ListenAndServeTLS("", "")
+		})
 	} else {
 		// Start an HTTP server on the configured port
-		server := &http.Server{
-			Addr:              fmt.Sprintf(":%s", p.Config.Port),
-			Handler:           loggingRouter,
-			ReadTimeout:       5 * time.Second, // TODO: updated it to use config
-			ReadHeaderTimeout: 5 * time.Second,
-			WriteTimeout:      5 * time.Second,
-			IdleTimeout:       120 * time.Second,
-			MaxHeaderBytes:    1 << 20,
-		}
-		if err := server.ListenAndServe(); err != nil {
-			cancel()
-			panic(err)
-		}
+		server := p.newServer(fmt.Sprintf(":%s", p.Config.Port), loggingRouter)
+		serve(server, server.ListenAndServe)
+	}
+
+	select {
+	case <-ctx.Done():
+		p.logger.Info("shutting down, draining requests", "timeout", p.shutdownTimeout())
+	case err = <-errs:
+		p.logger.Error("shutting down", "error", err)
 	}
+	cancel()
 
-	// wait for background goroutines (refresh) to exit
+	p.shutdownServers(servers)
+	// wait for background goroutines to exit before closing the stores
 	_ = g.Wait()
+	if closeErr := p.Close(); closeErr != nil {
+		p.logger.Error("failed to close stores", "error", closeErr)
+		err = errors.Join(err, closeErr)
+	}
+	return err
 }
 
 func (p *Proxy) getRouter() *mux.Router {
@@ -801,38 +851,30 @@ func (p *Proxy) getRouter() *mux.Router {
 
 	router.HandleFunc(RouteManage, p.handleContract).Methods(http.MethodGet, http.MethodPost)
 	router.HandleFunc(RouteProviderData, p.handleProviderData).Methods(http.MethodGet)
+	if !p.Config.Metrics.Disabled && p.Config.Metrics.Public {
+		router.Handle(RoutesMetrics, p.handleMetrics()).Methods(http.MethodGet)
+	}
 	
-	// x402 AI Agent Payment Routes
-	// Initialize x402 handler with provider address from config
-	if p.Config.X402Enabled {
-		p.InitX402(p.Config.X402ProviderAddress)
+	// x402 AI Agent Payment Routes (handler is initialized in NewProxy)
+	if p.x402 != nil {
 		p.RegisterX402Routes(router)
-		p.logger.Info("x402 AI agent payments enabled")
+		p.logger.Info("x402 AI agent payments enabled", "mode", p.Config.GetX402Mode())
 	}
 	
 	router.PathPrefix("/").Handler(
-		p.auth(
-			handlers.ProxyHeaders(
-				http.HandlerFunc(p.handleRequestAndRedirect),
+		p.instrument("", requestServiceName,
+			p.inspectJSONRPC(requestServiceName,
+				p.auth(
+					handlers.ProxyHeaders(
+						http.HandlerFunc(p.handleRequestAndRedirect),
+					),
+				),
 			),
 		),
 	)
 	return router
 }
 
-func (p *Proxy) logrusMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		logger := logrus.WithFields(logrus.Fields{
-			"method": r.Method,
-			"url":    r.URL.String(),
-			"remote": p.getRemoteAddr(r),
-		})
-		next.ServeHTTP(w, r)
-		logger.Infof("Request handled in %v", time.Since(start))
-	})
-}
-
 func respondWithError(w http.ResponseWriter, message string, code int) {
 	respondWithJSON(w, code, map[string]string{"error": message})
 }
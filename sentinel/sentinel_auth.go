@@ -179,22 +179,9 @@ func (auth ContractAuth) Validate(lastTimestamp int64, client common.PubKey) err
 
 	msg := fmt.Sprintf("%d:%d:%s", auth.ContractId, auth.Timestamp, auth.ChainId)
 
-	// --- DEBUG PRINTS ---
-	fmt.Printf("DEBUG: ContractId: %d\n", auth.ContractId)
-	fmt.Printf("DEBUG: Timestamp:  %d\n", auth.Timestamp)
-	fmt.Printf("DEBUG: ChainId:    '%s'\n", auth.ChainId)
-	fmt.Printf("DEBUG: Message:    '%s'\n", msg)
-	fmt.Printf("DEBUG: Client PubKey (bech32): %s\n", client.String())
-	fmt.Printf("DEBUG: Client PubKey (hex):    %x\n", pk.Bytes())
-	fmt.Printf("DEBUG: Signature (hex):        %x\n", auth.Signature)
-	// --- END DEBUG PRINTS ---
-
 	if !pk.VerifySignature([]byte(msg), auth.Signature) {
-		fmt.Println("DEBUG: Signature verification FAILED")
 		return fmt.Errorf("invalid signature")
 	}
-
-	fmt.Println("DEBUG: Signature verification PASSED")
 	return nil
 }
 
@@ -203,7 +190,7 @@ func (auth ContractAuth) String() string {
 	return fmt.Sprintf("Contract Id: %d, Timestamp: %d, Signature: %s", auth.ContractId, auth.Timestamp, sig)
 }
 
-func (p Proxy) fetchArkAuth(r *http.Request) (aa ArkAuth, err error) {
+func (p *Proxy) fetchArkAuth(r *http.Request) (aa ArkAuth, err error) {
 	rawHeader := r.Header.Get(QueryArkAuth)
 	if len(rawHeader) > 0 {
 		aa, err = parseArkAuth(rawHeader, p.Config.SourceChain)
@@ -223,7 +210,7 @@ func (p Proxy) fetchArkAuth(r *http.Request) (aa ArkAuth, err error) {
 	return aa, nil
 }
 
-func (p Proxy) auth(next http.Handler) http.Handler {
+func (p *Proxy) auth(next http.Handler) http.Handler {
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
@@ -277,10 +264,7 @@ func (p Proxy) auth(next http.Handler) http.Handler {
 			return
 		}
 
-		p.logger.Info("DEBUG: ArkAuth parsed",
-			"raw", r.URL.Query().Get(QueryArkAuth),
-			"arkAuth", aa.String(),
-		)
+		p.requestLogger(r).Debug("arkauth parsed", "contract_id", aa.ContractId, "nonce", aa.Nonce)
 
 		var contract types.Contract
 		if aa.ContractId > 0 {
@@ -325,7 +309,7 @@ func (p Proxy) auth(next http.Handler) http.Handler {
 				}
 
 				if len(conf.WhitelistIPAddresses) > 0 && !whitelisted {
-					p.logger.Info("DEBUG: IP not in contract whitelist, falling through to free tier", "addr", addr)
+					p.logger.Debug("IP not in contract whitelist, falling through to free tier", "addr", addr)
 					// Do not return; fall through to free tier logic
 				}
 
@@ -347,7 +331,7 @@ func (p Proxy) auth(next http.Handler) http.Handler {
 			}
 
 			// Log the raw header and derived service name.
-			p.logger.Info("DEBUG: service header and path",
+			p.logger.Debug("service header and path",
 				"header_service", rawHeaderService,
 				"url_path", r.URL.Path,
 				"derived_service_name", serviceName,
@@ -359,7 +343,7 @@ func (p Proxy) auth(next http.Handler) http.Handler {
 			p.serviceMu.RUnlock()
 
 			if ok {
-				p.logger.Info("DEBUG: service match check",
+				p.logger.Debug("service match check",
 					"contract_id", contract.Id,
 					"contract_service_enum", contract.Service,
 					"request_service_id", reqServiceID,
@@ -378,7 +362,7 @@ func (p Proxy) auth(next http.Handler) http.Handler {
 			} else {
 				// Legacy logging fallback
 				ser, serr := common.NewService(serviceName)
-				p.logger.Info("DEBUG: service not in registry; legacy parse",
+				p.logger.Debug("service not in registry; legacy parse",
 					"serviceName", serviceName,
 					"contract_id", contract.Id,
 					"contract_service_enum", contract.Service,
@@ -388,12 +372,12 @@ func (p Proxy) auth(next http.Handler) http.Handler {
 				// allow if registry doesn’t know it (dynamic addition)
 			}
 
-			httpCode, tierErr := p.paidTier(aa, remoteAddr)
+			httpCode, tierErr := p.paidTier(aa, remoteAddr, jsonRPCCalls(r))
 			if tierErr == nil {
 				next.ServeHTTP(w, r)
 				return
 			}
-			p.logger.Error("DEBUG: paidTier failed", "error", tierErr, "http_code", httpCode)
+			p.requestLogger(r).Error("paid tier refused", "error", tierErr, "http_code", httpCode)
 			http.Error(w, tierErr.Error(), httpCode)
 			return
 		}
@@ -419,7 +403,7 @@ const (
 	xRealIPName       = `X-Real-Ip`
 )
 
-func (p Proxy) getRemoteAddr(r *http.Request) string {
+func (p *Proxy) getRemoteAddr(r *http.Request) string {
 	realIP := r.Header.Get(xRealIPName)
 	if realIP != "" {
 		return realIP
@@ -436,41 +420,57 @@ func (p Proxy) getRemoteAddr(r *http.Request) string {
 	return ip
 }
 
-func (p Proxy) isRateLimited(contractId uint64, key string, limitTokens int, windowSeconds int) bool {
+func (p *Proxy) isRateLimited(contractId uint64, key string, limitTokens int, windowSeconds int) bool {
 	mu.Lock()
 	defer mu.Unlock()
 
 	key = fmt.Sprintf("%d-%s", contractId, key)
+	limit := rate.Limit(float64(limitTokens) / float64(windowSeconds))
 	limiter, exists := visitors[key]
 	if !exists {
-		limiter = rate.NewLimiter(rate.Limit(float64(limitTokens)/float64(windowSeconds)), limitTokens)
+		limiter = rate.NewLimiter(limit, limitTokens)
 		visitors[key] = limiter
+	} else if limiter.Burst() != limitTokens || limiter.Limit() != limit {
+		// the rate was reloaded or the contract changed, keep the tokens left
+		// but not more than the new burst
+		limiter.SetLimit(limit)
+		limiter.SetBurst(limitTokens)
 	}
 
 	allowed := limiter.Allow()
-	p.logger.Info("DEBUG: Rate limiting check",
+	p.logger.Debug("rate limiting check",
 		"key", key,
 		"limit_per_sec", limiter.Limit(),
 		"burst", limiter.Burst(),
 		"allowed", allowed,
 	)
-	if allowed {
-		p.logger.Debug("DEBUG: Rate limit result", "status", "allowed", "key", key)
-	} else {
-		p.logger.Debug("DEBUG: Rate limit result", "status", "rate limited", "key", key)
-	}
 	return !allowed
 }
 
-func (p Proxy) freeTier(remoteAddr string) (int, error) {
-	if ok := p.isRateLimited(0, remoteAddr, p.Config.FreeTierRateLimit, 60); ok {
+// resetFreeTierLimiters forgets the limiters of the free tier, so a reloaded
+// rate applies to every client at once
+func resetFreeTierLimiters() {
+	mu.Lock()
+	defer mu.Unlock()
+	for key := range visitors {
+		if strings.HasPrefix(key, "0-") {
+			delete(visitors, key)
+		}
+	}
+}
+
+func (p *Proxy) freeTier(remoteAddr string) (int, error) {
+	if ok := p.isRateLimited(0, remoteAddr, p.currentConfig().FreeTierRateLimit, 60); ok {
+		observeRateLimited(TierFree, "rate_limited")
 		return http.StatusTooManyRequests, fmt.Errorf("free client is rate limited (%s)", http.StatusText(429))
 	}
 
 	return http.StatusOK, nil
 }
 
-func (p Proxy) paidTier(aa ArkAuth, remoteAddr string) (code int, err error) {
+// paidTier serves a request of a contract. A JSON-RPC batch is worth one
+// request per call: its nonce must be at least that many past the last one.
+func (p *Proxy) paidTier(aa ArkAuth, remoteAddr string, requests int64) (code int, err error) {
 
 	// Fetch contract by ID; error if not found or datastore issue.
 	key := strconv.FormatUint(aa.ContractId, 10)
@@ -500,6 +500,7 @@ func (p Proxy) paidTier(aa ArkAuth, remoteAddr string) (code int, err error) {
 
 	// Enforce per-contract paid tier rate limiting.
 	if ok := p.isRateLimited(contract.Id, remoteAddr, int(contract.QueriesPerMinute), 60); ok {
+		observeRateLimited(TierPaid, "rate_limited")
 		return http.StatusTooManyRequests, fmt.Errorf("paid client is rate limited (%s)", http.StatusText(429))
 	}
 
@@ -571,6 +572,11 @@ func (p Proxy) paidTier(aa ArkAuth, remoteAddr string) (code int, err error) {
 		if claim.Nonce >= aa.Nonce {
 			return http.StatusBadRequest, fmt.Errorf("bad nonce (%d/%d)", aa.Nonce, claim.Nonce)
 		}
+		if aa.Nonce-claim.Nonce < requests {
+			return http.StatusBadRequest, fmt.Errorf("bad nonce (%d/%d): a batch of %d calls needs nonce %d", aa.Nonce, claim.Nonce, requests, claim.Nonce+requests)
+		}
+	} else if aa.Nonce < requests {
+		return http.StatusBadRequest, fmt.Errorf("bad nonce (%d/0): a batch of %d calls needs nonce %d", aa.Nonce, requests, requests)
 	}
 
 	// Update claim and contract state for Pay-As-You-Go contracts.
@@ -618,7 +624,7 @@ func (p Proxy) paidTier(aa ArkAuth, remoteAddr string) (code int, err error) {
 	return http.StatusOK, nil
 }
 
-func (p Proxy) enableCORS(w http.ResponseWriter, cors CORs) http.ResponseWriter {
+func (p *Proxy) enableCORS(w http.ResponseWriter, cors CORs) http.ResponseWriter {
 	if len(cors.AllowOrigins) > 0 {
 		w.Header().Set("Access-Control-Allow-Origin", strings.Join(cors.AllowOrigins, ", "))
 	}
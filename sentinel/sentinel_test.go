@@ -117,7 +117,7 @@ func TestHandleClaim(t *testing.T) {
 		Spender:    inputContract.Client,
 		Nonce:      10,
 	}
-	_, err = proxy.paidTier(arkAuth, "")
+	_, err = proxy.paidTier(arkAuth, "", 1)
 	require.NoError(t, err)
 
 	// get the expected claim
@@ -185,7 +185,7 @@ func TestHandleOpenClaims(t *testing.T) {
 		Spender:    inputContract.Client,
 		Nonce:      10,
 	}
-	_, err = proxy.paidTier(arkAuth, "")
+	_, err = proxy.paidTier(arkAuth, "", 1)
 	require.NoError(t, err)
 
 	// repeat for a second contract rom a different client
@@ -202,7 +202,7 @@ func TestHandleOpenClaims(t *testing.T) {
 		Spender:    inputContract.Client,
 		Nonce:      15,
 	}
-	_, err = proxy.paidTier(arkAuth, "")
+	_, err = proxy.paidTier(arkAuth, "", 1)
 	require.NoError(t, err)
 
 	// we should have 2 valid claim in our store.
@@ -0,0 +1,117 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+const (
+	defaultReadHeaderTimeout = 10 * time.Second
+	defaultReadTimeout       = 30 * time.Second
+	defaultWriteTimeout      = 60 * time.Second
+	defaultIdleTimeout       = 120 * time.Second
+	defaultMaxHeaderBytes    = 1 << 20
+
+	// defaultMaxBodySize is the largest request body to a service
+	defaultMaxBodySize = 1 << 20
+
+	// timeoutGrace is the time left to answer a request that timed out
+	timeoutGrace = 5 * time.Second
+)
+
+// withServerDefaults fills unset server timeouts with defaults
+func withServerDefaults(config conf.ServerConfig) conf.ServerConfig {
+	if config.ReadHeaderTimeout == 0 {
+		config.ReadHeaderTimeout = uint64(defaultReadHeaderTimeout / time.Second)
+	}
+	if config.ReadTimeout == 0 {
+		config.ReadTimeout = uint64(defaultReadTimeout / time.Second)
+	}
+	if config.WriteTimeout == 0 {
+		config.WriteTimeout = uint64(defaultWriteTimeout / time.Second)
+	}
+	if config.IdleTimeout == 0 {
+		config.IdleTimeout = uint64(defaultIdleTimeout / time.Second)
+	}
+	if config.MaxHeaderBytes <= 0 {
+		config.MaxHeaderBytes = defaultMaxHeaderBytes
+	}
+	return config
+}
+
+// newServer returns a server of handler on addr with the configured timeouts
+func (p *Proxy) newServer(addr string, handler http.Handler) *http.Server {
+	config := withServerDefaults(p.Config.Server)
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: time.Duration(config.ReadHeaderTimeout) * time.Second,
+		ReadTimeout:       time.Duration(config.ReadTimeout) * time.Second,
+		WriteTimeout:      time.Duration(config.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(config.IdleTimeout) * time.Second,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+	}
+}
+
+// requestTimeout returns how long a request to service may take
+func (p *Proxy) requestTimeout(service conf.ServiceConfig) time.Duration {
+	if service.Timeout > 0 {
+		return time.Duration(service.Timeout) * time.Second
+	}
+	return time.Duration(withServerDefaults(p.Config.Server).WriteTimeout) * time.Second
+}
+
+// maxBodySize returns the largest request body to service
+func (p *Proxy) maxBodySize(service string) int64 {
+	if svc, ok := p.serviceConfig(service); ok && svc.MaxBodySize > 0 {
+		return svc.MaxBodySize
+	}
+	return defaultMaxBodySize
+}
+
+// limitRequest bounds the body size and duration of a request to service.
+// The request timeout replaces the read and write timeouts of the server, so
+// long calls and streamed answers are not cut off, with some grace to answer
+// a request that timed out. The returned request carries the deadline to the
+// upstream, cancel releases it.
+func (p *Proxy) limitRequest(w http.ResponseWriter, r *http.Request, service conf.ServiceConfig) (*http.Request, context.CancelFunc) {
+	maxBody := service.MaxBodySize
+	if maxBody <= 0 {
+		maxBody = defaultMaxBodySize
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+
+	deadline := time.Now().Add(p.requestTimeout(service))
+	// unsupported by test recorders only, the server deadlines then hold
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(deadline.Add(timeoutGrace))
+	_ = rc.SetWriteDeadline(deadline.Add(timeoutGrace))
+	ctx, cancel := context.WithDeadline(r.Context(), deadline)
+	return r.WithContext(ctx), cancel
+}
+
+// flushInterval returns how often answers of service are flushed to the
+// client. The reverse proxy flushes server-sent events and answers without a
+// Content-Length right away whatever the interval.
+func flushInterval(service conf.ServiceConfig) time.Duration {
+	return time.Duration(service.FlushIntervalMs) * time.Millisecond
+}
+
+// proxyErrorStatus returns the status of a request the reverse proxy failed
+func proxyErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusGatewayTimeout
+	case errors.Is(err, errNoUpstream):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusBadGateway
+	}
+}
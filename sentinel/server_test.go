@@ -0,0 +1,82 @@
+package sentinel
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+func TestServiceLimits(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/slow":
+			time.Sleep(2 * time.Second)
+		case "/events":
+			// an event, then the rest once the client saw it
+			w.Header().Set("Content-Type", "text/event-stream")
+			_, _ = w.Write([]byte("data: 1\n\n"))
+			w.(http.Flusher).Flush()
+			<-r.Context().Done()
+		}
+	}))
+	defer upstream.Close()
+
+	proxy := &Proxy{
+		Config: conf.Configuration{Services: []conf.ServiceConfig{
+			{Name: "eth-mainnet-fullnode", Timeout: 1, MaxBodySize: 16},
+		}},
+		logger:  log.NewNopLogger(),
+		proxies: map[string]*url.URL{"eth-mainnet-fullnode": mustParseURL(t, upstream.URL)},
+	}
+
+	// the free tier and the paid routes past the payment check
+	for name, handler := range map[string]http.Handler{
+		"/eth-mainnet-fullnode":      proxy.instrument("", requestServiceName, http.HandlerFunc(proxy.handleRequestAndRedirect)),
+		"/x402/eth-mainnet-fullnode": http.HandlerFunc(proxy.handleX402Proxy),
+	} {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(handler)
+			defer server.Close()
+			base := server.URL + name
+
+			resp, err := http.Post(base+"/", "application/json", strings.NewReader(`{"jsonrpc":"2.0","id":1}`))
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+			resp, err = http.Get(base + "/slow")
+			require.NoError(t, err)
+			resp.Body.Close()
+			require.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+
+			// server-sent events reach the client as they are sent
+			resp, err = http.Get(base + "/events")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			line, err := bufio.NewReader(resp.Body).ReadString('\n')
+			require.NoError(t, err)
+			require.Equal(t, "data: 1\n", line)
+		})
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	proxy := &Proxy{Config: conf.Configuration{Server: conf.ServerConfig{WriteTimeout: 300}}}
+	server := proxy.newServer(":3636", http.NotFoundHandler())
+	require.Equal(t, 300*time.Second, server.WriteTimeout)
+	require.Equal(t, defaultReadTimeout, server.ReadTimeout)
+	require.Equal(t, defaultReadHeaderTimeout, server.ReadHeaderTimeout)
+	require.Equal(t, defaultMaxHeaderBytes, server.MaxHeaderBytes)
+
+	require.Equal(t, 300*time.Second, proxy.requestTimeout(conf.ServiceConfig{}))
+	require.Equal(t, 900*time.Second, proxy.requestTimeout(conf.ServiceConfig{Timeout: 900}))
+}
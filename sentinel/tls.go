@@ -0,0 +1,310 @@
+package sentinel
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+const (
+	defaultHTTPSPort = "443"
+
+	// certificateCheckInterval is how often certificate files are checked
+	// for a renewed certificate
+	certificateCheckInterval = time.Minute
+
+	// ACME directories known by name
+	ACMELetsEncrypt     = "letsencrypt"
+	ACMEZeroSSL         = "zerossl"
+	zeroSSLDirectoryURL = "https://acme.zerossl.com/v2/DV90"
+)
+
+// CertificateFiles serves a certificate and key read from PEM files. The
+// files are read again when they change, so renewed certificates are served
+// without a restart.
+type CertificateFiles struct {
+	certFile string
+	keyFile  string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// NewCertificateFiles reads the certificate of certFile and keyFile
+func NewCertificateFiles(certFile, keyFile string) (*CertificateFiles, error) {
+	c := &CertificateFiles{certFile: certFile, keyFile: keyFile}
+	if err := c.Reload(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Reload reads the certificate files again. The certificate served is kept
+// when they are invalid.
+func (c *CertificateFiles) Reload() error {
+	modTime, err := c.lastModified()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(c.certFile, c.keyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load certificate %s: %w", c.certFile, err)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cert = &cert
+	c.modTime = modTime
+	return nil
+}
+
+// lastModified returns when the certificate or key file last changed
+func (c *CertificateFiles) lastModified() (time.Time, error) {
+	var last time.Time
+	for _, file := range []string{c.certFile, c.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return last, fmt.Errorf("failed to read certificate: %w", err)
+		}
+		if info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+	return last, nil
+}
+
+// changed reports whether the files changed since they were last read
+func (c *CertificateFiles) changed() bool {
+	modTime, err := c.lastModified()
+	if err != nil {
+		return false
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return !modTime.Equal(c.modTime)
+}
+
+// GetCertificate implements tls.Config.GetCertificate
+func (c *CertificateFiles) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.cert, nil
+}
+
+// NewACMEManager returns the manager obtaining and renewing the certificates
+// of the ACME domains. They are kept in the cache directory across restarts.
+func NewACMEManager(config conf.ACMEConfig) (*autocert.Manager, error) {
+	if len(config.Domains) == 0 {
+		return nil, fmt.Errorf("tls.acme needs domains")
+	}
+	cacheDir := config.CacheDir
+	if cacheDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("tls.acme needs a cache_dir: %w", err)
+		}
+		cacheDir = filepath.Join(home, ".arkeo", "acme")
+	}
+
+	directoryURL := config.DirectoryURL
+	switch strings.ToLower(directoryURL) {
+	case "", ACMELetsEncrypt:
+		directoryURL = acme.LetsEncryptURL
+	case ACMEZeroSSL:
+		directoryURL = zeroSSLDirectoryURL
+		if config.EABKeyID == "" {
+			return nil, fmt.Errorf("tls.acme with ZeroSSL needs eab_key_id and eab_hmac_key")
+		}
+	}
+
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		Cache:      autocert.DirCache(cacheDir),
+		HostPolicy: autocert.HostWhitelist(config.Domains...),
+		Email:      config.Email,
+		Client:     &acme.Client{DirectoryURL: directoryURL},
+	}
+	if config.EABKeyID != "" {
+		key, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(config.EABHMACKey, "="))
+		if err != nil || len(key) == 0 {
+			return nil, fmt.Errorf("tls.acme eab_hmac_key is not base64url encoded")
+		}
+		manager.ExternalAccountBinding = &acme.ExternalAccountBinding{KID: config.EABKeyID, Key: key}
+	}
+	return manager, nil
+}
+
+// loadTLS reads the certificates, or sets up ACME, of the public port and the
+// admin routes
+func (p *Proxy) loadTLS() error {
+	config := p.Config.TLS
+	switch {
+	case config.ACME.Enabled:
+		manager, err := NewACMEManager(config.ACME)
+		if err != nil {
+			return err
+		}
+		p.acme = manager
+	case config.HasTLS():
+		certificates, err := NewCertificateFiles(config.Cert, config.Key)
+		if err != nil {
+			return err
+		}
+		p.certificates = certificates
+	}
+
+	admin := p.Config.Admin
+	if admin.ClientCA == "" || admin.Cert == "" || admin.Key == "" {
+		return nil
+	}
+	certificates, err := NewCertificateFiles(admin.Cert, admin.Key)
+	if err != nil {
+		return err
+	}
+	p.adminCertificates = certificates
+	return nil
+}
+
+// tlsConfig returns the TLS configuration of the public port
+func (p *Proxy) tlsConfig() *tls.Config {
+	config := &tls.Config{
+		// Policies
+		MinVersion: tls.VersionTLS13,
+		CipherSuites: []uint16{
+			tls.TLS_AES_128_GCM_SHA256,
+			tls.TLS_AES_256_GCM_SHA384,
+			tls.TLS_CHACHA20_POLY1305_SHA256,
+		},
+	}
+	if p.acme != nil {
+		config.GetCertificate = p.acme.GetCertificate
+		// answer the TLS-ALPN-01 challenges on the HTTPS port
+		config.NextProtos = []string{"h2", "http/1.1", acme.ALPNProto}
+	} else if p.certificates != nil {
+		config.GetCertificate = p.certificates.GetCertificate
+	}
+	return config
+}
+
+// adminTLSConfig returns the mutual TLS configuration of the admin routes,
+// nil when they are served without TLS
+func (p *Proxy) adminTLSConfig() (*tls.Config, error) {
+	admin := p.Config.Admin
+	if admin.ClientCA == "" {
+		return nil, nil
+	}
+	bundle, err := os.ReadFile(admin.ClientCA)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read admin client_ca: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(bundle) {
+		return nil, fmt.Errorf("admin client_ca %s has no PEM certificates", admin.ClientCA)
+	}
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS13,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	switch {
+	case p.adminCertificates != nil:
+		config.GetCertificate = p.adminCertificates.GetCertificate
+	case p.acme != nil:
+		config.GetCertificate = p.acme.GetCertificate
+	case p.certificates != nil:
+		config.GetCertificate = p.certificates.GetCertificate
+	default:
+		return nil, fmt.Errorf("admin client_ca needs admin cert and key, or a TLS certificate")
+	}
+	return config, nil
+}
+
+// httpsPort returns the port HTTPS is served on
+func (p *Proxy) httpsPort() string {
+	if p.Config.TLS.HTTPSPort == "" {
+		return defaultHTTPSPort
+	}
+	return p.Config.TLS.HTTPSPort
+}
+
+// httpPort returns the port redirecting to HTTPS
+func (p *Proxy) httpPort() string {
+	if p.Config.TLS.HTTPPort == "" {
+		return p.Config.Port
+	}
+	return p.Config.TLS.HTTPPort
+}
+
+// redirectToHTTPS sends requests to the same host on the HTTPS port, and
+// answers the ACME HTTP-01 challenges
+func (p *Proxy) redirectToHTTPS() http.Handler {
+	port := p.httpsPort()
+	redirect := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != defaultHTTPSPort {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+	if p.acme != nil {
+		return p.acme.HTTPHandler(redirect)
+	}
+	return redirect
+}
+
+// reloadCertificates reads the certificate files again, on a configuration
+// reload
+func (p *Proxy) reloadCertificates() {
+	for _, certificates := range []*CertificateFiles{p.certificates, p.adminCertificates} {
+		if certificates == nil {
+			continue
+		}
+		if err := certificates.Reload(); err != nil {
+			p.logger.Error("failed to reload certificate", "error", err)
+		}
+	}
+}
+
+// watchCertificates reads the certificate files again when they change,
+// until ctx is done. ACME certificates are renewed by their manager.
+func (p *Proxy) watchCertificates(ctx context.Context) {
+	if p.certificates == nil && p.adminCertificates == nil {
+		return
+	}
+	ticker := time.NewTicker(certificateCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, certificates := range []*CertificateFiles{p.certificates, p.adminCertificates} {
+				if certificates == nil || !certificates.changed() {
+					continue
+				}
+				if err := certificates.Reload(); err != nil {
+					p.logger.Error("failed to reload certificate", "error", err)
+					continue
+				}
+				p.logger.Info("certificate reloaded", "file", certificates.certFile)
+			}
+		}
+	}
+}
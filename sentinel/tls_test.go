@@ -0,0 +1,198 @@
+package sentinel
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/acme"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+// testCertificate is a certificate signed by a test CA, or self-signed
+type testCertificate struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCertificate(t *testing.T, name string, serial int64, ca *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	parent, parentKey := template, key
+	if ca == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+	} else {
+		parent, parentKey = ca.cert, ca.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCertificate{cert: cert, key: key}
+}
+
+// write writes the certificate and key as PEM files in dir
+func (c *testCertificate) write(t *testing.T, dir, name string) (certFile, keyFile string) {
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.cert.Raw}), 0o600))
+	der, err := x509.MarshalECPrivateKey(c.key)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), 0o600))
+	return certFile, keyFile
+}
+
+func TestCertificateFiles(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := newTestCertificate(t, "sentinel.example.com", 1, nil).write(t, dir, "sentinel")
+	certificates, err := NewCertificateFiles(certFile, keyFile)
+	require.NoError(t, err)
+	require.False(t, certificates.changed())
+
+	served := func() int64 {
+		cert, err := certificates.GetCertificate(nil)
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		require.NoError(t, err)
+		return leaf.SerialNumber.Int64()
+	}
+	require.Equal(t, int64(1), served())
+
+	// a renewed certificate is picked up
+	newTestCertificate(t, "sentinel.example.com", 2, nil).write(t, dir, "sentinel")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+	require.True(t, certificates.changed())
+	require.NoError(t, certificates.Reload())
+	require.False(t, certificates.changed())
+	require.Equal(t, int64(2), served())
+
+	// a broken one is not
+	require.NoError(t, os.WriteFile(certFile, []byte("garbage"), 0o600))
+	require.Error(t, certificates.Reload())
+	require.Equal(t, int64(2), served())
+
+	_, err = NewCertificateFiles(filepath.Join(dir, "missing.crt"), keyFile)
+	require.Error(t, err)
+}
+
+func TestNewACMEManager(t *testing.T) {
+	_, err := NewACMEManager(conf.ACMEConfig{Enabled: true})
+	require.Error(t, err)
+
+	manager, err := NewACMEManager(conf.ACMEConfig{Enabled: true, Domains: []string{"sentinel.example.com"}, CacheDir: t.TempDir()})
+	require.NoError(t, err)
+	require.Equal(t, acme.LetsEncryptURL, manager.Client.DirectoryURL)
+	require.Nil(t, manager.ExternalAccountBinding)
+
+	// ZeroSSL binds the account to an existing one
+	_, err = NewACMEManager(conf.ACMEConfig{Domains: []string{"sentinel.example.com"}, DirectoryURL: ACMEZeroSSL})
+	require.Error(t, err)
+	manager, err = NewACMEManager(conf.ACMEConfig{
+		Domains:      []string{"sentinel.example.com"},
+		CacheDir:     t.TempDir(),
+		DirectoryURL: ACMEZeroSSL,
+		EABKeyID:     "kid-1",
+		EABHMACKey:   "c2VjcmV0LWtleQ",
+	})
+	require.NoError(t, err)
+	require.Equal(t, zeroSSLDirectoryURL, manager.Client.DirectoryURL)
+	require.Equal(t, "kid-1", manager.ExternalAccountBinding.KID)
+	require.Equal(t, []byte("secret-key"), manager.ExternalAccountBinding.Key)
+
+	_, err = NewACMEManager(conf.ACMEConfig{Domains: []string{"sentinel.example.com"}, EABKeyID: "kid-1", EABHMACKey: "!!"})
+	require.Error(t, err)
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	proxy := &Proxy{Config: conf.Configuration{Port: "3636", TLS: conf.TLSConfiguration{HTTPSPort: "8443"}}}
+	require.Equal(t, "3636", proxy.httpPort())
+
+	w := httptest.NewRecorder()
+	proxy.redirectToHTTPS().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://sentinel.example.com:3636/metadata.json?x=1", nil))
+	require.Equal(t, http.StatusMovedPermanently, w.Code)
+	require.Equal(t, "https://sentinel.example.com:8443/metadata.json?x=1", w.Header().Get("Location"))
+
+	proxy.Config.TLS = conf.TLSConfiguration{HTTPPort: "80"}
+	require.Equal(t, "80", proxy.httpPort())
+	w = httptest.NewRecorder()
+	proxy.redirectToHTTPS().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://sentinel.example.com/claims", nil))
+	require.Equal(t, "https://sentinel.example.com/claims", w.Header().Get("Location"))
+}
+
+func TestAdminMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCertificate(t, "sentinel-ca", 1, nil)
+	caFile, _ := ca.write(t, dir, "ca")
+	certFile, keyFile := newTestCertificate(t, "admin.example.com", 2, ca).write(t, dir, "admin")
+	client := newTestCertificate(t, "operator", 3, ca)
+
+	proxy := &Proxy{
+		Config: conf.Configuration{Admin: conf.AdminConfig{ClientCA: caFile, Cert: certFile, Key: keyFile}},
+		logger: log.NewNopLogger(),
+	}
+	require.NoError(t, proxy.loadTLS())
+	adminTLS, err := proxy.adminTLSConfig()
+	require.NoError(t, err)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := &http.Server{Handler: proxy.getAdminRouter(), TLSConfig: adminTLS, ReadHeaderTimeout: time.Second}
+	go func() { _ = server.ServeTLS(listener, "", "") }()
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	post := func(certificates []tls.Certificate) (*http.Response, error) {
+		httpClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			ServerName:   "admin.example.com",
+			Certificates: certificates,
+		}}}
+		return httpClient.Post("https://"+listener.Addr().String()+RoutesAdminReload, "", nil)
+	}
+
+	// without a client certificate the handshake fails
+	_, err = post(nil)
+	require.Error(t, err)
+
+	resp, err := post([]tls.Certificate{{Certificate: [][]byte{client.cert.Raw}, PrivateKey: client.key}})
+	require.NoError(t, err)
+	resp.Body.Close()
+	// past mutual TLS, the reload fails for want of a configuration file
+	require.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+
+	// mutual TLS needs a server certificate
+	proxy = &Proxy{Config: conf.Configuration{Admin: conf.AdminConfig{ClientCA: caFile}}}
+	_, err = proxy.adminTLSConfig()
+	require.Error(t, err)
+	proxy.Config.Admin.ClientCA = filepath.Join(dir, "missing.pem")
+	_, err = proxy.adminTLSConfig()
+	require.True(t, errors.Is(err, os.ErrNotExist), err)
+}
@@ -0,0 +1,863 @@
+package sentinel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+const (
+	defaultUpstreamInterval     = 15
+	defaultUpstreamTimeout      = 5
+	defaultUpstreamEjectAfter   = 3
+	defaultUpstreamEjectSeconds = 30
+	defaultUpstreamRetries      = 1
+	defaultUpstreamMaxLag       = 5
+
+	// maxUpstreamRetryBodySize bounds the body buffered to retry a request
+	maxUpstreamRetryBodySize = 1 << 20
+
+	// maxUpstreamProbeSize bounds the probe answer read
+	maxUpstreamProbeSize = 1 << 16
+)
+
+// upstream probes, all but UpstreamProbeHTTP report the block height
+const (
+	UpstreamProbeEVM        = "eth_blockNumber"
+	UpstreamProbeBitcoin    = "getblockcount"
+	UpstreamProbeCosmos     = "/status"
+	UpstreamProbeCosmosREST = "/cosmos/base/tendermint/v1beta1/blocks/latest"
+	UpstreamProbeHTTP       = "/"
+)
+
+var (
+	// upstreamEVMChains are the chains probed with eth_blockNumber
+	upstreamEVMChains = map[string]bool{
+		"eth": true, "etc": true, "bsc": true, "avax": true, "polygon": true,
+		"optimism": true, "base": true, "arbitrum": true, "bera": true,
+		"blast": true, "celo": true, "ftm": true, "flare": true,
+	}
+
+	// upstreamBitcoinChains are the chains probed with getblockcount
+	upstreamBitcoinChains = map[string]bool{
+		"btc": true, "bch": true, "ltc": true, "doge": true, "dash": true,
+		"dgb": true, "btg": true,
+	}
+
+	// upstreamCosmosChains are the chains whose nodes serve the Cosmos REST
+	// api, "-rpc" services serve CometBFT RPC
+	upstreamCosmosChains = map[string]bool{
+		"arkeo": true, "gaia": true, "osmosis": true, "akash": true, "agoric": true,
+		"allora": true, "thorchain": true, "babylon": true, "cheqd": true,
+		"dvpn": true, "dym": true, "arch": true,
+	}
+
+	errNoUpstream = errors.New("no upstream available")
+)
+
+// withUpstreamHealthDefaults fills unset health check settings with defaults
+func withUpstreamHealthDefaults(config conf.UpstreamHealthConfig) conf.UpstreamHealthConfig {
+	if config.Interval == 0 {
+		config.Interval = defaultUpstreamInterval
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultUpstreamTimeout
+	}
+	if config.EjectAfter <= 0 {
+		config.EjectAfter = defaultUpstreamEjectAfter
+	}
+	if config.EjectSeconds == 0 {
+		config.EjectSeconds = defaultUpstreamEjectSeconds
+	}
+	if config.Retries == 0 {
+		config.Retries = defaultUpstreamRetries
+	}
+	if config.Retries < 0 {
+		config.Retries = 0
+	}
+	if config.MaxLag == 0 {
+		config.MaxLag = defaultUpstreamMaxLag
+	}
+	return config
+}
+
+// defaultUpstreamProbe infers the probe of a service from its name:
+// "<chain>-<network>-<kind>"
+func defaultUpstreamProbe(service string) string {
+	parts := strings.Split(strings.ToLower(service), "-")
+	chain, kind := parts[0], parts[len(parts)-1]
+	switch {
+	case kind == "rpc":
+		return UpstreamProbeCosmos
+	case kind == "unchained", kind == "blockbook", kind == "grpc", kind == "rest":
+		return UpstreamProbeHTTP
+	case upstreamEVMChains[chain]:
+		return UpstreamProbeEVM
+	case upstreamBitcoinChains[chain]:
+		return UpstreamProbeBitcoin
+	case upstreamCosmosChains[chain]:
+		return UpstreamProbeCosmosREST
+	}
+	return UpstreamProbeHTTP
+}
+
+// Upstream is a node serving a service
+type Upstream struct {
+	Name   string
+	URL    *url.URL
+	Weight int
+
+	// guarded by the pool
+	current      int
+	healthy      bool
+	failures     int
+	ejectedUntil time.Time
+	lastCheck    time.Time
+	lastError    string
+	height       uint64
+}
+
+// UpstreamStatus is the health of an upstream reported in /metadata.json.
+// Urls are left out, they may carry credentials.
+type UpstreamStatus struct {
+	Name      string `json:"name"`
+	Weight    int    `json:"weight"`
+	Healthy   bool   `json:"healthy"`
+	Ejected   bool   `json:"ejected"`
+	Failures  int    `json:"failures"`
+	LastCheck int64  `json:"last_check,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+	Height    uint64 `json:"height,omitempty"`
+	Lag       uint64 `json:"lag,omitempty"`
+	InSync    bool   `json:"in_sync"`
+}
+
+// ServiceSyncStatus is how far the upstreams of a service are from the best
+// height they reported, for the directory to spot providers serving stale data
+type ServiceSyncStatus struct {
+	Height    uint64 `json:"height"`     // best height of the healthy upstreams
+	MaxLag    int    `json:"max_lag"`    // blocks an upstream can be behind and still serve
+	InSync    int    `json:"in_sync"`    // upstreams serving within max_lag of height
+	Upstreams int    `json:"upstreams"`  // upstreams of the service
+	UpdatedAt int64  `json:"updated_at"` // last probe
+}
+
+// UpstreamPool spreads the requests of a service over its upstreams by
+// weight. Upstreams failing their probe, failing EjectAfter requests in a
+// row, or more than MaxLag blocks behind the best height are skipped until
+// they recover.
+type UpstreamPool struct {
+	Service string
+	Probe   string
+	MaxLag  int
+
+	logger    log.Logger
+	config    conf.UpstreamHealthConfig
+	upstreams []*Upstream
+	mu        sync.Mutex
+}
+
+// NewUpstreamPool creates an empty pool probing its upstreams with probe, a
+// JSON-RPC method or a path
+func NewUpstreamPool(service, probe string, config conf.UpstreamHealthConfig, logger log.Logger) *UpstreamPool {
+	if probe == "" {
+		probe = defaultUpstreamProbe(service)
+	}
+	if logger == nil {
+		logger = log.NewNopLogger()
+	}
+	config = withUpstreamHealthDefaults(config)
+	return &UpstreamPool{
+		Service: service,
+		Probe:   probe,
+		MaxLag:  config.MaxLag,
+		logger:  logger,
+		config:  config,
+	}
+}
+
+// Add adds an upstream, healthy until probed. Unnamed upstreams are named
+// after their position.
+func (p *UpstreamPool) Add(name string, u *url.URL, weight int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if name == "" {
+		name = fmt.Sprintf("upstream-%d", len(p.upstreams))
+	}
+	if weight <= 0 {
+		weight = 1
+	}
+	p.upstreams = append(p.upstreams, &Upstream{Name: name, URL: u, Weight: weight, healthy: true})
+}
+
+// sameUpstreams tells if other probes and spreads requests over the same
+// upstreams as p
+func (p *UpstreamPool) sameUpstreams(other *UpstreamPool) bool {
+	if p.Probe != other.Probe || p.MaxLag != other.MaxLag {
+		return false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	other.mu.Lock()
+	defer other.mu.Unlock()
+	if len(p.upstreams) != len(other.upstreams) {
+		return false
+	}
+	for i, u := range p.upstreams {
+		o := other.upstreams[i]
+		if u.Name != o.Name || u.Weight != o.Weight || u.URL.String() != o.URL.String() {
+			return false
+		}
+	}
+	return true
+}
+
+// Len returns the number of upstreams
+func (p *UpstreamPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.upstreams)
+}
+
+// Pick returns the next upstream not in tried, by smooth weighted round robin
+// among the available ones in sync. When none is available the unavailable
+// ones in sync are tried rather than refusing the request. Lagging upstreams
+// never serve, so stale data is not sold. It returns nil once all were tried,
+// or when no upstream is in sync.
+func (p *UpstreamPool) Pick(tried map[*Upstream]bool) *Upstream {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return pickWeighted(p.candidates(tried))
+}
+
+// exhausted reports whether Pick has no upstream left to try
+func (p *UpstreamPool) exhausted(tried map[*Upstream]bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.candidates(tried)) == 0
+}
+
+// candidates returns the upstreams not in tried that Pick chooses from
+func (p *UpstreamPool) candidates(tried map[*Upstream]bool) []*Upstream {
+	now := time.Now()
+	best := p.bestHeight()
+	filters := []func(u *Upstream) bool{
+		func(u *Upstream) bool { return u.available(now) },
+		func(u *Upstream) bool { return true },
+	}
+	var candidates []*Upstream
+	for _, filter := range filters {
+		for _, u := range p.upstreams {
+			if !tried[u] && p.inSync(u, best) && filter(u) {
+				candidates = append(candidates, u)
+			}
+		}
+		if len(candidates) > 0 {
+			break
+		}
+	}
+	return candidates
+}
+
+// pickWeighted picks among candidates by smooth weighted round robin
+func pickWeighted(candidates []*Upstream) *Upstream {
+	var best *Upstream
+	total := 0
+	for _, u := range candidates {
+		u.current += u.Weight
+		total += u.Weight
+		if best == nil || u.current > best.current {
+			best = u
+		}
+	}
+	if best != nil {
+		best.current -= total
+	}
+	return best
+}
+
+func (u *Upstream) available(now time.Time) bool {
+	return u.healthy && !now.Before(u.ejectedUntil)
+}
+
+// bestHeight returns the highest block reported by a healthy upstream
+func (p *UpstreamPool) bestHeight() uint64 {
+	var best uint64
+	for _, u := range p.upstreams {
+		if u.healthy && u.height > best {
+			best = u.height
+		}
+	}
+	return best
+}
+
+// inSync reports whether an upstream is within MaxLag blocks of best.
+// Upstreams whose probe reports no height are always in sync.
+func (p *UpstreamPool) inSync(u *Upstream, best uint64) bool {
+	return p.MaxLag < 0 || u.height == 0 || best <= u.height+uint64(p.MaxLag)
+}
+
+// ReportSuccess clears the failures of an upstream
+func (p *UpstreamPool) ReportSuccess(u *Upstream) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	u.failures = 0
+	u.ejectedUntil = time.Time{}
+}
+
+// ReportFailure counts a failed request, the upstream is ejected for
+// EjectSeconds once EjectAfter requests failed in a row
+func (p *UpstreamPool) ReportFailure(u *Upstream, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	u.failures++
+	u.lastError = upstreamErrorSummary(err)
+	if u.failures >= p.config.EjectAfter && !time.Now().Before(u.ejectedUntil) {
+		u.ejectedUntil = time.Now().Add(time.Duration(p.config.EjectSeconds) * time.Second)
+		p.logger.Error("upstream ejected", "service", p.Service, "upstream", u.Name, "failures", u.failures, "error", err)
+	}
+}
+
+// Check probes every upstream once
+func (p *UpstreamPool) Check(ctx context.Context, client *http.Client) {
+	p.mu.Lock()
+	upstreams := append([]*Upstream(nil), p.upstreams...)
+	p.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for _, u := range upstreams {
+		wg.Add(1)
+		go func(u *Upstream) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, time.Duration(p.config.Timeout)*time.Second)
+			defer cancel()
+			height, err := probeUpstream(ctx, client, u.URL, p.Probe)
+			p.record(u, height, err)
+		}(u)
+	}
+	wg.Wait()
+}
+
+// record keeps the outcome of a probe, a successful one ends an ejection
+func (p *UpstreamPool) record(u *Upstream, height uint64, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	u.lastCheck = time.Now()
+	if err != nil {
+		if u.healthy {
+			p.logger.Error("upstream unhealthy", "service", p.Service, "upstream", u.Name, "probe", p.Probe, "error", err)
+		}
+		u.healthy = false
+		u.lastError = upstreamErrorSummary(err)
+		return
+	}
+	if !u.healthy {
+		p.logger.Info("upstream recovered", "service", p.Service, "upstream", u.Name)
+	}
+	wasInSync := p.inSync(u, p.bestHeight())
+	u.healthy = true
+	u.height = height
+	if best := p.bestHeight(); wasInSync && !p.inSync(u, best) {
+		p.logger.Error("upstream behind", "service", p.Service, "upstream", u.Name, "height", height, "best", best, "max_lag", p.MaxLag)
+	}
+	u.failures = 0
+	u.ejectedUntil = time.Time{}
+	u.lastError = ""
+}
+
+// Status returns the health of the upstreams
+func (p *UpstreamPool) Status() []UpstreamStatus {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	best := p.bestHeight()
+	status := make([]UpstreamStatus, 0, len(p.upstreams))
+	for _, u := range p.upstreams {
+		s := UpstreamStatus{
+			Name:      u.Name,
+			Weight:    u.Weight,
+			Healthy:   u.healthy,
+			Ejected:   now.Before(u.ejectedUntil),
+			Failures:  u.failures,
+			LastError: u.lastError,
+			Height:    u.height,
+			InSync:    p.inSync(u, best),
+		}
+		if !u.lastCheck.IsZero() {
+			s.LastCheck = u.lastCheck.Unix()
+		}
+		if u.height > 0 && best > u.height {
+			s.Lag = best - u.height
+		}
+		status = append(status, s)
+	}
+	return status
+}
+
+// SyncStatus returns the best height of the upstreams and how many serve
+// within MaxLag of it. ok is false until a probe reported a height.
+func (p *UpstreamPool) SyncStatus() (ServiceSyncStatus, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	best := p.bestHeight()
+	status := ServiceSyncStatus{Height: best, MaxLag: p.MaxLag, Upstreams: len(p.upstreams)}
+	for _, u := range p.upstreams {
+		if u.available(now) && p.inSync(u, best) {
+			status.InSync++
+		}
+		if u.lastCheck.Unix() > status.UpdatedAt {
+			status.UpdatedAt = u.lastCheck.Unix()
+		}
+	}
+	return status, best > 0
+}
+
+// Transport returns a round tripper sending requests to the upstreams of the
+// pool. rewrite points the outgoing request at the chosen upstream.
+// Idempotent requests that fail are sent to another upstream, up to Retries
+// times.
+func (p *UpstreamPool) Transport(rewrite func(req *http.Request, target *url.URL)) http.RoundTripper {
+	return &upstreamTransport{pool: p, base: http.DefaultTransport, rewrite: rewrite}
+}
+
+type upstreamTransport struct {
+	pool    *UpstreamPool
+	base    http.RoundTripper
+	rewrite func(req *http.Request, target *url.URL)
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *upstreamTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := 1
+	var body []byte
+	if t.pool.config.Retries > 0 && isIdempotentRequest(req) {
+		var ok bool
+		if body, ok = bufferRetryBody(req); ok {
+			attempts += t.pool.config.Retries
+		}
+	}
+
+	tried := make(map[*Upstream]bool)
+	lastErr := errNoUpstream
+	for attempt := 0; attempt < attempts; attempt++ {
+		u := t.pool.Pick(tried)
+		if u == nil {
+			break
+		}
+		tried[u] = true
+
+		out := req.Clone(req.Context())
+		if body != nil {
+			out.Body = io.NopCloser(bytes.NewReader(body))
+			out.ContentLength = int64(len(body))
+		}
+		t.rewrite(out, u.URL)
+
+		start := time.Now()
+		resp, err := t.base.RoundTrip(out)
+		if req.Context().Err() != nil {
+			// the client went away, not the upstream
+			return resp, err
+		}
+		upstreamDuration.WithLabelValues(t.pool.Service, u.Name).Observe(time.Since(start).Seconds())
+		if err == nil && !upstreamUnavailable(resp.StatusCode) {
+			t.pool.ReportSuccess(u)
+			return resp, nil
+		}
+		if err == nil {
+			err = &upstreamError{reason: fmt.Sprintf("status %d", resp.StatusCode)}
+		}
+		upstreamFailures.WithLabelValues(t.pool.Service, u.Name).Inc()
+		t.pool.ReportFailure(u, err)
+		t.pool.logger.Error("upstream request failed", "service", t.pool.Service, "upstream", u.Name, "attempt", attempt+1, "error", err)
+		if resp != nil {
+			if attempt == attempts-1 || t.pool.exhausted(tried) {
+				// nothing left to try, the client gets the upstream answer
+				return resp, nil
+			}
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxUpstreamProbeSize))
+			resp.Body.Close()
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// upstreamUnavailable reports whether an answer means the upstream could not
+// serve the request, rather than the request being wrong
+func upstreamUnavailable(status int) bool {
+	return status == http.StatusBadGateway || status == http.StatusServiceUnavailable || status == http.StatusGatewayTimeout
+}
+
+// isIdempotentRequest reports whether a request can be sent to another
+// upstream after a failure: reads, and JSON-RPC calls that do not send or
+// broadcast anything
+func isIdempotentRequest(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	case http.MethodPost:
+	default:
+		return false
+	}
+	if r.Header.Get("Idempotency-Key") != "" {
+		return true
+	}
+	methods := jsonRPCMethods(r)
+	if len(methods) == 0 {
+		return false
+	}
+	for _, method := range methods {
+		lower := strings.ToLower(method)
+		if strings.Contains(lower, "send") || strings.Contains(lower, "broadcast") || strings.Contains(lower, "submit") {
+			return false
+		}
+	}
+	return true
+}
+
+// bufferRetryBody reads the body of r so it can be sent more than once. Bodies
+// over maxUpstreamRetryBodySize are left as they are and not retried.
+func bufferRetryBody(r *http.Request) ([]byte, bool) {
+	if r.Body == nil || r.Body == http.NoBody {
+		return nil, true
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxUpstreamRetryBodySize+1))
+	if err != nil || len(body) > maxUpstreamRetryBodySize {
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+		return nil, false
+	}
+	r.Body.Close()
+	return body, true
+}
+
+// setUpstreamURL points the scheme, host, query and basic auth of an outgoing
+// request at an upstream
+func setUpstreamURL(req *http.Request, target *url.URL) {
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	req.URL.User = nil
+	if target.RawQuery == "" || req.URL.RawQuery == "" {
+		req.URL.RawQuery = target.RawQuery + req.URL.RawQuery
+	} else {
+		req.URL.RawQuery = target.RawQuery + "&" + req.URL.RawQuery
+	}
+	if _, ok := req.Header["User-Agent"]; !ok {
+		// explicitly disable User-Agent so it's not set to default value
+		req.Header.Set("User-Agent", "")
+	}
+	if passwd, ok := target.User.Password(); ok {
+		req.SetBasicAuth(target.User.Username(), passwd)
+	}
+}
+
+// joinUpstreamPath prefixes the path of a request with the upstream path
+func joinUpstreamPath(base, rest string) string {
+	if base == "" || base == "/" {
+		return rest
+	}
+	return path.Join(base, rest)
+}
+
+// upstreamError is a failure reported as is in /metadata.json
+type upstreamError struct {
+	reason string
+}
+
+func (e *upstreamError) Error() string {
+	return e.reason
+}
+
+// upstreamErrorSummary describes a failure without the upstream address
+func upstreamErrorSummary(err error) string {
+	var upstreamErr *upstreamError
+	var netErr net.Error
+	switch {
+	case errors.As(err, &upstreamErr):
+		return upstreamErr.reason
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "unreachable"
+	}
+}
+
+// probeUpstream sends the probe of a pool to an upstream: a GET of the path,
+// or a JSON-RPC call of the method that must return a result. It returns the
+// block height of the upstream, 0 when the probe does not report one.
+func probeUpstream(ctx context.Context, client *http.Client, u *url.URL, probe string) (uint64, error) {
+	target := *u
+	target.User = nil
+	var req *http.Request
+	var err error
+	if strings.HasPrefix(probe, "/") {
+		target.Path = joinUpstreamPath(u.Path, probe)
+		req, err = http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	} else {
+		body := fmt.Sprintf(`{"jsonrpc":"2.0","id":1,"method":%q,"params":[]}`, probe)
+		req, err = http.NewRequestWithContext(ctx, http.MethodPost, target.String(), strings.NewReader(body))
+		if req != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+	}
+	if err != nil {
+		return 0, err
+	}
+	if passwd, ok := u.User.Password(); ok {
+		req.SetBasicAuth(u.User.Username(), passwd)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case probe == UpstreamProbeHTTP && resp.StatusCode < http.StatusInternalServerError:
+		// any answer proves the node is up
+		return 0, nil
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return 0, &upstreamError{reason: fmt.Sprintf("status %d", resp.StatusCode)}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamProbeSize))
+	if err != nil {
+		return 0, err
+	}
+	return parseProbeHeight(probe, body)
+}
+
+// parseProbeHeight reads the block height in the answer of a probe:
+// eth_blockNumber returns a hex quantity, getblockcount a number, CometBFT
+// /status and the Cosmos REST latest block a decimal string
+func parseProbeHeight(probe string, body []byte) (uint64, error) {
+	switch probe {
+	case UpstreamProbeCosmos:
+		var status struct {
+			SyncInfo *struct {
+				LatestBlockHeight string `json:"latest_block_height"`
+			} `json:"sync_info"`
+			Result *struct {
+				SyncInfo struct {
+					LatestBlockHeight string `json:"latest_block_height"`
+				} `json:"sync_info"`
+			} `json:"result"`
+		}
+		if err := json.Unmarshal(body, &status); err != nil {
+			return 0, &upstreamError{reason: "invalid probe answer"}
+		}
+		height := ""
+		if status.Result != nil {
+			height = status.Result.SyncInfo.LatestBlockHeight
+		} else if status.SyncInfo != nil {
+			height = status.SyncInfo.LatestBlockHeight
+		}
+		return parseHeight(height)
+	case UpstreamProbeCosmosREST:
+		var latest struct {
+			Block struct {
+				Header struct {
+					Height string `json:"height"`
+				} `json:"header"`
+			} `json:"block"`
+			SdkBlock struct {
+				Header struct {
+					Height string `json:"height"`
+				} `json:"header"`
+			} `json:"sdk_block"`
+		}
+		if err := json.Unmarshal(body, &latest); err != nil {
+			return 0, &upstreamError{reason: "invalid probe answer"}
+		}
+		if latest.SdkBlock.Header.Height != "" {
+			return parseHeight(latest.SdkBlock.Header.Height)
+		}
+		return parseHeight(latest.Block.Header.Height)
+	}
+	if strings.HasPrefix(probe, "/") {
+		return 0, nil
+	}
+
+	var answer struct {
+		Result json.RawMessage `json:"result"`
+		Error  json.RawMessage `json:"error"`
+	}
+	if err := json.Unmarshal(body, &answer); err != nil {
+		return 0, &upstreamError{reason: "invalid probe answer"}
+	}
+	if len(answer.Error) > 0 && string(answer.Error) != "null" {
+		return 0, &upstreamError{reason: "probe error: " + string(answer.Error)}
+	}
+	if len(answer.Result) == 0 || string(answer.Result) == "null" {
+		return 0, &upstreamError{reason: "empty probe result"}
+	}
+	switch probe {
+	case UpstreamProbeEVM:
+		var quantity string
+		if err := json.Unmarshal(answer.Result, &quantity); err != nil {
+			return 0, &upstreamError{reason: "invalid block number"}
+		}
+		height, err := strconv.ParseUint(strings.TrimPrefix(quantity, "0x"), 16, 64)
+		if err != nil {
+			return 0, &upstreamError{reason: "invalid block number"}
+		}
+		return height, nil
+	case UpstreamProbeBitcoin:
+		var height uint64
+		if err := json.Unmarshal(answer.Result, &height); err != nil {
+			return 0, &upstreamError{reason: "invalid block count"}
+		}
+		return height, nil
+	}
+	return 0, nil
+}
+
+// parseHeight parses a decimal block height
+func parseHeight(value string) (uint64, error) {
+	height, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, &upstreamError{reason: "invalid block height"}
+	}
+	return height, nil
+}
+
+// loadUpstreams builds the upstream pool of every service with a proxy. The
+// proxy url, from rpc_url or the environment, is the first upstream unless
+// only upstreams are configured, then it is the first of them.
+func loadUpstreams(config conf.Configuration, logger log.Logger, proxies map[string]*url.URL) map[string]*UpstreamPool {
+	logger = logger.With("module", LogModuleUpstreams)
+	services := make(map[string]conf.ServiceConfig)
+	for _, svc := range config.Services {
+		services[svc.Name] = svc
+	}
+
+	pools := make(map[string]*UpstreamPool)
+	for name, primary := range proxies {
+		if primary == nil {
+			continue
+		}
+		svc := services[name]
+		pool := NewUpstreamPool(name, svc.HealthCheck, config.UpstreamHealth, logger)
+		if svc.MaxLag != 0 {
+			pool.MaxLag = svc.MaxLag
+		}
+		upstreams := svc.Upstreams
+		if svc.RpcUrl != "" || len(upstreams) == 0 {
+			pool.Add("", primary, 1)
+		} else {
+			pool.Add(upstreams[0].Name, primary, upstreams[0].Weight)
+			upstreams = upstreams[1:]
+		}
+		for _, upstream := range upstreams {
+			u, err := url.Parse(strings.TrimSpace(upstream.RpcUrl))
+			if err != nil || u.Host == "" {
+				logger.Error("invalid upstream url", "service", name, "upstream", upstream.Name)
+				continue
+			}
+			if upstream.RpcUser != "" && upstream.RpcPass != "" {
+				u.User = url.UserPassword(upstream.RpcUser, upstream.RpcPass)
+			}
+			pool.Add(upstream.Name, u, upstream.Weight)
+		}
+		pools[name] = pool
+	}
+	return pools
+}
+
+// upstreamPool returns the upstreams of a service, nil when it is not served
+func (p *Proxy) upstreamPool(service string) *UpstreamPool {
+	p.proxyMu.RLock()
+	pool := p.upstreams[service]
+	primary := p.proxies[service]
+	p.proxyMu.RUnlock()
+	if pool != nil {
+		return pool
+	}
+	if primary == nil {
+		return nil
+	}
+	pool = NewUpstreamPool(service, "", p.Config.UpstreamHealth, p.logger.With("module", LogModuleUpstreams))
+	pool.Add("", primary, 1)
+	return pool
+}
+
+// upstreamStatus returns the health of the upstreams of every service
+func (p *Proxy) upstreamStatus() map[string][]UpstreamStatus {
+	p.proxyMu.RLock()
+	defer p.proxyMu.RUnlock()
+	if len(p.upstreams) == 0 {
+		return nil
+	}
+	status := make(map[string][]UpstreamStatus, len(p.upstreams))
+	for name, pool := range p.upstreams {
+		status[name] = pool.Status()
+	}
+	return status
+}
+
+// syncStatus returns the sync status of every service whose upstreams report
+// their height
+func (p *Proxy) syncStatus() map[string]ServiceSyncStatus {
+	p.proxyMu.RLock()
+	defer p.proxyMu.RUnlock()
+	status := make(map[string]ServiceSyncStatus)
+	for name, pool := range p.upstreams {
+		if s, ok := pool.SyncStatus(); ok {
+			status[name] = s
+		}
+	}
+	if len(status) == 0 {
+		return nil
+	}
+	return status
+}
+
+// checkUpstreams probes the upstreams of every service until ctx is done
+func (p *Proxy) checkUpstreams(ctx context.Context) {
+	config := withUpstreamHealthDefaults(p.Config.UpstreamHealth)
+	if config.Disabled {
+		return
+	}
+	client := &http.Client{}
+	ticker := time.NewTicker(time.Duration(config.Interval) * time.Second)
+	defer ticker.Stop()
+	for {
+		p.proxyMu.RLock()
+		pools := make([]*UpstreamPool, 0, len(p.upstreams))
+		for _, pool := range p.upstreams {
+			pools = append(pools, pool)
+		}
+		p.proxyMu.RUnlock()
+
+		var wg sync.WaitGroup
+		for _, pool := range pools {
+			wg.Add(1)
+			go func(pool *UpstreamPool) {
+				defer wg.Done()
+				pool.Check(ctx, client)
+			}(pool)
+		}
+		wg.Wait()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package sentinel
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+func TestDefaultUpstreamProbe(t *testing.T) {
+	require.Equal(t, UpstreamProbeEVM, defaultUpstreamProbe("eth-mainnet-fullnode"))
+	require.Equal(t, UpstreamProbeBitcoin, defaultUpstreamProbe("btc-mainnet-fullnode"))
+	require.Equal(t, UpstreamProbeCosmos, defaultUpstreamProbe("gaia-mainnet-rpc"))
+	require.Equal(t, UpstreamProbeHTTP, defaultUpstreamProbe("btc-mainnet-blockbook"))
+	require.Equal(t, UpstreamProbeCosmosREST, defaultUpstreamProbe("arkeo-mainnet-fullnode"))
+	require.Equal(t, UpstreamProbeHTTP, defaultUpstreamProbe("sol-mainnet-fullnode"))
+}
+
+func TestUpstreamPoolPick(t *testing.T) {
+	pool := NewUpstreamPool("eth-mainnet-fullnode", "", conf.UpstreamHealthConfig{EjectAfter: 2}, log.NewNopLogger())
+	pool.Add("a", &url.URL{Scheme: "http", Host: "a"}, 3)
+	pool.Add("", &url.URL{Scheme: "http", Host: "b"}, 0)
+
+	// weights
+	counts := map[string]int{}
+	for i := 0; i < 8; i++ {
+		counts[pool.Pick(nil).Name]++
+	}
+	require.Equal(t, map[string]int{"a": 6, "upstream-1": 2}, counts)
+
+	// passive ejection
+	a := pool.upstreams[0]
+	pool.ReportFailure(a, errors.New("connection refused"))
+	require.False(t, pool.Status()[0].Ejected)
+	pool.ReportFailure(a, errors.New("connection refused"))
+	status := pool.Status()[0]
+	require.True(t, status.Ejected)
+	require.Equal(t, "unreachable", status.LastError)
+	for i := 0; i < 4; i++ {
+		require.Equal(t, "upstream-1", pool.Pick(nil).Name)
+	}
+	require.Equal(t, a, pool.Pick(map[*Upstream]bool{pool.upstreams[1]: true}))
+	require.Nil(t, pool.Pick(map[*Upstream]bool{a: true, pool.upstreams[1]: true}))
+
+	pool.ReportSuccess(a)
+	require.False(t, pool.Status()[0].Ejected)
+}
+
+func TestUpstreamPoolCheck(t *testing.T) {
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		require.Contains(t, string(body), `"method":"eth_blockNumber"`)
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+	defer healthy.Close()
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"syncing"}}`))
+	}))
+	defer failing.Close()
+
+	pool := NewUpstreamPool("eth-mainnet-fullnode", "", conf.UpstreamHealthConfig{}, log.NewNopLogger())
+	pool.Add("healthy", mustParseURL(t, healthy.URL), 1)
+	pool.Add("failing", mustParseURL(t, failing.URL), 1)
+	pool.Check(context.Background(), healthy.Client())
+
+	status := pool.Status()
+	require.True(t, status[0].Healthy)
+	require.NotZero(t, status[0].LastCheck)
+	require.Equal(t, uint64(16), status[0].Height)
+	require.False(t, status[1].Healthy)
+	require.Contains(t, status[1].LastError, "syncing")
+	for i := 0; i < 3; i++ {
+		require.Equal(t, "healthy", pool.Pick(nil).Name)
+	}
+}
+
+func TestParseProbeHeight(t *testing.T) {
+	for _, tc := range []struct {
+		probe  string
+		body   string
+		height uint64
+	}{
+		{UpstreamProbeEVM, `{"jsonrpc":"2.0","id":1,"result":"0x12d687"}`, 1234567},
+		{UpstreamProbeBitcoin, `{"result":850000,"error":null,"id":1}`, 850000},
+		{UpstreamProbeCosmos, `{"jsonrpc":"2.0","id":-1,"result":{"sync_info":{"latest_block_height":"2048","catching_up":false}}}`, 2048},
+		{UpstreamProbeCosmos, `{"sync_info":{"latest_block_height":"2049"}}`, 2049},
+		{UpstreamProbeCosmosREST, `{"block":{"header":{"height":"300"}},"sdk_block":{"header":{"height":"301"}}}`, 301},
+		{"/health", `ok`, 0},
+	} {
+		height, err := parseProbeHeight(tc.probe, []byte(tc.body))
+		require.NoError(t, err, tc.probe)
+		require.Equal(t, tc.height, height, tc.probe)
+	}
+
+	_, err := parseProbeHeight(UpstreamProbeEVM, []byte(`{"result":"latest"}`))
+	require.Error(t, err)
+	_, err = parseProbeHeight(UpstreamProbeCosmosREST, []byte(`{"block":{}}`))
+	require.Error(t, err)
+}
+
+func TestUpstreamPoolLag(t *testing.T) {
+	pool := NewUpstreamPool("btc-mainnet-fullnode", "", conf.UpstreamHealthConfig{MaxLag: 2}, log.NewNopLogger())
+	pool.Add("tip", &url.URL{Scheme: "http", Host: "a"}, 1)
+	pool.Add("behind", &url.URL{Scheme: "http", Host: "b"}, 1)
+	pool.Add("unknown", &url.URL{Scheme: "http", Host: "c"}, 1)
+	_, ok := pool.SyncStatus()
+	require.False(t, ok)
+
+	pool.record(pool.upstreams[0], 100, nil)
+	pool.record(pool.upstreams[1], 97, nil)
+	for i := 0; i < 4; i++ {
+		require.NotEqual(t, "behind", pool.Pick(nil).Name)
+	}
+	status := pool.Status()
+	require.True(t, status[0].InSync)
+	require.False(t, status[1].InSync)
+	require.Equal(t, uint64(3), status[1].Lag)
+	sync, ok := pool.SyncStatus()
+	require.True(t, ok)
+	require.Equal(t, ServiceSyncStatus{Height: 100, MaxLag: 2, InSync: 2, Upstreams: 3, UpdatedAt: sync.UpdatedAt}, sync)
+
+	// lagging upstreams never serve, even when nothing else can
+	tried := map[*Upstream]bool{pool.upstreams[0]: true, pool.upstreams[2]: true}
+	require.Nil(t, pool.Pick(tried))
+	require.True(t, pool.exhausted(tried))
+
+	// within the lag once it catches up, an unhealthy tip does not count
+	pool.record(pool.upstreams[1], 98, nil)
+	require.True(t, pool.Status()[1].InSync)
+	pool.record(pool.upstreams[0], 0, errors.New("down"))
+	sync, _ = pool.SyncStatus()
+	require.Equal(t, uint64(98), sync.Height)
+}
+
+func TestUpstreamPoolAllLagging(t *testing.T) {
+	lagging := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("a lagging upstream served the request")
+	}))
+	defer lagging.Close()
+	tip := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer tip.Close()
+
+	pool := NewUpstreamPool("btc-mainnet-fullnode", "", conf.UpstreamHealthConfig{MaxLag: 2, Retries: 2}, log.NewNopLogger())
+	pool.Add("tip", mustParseURL(t, tip.URL), 1)
+	pool.Add("behind", mustParseURL(t, lagging.URL), 1)
+	pool.Add("further", mustParseURL(t, lagging.URL), 1)
+	pool.record(pool.upstreams[0], 100, nil)
+	pool.record(pool.upstreams[1], 97, nil)
+	pool.record(pool.upstreams[2], 90, nil)
+
+	// once the tip was tried every upstream left lags
+	tried := map[*Upstream]bool{pool.upstreams[0]: true}
+	require.Nil(t, pool.Pick(tried))
+	require.True(t, pool.exhausted(tried))
+
+	// a failing tip is not replaced by a lagging upstream
+	proxy := &httputil.ReverseProxy{
+		Director:     func(req *http.Request) {},
+		Transport:    pool.Transport(setUpstreamURL),
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) { w.WriteHeader(proxyErrorStatus(err)) },
+	}
+	w := httptest.NewRecorder()
+	proxy.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	// without upstreams in sync the request is refused
+	empty := NewUpstreamPool("btc-mainnet-fullnode", "", conf.UpstreamHealthConfig{}, log.NewNopLogger())
+	proxy.Transport = empty.Transport(setUpstreamURL)
+	w = httptest.NewRecorder()
+	proxy.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestUpstreamTransportRetry(t *testing.T) {
+	var downCalls, upCalls int32
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downCalls, 1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&upCalls, 1)
+		body, _ := io.ReadAll(r.Body)
+		require.Equal(t, "/base/block", r.URL.Path)
+		_, _ = w.Write(body)
+	}))
+	defer up.Close()
+
+	pool := NewUpstreamPool("eth-mainnet-fullnode", "", conf.UpstreamHealthConfig{}, log.NewNopLogger())
+	pool.Add("down", mustParseURL(t, down.URL), 1)
+	pool.Add("up", mustParseURL(t, up.URL+"/base"), 1)
+	client := &http.Client{Transport: pool.Transport(func(req *http.Request, target *url.URL) {
+		setUpstreamURL(req, target)
+		req.URL.Path = joinUpstreamPath(target.Path, "/block")
+	})}
+
+	// reads are retried on the other upstream
+	for i := 0; i < 2; i++ {
+		body := `{"jsonrpc":"2.0","id":1,"method":"eth_getBlockByNumber","params":["latest",false]}`
+		resp, err := client.Post("http://sentinel/eth-mainnet-fullnode", "application/json", strings.NewReader(body))
+		require.NoError(t, err)
+		got, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		require.Equal(t, body, string(got))
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&downCalls))
+	require.Equal(t, int32(2), atomic.LoadInt32(&upCalls))
+
+	// transactions are not sent twice
+	pool.ReportSuccess(pool.upstreams[0])
+	for i := 0; i < 2; i++ {
+		resp, err := client.Post("http://sentinel/eth-mainnet-fullnode", "application/json",
+			strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"eth_sendRawTransaction","params":["0x00"]}`))
+		require.NoError(t, err)
+		resp.Body.Close()
+	}
+	require.Equal(t, int32(2), atomic.LoadInt32(&downCalls))
+	require.Equal(t, int32(3), atomic.LoadInt32(&upCalls))
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	return u
+}
@@ -0,0 +1,232 @@
+package sentinel
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+const (
+	defaultWebSocketMaxMessageSize = 1 << 20
+	defaultWebSocketIdleTimeout    = 300
+
+	webSocketCloseTimeout = 5 * time.Second
+)
+
+// webSocketUpgrader accepts upgrades from any origin like the plain routes,
+// the contract or free tier decides who is served
+var webSocketUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// withWebSocketDefaults fills unset WebSocket limits with defaults
+func withWebSocketDefaults(config conf.WebSocketConfig) conf.WebSocketConfig {
+	if config.MaxMessageSize <= 0 {
+		config.MaxMessageSize = defaultWebSocketMaxMessageSize
+	}
+	if config.IdleTimeout == 0 {
+		config.IdleTimeout = defaultWebSocketIdleTimeout
+	}
+	return config
+}
+
+// webSocketConns counts the open WebSocket connections of each service
+type webSocketConns struct {
+	mu   sync.Mutex
+	open map[string]uint64
+}
+
+// acquire takes a connection slot of service, it reports false when max
+// connections are already open. release frees the slot.
+func (c *webSocketConns) acquire(service string, max uint64) (release func(), ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.open == nil {
+		c.open = make(map[string]uint64)
+	}
+	if max > 0 && c.open[service] >= max {
+		return nil, false
+	}
+	c.open[service]++
+	webSocketConnections.WithLabelValues(service).Inc()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+			c.open[service]--
+			webSocketConnections.WithLabelValues(service).Dec()
+		})
+	}, true
+}
+
+// serveWebSocket relays a WebSocket upgrade to an upstream of pool within
+// the WebSocket limits of the service
+func (p *Proxy) serveWebSocket(w http.ResponseWriter, r *http.Request, pool *UpstreamPool, rewrite func(req *http.Request, uri *url.URL)) {
+	logger := p.requestLogger(r)
+	svc, _ := p.serviceConfig(pool.Service)
+	config := withWebSocketDefaults(svc.WebSocket)
+
+	release, ok := p.webSockets.acquire(pool.Service, config.MaxConnections)
+	if !ok {
+		logger.Info("websocket refused", "service", pool.Service, "reason", "too many connections")
+		respondWithError(w, "too many websocket connections", http.StatusServiceUnavailable)
+		return
+	}
+	defer release()
+
+	upstream := pool.Pick(nil)
+	if upstream == nil {
+		logger.Info("websocket refused", "service", pool.Service, "reason", errNoUpstream)
+		respondWithError(w, errNoUpstream.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	out := r.Clone(r.Context())
+	rewrite(out, upstream.URL)
+	target := *out.URL
+	target.User = upstream.URL.User
+	// use the WebSocket scheme
+	if target.Scheme == "https" {
+		target.Scheme = "wss"
+	} else {
+		target.Scheme = "ws"
+	}
+	logger.Debug("websocket upgrade", "service", pool.Service, "upstream", upstream.Name)
+
+	upstreamConn, resp, err := websocket.DefaultDialer.DialContext(r.Context(), target.String(), webSocketHeaders(r))
+	if err != nil {
+		logger.Error("failed to dial websocket upstream", "service", pool.Service, "upstream", upstream.Name, "error", err)
+		status := http.StatusBadGateway
+		if resp != nil && resp.StatusCode >= http.StatusBadRequest {
+			// pass refusals of the upstream, such as a missing route, along
+			status = resp.StatusCode
+		}
+		respondWithError(w, "upstream unavailable", status)
+		return
+	}
+	defer upstreamConn.Close()
+
+	header := http.Header{}
+	if protocol := upstreamConn.Subprotocol(); protocol != "" {
+		header.Set("Sec-WebSocket-Protocol", protocol)
+	}
+	if cookies := resp.Header.Values("Set-Cookie"); len(cookies) > 0 {
+		header["Set-Cookie"] = cookies
+	}
+	client, err := webSocketUpgrader.Upgrade(w, r, header)
+	if err != nil {
+		// the upgrader already answered the client
+		logger.Error("failed to upgrade websocket", "service", pool.Service, "error", err)
+		return
+	}
+	defer client.Close()
+
+	reason := relayWebSocket(client, upstreamConn, config)
+	logger.Debug("websocket closed", "service", pool.Service, "upstream", upstream.Name, "reason", reason)
+}
+
+// webSocketHeaders returns the upgrade headers to forward upstream. The
+// handshake headers are set by the dialer.
+func webSocketHeaders(r *http.Request) http.Header {
+	out := http.Header{}
+	for k, v := range r.Header {
+		switch http.CanonicalHeaderKey(k) {
+		case "Upgrade", "Connection", "Sec-Websocket-Key", "Sec-Websocket-Version", "Sec-Websocket-Extensions":
+			continue
+		}
+		out[k] = v
+	}
+	if clientIP, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		if prior := out.Values("X-Forwarded-For"); len(prior) > 0 {
+			clientIP = strings.Join(prior, ", ") + ", " + clientIP
+		}
+		out.Set("X-Forwarded-For", clientIP)
+	}
+	return out
+}
+
+// relayWebSocket copies messages between client and upstream until either
+// side closes, a message is too large, the connection is idle for too long or
+// has been open for too long, and returns why the relay ended
+func relayWebSocket(client, upstream *websocket.Conn, config conf.WebSocketConfig) string {
+	client.SetReadLimit(config.MaxMessageSize)
+	upstream.SetReadLimit(config.MaxMessageSize)
+
+	done := make(chan string, 2)
+	active := make(chan struct{}, 1)
+	closeConn := func(conn *websocket.Conn, code int, text string) {
+		if code == websocket.CloseAbnormalClosure {
+			// a dropped connection, which has no close frame of its own
+			code = websocket.CloseGoingAway
+		}
+		msg := websocket.FormatCloseMessage(code, text)
+		_ = conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(webSocketCloseTimeout))
+	}
+
+	relay := func(from, to *websocket.Conn) {
+		for {
+			messageType, data, err := from.ReadMessage()
+			if err != nil {
+				code, text := websocket.CloseNormalClosure, ""
+				var closeErr *websocket.CloseError
+				switch {
+				case errors.As(err, &closeErr):
+					code, text = closeErr.Code, closeErr.Text
+				case errors.Is(err, websocket.ErrReadLimit):
+					code, text = websocket.CloseMessageTooBig, "message too big"
+					closeConn(from, code, text)
+				}
+				closeConn(to, code, text)
+				done <- fmt.Sprintf("closed: %d", code)
+				return
+			}
+			select {
+			case active <- struct{}{}:
+			default:
+			}
+			if err := to.WriteMessage(messageType, data); err != nil {
+				done <- fmt.Sprintf("write failed: %s", err)
+				return
+			}
+		}
+	}
+	go relay(client, upstream)
+	go relay(upstream, client)
+
+	idleTimeout := time.Duration(config.IdleTimeout) * time.Second
+	idle := time.NewTimer(idleTimeout)
+	defer idle.Stop()
+	var expired <-chan time.Time
+	if config.MaxDuration > 0 {
+		timer := time.NewTimer(time.Duration(config.MaxDuration) * time.Second)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	for {
+		select {
+		case reason := <-done:
+			return reason
+		case <-active:
+			idle.Reset(idleTimeout)
+		case <-idle.C:
+			closeConn(client, websocket.CloseGoingAway, "idle timeout")
+			closeConn(upstream, websocket.CloseGoingAway, "idle timeout")
+			return "idle timeout"
+		case <-expired:
+			closeConn(client, websocket.CloseGoingAway, "connection too long")
+			closeConn(upstream, websocket.CloseGoingAway, "connection too long")
+			return "max duration"
+		}
+	}
+}
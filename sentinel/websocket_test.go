@@ -0,0 +1,98 @@
+package sentinel
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+func TestWebSocketLimits(t *testing.T) {
+	// the upstream echoes every message
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}))
+	defer upstream.Close()
+
+	proxy := &Proxy{
+		Config: conf.Configuration{Services: []conf.ServiceConfig{{
+			Name:      "eth-mainnet-fullnode",
+			Timeout:   1,
+			WebSocket: conf.WebSocketConfig{MaxConnections: 1, MaxMessageSize: 64},
+		}}},
+		logger:  log.NewNopLogger(),
+		proxies: map[string]*url.URL{"eth-mainnet-fullnode": mustParseURL(t, upstream.URL)},
+	}
+	server := httptest.NewServer(http.HandlerFunc(proxy.handleRequestAndRedirect))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/eth-mainnet-fullnode/"
+
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"method":"eth_subscribe"}`)))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, `{"method":"eth_subscribe"}`, string(data))
+
+	// one connection at a time
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.ErrorIs(t, err, websocket.ErrBadHandshake)
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	// messages past the limit close the connection
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(strings.Repeat("x", 65))))
+	_, _, err = conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig), err)
+}
+
+func TestRelayWebSocketIdle(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _, _ = conn.ReadMessage()
+	}))
+	defer upstream.Close()
+
+	reasons := make(chan string, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamConn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(upstream.URL, "http"), nil)
+		require.NoError(t, err)
+		defer upstreamConn.Close()
+		client, err := webSocketUpgrader.Upgrade(w, r, nil)
+		require.NoError(t, err)
+		defer client.Close()
+		reasons <- relayWebSocket(client, upstreamConn, withWebSocketDefaults(conf.WebSocketConfig{IdleTimeout: 1}))
+	}))
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	require.NoError(t, err)
+	defer conn.Close()
+	_, _, err = conn.ReadMessage()
+	require.True(t, websocket.IsCloseError(err, websocket.CloseGoingAway), err)
+	require.Equal(t, "idle timeout", <-reasons)
+}
@@ -0,0 +1,409 @@
+package sentinel
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/types/bech32"
+
+	"github.com/arkeonetwork/arkeo/common/cosmos"
+)
+
+const (
+	// x402ArkeoTxMaxAge is how old a referenced tx may be to pay for a request
+	x402ArkeoTxMaxAge = 10 * time.Minute
+
+	// x402ArkeoConfirmTimeout is how long settling waits for a broadcast tx
+	// to be included in a block
+	x402ArkeoConfirmTimeout = 30 * time.Second
+
+	msgSendTypeURL = "/cosmos.bank.v1beta1.MsgSend"
+)
+
+// ArkeoVerifier verifies "exact" payments on "arkeo:<chain-id>" networks
+// against the Arkeo chain REST API, the same endpoint MemStore fetches
+// contracts from. A payment is a bank MsgSend of the required uarkeo to the
+// provider, either as a signed tx the sentinel broadcasts when settling or as
+// the hash of a tx the agent already broadcast. Tx hashes are public, so a
+// hash is signed by the sender and the payment store must reject a hash that
+// was used before.
+type ArkeoVerifier struct {
+	baseURL     string
+	authManager *ArkeoAuthManager
+	client      *http.Client
+
+	// overridable for tests
+	now          func() time.Time
+	pollInterval time.Duration
+
+	chainMu sync.Mutex
+	chainID string
+}
+
+type arkeoCoin struct {
+	Denom  string `json:"denom"`
+	Amount string `json:"amount"`
+}
+
+type arkeoTxMsg struct {
+	Type        string      `json:"@type"`
+	FromAddress string      `json:"from_address"`
+	ToAddress   string      `json:"to_address"`
+	Amount      []arkeoCoin `json:"amount"`
+}
+
+type arkeoTx struct {
+	Body struct {
+		Messages []arkeoTxMsg `json:"messages"`
+		Memo     string       `json:"memo"`
+	} `json:"body"`
+}
+
+type arkeoTxResult struct {
+	Height    string `json:"height"`
+	TxHash    string `json:"txhash"`
+	Code      uint32 `json:"code"`
+	RawLog    string `json:"raw_log"`
+	Timestamp string `json:"timestamp"`
+}
+
+// NewArkeoVerifier creates a verifier querying the Arkeo REST API at baseURL
+func NewArkeoVerifier(baseURL string, authManager *ArkeoAuthManager) *ArkeoVerifier {
+	return &ArkeoVerifier{
+		baseURL:      strings.TrimRight(baseURL, "/"),
+		authManager:  authManager,
+		client:       &http.Client{Timeout: 10 * time.Second},
+		now:          time.Now,
+		pollInterval: time.Second,
+	}
+}
+
+// Verify checks the payment sends enough uarkeo to the provider on the right
+// chain. A referenced tx must also be successful and recent.
+func (v *ArkeoVerifier) Verify(paymentPayload string, requirements PaymentRequirements) (*VerifyResponse, error) {
+	payload, err := DecodePaymentPayload(paymentPayload)
+	if err != nil {
+		return nil, err
+	}
+	payment, err := payload.ArkeoTx()
+	if err != nil {
+		return nil, err
+	}
+	if payload.Scheme != requirements.Scheme {
+		return &VerifyResponse{Valid: false, Error: fmt.Sprintf("scheme %q does not match %q", payload.Scheme, requirements.Scheme), InvalidReason: X402ReasonUnsupportedScheme}, nil
+	}
+	if payload.Network != requirements.Network {
+		return &VerifyResponse{Valid: false, Error: fmt.Sprintf("network %q does not match %q", payload.Network, requirements.Network), InvalidReason: X402ReasonWrongNetwork}, nil
+	}
+
+	chainID, err := v.getChainID()
+	if err != nil {
+		return nil, err
+	}
+	if "arkeo:"+chainID != requirements.Network {
+		return nil, fmt.Errorf("arkeo node is on chain %s, not %s", chainID, requirements.Network)
+	}
+
+	var tx arkeoTx
+	if payment.Tx != "" {
+		tx, err = v.decodeTx(payment.Tx)
+		if err != nil {
+			return &VerifyResponse{Valid: false, Error: err.Error(), InvalidReason: X402ReasonInvalidPayload}, nil
+		}
+	} else {
+		var result arkeoTxResult
+		var found bool
+		tx, result, found, err = v.getTx(payment.TxHash)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return &VerifyResponse{Valid: false, Error: fmt.Sprintf("tx %s not found", payment.TxHash), InvalidReason: X402ReasonInvalidPayload}, nil
+		}
+		if err := v.checkTxResult(result); err != nil {
+			return &VerifyResponse{Valid: false, Error: err.Error(), InvalidReason: X402ErrorReason(err)}, nil
+		}
+	}
+
+	payer, err := checkArkeoPayment(tx, requirements)
+	if err == nil && payment.Tx == "" {
+		err = checkArkeoTxHashSignature(payment, requirements.Network, payer)
+	}
+	if err != nil {
+		return &VerifyResponse{Valid: false, Error: err.Error(), InvalidReason: X402ErrorReason(err), Payer: payer}, nil
+	}
+	return &VerifyResponse{Valid: true, Payer: payer, Settled: payment.TxHash != ""}, nil
+}
+
+// Settle broadcasts a signed payment and waits until it is included in a
+// block. A referenced tx is already settled and only verified again.
+func (v *ArkeoVerifier) Settle(paymentPayload string, requirements PaymentRequirements) (*SettleResponse, error) {
+	verifyResp, err := v.Verify(paymentPayload, requirements)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyResp.Valid {
+		return &SettleResponse{Success: false, Error: verifyResp.Error, ErrorReason: verifyResp.InvalidReason, Payer: verifyResp.Payer}, nil
+	}
+
+	payload, _ := DecodePaymentPayload(paymentPayload)
+	payment, _ := payload.ArkeoTx()
+	hash, err := payment.Hash()
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.Tx != "" {
+		result, err := v.broadcastTx(payment.Tx)
+		if err != nil {
+			return nil, err
+		}
+		if result.Code != 0 {
+			return &SettleResponse{Success: false, Error: fmt.Sprintf("tx rejected with code %d: %s", result.Code, result.RawLog), ErrorReason: arkeoTxFailureReason(result.Code), Payer: verifyResp.Payer}, nil
+		}
+		result, err = v.waitForTx(hash)
+		if err != nil {
+			return nil, err
+		}
+		if result.Code != 0 {
+			return &SettleResponse{Success: false, Error: fmt.Sprintf("tx failed with code %d: %s", result.Code, result.RawLog), ErrorReason: arkeoTxFailureReason(result.Code), Payer: verifyResp.Payer}, nil
+		}
+	}
+
+	return &SettleResponse{
+		Success:      true,
+		SettlementID: hash,
+		TxHash:       hash,
+		Payer:        verifyResp.Payer,
+	}, nil
+}
+
+// checkArkeoPayment sums the MsgSend amounts to the provider and returns the
+// sender of the payment
+func checkArkeoPayment(tx arkeoTx, requirements PaymentRequirements) (string, error) {
+	required, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		return "", fmt.Errorf("invalid required amount %q", requirements.Amount)
+	}
+
+	payer := ""
+	paid := new(big.Int)
+	for _, msg := range tx.Body.Messages {
+		if msg.Type != msgSendTypeURL || msg.ToAddress != requirements.PayTo {
+			continue
+		}
+		if payer == "" {
+			payer = msg.FromAddress
+		}
+		for _, coin := range msg.Amount {
+			if coin.Denom != requirements.Asset {
+				continue
+			}
+			if amount, ok := new(big.Int).SetString(coin.Amount, 10); ok {
+				paid.Add(paid, amount)
+			}
+		}
+	}
+	if payer == "" {
+		return "", newX402Error(X402ReasonWrongRecipient, "tx does not send %s to %s", requirements.Asset, requirements.PayTo)
+	}
+	if paid.Cmp(required) < 0 {
+		return payer, newX402Error(X402ReasonInsufficientFunds, "insufficient amount %s%s, %s%s required", paid, requirements.Asset, required, requirements.Asset)
+	}
+	return payer, nil
+}
+
+// arkeoTxHashMessage returns the message the sender of a broadcast tx signs
+// to pay with its hash
+func arkeoTxHashMessage(network, hash string) string {
+	return network + ":" + strings.ToUpper(hash)
+}
+
+// checkArkeoTxHashSignature checks a tx hash is presented by the sender of
+// the tx, rather than by whoever saw it on chain first
+func checkArkeoTxHashSignature(payment ArkeoTxPayload, network, payer string) error {
+	if payment.PubKey == "" || payment.Signature == "" {
+		return newX402Error(X402ReasonInvalidSignature, "a tx hash must be signed by its sender")
+	}
+	pk, err := cosmos.GetPubKeyFromBech32(cosmos.Bech32PubKeyTypeAccPub, payment.PubKey)
+	if err != nil {
+		return newX402Error(X402ReasonInvalidPayload, "invalid pubKey: %w", err)
+	}
+	_, payerBytes, err := bech32.DecodeAndConvert(payer)
+	if err != nil || !bytes.Equal(payerBytes, pk.Address()) {
+		return newX402Error(X402ReasonInvalidSignature, "pubKey is not the sender %s", payer)
+	}
+	signature, err := hex.DecodeString(payment.Signature)
+	if err != nil || !pk.VerifySignature([]byte(arkeoTxHashMessage(network, payment.TxHash)), signature) {
+		return newX402Error(X402ReasonInvalidSignature, "invalid tx hash signature")
+	}
+	return nil
+}
+
+// arkeoTxFailureReason maps the ABCI code of a failed bank send
+func arkeoTxFailureReason(code uint32) string {
+	switch code {
+	case 5: // sdkerrors.ErrInsufficientFunds
+		return X402ReasonInsufficientFunds
+	case 4, 8: // sdkerrors.ErrUnauthorized, sdkerrors.ErrInvalidPubKey
+		return X402ReasonInvalidSignature
+	case 30: // sdkerrors.ErrTxTimeoutHeight
+		return X402ReasonExpired
+	default:
+		return X402ReasonSettlementFailed
+	}
+}
+
+func (v *ArkeoVerifier) checkTxResult(result arkeoTxResult) error {
+	if result.Code != 0 {
+		return newX402Error(X402ReasonInvalidPayload, "tx %s failed with code %d", result.TxHash, result.Code)
+	}
+	timestamp, err := time.Parse(time.RFC3339, result.Timestamp)
+	if err != nil {
+		return newX402Error(X402ReasonInvalidPayload, "invalid tx timestamp %q: %w", result.Timestamp, err)
+	}
+	if v.now().Sub(timestamp) > x402ArkeoTxMaxAge {
+		return newX402Error(X402ReasonExpired, "tx %s is older than %s", result.TxHash, x402ArkeoTxMaxAge)
+	}
+	return nil
+}
+
+// getChainID returns the chain id of the node, it is fetched once
+func (v *ArkeoVerifier) getChainID() (string, error) {
+	v.chainMu.Lock()
+	defer v.chainMu.Unlock()
+	if v.chainID != "" {
+		return v.chainID, nil
+	}
+
+	var nodeInfo struct {
+		DefaultNodeInfo struct {
+			Network string `json:"network"`
+		} `json:"default_node_info"`
+	}
+	status, err := v.do(http.MethodGet, "/cosmos/base/tendermint/v1beta1/node_info", nil, &nodeInfo)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK || nodeInfo.DefaultNodeInfo.Network == "" {
+		return "", fmt.Errorf("fail to get arkeo chain id, status %d", status)
+	}
+	v.chainID = nodeInfo.DefaultNodeInfo.Network
+	return v.chainID, nil
+}
+
+func (v *ArkeoVerifier) decodeTx(txBytes string) (arkeoTx, error) {
+	var decoded struct {
+		Tx arkeoTx `json:"tx"`
+	}
+	status, err := v.do(http.MethodPost, "/cosmos/tx/v1beta1/decode", map[string]string{"tx_bytes": txBytes}, &decoded)
+	if err != nil {
+		return arkeoTx{}, err
+	}
+	if status != http.StatusOK {
+		return arkeoTx{}, fmt.Errorf("invalid arkeo tx")
+	}
+	return decoded.Tx, nil
+}
+
+func (v *ArkeoVerifier) getTx(hash string) (arkeoTx, arkeoTxResult, bool, error) {
+	var res struct {
+		Tx         arkeoTx       `json:"tx"`
+		TxResponse arkeoTxResult `json:"tx_response"`
+	}
+	status, err := v.do(http.MethodGet, "/cosmos/tx/v1beta1/txs/"+strings.ToUpper(hash), nil, &res)
+	if err != nil {
+		return arkeoTx{}, arkeoTxResult{}, false, err
+	}
+	switch status {
+	case http.StatusOK:
+		return res.Tx, res.TxResponse, true, nil
+	case http.StatusNotFound, http.StatusBadRequest:
+		return arkeoTx{}, arkeoTxResult{}, false, nil
+	default:
+		return arkeoTx{}, arkeoTxResult{}, false, fmt.Errorf("fail to get tx %s, status %d", hash, status)
+	}
+}
+
+func (v *ArkeoVerifier) broadcastTx(txBytes string) (arkeoTxResult, error) {
+	var res struct {
+		TxResponse arkeoTxResult `json:"tx_response"`
+	}
+	body := map[string]string{"tx_bytes": txBytes, "mode": "BROADCAST_MODE_SYNC"}
+	status, err := v.do(http.MethodPost, "/cosmos/tx/v1beta1/txs", body, &res)
+	if err != nil {
+		return arkeoTxResult{}, err
+	}
+	if status != http.StatusOK {
+		return arkeoTxResult{}, fmt.Errorf("fail to broadcast tx, status %d", status)
+	}
+	return res.TxResponse, nil
+}
+
+// waitForTx polls the chain until the tx is included in a block
+func (v *ArkeoVerifier) waitForTx(hash string) (arkeoTxResult, error) {
+	deadline := v.now().Add(x402ArkeoConfirmTimeout)
+	for {
+		_, result, found, err := v.getTx(hash)
+		if err != nil {
+			return result, err
+		}
+		if found {
+			return result, nil
+		}
+		if v.now().After(deadline) {
+			return result, fmt.Errorf("tx %s not included after %s", hash, x402ArkeoConfirmTimeout)
+		}
+		time.Sleep(v.pollInterval)
+	}
+}
+
+// do sends a request to the REST API and decodes the response into out
+func (v *ArkeoVerifier) do(method, path string, in, out interface{}) (int, error) {
+	var body io.Reader
+	if in != nil {
+		buf, err := json.Marshal(in)
+		if err != nil {
+			return 0, fmt.Errorf("fail to marshal request: %w", err)
+		}
+		body = bytes.NewReader(buf)
+	}
+	req, err := http.NewRequest(method, v.baseURL+path, body)
+	if err != nil {
+		return 0, fmt.Errorf("fail to create http request: %w", err)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if v.authManager != nil {
+		authHeader, err := v.authManager.GenerateAuthHeader()
+		if err != nil {
+			return 0, fmt.Errorf("fail to generate auth header: %w", err)
+		}
+		req.Header.Set(QueryArkAuth, authHeader)
+	}
+
+	res, err := v.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("fail to send http request: %w", err)
+	}
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, fmt.Errorf("fail to read response body: %w", err)
+	}
+	if res.StatusCode == http.StatusOK {
+		if err := json.Unmarshal(resBody, out); err != nil {
+			return res.StatusCode, fmt.Errorf("fail to unmarshal response: %w", err)
+		}
+	}
+	return res.StatusCode, nil
+}
@@ -0,0 +1,229 @@
+package sentinel
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/common/cosmos"
+)
+
+const (
+	testArkeoPayTo = "tarkeo1provider"
+	testArkeoPayer = "tarkeo1payer"
+)
+
+func testArkeoRequirements() PaymentRequirements {
+	return PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "arkeo:arkeo-testnet-1",
+		Amount:            "850000",
+		Asset:             "uarkeo",
+		PayTo:             testArkeoPayTo,
+		MaxTimeoutSeconds: 60,
+	}
+}
+
+func testArkeoPayment(t *testing.T, payment ArkeoTxPayload) string {
+	t.Helper()
+	return testArkeoPaymentOn(t, "arkeo:arkeo-testnet-1", payment)
+}
+
+func testArkeoPaymentOn(t *testing.T, network string, payment ArkeoTxPayload) string {
+	t.Helper()
+	raw, err := json.Marshal(payment)
+	require.NoError(t, err)
+	header, err := json.Marshal(PaymentPayload{
+		X402Version: X402Version,
+		Scheme:      "exact",
+		Network:     network,
+		Payload:     raw,
+	})
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(header)
+}
+
+func testArkeoTx(to, amount string) map[string]interface{} {
+	return testArkeoTxFrom(testArkeoPayer, to, amount)
+}
+
+func testArkeoTxFrom(from, to, amount string) map[string]interface{} {
+	return map[string]interface{}{
+		"body": map[string]interface{}{
+			"messages": []map[string]interface{}{{
+				"@type":        msgSendTypeURL,
+				"from_address": from,
+				"to_address":   to,
+				"amount":       []map[string]string{{"denom": "uarkeo", "amount": amount}},
+			}},
+		},
+	}
+}
+
+// testArkeoSender is the key of an agent paying with tx hashes
+type testArkeoSender struct {
+	key     *secp256k1.PrivKey
+	address string
+}
+
+func newTestArkeoSender(t *testing.T) testArkeoSender {
+	t.Helper()
+	key := secp256k1.GenPrivKey()
+	return testArkeoSender{key: key, address: cosmos.AccAddress(key.PubKey().Address()).String()}
+}
+
+// sign returns the payload of a tx hash signed by the sender
+func (s testArkeoSender) sign(t *testing.T, network, hash string) ArkeoTxPayload {
+	t.Helper()
+	pubKey, err := cosmos.Bech32ifyPubKey(cosmos.Bech32PubKeyTypeAccPub, s.key.PubKey())
+	require.NoError(t, err)
+	signature, err := s.key.Sign([]byte(arkeoTxHashMessage(network, hash)))
+	require.NoError(t, err)
+	return ArkeoTxPayload{TxHash: hash, PubKey: pubKey, Signature: hex.EncodeToString(signature)}
+}
+
+// testArkeoNode fakes the REST endpoints of an arkeo node. Txs are looked up
+// by hash, broadcast txs become available right away.
+type testArkeoNode struct {
+	txs       map[string]map[string]interface{}
+	decoded   map[string]interface{}
+	broadcast int
+	lastTx    string
+}
+
+func (n *testArkeoNode) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/cosmos/base/tendermint/v1beta1/node_info":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"default_node_info": map[string]string{"network": "arkeo-testnet-1"}})
+	case strings.HasPrefix(r.URL.Path, "/cosmos/auth/v1beta1/account_info/"):
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"info": map[string]string{"account_number": "7", "sequence": "3"}})
+	case r.URL.Path == "/cosmos/tx/v1beta1/decode":
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"tx": n.decoded})
+	case r.URL.Path == "/cosmos/tx/v1beta1/txs" && r.Method == http.MethodPost:
+		var req struct {
+			TxBytes string `json:"tx_bytes"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		hash, _ := ArkeoTxPayload{Tx: req.TxBytes}.Hash()
+		n.broadcast++
+		n.lastTx = req.TxBytes
+		n.txs[hash] = map[string]interface{}{
+			"tx":          n.decoded,
+			"tx_response": map[string]interface{}{"txhash": hash, "code": 0, "timestamp": time.Now().UTC().Format(time.RFC3339)},
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"tx_response": map[string]interface{}{"txhash": hash, "code": 0}})
+	case strings.HasPrefix(r.URL.Path, "/cosmos/tx/v1beta1/txs/"):
+		tx, ok := n.txs[strings.TrimPrefix(r.URL.Path, "/cosmos/tx/v1beta1/txs/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(tx)
+	default:
+		w.WriteHeader(http.StatusNotImplemented)
+	}
+}
+
+func TestArkeoVerifier_TxHash(t *testing.T) {
+	now := time.Now()
+	sender := newTestArkeoSender(t)
+	txResponse := func(hash string, code int, at time.Time) map[string]interface{} {
+		return map[string]interface{}{"txhash": hash, "code": code, "timestamp": at.UTC().Format(time.RFC3339)}
+	}
+	node := &testArkeoNode{txs: map[string]map[string]interface{}{
+		"AAAA": {"tx": testArkeoTxFrom(sender.address, testArkeoPayTo, "850000"), "tx_response": txResponse("AAAA", 0, now)},
+		"BBBB": {"tx": testArkeoTxFrom(sender.address, testArkeoPayTo, "1"), "tx_response": txResponse("BBBB", 0, now)},
+		"CCCC": {"tx": testArkeoTxFrom(sender.address, testArkeoPayTo, "850000"), "tx_response": txResponse("CCCC", 0, now.Add(-time.Hour))},
+		"DDDD": {"tx": testArkeoTxFrom(sender.address, testArkeoPayTo, "850000"), "tx_response": txResponse("DDDD", 5, now)},
+		"EEEE": {"tx": testArkeoTxFrom(sender.address, "tarkeo1someoneelse", "850000"), "tx_response": txResponse("EEEE", 0, now)},
+	}}
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	verifier := NewArkeoVerifier(server.URL, nil)
+	requirements := testArkeoRequirements()
+
+	resp, err := verifier.Verify(testArkeoPayment(t, sender.sign(t, requirements.Network, "aaaa")), requirements)
+	require.NoError(t, err)
+	require.True(t, resp.Valid, resp.Error)
+	require.Equal(t, sender.address, resp.Payer)
+	require.True(t, resp.Settled, "a broadcast tx cannot be voided")
+
+	settle, err := verifier.Settle(testArkeoPayment(t, sender.sign(t, requirements.Network, "aaaa")), requirements)
+	require.NoError(t, err)
+	require.True(t, settle.Success)
+	require.Equal(t, "AAAA", settle.TxHash)
+
+	for _, hash := range []string{"BBBB", "CCCC", "DDDD", "EEEE", "FFFF"} {
+		resp, err = verifier.Verify(testArkeoPayment(t, sender.sign(t, requirements.Network, hash)), requirements)
+		require.NoError(t, err)
+		require.False(t, resp.Valid, hash)
+	}
+
+	// only the sender can pay with the hash of its tx
+	thief := newTestArkeoSender(t)
+	for name, payment := range map[string]ArkeoTxPayload{
+		"unsigned":       {TxHash: "AAAA"},
+		"another sender": thief.sign(t, requirements.Network, "AAAA"),
+		"another hash":   {TxHash: "AAAA", PubKey: sender.sign(t, requirements.Network, "BBBB").PubKey, Signature: sender.sign(t, requirements.Network, "BBBB").Signature},
+		"another chain":  {TxHash: "AAAA", PubKey: sender.sign(t, "arkeo:arkeo-main-1", "AAAA").PubKey, Signature: sender.sign(t, "arkeo:arkeo-main-1", "AAAA").Signature},
+	} {
+		resp, err = verifier.Verify(testArkeoPayment(t, payment), requirements)
+		require.NoError(t, err)
+		require.False(t, resp.Valid, name)
+		require.Equal(t, X402ReasonInvalidSignature, resp.InvalidReason, name)
+	}
+
+	// the node is on another chain
+	wrongChain := requirements
+	wrongChain.Network = "arkeo:arkeo-main-1"
+	_, err = verifier.Verify(testArkeoPaymentOn(t, wrongChain.Network, sender.sign(t, wrongChain.Network, "AAAA")), wrongChain)
+	require.Error(t, err)
+}
+
+func TestArkeoVerifier_SignedTx(t *testing.T) {
+	node := &testArkeoNode{
+		txs:     map[string]map[string]interface{}{},
+		decoded: testArkeoTx(testArkeoPayTo, "850000"),
+	}
+	server := httptest.NewServer(node)
+	defer server.Close()
+
+	verifier := NewArkeoVerifier(server.URL, nil)
+	verifier.pollInterval = time.Millisecond
+	requirements := testArkeoRequirements()
+	signed := ArkeoTxPayload{Tx: base64.StdEncoding.EncodeToString([]byte("signed-tx"))}
+	payment := testArkeoPayment(t, signed)
+
+	resp, err := verifier.Verify(payment, requirements)
+	require.NoError(t, err)
+	require.True(t, resp.Valid, resp.Error)
+	require.Zero(t, node.broadcast)
+	require.False(t, resp.Settled)
+
+	settle, err := verifier.Settle(payment, requirements)
+	require.NoError(t, err)
+	require.True(t, settle.Success, settle.Error)
+	require.Equal(t, 1, node.broadcast)
+	hash, err := signed.Hash()
+	require.NoError(t, err)
+	require.Equal(t, hash, settle.TxHash)
+
+	// the signed tx and its hash share a replay record
+	signedRecord := NewX402PaymentRecord(payment, requirements, time.Now())
+	hashRecord := NewX402PaymentRecord(testArkeoPayment(t, ArkeoTxPayload{TxHash: strings.ToLower(hash)}), requirements, time.Now())
+	require.Equal(t, signedRecord.Key, hashRecord.Key)
+
+	node.decoded = testArkeoTx(testArkeoPayTo, "100")
+	resp, err = verifier.Verify(payment, requirements)
+	require.NoError(t, err)
+	require.False(t, resp.Valid)
+}
@@ -0,0 +1,382 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+const (
+	// RouteX402WellKnown is where agents discover what the sentinel sells
+	RouteX402WellKnown = "/.well-known/x402"
+
+	// RouteX402Catalog serves the same catalog under the x402 routes
+	RouteX402Catalog = "/x402/catalog"
+
+	// RouteX402OpenAPI serves only the OpenAPI document of the catalog
+	RouteX402OpenAPI = "/x402/openapi.json"
+)
+
+// X402Catalog lists every paid service of the sentinel with what an agent
+// needs to call it: payment options, prices, limits and an example request.
+// Tools describes each service as a function for tool calling frameworks,
+// OpenAPI describes the same endpoints as an OpenAPI 3.1 document.
+type X402Catalog struct {
+	X402Version int                    `json:"x402Version"`
+	Mode        string                 `json:"mode"`
+	Provider    X402CatalogProvider    `json:"provider"`
+	Accepts     []X402CatalogAccept    `json:"accepts"`
+	Services    []X402CatalogService   `json:"services"`
+	Tools       []X402CatalogTool      `json:"tools"`
+	OpenAPI     map[string]interface{} `json:"openapi"`
+}
+
+// X402CatalogProvider describes the provider running the sentinel
+type X402CatalogProvider struct {
+	Moniker     string `json:"moniker,omitempty"`
+	Website     string `json:"website,omitempty"`
+	Description string `json:"description,omitempty"`
+	Location    string `json:"location,omitempty"`
+	PubKey      string `json:"pubkey,omitempty"`
+}
+
+// X402CatalogAccept is a payment option, priced per compute unit
+type X402CatalogAccept struct {
+	Scheme    string `json:"scheme"`
+	Network   string `json:"network"`
+	Asset     string `json:"asset"`
+	PayTo     string `json:"payTo"`
+	UnitPrice string `json:"unitPrice"`
+	Decimals  int    `json:"decimals,omitempty"`
+}
+
+// X402CatalogService is a paid service
+type X402CatalogService struct {
+	Name         string             `json:"name"`
+	ID           int32              `json:"id"`
+	Type         string             `json:"type,omitempty"`
+	Description  string             `json:"description,omitempty"`
+	Endpoint     string             `json:"endpoint"`
+	Requirements string             `json:"requirements"`
+	Pricing      X402CatalogPricing `json:"pricing"`
+	RateLimits   map[string]int     `json:"rateLimits,omitempty"`
+	Example      X402CatalogExample `json:"example"`
+}
+
+// X402CatalogPricing is the compute units charged for a request
+type X402CatalogPricing struct {
+	ComputeUnits    uint64            `json:"computeUnits"`
+	Methods         map[string]uint64 `json:"methods,omitempty"`
+	Paths           map[string]uint64 `json:"paths,omitempty"`
+	MaxComputeUnits uint64            `json:"maxComputeUnits,omitempty"`
+}
+
+// X402CatalogExample is a request an agent can send as is, after paying
+type X402CatalogExample struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Body   interface{} `json:"body,omitempty"`
+}
+
+// X402CatalogTool is a service described as a callable function
+type X402CatalogTool struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description"`
+	Parameters  map[string]interface{} `json:"parameters"`
+}
+
+// BuildX402Catalog builds the catalog of the services with a configured
+// upstream
+func (p *Proxy) BuildX402Catalog() X402Catalog {
+	cfg := p.currentConfig()
+	catalog := X402Catalog{
+		X402Version: X402Version,
+		Mode:        cfg.GetX402Mode(),
+		Provider: X402CatalogProvider{
+			Moniker:     cfg.Moniker,
+			Website:     cfg.Website,
+			Description: cfg.Description,
+			Location:    cfg.Location,
+		},
+		Accepts:  []X402CatalogAccept{},
+		Services: []X402CatalogService{},
+		Tools:    []X402CatalogTool{},
+	}
+	if !cfg.ProviderPubKey.IsEmpty() {
+		catalog.Provider.PubKey = cfg.ProviderPubKey.String()
+	}
+	for _, option := range p.x402.paymentOptions() {
+		accept := X402CatalogAccept{
+			Scheme:    option.Scheme,
+			Network:   option.Network,
+			Asset:     option.Asset,
+			PayTo:     option.PayTo,
+			UnitPrice: option.Amount,
+		}
+		if decimals, ok := option.Extra["decimals"].(int); ok {
+			accept.Decimals = decimals
+		}
+		catalog.Accepts = append(catalog.Accepts, accept)
+	}
+
+	configured := make(map[string]string, len(cfg.Services))
+	for _, svc := range cfg.Services {
+		configured[strings.ToLower(svc.Name)] = svc.Type
+	}
+
+	p.proxyMu.RLock()
+	names := make([]string, 0, len(p.proxies))
+	for name, target := range p.proxies {
+		if target != nil {
+			names = append(names, name)
+		}
+	}
+	p.proxyMu.RUnlock()
+	sort.Strings(names)
+
+	p.serviceMu.RLock()
+	defer p.serviceMu.RUnlock()
+	for _, name := range names {
+		id, known := p.serviceIDs[name]
+		if !known {
+			continue
+		}
+		info := p.serviceInfo[name]
+		service := X402CatalogService{
+			Name:         name,
+			ID:           id,
+			Type:         configured[name],
+			Description:  info.Description,
+			Endpoint:     "/x402/" + name + "/",
+			Requirements: strings.Replace(RouteX402Requirements, "{service}", name, 1),
+			Pricing:      p.x402CatalogPricing(name),
+		}
+		if service.Type == "" {
+			service.Type = info.Type
+		}
+		service.RateLimits = x402CatalogRateLimits(cfg.FreeTierRateLimit, cfg.X402Limits)
+		service.Example = x402CatalogExample(service)
+		catalog.Services = append(catalog.Services, service)
+		catalog.Tools = append(catalog.Tools, x402CatalogTool(service))
+	}
+	catalog.OpenAPI = x402OpenAPI(catalog)
+	return catalog
+}
+
+// x402CatalogRateLimits lists the limits that are set
+func x402CatalogRateLimits(freeTier int, limits conf.X402LimitsConfig) map[string]int {
+	rateLimits := make(map[string]int)
+	for name, limit := range map[string]int{
+		"freeTierPerMinute":  freeTier,
+		"ipPerMinute":        limits.IPRequestsPerMinute,
+		"payerPerMinute":     limits.PayerRequestsPerMinute,
+		"payerMaxConcurrent": limits.PayerMaxConcurrent,
+	} {
+		if limit > 0 {
+			rateLimits[name] = limit
+		}
+	}
+	if len(rateLimits) == 0 {
+		return nil
+	}
+	return rateLimits
+}
+
+func (p *Proxy) x402CatalogPricing(service string) X402CatalogPricing {
+	pricing := X402CatalogPricing{ComputeUnits: p.x402.Pricing.DefaultUnits(service)}
+	configured, ok := p.x402.Pricing.Service(service)
+	if !ok {
+		return pricing
+	}
+	if len(configured.Methods) > 0 {
+		pricing.Methods = configured.Methods
+	}
+	if len(configured.Paths) > 0 {
+		pricing.Paths = make(map[string]uint64, len(configured.Paths))
+		for _, path := range configured.Paths {
+			pricing.Paths[path.Pattern] = path.ComputeUnits
+		}
+	}
+	pricing.MaxComputeUnits = configured.MaxComputeUnits
+	return pricing
+}
+
+// isRESTService reports whether a service is called with plain HTTP requests
+// rather than JSON-RPC
+func isRESTService(service X402CatalogService) bool {
+	return strings.EqualFold(service.Type, "rest") || strings.HasSuffix(service.Name, "-rest") || len(service.Pricing.Paths) > 0
+}
+
+// x402CatalogExample returns a cheap request to the service, a priced method
+// when there is one
+func x402CatalogExample(service X402CatalogService) X402CatalogExample {
+	if isRESTService(service) {
+		return X402CatalogExample{Method: http.MethodGet, URL: service.Endpoint}
+	}
+	method := ""
+	for name := range service.Pricing.Methods {
+		if method == "" || name < method {
+			method = name
+		}
+	}
+	if method == "" {
+		switch {
+		case strings.HasPrefix(service.Name, "btc") || strings.HasPrefix(service.Name, "bitcoin"):
+			method = "getblockcount"
+		case strings.HasSuffix(service.Name, "-rpc") || strings.EqualFold(service.Type, "tendermint"):
+			method = "status"
+		default:
+			method = "eth_blockNumber"
+		}
+	}
+	return X402CatalogExample{
+		Method: http.MethodPost,
+		URL:    service.Endpoint,
+		Body:   map[string]interface{}{"jsonrpc": "2.0", "id": 1, "method": method, "params": []interface{}{}},
+	}
+}
+
+// x402ToolName turns a service name into a function name tool calling
+// frameworks accept
+func x402ToolName(service string) string {
+	return "call_" + strings.NewReplacer("-", "_", ".", "_").Replace(service)
+}
+
+func x402CatalogTool(service X402CatalogService) X402CatalogTool {
+	description := service.Description
+	if description == "" {
+		description = fmt.Sprintf("Call the %s service", service.Name)
+	}
+	description += fmt.Sprintf(", paid with x402 (%d compute units per request)", service.Pricing.ComputeUnits)
+	return X402CatalogTool{
+		Name:        x402ToolName(service.Name),
+		Description: description,
+		Parameters:  x402RequestSchema(service),
+	}
+}
+
+// x402RequestSchema is the JSON schema of a request to the service
+func x402RequestSchema(service X402CatalogService) map[string]interface{} {
+	if isRESTService(service) {
+		return map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"path": map[string]interface{}{"type": "string", "description": "path below " + service.Endpoint},
+			},
+			"required": []string{"path"},
+		}
+	}
+	method := map[string]interface{}{"type": "string", "description": "JSON-RPC method"}
+	if len(service.Pricing.Methods) > 0 {
+		methods := make([]string, 0, len(service.Pricing.Methods))
+		for name := range service.Pricing.Methods {
+			methods = append(methods, name)
+		}
+		sort.Strings(methods)
+		method["examples"] = methods
+	}
+	return map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"jsonrpc": map[string]interface{}{"type": "string", "const": "2.0"},
+			"id":      map[string]interface{}{"type": []string{"integer", "string"}},
+			"method":  method,
+			"params":  map[string]interface{}{"type": []string{"array", "object"}},
+		},
+		"required": []string{"jsonrpc", "id", "method"},
+	}
+}
+
+// x402OpenAPI describes the paid endpoints of the catalog as an OpenAPI 3.1
+// document
+func x402OpenAPI(catalog X402Catalog) map[string]interface{} {
+	paymentHeader := map[string]interface{}{
+		"name":        "X-PAYMENT",
+		"in":          "header",
+		"description": "base64 encoded x402 payment payload, omit it to get the payment requirements",
+		"schema":      map[string]interface{}{"type": "string"},
+	}
+	responses := map[string]interface{}{
+		"200": map[string]interface{}{
+			"description": "upstream response, the receipt is in the X-PAYMENT-RESPONSE header",
+			"headers": map[string]interface{}{
+				X402PaymentResponseHeader: map[string]interface{}{"schema": map[string]interface{}{"type": "string"}},
+			},
+		},
+		"402": map[string]interface{}{
+			"description": "payment required",
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": map[string]interface{}{"$ref": "#/components/schemas/PaymentRequired"}},
+			},
+		},
+	}
+
+	paths := make(map[string]interface{}, len(catalog.Services))
+	for _, service := range catalog.Services {
+		operation := map[string]interface{}{
+			"operationId": x402ToolName(service.Name),
+			"summary":     x402CatalogTool(service).Description,
+			"parameters":  []interface{}{paymentHeader},
+			"responses":   responses,
+		}
+		if isRESTService(service) {
+			operation["parameters"] = []interface{}{paymentHeader, map[string]interface{}{
+				"name": "path", "in": "path", "required": true,
+				"schema": map[string]interface{}{"type": "string"},
+			}}
+			paths[service.Endpoint+"{path}"] = map[string]interface{}{"get": operation}
+			continue
+		}
+		operation["requestBody"] = map[string]interface{}{
+			"required": true,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{"schema": x402RequestSchema(service)},
+			},
+		}
+		paths[service.Endpoint] = map[string]interface{}{"post": operation}
+	}
+
+	title := "x402 services"
+	if catalog.Provider.Moniker != "" {
+		title = catalog.Provider.Moniker + " " + title
+	}
+	return map[string]interface{}{
+		"openapi": "3.1.0",
+		"info": map[string]interface{}{
+			"title":       title,
+			"version":     fmt.Sprintf("%d", catalog.X402Version),
+			"description": catalog.Provider.Description,
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": map[string]interface{}{
+				"PaymentRequired": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"x402Version": map[string]interface{}{"type": "integer"},
+						"error":       map[string]interface{}{"type": "string"},
+						"accepts":     map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "object"}},
+					},
+				},
+			},
+		},
+	}
+}
+
+// handleX402Catalog serves the catalog of paid services
+func (p *Proxy) handleX402Catalog(w http.ResponseWriter, r *http.Request) {
+	p.x402.SetModeHeader(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.BuildX402Catalog())
+}
+
+// handleX402OpenAPI serves the OpenAPI document of the catalog
+func (p *Proxy) handleX402OpenAPI(w http.ResponseWriter, r *http.Request) {
+	p.x402.SetModeHeader(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(p.BuildX402Catalog().OpenAPI)
+}
@@ -0,0 +1,89 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+func TestX402Catalog(t *testing.T) {
+	handler := NewX402Handler(testPayTo)
+	handler.Accepts = []PaymentRequirements{testRequirements()}
+	handler.Pricing = NewX402Pricing(map[string]conf.X402ServicePricing{
+		"eth-mainnet-fullnode": {ComputeUnits: 2, Methods: map[string]uint64{"eth_getLogs": 50}},
+	})
+	proxy := &Proxy{
+		Config: conf.Configuration{
+			Moniker:           "provider",
+			FreeTierRateLimit: 10,
+			Services:          []conf.ServiceConfig{{Name: "eth-mainnet-fullnode", Type: "evm"}},
+		},
+		x402:   handler,
+		logger: log.NewNopLogger(),
+		proxies: map[string]*url.URL{
+			"eth-mainnet-fullnode": {Scheme: "http", Host: "eth"},
+			"gaia-mainnet-rest":    {Scheme: "http", Host: "gaia"},
+			"btc-mainnet-fullnode": nil,
+			"unregistered":         {Scheme: "http", Host: "other"},
+		},
+		serviceIDs: map[string]int32{"eth-mainnet-fullnode": 2, "gaia-mainnet-rest": 5, "btc-mainnet-fullnode": 1},
+		serviceInfo: map[string]registryService{
+			"gaia-mainnet-rest": {Description: "Cosmos Hub REST", Type: "rest"},
+		},
+	}
+	router := mux.NewRouter()
+	router.HandleFunc(RouteX402WellKnown, proxy.handleX402Catalog).Methods(http.MethodGet)
+	router.HandleFunc(RouteX402OpenAPI, proxy.handleX402OpenAPI).Methods(http.MethodGet)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, RouteX402WellKnown, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var catalog X402Catalog
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&catalog))
+
+	// only registered services with an upstream are listed
+	require.Len(t, catalog.Services, 2)
+	eth, gaia := catalog.Services[0], catalog.Services[1]
+	require.Equal(t, "eth-mainnet-fullnode", eth.Name)
+	require.Equal(t, int32(2), eth.ID)
+	require.Equal(t, "evm", eth.Type)
+	require.Equal(t, "/x402/requirements/eth-mainnet-fullnode", eth.Requirements)
+	require.Equal(t, uint64(2), eth.Pricing.ComputeUnits)
+	require.Equal(t, uint64(50), eth.Pricing.Methods["eth_getLogs"])
+	require.Equal(t, 10, eth.RateLimits["freeTierPerMinute"])
+	require.Equal(t, http.MethodPost, eth.Example.Method)
+	require.Equal(t, "eth_getLogs", eth.Example.Body.(map[string]interface{})["method"])
+
+	require.Equal(t, "Cosmos Hub REST", gaia.Description)
+	require.Equal(t, "rest", gaia.Type)
+	require.Equal(t, http.MethodGet, gaia.Example.Method)
+
+	require.Len(t, catalog.Accepts, 1)
+	require.Equal(t, "1000", catalog.Accepts[0].UnitPrice)
+	require.Equal(t, "provider", catalog.Provider.Moniker)
+
+	require.Len(t, catalog.Tools, 2)
+	require.Equal(t, "call_eth_mainnet_fullnode", catalog.Tools[0].Name)
+	require.Equal(t, "object", catalog.Tools[0].Parameters["type"])
+
+	// the OpenAPI document has an operation per service
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, RouteX402OpenAPI, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var openapi struct {
+		OpenAPI string                            `json:"openapi"`
+		Paths   map[string]map[string]interface{} `json:"paths"`
+	}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&openapi))
+	require.Equal(t, "3.1.0", openapi.OpenAPI)
+	require.Contains(t, openapi.Paths["/x402/eth-mainnet-fullnode/"], "post")
+	require.Contains(t, openapi.Paths["/x402/gaia-mainnet-rest/{path}"], "get")
+}
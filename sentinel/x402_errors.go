@@ -0,0 +1,102 @@
+package sentinel
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// x402 error reasons. They are the only payment failure details sent to
+// clients, so agents can react without parsing free text.
+const (
+	X402ReasonInvalidPayload      = "invalid_payload"
+	X402ReasonUnsupportedScheme   = "unsupported_scheme"
+	X402ReasonWrongNetwork        = "wrong_network"
+	X402ReasonWrongRecipient      = "wrong_recipient"
+	X402ReasonInsufficientFunds   = "insufficient_funds"
+	X402ReasonInvalidSignature    = "invalid_signature"
+	X402ReasonExpired             = "expired"
+	X402ReasonReplayed            = "replayed"
+	X402ReasonSettlementFailed    = "settlement_failed"
+	X402ReasonVerifierUnavailable = "verifier_unavailable"
+	X402ReasonUpstreamFailed      = "upstream_failed" // only in receipts, the upstream failed and nothing was charged
+)
+
+// X402Error is a payment failure with the reason reported to the client. The
+// wrapped error is only logged.
+type X402Error struct {
+	Reason string
+	Err    error
+}
+
+func (e *X402Error) Error() string {
+	if e.Err == nil {
+		return e.Reason
+	}
+	return e.Reason + ": " + e.Err.Error()
+}
+
+func (e *X402Error) Unwrap() error {
+	return e.Err
+}
+
+// newX402Error creates a payment failure with a formatted internal message
+func newX402Error(reason, format string, args ...interface{}) *X402Error {
+	return &X402Error{Reason: reason, Err: fmt.Errorf(format, args...)}
+}
+
+// X402ErrorReason returns the client facing reason of an error, errors not
+// raised by a payment check mean the verifier could not be reached
+func X402ErrorReason(err error) string {
+	if errors.Is(err, ErrX402PaymentReplayed) {
+		return X402ReasonReplayed
+	}
+	var x402Err *X402Error
+	if errors.As(err, &x402Err) {
+		return x402Err.Reason
+	}
+	return X402ReasonVerifierUnavailable
+}
+
+// X402ErrorStatus returns the HTTP status of a payment failure reason
+func X402ErrorStatus(reason string) int {
+	if reason == X402ReasonVerifierUnavailable {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusPaymentRequired
+}
+
+// normalizeX402Reason maps the reason reported by a verifier, which may be a
+// remote facilitator using its own vocabulary, to one of the x402 reasons
+func normalizeX402Reason(reason string) string {
+	reason = strings.ToLower(reason)
+	switch reason {
+	case X402ReasonInvalidPayload, X402ReasonUnsupportedScheme, X402ReasonWrongNetwork, X402ReasonWrongRecipient,
+		X402ReasonInsufficientFunds, X402ReasonInvalidSignature, X402ReasonExpired, X402ReasonReplayed,
+		X402ReasonSettlementFailed, X402ReasonVerifierUnavailable:
+		return reason
+	}
+	switch {
+	case reason == "":
+		return X402ReasonInvalidPayload
+	case strings.Contains(reason, "insufficient"):
+		return X402ReasonInsufficientFunds
+	case strings.Contains(reason, "signature"):
+		return X402ReasonInvalidSignature
+	case strings.Contains(reason, "expired"), strings.Contains(reason, "valid_before"), strings.Contains(reason, "valid_after"):
+		return X402ReasonExpired
+	case strings.Contains(reason, "network"):
+		return X402ReasonWrongNetwork
+	case strings.Contains(reason, "scheme"):
+		return X402ReasonUnsupportedScheme
+	case strings.Contains(reason, "recipient"), strings.Contains(reason, "pay_to"):
+		return X402ReasonWrongRecipient
+	case strings.Contains(reason, "replay"), strings.Contains(reason, "nonce"):
+		return X402ReasonReplayed
+	case strings.Contains(reason, "settle"):
+		return X402ReasonSettlementFailed
+	default:
+		return X402ReasonInvalidPayload
+	}
+}
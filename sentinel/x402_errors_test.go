@@ -0,0 +1,116 @@
+package sentinel
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// failingVerifier fails like a facilitator returning an internal error body
+type failingVerifier struct{}
+
+func (failingVerifier) Verify(string, PaymentRequirements) (*VerifyResponse, error) {
+	return nil, fmt.Errorf("facilitator returned status 500: secret-internal-detail")
+}
+
+func (failingVerifier) Settle(string, PaymentRequirements) (*SettleResponse, error) {
+	return nil, fmt.Errorf("facilitator returned status 500: secret-internal-detail")
+}
+
+func TestNormalizeX402Reason(t *testing.T) {
+	require.Equal(t, X402ReasonInsufficientFunds, normalizeX402Reason("insufficient_funds"))
+	require.Equal(t, X402ReasonInvalidSignature, normalizeX402Reason("invalid_exact_evm_payload_signature"))
+	require.Equal(t, X402ReasonExpired, normalizeX402Reason("invalid_exact_evm_payload_authorization_valid_before"))
+	require.Equal(t, X402ReasonWrongNetwork, normalizeX402Reason("invalid_network"))
+	require.Equal(t, X402ReasonWrongRecipient, normalizeX402Reason("invalid_exact_evm_payload_recipient_mismatch"))
+	require.Equal(t, X402ReasonSettlementFailed, normalizeX402Reason(X402ReasonSettlementFailed))
+	require.Equal(t, X402ReasonInvalidPayload, normalizeX402Reason("something odd"))
+}
+
+func TestX402Middleware_Errors(t *testing.T) {
+	store, err := NewX402PaymentStore("")
+	require.NoError(t, err)
+	defer store.Close()
+
+	handler := NewX402Handler(testPayTo)
+	handler.Verifier = NewLocalVerifier()
+	handler.Store = store
+	handler.Accepts = []PaymentRequirements{testRequirements()}
+	proxy := &Proxy{x402: handler, logger: log.NewNopLogger(), proxies: map[string]*url.URL{"eth": {}}}
+	serve := proxy.x402Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+
+	send := func(payment string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/x402/eth/", nil)
+		req.Header.Set("X-PAYMENT", payment)
+		rec := httptest.NewRecorder()
+		serve.ServeHTTP(rec, req)
+		return rec
+	}
+	reason := func(rec *httptest.ResponseRecorder) string {
+		var body PaymentRequiredResponse
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		require.NotEmpty(t, body.Accepts)
+		return body.Error
+	}
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	now := time.Now()
+
+	// a settled payment carries the receipt
+	payment := signTestPayment(t, key, testRequirements(), testAuthorization(key, "1000", now))
+	rec := send(payment)
+	require.Equal(t, http.StatusOK, rec.Code)
+	raw, err := base64.StdEncoding.DecodeString(rec.Header().Get(X402PaymentResponseHeader))
+	require.NoError(t, err)
+	var receipt X402PaymentResponse
+	require.NoError(t, json.Unmarshal(raw, &receipt))
+	require.True(t, receipt.Success)
+	require.Equal(t, "eip155:84532", receipt.Network)
+	require.Equal(t, crypto.PubkeyToAddress(key.PublicKey).Hex(), receipt.Payer)
+	require.NotEmpty(t, receipt.TxHash)
+
+	rec = send(payment)
+	require.Equal(t, http.StatusPaymentRequired, rec.Code)
+	require.Equal(t, X402ReasonReplayed, reason(rec))
+
+	rec = send(signTestPayment(t, key, testRequirements(), testAuthorization(key, "999", now)))
+	require.Equal(t, X402ReasonInsufficientFunds, reason(rec))
+
+	expired := testAuthorization(key, "1000", now)
+	expired.ValidBefore = strconv.FormatInt(now.Unix(), 10)
+	rec = send(signTestPayment(t, key, testRequirements(), expired))
+	require.Equal(t, X402ReasonExpired, reason(rec))
+
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	rec = send(signTestPayment(t, other, testRequirements(), testAuthorization(key, "1000", now)))
+	require.Equal(t, X402ReasonInvalidSignature, reason(rec))
+
+	mainnet := testRequirements()
+	mainnet.Network = "eip155:8453"
+	rec = send(signTestPayment(t, key, mainnet, testAuthorization(key, "1000", now)))
+	require.Equal(t, X402ReasonWrongNetwork, reason(rec))
+
+	rec = send("garbage")
+	require.Equal(t, X402ReasonInvalidPayload, reason(rec))
+
+	// verifier failures do not leak their details
+	handler.Verifier = failingVerifier{}
+	rec = send(signTestPayment(t, key, testRequirements(), testAuthorization(key, "1000", now)))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.NotContains(t, rec.Body.String(), "secret")
+	require.Equal(t, X402ReasonVerifierUnavailable, reason(rec))
+}
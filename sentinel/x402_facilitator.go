@@ -31,11 +31,20 @@ type VerifyResponse struct {
 	SettlementID string `json:"settlementId,omitempty"`
 	Error        string `json:"error,omitempty"`
 	TxHash       string `json:"txHash,omitempty"`
+	Payer        string `json:"payer,omitempty"`
+
+	// InvalidReason is the machine readable reason of an invalid payment
+	InvalidReason string `json:"invalidReason,omitempty"`
+
+	// Settled is set when the funds already moved, such a payment cannot be
+	// voided if the request fails
+	Settled bool `json:"settled,omitempty"`
 }
 
 // SettleRequest to settle a payment
 type SettleRequest struct {
-	PaymentPayload string `json:"paymentPayload"`
+	PaymentPayload string              `json:"paymentPayload"`
+	Requirements   PaymentRequirements `json:"requirements"`
 }
 
 // SettleResponse from facilitator
@@ -44,6 +53,14 @@ type SettleResponse struct {
 	SettlementID string `json:"settlementId,omitempty"`
 	TxHash       string `json:"txHash,omitempty"`
 	Error        string `json:"error,omitempty"`
+	Payer        string `json:"payer,omitempty"`
+
+	// ErrorReason is the machine readable reason of a failed settlement
+	ErrorReason string `json:"errorReason,omitempty"`
+
+	// Uncollected is set when the payment was accepted without moving the
+	// funds, its signed authorization still has to be submitted on chain
+	Uncollected bool `json:"-"`
 }
 
 // NewX402Facilitator creates a facilitator client from environment/file
@@ -149,9 +166,10 @@ func (f *X402Facilitator) Verify(paymentPayload string, requirements PaymentRequ
 }
 
 // Settle settles a verified payment on-chain
-func (f *X402Facilitator) Settle(paymentPayload string) (*SettleResponse, error) {
+func (f *X402Facilitator) Settle(paymentPayload string, requirements PaymentRequirements) (*SettleResponse, error) {
 	reqBody := SettleRequest{
 		PaymentPayload: paymentPayload,
+		Requirements:   requirements,
 	}
 	
 	jsonBody, err := json.Marshal(reqBody)
@@ -190,28 +208,3 @@ func (f *X402Facilitator) Settle(paymentPayload string) (*SettleResponse, error)
 	
 	return &settleResp, nil
 }
-
-// VerifyAndSettle does both verification and settlement in one call
-func (f *X402Facilitator) VerifyAndSettle(paymentPayload string, requirements PaymentRequirements) (bool, string, error) {
-	// First verify
-	verifyResp, err := f.Verify(paymentPayload, requirements)
-	if err != nil {
-		return false, "", fmt.Errorf("verification failed: %w", err)
-	}
-	
-	if !verifyResp.Valid {
-		return false, "", fmt.Errorf("payment not valid: %s", verifyResp.Error)
-	}
-	
-	// Then settle
-	settleResp, err := f.Settle(paymentPayload)
-	if err != nil {
-		return false, "", fmt.Errorf("settlement failed: %w", err)
-	}
-	
-	if !settleResp.Success {
-		return false, "", fmt.Errorf("settlement failed: %s", settleResp.Error)
-	}
-	
-	return true, settleResp.SettlementID, nil
-}
@@ -2,14 +2,31 @@ package sentinel
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
 )
 
 // X402 Protocol Version
 const X402Version = 2
 
+// x402 payment schemes
+const (
+	X402SchemeExact = "exact" // the quoted amount is paid before the request is served
+	X402SchemeUpto  = "upto"  // the payer authorizes a maximum, the metered amount is settled after the response
+)
+
+// X402PaymentResponseHeader carries the settlement receipt of a paid request
+const X402PaymentResponseHeader = "X-PAYMENT-RESPONSE"
+
+// X402ModeHeader flags responses served by a sentinel running in x402 dev mode
+const X402ModeHeader = "X-X402-Mode"
+
 // PaymentRequirements defines what payment is accepted for a resource
 type PaymentRequirements struct {
 	Scheme            string                 `json:"scheme"`
@@ -30,10 +47,10 @@ type ResourceInfo struct {
 
 // PaymentRequiredResponse is the HTTP 402 response body
 type PaymentRequiredResponse struct {
-	X402Version int                   `json:"x402Version"`
-	Error       string                `json:"error,omitempty"`
-	Resource    ResourceInfo          `json:"resource"`
-	Accepts     []PaymentRequirements `json:"accepts"`
+	X402Version int                    `json:"x402Version"`
+	Error       string                 `json:"error,omitempty"`
+	Resource    ResourceInfo           `json:"resource"`
+	Accepts     []PaymentRequirements  `json:"accepts"`
 	Extensions  map[string]interface{} `json:"extensions,omitempty"`
 }
 
@@ -42,6 +59,9 @@ type X402Handler struct {
 	// Provider's payment address
 	ProviderAddress string
 	
+	// Provider's arkeo address receiving ARKEO payments (defaults to ProviderAddress)
+	ArkeoAddress string
+
 	// Accepted payment methods
 	AcceptUSDC  bool
 	AcceptARKEO bool
@@ -55,6 +75,44 @@ type X402Handler struct {
 	
 	// Facilitator URL for payment verification
 	FacilitatorURL string
+
+	// Verifier checks and settles payments (facilitator or local)
+	Verifier PaymentVerifier
+
+	// DevMode accepts unverified payments and flags every response
+	DevMode bool
+
+	// Store remembers accepted payments to reject replays (optional)
+	Store *X402PaymentStore
+
+	// Pricing converts requests into compute units (nil charges one unit per request)
+	Pricing *X402Pricing
+
+	// Accepts are the configured payment options priced per compute unit. When
+	// empty the legacy USDC/ARKEO options are derived from the fields above.
+	Accepts []PaymentRequirements
+
+	// Sessions sells prepaid sessions (optional)
+	Sessions *X402Sessions
+
+	// Receipts keeps the outcome of payments for their payers (optional)
+	Receipts *X402ReceiptStore
+
+	// Ledger accrues settled revenue to report on chain (optional)
+	Ledger *X402LedgerStore
+
+	// WebSocket limits the budgets sold with WebSocket upgrades
+	WebSocket conf.X402WebSocketConfig
+
+	// Limits rate limits clients and payers (nil allows everything)
+	Limits *X402Limits
+
+	// Spend tracks what payers spent (optional), SpendCaps caps it per network
+	Spend     *X402SpendStore
+	SpendCaps map[string]conf.X402SpendCap
+
+	// pricesMu guards the prices and Accepts once serving, see SetPrices
+	pricesMu sync.RWMutex
 }
 
 // NewX402Handler creates a new x402 payment handler
@@ -71,69 +129,156 @@ func NewX402Handler(providerAddress string) *X402Handler {
 }
 
 // BuildPaymentRequirements creates the x402 payment requirements for a service
-func (h *X402Handler) BuildPaymentRequirements(service string, requestURL string) PaymentRequiredResponse {
+// request costing the given compute units. Metered ("upto") options quote the
+// most the request can be charged.
+func (h *X402Handler) BuildPaymentRequirements(service string, requestURL string, computeUnits uint64) PaymentRequiredResponse {
+	return PaymentRequiredResponse{
+		X402Version: X402Version,
+		Error:       "Payment required to access this RPC endpoint",
+		Resource: ResourceInfo{
+			URL:         requestURL,
+			Description: "Arkeo RPC Service: " + service,
+			MimeType:    "application/json",
+		},
+		Accepts: h.priceOptions(h.paymentOptions(), service, computeUnits),
+		Extensions: map[string]interface{}{
+			"computeUnits": computeUnits,
+		},
+	}
+}
+
+// priceOptions prices the payment options for a service request costing the
+// given compute units, in the order of options
+func (h *X402Handler) priceOptions(options []PaymentRequirements, service string, computeUnits uint64) []PaymentRequirements {
 	accepts := []PaymentRequirements{}
+	for _, option := range options {
+		if option.Scheme == X402SchemeUpto {
+			maxUnits := h.Pricing.MaxUnits(service, computeUnits)
+			extra := make(map[string]interface{}, len(option.Extra)+2)
+			for k, v := range option.Extra {
+				extra[k] = v
+			}
+			extra["unitPrice"] = option.Amount
+			extra["maxComputeUnits"] = maxUnits
+			option.Extra = extra
+			option.Amount = priceForUnits(option.Amount, maxUnits)
+		} else {
+			option.Amount = priceForUnits(option.Amount, computeUnits)
+		}
+		accepts = append(accepts, option)
+	}
+	return accepts
+}
+
+// SetPrices replaces the prices and payment options of a serving handler,
+// as on a configuration reload. Empty prices are left unchanged.
+func (h *X402Handler) SetPrices(usdc, arkeo string, accepts []PaymentRequirements) {
+	h.pricesMu.Lock()
+	defer h.pricesMu.Unlock()
+	if usdc != "" {
+		h.PricePerRequestUSDC = usdc
+	}
+	if arkeo != "" {
+		h.PricePerRequestARKEO = arkeo
+	}
+	h.Accepts = accepts
+}
+
+// paymentOptions returns the payment options priced per compute unit
+func (h *X402Handler) paymentOptions() []PaymentRequirements {
+	h.pricesMu.RLock()
+	defer h.pricesMu.RUnlock()
+	if len(h.Accepts) > 0 {
+		return h.Accepts
+	}
 	
-	// USDC on Ethereum Mainnet
+	options := []PaymentRequirements{}
 	if h.AcceptUSDC {
-		accepts = append(accepts, PaymentRequirements{
-			Scheme:            "exact",
-			Network:           "eip155:1", // Ethereum Mainnet
-			Amount:            h.PricePerRequestUSDC,
-			Asset:             "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48", // USDC
-			PayTo:             h.ProviderAddress,
-			MaxTimeoutSeconds: 60,
-			Extra: map[string]interface{}{
-				"name":    "USDC",
-				"version": "2",
-			},
-		})
-		
-		// USDC on Base (cheaper option)
-		accepts = append(accepts, PaymentRequirements{
-			Scheme:            "exact",
-			Network:           "eip155:8453", // Base
-			Amount:            h.PricePerRequestUSDC,
-			Asset:             "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913", // USDC on Base
-			PayTo:             h.ProviderAddress,
-			MaxTimeoutSeconds: 60,
-			Extra: map[string]interface{}{
-				"name":      "USDC",
-				"version":   "2",
-				"chain":     "Base",
-				"gasSaving": "true",
-			},
-		})
+		for _, network := range []string{"eip155:1", "eip155:8453"} {
+			preset := x402NetworkPresets[network]
+			options = append(options, PaymentRequirements{
+				Scheme:            X402SchemeExact,
+				Network:           network,
+				Amount:            h.PricePerRequestUSDC,
+				Asset:             preset.Asset,
+				PayTo:             h.ProviderAddress,
+				MaxTimeoutSeconds: x402DefaultTimeoutSeconds,
+				Extra: map[string]interface{}{
+					"name":     preset.Extra["name"],
+					"version":  preset.Extra["version"],
+					"decimals": preset.Decimals,
+				},
+			})
+		}
 	}
 	
 	// ARKEO token (with discount)
 	if h.AcceptARKEO {
-		accepts = append(accepts, PaymentRequirements{
-			Scheme:            "exact",
+		payTo := h.ArkeoAddress
+		if payTo == "" {
+			payTo = h.ProviderAddress
+		}
+		options = append(options, PaymentRequirements{
+			Scheme:            X402SchemeExact,
 			Network:           "arkeo:arkeo-main-1", // Arkeo Mainnet
 			Amount:            h.PricePerRequestARKEO,
-			Asset:             "uarkeo", // Native ARKEO token
-			PayTo:             h.ProviderAddress,
-			MaxTimeoutSeconds: 60,
+			Asset:             arkeoAssetPreset.Asset,
+			PayTo:             payTo,
+			MaxTimeoutSeconds: x402DefaultTimeoutSeconds,
 			Extra: map[string]interface{}{
 				"name":     "ARKEO",
-				"discount": "15%",
-				"note":     "Pay with ARKEO for 15% off!",
+				"decimals": arkeoAssetPreset.Decimals,
+				"discount": fmt.Sprintf("%d%%", h.ARKEODiscountPercent),
+				"note":     fmt.Sprintf("Pay with ARKEO for %d%% off!", h.ARKEODiscountPercent),
 			},
 		})
 	}
+	return options
+}
+
+// arkeoNetwork returns the first arkeo network accepted when native ARKEO
+// payments are verified
+func (h *X402Handler) arkeoNetwork() (string, bool) {
+	verifier, ok := h.Verifier.(NetworkVerifier)
+	if !ok || verifier.Arkeo == nil {
+		return "", false
+	}
+	for _, option := range h.paymentOptions() {
+		if strings.HasPrefix(option.Network, "arkeo:") {
+			return option.Network, true
+		}
+	}
+	return "", false
+}
+
+// selectRequirements returns the index of the quoted requirement matching the
+// scheme, network and asset the client signed for
+func (h *X402Handler) selectRequirements(paymentPayload string, accepts []PaymentRequirements) (int, error) {
+	payload, err := DecodePaymentPayload(paymentPayload)
+	if err != nil {
+		// dev mode accepts any header, verify against the first option
+		if h.DevMode && len(accepts) > 0 {
+			return 0, nil
+		}
+		return 0, err
+	}
 	
-	return PaymentRequiredResponse{
-		X402Version: X402Version,
-		Error:       "Payment required to access this RPC endpoint",
-		Resource: ResourceInfo{
-			URL:         requestURL,
-			Description: "Arkeo RPC Service: " + service,
-			MimeType:    "application/json",
-		},
-		Accepts:    accepts,
-		Extensions: map[string]interface{}{},
+	for i, requirements := range accepts {
+		if requirements.Scheme != payload.Scheme || requirements.Network != payload.Network {
+			continue
+		}
+		if payload.Accepted != nil && payload.Accepted.Asset != "" && !strings.EqualFold(payload.Accepted.Asset, requirements.Asset) {
+			continue
+		}
+		return i, nil
 	}
+	reason := X402ReasonWrongNetwork
+	for _, requirements := range accepts {
+		if requirements.Network == payload.Network {
+			reason = X402ReasonUnsupportedScheme
+		}
+	}
+	return 0, newX402Error(reason, "no payment option for scheme %q on network %q", payload.Scheme, payload.Network)
 }
 
 // CheckPaymentHeader checks if the request has a valid x402 payment
@@ -153,45 +298,166 @@ func (h *X402Handler) CheckPaymentHeader(r *http.Request) (bool, string) {
 	return true, paymentHeader
 }
 
-// Facilitator is the x402 facilitator client (initialized on first use)
-var facilitator *X402Facilitator
+// X402Payment is a verified payment reserved for a request until it is settled
+type X402Payment struct {
+	// Payload is the X-PAYMENT header of the request
+	Payload string
+
+	// Requirements is the quoted requirement the payment was verified against
+	Requirements PaymentRequirements
+
+	// UnitPrice is the price of one compute unit in the asset of the payment
+	UnitPrice string
+
+	// Payer is the address paying, when the verifier reports it
+	Payer string
+
+	// Prepaid is set when the funds moved before settlement, the payment
+	// cannot be voided
+	Prepaid bool
+
+	record X402PaymentRecord
+}
 
-// VerifyPayment verifies the payment with the Coinbase facilitator
+// Metered reports whether the charge is only known once the upstream answered
+func (p *X402Payment) Metered() bool {
+	return p.Requirements.Scheme == X402SchemeUpto
+}
+
+// VerifyPayment verifies and settles the payment for a request costing the
+// given compute units with the configured verifier
 // Returns: (verified bool, settlementID string, error)
-func (h *X402Handler) VerifyPayment(paymentPayload string) (bool, string, error) {
-	// Initialize facilitator on first use
-	if facilitator == nil {
-		var err error
-		facilitator, err = NewX402Facilitator()
-		if err != nil {
-			// Fall back to demo mode if no credentials
-			return true, "demo-" + time.Now().Format("20060102150405"), nil
+func (h *X402Handler) VerifyPayment(paymentPayload string, computeUnits uint64) (bool, string, error) {
+	payment, err := h.AuthorizePayment("", paymentPayload, computeUnits)
+	if err != nil {
+		return false, "", err
+	}
+	settleResp, err := h.SettlePayment(payment, computeUnits)
+	if err != nil {
+		return false, "", err
+	}
+	return true, settleResp.SettlementID, nil
+}
+
+// AuthorizePayment verifies the payment for a request to service costing the
+// given compute units and reserves it so it cannot be replayed. Nothing is
+// charged until SettlePayment.
+func (h *X402Handler) AuthorizePayment(service, paymentPayload string, computeUnits uint64) (*X402Payment, error) {
+	if h.Verifier == nil {
+		return nil, fmt.Errorf("no payment verifier configured")
+	}
+
+	// Verify against the option the client actually chose, quoted from the
+	// same options as its unit price should the prices be reloaded meanwhile
+	options := h.paymentOptions()
+	accepts := h.priceOptions(options, service, computeUnits)
+	i, err := h.selectRequirements(paymentPayload, accepts)
+	if err != nil {
+		return nil, err
+	}
+	payment := &X402Payment{
+		Payload:      paymentPayload,
+		Requirements: accepts[i],
+		UnitPrice:    options[i].Amount,
+	}
+
+	network := payment.Requirements.Network
+	start := time.Now()
+	verifyResp, err := h.Verifier.Verify(paymentPayload, payment.Requirements)
+	x402VerifyDuration.WithLabelValues(network).Observe(time.Since(start).Seconds())
+	if err != nil {
+		x402VerifyFailures.WithLabelValues(network, X402ErrorReason(err)).Inc()
+		return nil, fmt.Errorf("verification failed: %w", err)
+	}
+	if !verifyResp.Valid {
+		reason := verifyResp.InvalidReason
+		if reason == "" {
+			reason = verifyResp.Error
 		}
+		reason = normalizeX402Reason(reason)
+		x402VerifyFailures.WithLabelValues(network, reason).Inc()
+		return nil, newX402Error(reason, "payment not valid: %s", verifyResp.Error)
 	}
+	payment.Payer = verifyResp.Payer
+	payment.Prepaid = verifyResp.Settled
 	
-	// Use first payment requirement for verification
-	requirements := PaymentRequirements{
-		Scheme:            "exact",
-		Network:           "eip155:8453", // Base
-		Amount:            h.PricePerRequestUSDC,
-		Asset:             "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913", // USDC on Base
-		PayTo:             h.ProviderAddress,
-		MaxTimeoutSeconds: 60,
+	// Reserve the payment before settling so concurrent replays are rejected
+	if h.Store != nil {
+		payment.record = NewX402PaymentRecord(paymentPayload, payment.Requirements, time.Now())
+		if err := h.Store.Reserve(payment.record); err != nil {
+			return nil, err
+		}
+	}
+	return payment, nil
+}
+
+// SettlePayment charges an authorized payment. Exact payments are charged the
+// quoted amount, metered payments the price of the given compute units.
+func (h *X402Handler) SettlePayment(payment *X402Payment, computeUnits uint64) (*SettleResponse, error) {
+	requirements := payment.Requirements
+	if payment.Metered() {
+		requirements.Amount = priceForUnits(payment.UnitPrice, computeUnits)
 	}
 	
-	// Verify and settle via facilitator
-	valid, settlementID, err := facilitator.VerifyAndSettle(paymentPayload, requirements)
+	start := time.Now()
+	settleResp, err := h.Verifier.Settle(payment.Payload, requirements)
+	x402SettleDuration.WithLabelValues(requirements.Network).Observe(time.Since(start).Seconds())
+	if err == nil && !settleResp.Success {
+		reason := X402ReasonSettlementFailed
+		if settleResp.ErrorReason != "" {
+			reason = normalizeX402Reason(settleResp.ErrorReason)
+		}
+		err = newX402Error(reason, "%s", settleResp.Error)
+	}
 	if err != nil {
-		return false, "", err
+		x402SettleFailures.WithLabelValues(requirements.Network, X402ErrorReason(err)).Inc()
+		// the payment was not taken, allow the client to retry it
+		h.ReleasePayment(payment)
+		return nil, fmt.Errorf("settlement failed: %w", err)
 	}
 	
-	return valid, settlementID, nil
+	if h.Store != nil {
+		if settleResp.Uncollected {
+			// keep the signed authorization, it is the only way to collect
+			// the payment
+			payload, err := DecodePaymentPayload(payment.Payload)
+			var authorization []byte
+			if err == nil {
+				authorization, err = json.Marshal(payload)
+			}
+			if err == nil {
+				err = h.Store.SetUncollected(payment.record.Key, settleResp.SettlementID, authorization, requirements.Amount)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to record uncollected payment: %w", err)
+			}
+		} else if err := h.Store.SetSettlement(payment.record.Key, settleResp.SettlementID); err != nil {
+			return nil, fmt.Errorf("failed to record settlement: %w", err)
+		}
+	}
+	return settleResp, nil
+}
+
+// ReleasePayment forgets an authorized payment that was not charged, so the
+// client can use it again
+func (h *X402Handler) ReleasePayment(payment *X402Payment) {
+	if h.Store != nil {
+		_ = h.Store.Remove(payment.record.Key)
+	}
+}
+
+// SetModeHeader flags the response when the handler runs in dev mode
+func (h *X402Handler) SetModeHeader(w http.ResponseWriter) {
+	if h.DevMode {
+		w.Header().Set(X402ModeHeader, "dev")
+	}
 }
 
 // WritePaymentRequired writes the HTTP 402 response
-func (h *X402Handler) WritePaymentRequired(w http.ResponseWriter, service string, requestURL string) {
-	response := h.BuildPaymentRequirements(service, requestURL)
+func (h *X402Handler) WritePaymentRequired(w http.ResponseWriter, service string, requestURL string, computeUnits uint64) {
+	response := h.BuildPaymentRequirements(service, requestURL, computeUnits)
 	
+	h.SetModeHeader(w)
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("X-X402-Version", "2")
 	w.WriteHeader(http.StatusPaymentRequired) // HTTP 402
@@ -199,37 +465,210 @@ func (h *X402Handler) WritePaymentRequired(w http.ResponseWriter, service string
 	json.NewEncoder(w).Encode(response)
 }
 
+// WritePaymentError writes a failed payment as a 402 carrying the x402 reason
+// and the payment options to retry with. The error itself is never sent.
+func (h *X402Handler) WritePaymentError(w http.ResponseWriter, service string, requestURL string, computeUnits uint64, err error) {
+	reason := X402ErrorReason(err)
+	response := h.BuildPaymentRequirements(service, requestURL, computeUnits)
+	response.Error = reason
+	var spendErr *X402SpendLimitError
+	if errors.As(err, &spendErr) {
+		response.Extensions["spendLimit"] = spendErr
+	}
+
+	h.SetModeHeader(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-X402-Version", "2")
+	w.WriteHeader(X402ErrorStatus(reason))
+
+	json.NewEncoder(w).Encode(response)
+}
+
+// ServePayment authorizes the payment of a request, serves it with next and
+// settles it once the upstream status is known. Requests failing upstream
+// (5xx, timeouts) are not charged. The receipt is sent in the
+// X-PAYMENT-RESPONSE header. Payment failures are written to w and returned
+// for logging.
+func (h *X402Handler) ServePayment(w http.ResponseWriter, r *http.Request, next http.Handler, service, paymentPayload string, computeUnits uint64) (X402PaymentResponse, error) {
+	payment, err := h.AuthorizePayment(service, paymentPayload, computeUnits)
+	if err != nil {
+		h.WritePaymentError(w, service, r.URL.String(), computeUnits, err)
+		return X402PaymentResponse{Success: false, ErrorReason: X402ErrorReason(err)}, err
+	}
+
+	// Paying does not lift the limits of the payer, the payment stays unused
+	release, err := h.Limits.AcquirePayer(payment.Payer)
+	if err != nil {
+		h.ReleasePayment(payment)
+		limitErr := asX402LimitError(err)
+		h.WriteLimitError(w, limitErr)
+		return X402PaymentResponse{Success: false, ErrorReason: limitErr.Reason, Payer: payment.Payer}, err
+	}
+	defer release()
+
+	// Budgets are checked and held before anything is settled
+	spend, err := h.ReserveSpend(r, payment)
+	if err != nil {
+		h.ReleasePayment(payment)
+		h.WritePaymentError(w, service, r.URL.String(), computeUnits, err)
+		return X402PaymentResponse{Success: false, ErrorReason: X402ErrorReason(err), Payer: payment.Payer}, err
+	}
+	defer spend.Release()
+
+	// Metered payments are settled once the upstream answered
+	if payment.Metered() {
+		return h.serveMetered(w, r, next, service, payment, spend, computeUnits)
+	}
+
+	var receipt X402PaymentResponse
+	var settleErr error
+	deferred := &x402DeferredWriter{ResponseWriter: w, decide: func(status int) bool {
+		if status >= http.StatusInternalServerError {
+			receipt = h.VoidPayment(service, payment, computeUnits, status)
+			w.Header().Set(X402PaymentResponseHeader, receipt.Encode())
+			return true
+		}
+		settleResp, err := h.SettlePayment(payment, computeUnits)
+		if err != nil {
+			// the response is withheld, it was not paid for
+			settleErr = err
+			receipt = NewX402PaymentFailure(payment, err)
+			for k := range w.Header() {
+				w.Header().Del(k)
+			}
+			w.Header().Set(X402PaymentResponseHeader, receipt.Encode())
+			h.WritePaymentError(w, service, r.URL.String(), computeUnits, err)
+			return false
+		}
+		receipt = NewX402PaymentResponse(payment, settleResp, computeUnits)
+		h.CreditRevenue(service, payment, receipt.Amount, 1)
+		spend.Settle(receipt.Amount)
+		h.RecordReceipt(service, payment, receipt, status)
+		w.Header().Set(X402PaymentResponseHeader, receipt.Encode())
+		return true
+	}}
+	next.ServeHTTP(deferred, r)
+	deferred.finish()
+	return receipt, settleErr
+}
+
+// x402DeferredWriter holds back the response of a paid request until its
+// status is known, so the payment is only settled for requests the upstream
+// served. decide settles or voids the payment and reports whether the
+// upstream response may be sent.
+type x402DeferredWriter struct {
+	http.ResponseWriter
+	decide  func(status int) bool
+	decided bool
+	discard bool
+}
+
+func (d *x402DeferredWriter) WriteHeader(status int) {
+	if d.decided {
+		return
+	}
+	d.decided = true
+	if !d.decide(status) {
+		d.discard = true
+		return
+	}
+	d.ResponseWriter.WriteHeader(status)
+}
+
+func (d *x402DeferredWriter) Write(b []byte) (int, error) {
+	if !d.decided {
+		d.WriteHeader(http.StatusOK)
+	}
+	if d.discard {
+		return len(b), nil
+	}
+	return d.ResponseWriter.Write(b)
+}
+
+// Flush lets streamed responses through once the payment is settled
+func (d *x402DeferredWriter) Flush() {
+	if !d.decided || d.discard {
+		return
+	}
+	if flusher, ok := d.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (d *x402DeferredWriter) Unwrap() http.ResponseWriter {
+	return d.ResponseWriter
+}
+
+// finish settles a request whose handler wrote nothing
+func (d *x402DeferredWriter) finish() {
+	if !d.decided {
+		d.WriteHeader(http.StatusOK)
+	}
+}
+
+// CreditRevenue adds a settled amount and the requests it paid for to the
+// ledger, when revenue is reported on chain
+func (h *X402Handler) CreditRevenue(service string, payment *X402Payment, amount string, requests int64) {
+	if h.Ledger == nil {
+		return
+	}
+	network, asset := "", ""
+	if payment != nil {
+		network, asset = payment.Requirements.Network, payment.Requirements.Asset
+	}
+	// failures are logged by the store, the request was paid either way
+	_ = h.Ledger.Credit(service, network, asset, amount, requests)
+}
+
 // Middleware wraps an HTTP handler with x402 payment verification
 func (h *X402Handler) Middleware(next http.Handler, service string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.SetModeHeader(w)
+
+		computeUnits := h.Pricing.ComputeUnits(service, r)
+
+		// Prepaid sessions skip the per request payment
+		if h.Sessions != nil {
+			if token := h.Sessions.TokenFromRequest(r); token != "" {
+				_, _ = h.ServeSession(w, r, next, service, token, computeUnits)
+				return
+			}
+		}
+
 		// Check for payment header
 		hasPayment, paymentPayload := h.CheckPaymentHeader(r)
 		
 		if !hasPayment {
 			// No payment - return 402 Payment Required
-			h.WritePaymentRequired(w, service, r.URL.String())
+			h.WritePaymentRequired(w, service, r.URL.String(), computeUnits)
 			return
 		}
 		
-		// Verify payment
-		verified, settlementID, err := h.VerifyPayment(paymentPayload)
-		if err != nil || !verified {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "Payment verification failed",
-			})
-			return
-		}
-		
-		// Payment verified - add settlement ID to response headers
-		w.Header().Set("X-Settlement-ID", settlementID)
-		
-		// Proceed to actual handler
-		next.ServeHTTP(w, r)
+		_, _ = h.ServePayment(w, r, next, service, paymentPayload, computeUnits)
 	})
 }
 
+// Close closes the stores of the handler
+func (h *X402Handler) Close() error {
+	var errs []error
+	if h.Store != nil {
+		errs = append(errs, h.Store.Close())
+	}
+	if h.Receipts != nil {
+		errs = append(errs, h.Receipts.Close())
+	}
+	if h.Ledger != nil {
+		errs = append(errs, h.Ledger.Close())
+	}
+	if h.Spend != nil {
+		errs = append(errs, h.Spend.Close())
+	}
+	if h.Sessions != nil {
+		errs = append(errs, h.Sessions.Close())
+	}
+	return errors.Join(errs...)
+}
+
 // ExtractService extracts the service name from the request path
 func ExtractService(path string) string {
 	// Expected format: /{service}/... or /x402/{service}/...
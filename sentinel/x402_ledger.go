@@ -0,0 +1,200 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const x402LedgerPrefix = "ledger/"
+
+// X402LedgerStore accrues the x402 revenue of the provider until it is
+// reported on chain
+type X402LedgerStore struct {
+	logger zerolog.Logger
+	db     *leveldb.DB
+	mu     sync.Mutex
+}
+
+// X402LedgerEntry is the unreported revenue of a service in one asset.
+// Amount is in atomic units of the asset.
+type X402LedgerEntry struct {
+	Service  string `json:"service"`
+	Network  string `json:"network"`
+	Asset    string `json:"asset"`
+	Requests int64  `json:"requests"`
+	Amount   string `json:"amount"`
+}
+
+func (e X402LedgerEntry) Key() string {
+	return fmt.Sprintf("%s%s/%s/%s", x402LedgerPrefix, e.Service, e.Network, e.Asset)
+}
+
+func NewX402LedgerStore(levelDbFolder string) (*X402LedgerStore, error) {
+	var db *leveldb.DB
+	var err error
+	if len(levelDbFolder) == 0 {
+		log.Warn().Msg("x402 ledger store folder is empty, create in memory storage")
+		// no directory given, use in memory store
+		storage := storage.NewMemStorage()
+		db, err = leveldb.Open(storage, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fail to in memory open level db: %w", err)
+		}
+	} else {
+		db, err = leveldb.OpenFile(levelDbFolder, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fail to open level db %s: %w", levelDbFolder, err)
+		}
+	}
+	return &X402LedgerStore{
+		logger: log.With().Str("module", "x402-ledger-storage").Logger(),
+		db:     db,
+	}, nil
+}
+
+// Credit adds requests and amount to the entry of service, network and asset
+func (s *X402LedgerStore) Credit(service, network, asset, amount string, requests int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, err := s.get(X402LedgerEntry{Service: service, Network: network, Asset: asset})
+	if err != nil {
+		return err
+	}
+	entry.Requests += requests
+	entry.Amount, err = addX402Amounts(entry.Amount, amount, 1)
+	if err != nil {
+		return err
+	}
+	return s.set(entry)
+}
+
+// Debit removes reported entries from the ledger. Revenue credited after the
+// entries were listed stays in the ledger.
+func (s *X402LedgerStore) Debit(entries []X402LedgerEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch := new(leveldb.Batch)
+	for _, reported := range entries {
+		entry, err := s.get(reported)
+		if err != nil {
+			return err
+		}
+		entry.Requests -= reported.Requests
+		entry.Amount, err = addX402Amounts(entry.Amount, reported.Amount, -1)
+		if err != nil {
+			return err
+		}
+		if entry.Requests <= 0 && entry.Amount == "0" {
+			batch.Delete([]byte(entry.Key()))
+			continue
+		}
+		buf, err := json.Marshal(entry)
+		if err != nil {
+			s.logger.Error().Err(err).Msg("fail to marshal x402 ledger entry")
+			return err
+		}
+		batch.Put([]byte(entry.Key()), buf)
+	}
+	if err := s.db.Write(batch, nil); err != nil {
+		s.logger.Error().Err(err).Msg("fail to debit x402 ledger")
+		return err
+	}
+	return nil
+}
+
+// List returns every ledger entry, sorted by key
+func (s *X402LedgerStore) List() []X402LedgerEntry {
+	iterator := s.db.NewIterator(util.BytesPrefix([]byte(x402LedgerPrefix)), nil)
+	defer iterator.Release()
+	var results []X402LedgerEntry
+	for iterator.Next() {
+		buf := iterator.Value()
+		if len(buf) == 0 {
+			continue
+		}
+
+		var item X402LedgerEntry
+		if err := json.Unmarshal(buf, &item); err != nil {
+			s.logger.Error().Err(err).Msg("fail to unmarshal x402 ledger entry")
+			continue
+		}
+
+		results = append(results, item)
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].Key() < results[j].Key() })
+
+	return results
+}
+
+// Close underlying db
+func (s *X402LedgerStore) Close() error {
+	return s.db.Close()
+}
+
+// get returns the stored entry matching key, or key itself with a zero
+// amount when there is none
+func (s *X402LedgerStore) get(key X402LedgerEntry) (X402LedgerEntry, error) {
+	entry := X402LedgerEntry{Service: key.Service, Network: key.Network, Asset: key.Asset, Amount: "0"}
+	buf, err := s.db.Get([]byte(key.Key()), nil)
+	if errors.Is(err, leveldb.ErrNotFound) {
+		return entry, nil
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Msg("fail to get x402 ledger entry")
+		return entry, err
+	}
+	if err := json.Unmarshal(buf, &entry); err != nil {
+		s.logger.Error().Err(err).Msg("fail to unmarshal x402 ledger entry")
+		return entry, err
+	}
+	return entry, nil
+}
+
+func (s *X402LedgerStore) set(entry X402LedgerEntry) error {
+	buf, err := json.Marshal(entry)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("fail to marshal x402 ledger entry")
+		return err
+	}
+	if err := s.db.Put([]byte(entry.Key()), buf, nil); err != nil {
+		s.logger.Error().Err(err).Msg("fail to set x402 ledger entry")
+		return err
+	}
+	return nil
+}
+
+// addX402Amounts returns a + sign*b of two atomic amounts, never below zero
+func addX402Amounts(a, b string, sign int64) (string, error) {
+	x, ok := new(big.Int).SetString(defaultString(a, "0"), 10)
+	if !ok {
+		return "", fmt.Errorf("invalid amount %q", a)
+	}
+	y, ok := new(big.Int).SetString(defaultString(b, "0"), 10)
+	if !ok {
+		return "", fmt.Errorf("invalid amount %q", b)
+	}
+	x.Add(x, y.Mul(y, big.NewInt(sign)))
+	if x.Sign() < 0 {
+		x.SetInt64(0)
+	}
+	return x.String(), nil
+}
+
+func defaultString(s, def string) string {
+	if s == "" {
+		return def
+	}
+	return s
+}
@@ -0,0 +1,32 @@
+package sentinel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestX402LedgerStore(t *testing.T) {
+	store, err := NewX402LedgerStore("")
+	require.NoError(t, err)
+	defer store.Close()
+
+	require.NoError(t, store.Credit("eth", "arkeo:arkeo-testnet-1", "uarkeo", "100", 1))
+	require.NoError(t, store.Credit("eth", "arkeo:arkeo-testnet-1", "uarkeo", "250", 1))
+	require.NoError(t, store.Credit("eth", "eip155:8453", "0xusdc", "1000", 1))
+	require.Error(t, store.Credit("eth", "eip155:8453", "0xusdc", "lots", 1))
+
+	entries := store.List()
+	require.Len(t, entries, 2)
+	require.Equal(t, int64(2), entries[0].Requests)
+	require.Equal(t, "350", entries[0].Amount)
+	require.Equal(t, "1000", entries[1].Amount)
+
+	// revenue credited after the report stays in the ledger
+	require.NoError(t, store.Credit("eth", "arkeo:arkeo-testnet-1", "uarkeo", "50", 1))
+	require.NoError(t, store.Debit(entries))
+	entries = store.List()
+	require.Len(t, entries, 1)
+	require.Equal(t, int64(1), entries[0].Requests)
+	require.Equal(t, "50", entries[0].Amount)
+}
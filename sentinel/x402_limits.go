@@ -0,0 +1,336 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+// x402 limit reasons, sent to clients like the payment error reasons
+const (
+	X402ReasonRateLimited = "rate_limited"
+	X402ReasonDenied      = "denied"
+)
+
+const (
+	// x402LimiterIdle is how long an unused limiter is kept, long enough for
+	// its bucket to refill
+	x402LimiterIdle = 10 * time.Minute
+
+	// x402ConcurrencyRetryAfter is the Retry-After of a request refused for
+	// having too many in flight
+	x402ConcurrencyRetryAfter = time.Second
+)
+
+// X402LimitError refuses a request before it reaches the upstream
+type X402LimitError struct {
+	Reason     string
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *X402LimitError) Error() string {
+	return e.Reason + ": " + e.Err.Error()
+}
+
+func (e *X402LimitError) Unwrap() error {
+	return e.Err
+}
+
+// Status returns the HTTP status of the refusal
+func (e *X402LimitError) Status() int {
+	if e.Reason == X402ReasonDenied {
+		return http.StatusForbidden
+	}
+	return http.StatusTooManyRequests
+}
+
+// asX402LimitError returns the limit error in err. Other errors refuse the
+// request as rate limited, so a limit is never skipped.
+func asX402LimitError(err error) *X402LimitError {
+	var limitErr *X402LimitError
+	if errors.As(err, &limitErr) {
+		return limitErr
+	}
+	return &X402LimitError{Reason: X402ReasonRateLimited, Err: err}
+}
+
+type x402Limiter struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// X402Limits rate limits the x402 routes per client IP and per payer, caps
+// the requests a payer has in flight and refuses denied payers and IPs. A
+// nil X402Limits allows everything.
+type X402Limits struct {
+	mu         sync.Mutex
+	config     conf.X402LimitsConfig
+	denyPayers map[string]bool
+	denyNets   []*net.IPNet
+	trusted    []*net.IPNet
+	ips        map[string]*x402Limiter
+	payers     map[string]*x402Limiter
+	inFlight   map[string]int
+}
+
+// NewX402Limits creates the limits, deny entries are payer addresses, IPs or
+// CIDRs
+func NewX402Limits(config conf.X402LimitsConfig) (*X402Limits, error) {
+	l := &X402Limits{
+		config:     config,
+		denyPayers: make(map[string]bool),
+		ips:        make(map[string]*x402Limiter),
+		payers:     make(map[string]*x402Limiter),
+		inFlight:   make(map[string]int),
+	}
+	for _, entry := range config.Deny {
+		entry = strings.TrimSpace(entry)
+		switch {
+		case entry == "":
+			continue
+		case strings.Contains(entry, "/") || net.ParseIP(entry) != nil:
+			network, err := parseIPNetwork(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid x402 deny entry %q: %w", entry, err)
+			}
+			l.denyNets = append(l.denyNets, network)
+		default:
+			l.denyPayers[strings.ToLower(entry)] = true
+		}
+	}
+	for _, entry := range config.TrustedProxies {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		network, err := parseIPNetwork(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x402 trusted proxy %q: %w", entry, err)
+		}
+		l.trusted = append(l.trusted, network)
+	}
+	return l, nil
+}
+
+// parseIPNetwork parses a CIDR, or an IP as the network of that IP alone
+func parseIPNetwork(entry string) (*net.IPNet, error) {
+	if strings.Contains(entry, "/") {
+		_, network, err := net.ParseCIDR(entry)
+		return network, err
+	}
+	ip := net.ParseIP(entry)
+	if ip == nil {
+		return nil, fmt.Errorf("not an IP")
+	}
+	bits := 8 * len(ip.To16())
+	if ip.To4() != nil {
+		ip, bits = ip.To4(), 32
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+}
+
+// Update applies new limits, as on a configuration reload. The requests in
+// flight are kept, the rate limiters restart when a rate changed.
+func (l *X402Limits) Update(config conf.X402LimitsConfig) error {
+	next, err := NewX402Limits(config)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if config.IPRequestsPerMinute != l.config.IPRequestsPerMinute {
+		l.ips = next.ips
+	}
+	if config.PayerRequestsPerMinute != l.config.PayerRequestsPerMinute {
+		l.payers = next.payers
+	}
+	l.config, l.denyPayers, l.denyNets, l.trusted = next.config, next.denyPayers, next.denyNets, next.trusted
+	return nil
+}
+
+// ClientIP returns the IP the limits of a request apply to. Forwarded
+// headers are only believed from trusted proxies, otherwise any client could
+// pick its own IP. The X-Forwarded-For chain is read from the nearest hop
+// back to the first address not of a trusted proxy.
+func (l *X402Limits) ClientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if l == nil {
+		return ip
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.isTrusted(ip) {
+		return ip
+	}
+	if realIP := strings.TrimSpace(r.Header.Get(xRealIPName)); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	hops := strings.Split(r.Header.Get(forwardHeaderName), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !l.isTrusted(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func (l *X402Limits) isTrusted(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, network := range l.trusted {
+		if network.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowIP checks a request from ip against the deny list and the per IP rate
+func (l *X402Limits) AllowIP(ip string) error {
+	if l == nil {
+		return nil
+	}
+	// a forwarded chain starts with the client
+	ip, _, _ = strings.Cut(ip, ",")
+	ip = strings.TrimSpace(ip)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if parsed := net.ParseIP(ip); parsed != nil {
+		for _, network := range l.denyNets {
+			if network.Contains(parsed) {
+				return &X402LimitError{Reason: X402ReasonDenied, Err: fmt.Errorf("ip %s is denied", ip)}
+			}
+		}
+	}
+	if l.config.IPRequestsPerMinute <= 0 {
+		return nil
+	}
+	if wait := l.take(l.ips, ip, l.config.IPRequestsPerMinute); wait > 0 {
+		return &X402LimitError{Reason: X402ReasonRateLimited, RetryAfter: wait, Err: fmt.Errorf("ip %s is rate limited", ip)}
+	}
+	return nil
+}
+
+// AcquirePayer checks a paid request of payer against the deny list, the per
+// payer rate and the in-flight cap. The returned release must be called once
+// the request is served.
+func (l *X402Limits) AcquirePayer(payer string) (func(), error) {
+	release := func() {}
+	if l == nil || payer == "" {
+		return release, nil
+	}
+	payer = strings.ToLower(payer)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.denyPayers[payer] {
+		return release, &X402LimitError{Reason: X402ReasonDenied, Err: fmt.Errorf("payer %s is denied", payer)}
+	}
+	if l.config.PayerMaxConcurrent > 0 && l.inFlight[payer] >= l.config.PayerMaxConcurrent {
+		return release, &X402LimitError{
+			Reason:     X402ReasonRateLimited,
+			RetryAfter: x402ConcurrencyRetryAfter,
+			Err:        fmt.Errorf("payer %s has %d requests in flight", payer, l.inFlight[payer]),
+		}
+	}
+	if l.config.PayerRequestsPerMinute > 0 {
+		if wait := l.take(l.payers, payer, l.config.PayerRequestsPerMinute); wait > 0 {
+			return release, &X402LimitError{Reason: X402ReasonRateLimited, RetryAfter: wait, Err: fmt.Errorf("payer %s is rate limited", payer)}
+		}
+	}
+	if l.config.PayerMaxConcurrent <= 0 {
+		return release, nil
+	}
+
+	l.inFlight[payer]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.inFlight[payer]--; l.inFlight[payer] <= 0 {
+				delete(l.inFlight, payer)
+			}
+		})
+	}, nil
+}
+
+// take spends a token of the limiter of key and returns how long to wait when
+// there is none. Callers hold l.mu.
+func (l *X402Limits) take(limiters map[string]*x402Limiter, key string, perMinute int) time.Duration {
+	now := time.Now()
+	entry, exists := limiters[key]
+	if !exists {
+		entry = &x402Limiter{limiter: rate.NewLimiter(rate.Limit(float64(perMinute)/60), perMinute)}
+		limiters[key] = entry
+	}
+	entry.lastSeen = now
+
+	reservation := entry.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return time.Minute
+	}
+	wait := reservation.DelayFrom(now)
+	if wait > 0 {
+		reservation.CancelAt(now)
+	}
+	return wait
+}
+
+// Prune drops the limiters unused for long enough to have refilled
+func (l *X402Limits) Prune(now time.Time) int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	pruned := 0
+	for _, limiters := range []map[string]*x402Limiter{l.ips, l.payers} {
+		for key, entry := range limiters {
+			if now.Sub(entry.lastSeen) > x402LimiterIdle {
+				delete(limiters, key)
+				pruned++
+			}
+		}
+	}
+	return pruned
+}
+
+// WriteLimitError refuses a request with 429, or 403 when denied. Rate
+// limited clients are told when to retry in Retry-After.
+func (h *X402Handler) WriteLimitError(w http.ResponseWriter, err *X402LimitError) {
+	observeRateLimited(TierX402, err.Reason)
+	h.SetModeHeader(w)
+	body := map[string]interface{}{"error": err.Reason}
+	if err.RetryAfter > 0 {
+		seconds := int(math.Ceil(err.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		body["retryAfter"] = seconds
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(err.Status())
+	json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,154 @@
+package sentinel
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+func TestX402Limits(t *testing.T) {
+	limits, err := NewX402Limits(conf.X402LimitsConfig{
+		PayerRequestsPerMinute: 2,
+		IPRequestsPerMinute:    3,
+		PayerMaxConcurrent:     1,
+		Deny:                   []string{"0xBAD0000000000000000000000000000000000000", "10.0.0.0/8", "192.168.1.1"},
+	})
+	require.NoError(t, err)
+
+	// deny list
+	var limitErr *X402LimitError
+	require.ErrorAs(t, limits.AllowIP("10.1.2.3"), &limitErr)
+	require.Equal(t, http.StatusForbidden, limitErr.Status())
+	require.Error(t, limits.AllowIP("192.168.1.1, 127.0.0.1"))
+	_, err = limits.AcquirePayer("0xbad0000000000000000000000000000000000000")
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, X402ReasonDenied, limitErr.Reason)
+
+	// per ip rate
+	for i := 0; i < 3; i++ {
+		require.NoError(t, limits.AllowIP("1.2.3.4"))
+	}
+	require.ErrorAs(t, limits.AllowIP("1.2.3.4"), &limitErr)
+	require.Equal(t, http.StatusTooManyRequests, limitErr.Status())
+	require.Greater(t, limitErr.RetryAfter, time.Duration(0))
+	require.NoError(t, limits.AllowIP("1.2.3.5"))
+
+	// in flight cap, then per payer rate
+	release, err := limits.AcquirePayer("0xabc")
+	require.NoError(t, err)
+	_, err = limits.AcquirePayer("0xABC")
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, x402ConcurrencyRetryAfter, limitErr.RetryAfter)
+	release()
+	release()
+	release, err = limits.AcquirePayer("0xabc")
+	require.NoError(t, err)
+	release()
+	_, err = limits.AcquirePayer("0xabc")
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, X402ReasonRateLimited, limitErr.Reason)
+
+	require.Zero(t, limits.Prune(time.Now()))
+	require.Equal(t, 3, limits.Prune(time.Now().Add(time.Hour)))
+
+	_, err = NewX402Limits(conf.X402LimitsConfig{Deny: []string{"10.0.0.0/99"}})
+	require.Error(t, err)
+
+	// nil limits allow everything
+	var none *X402Limits
+	require.NoError(t, none.AllowIP("10.1.2.3"))
+	_, err = none.AcquirePayer("0xbad0000000000000000000000000000000000000")
+	require.NoError(t, err)
+
+	// wrapped limit errors keep their status, other errors are rate limited
+	denied := &X402LimitError{Reason: X402ReasonDenied, Err: errors.New("blocked")}
+	require.Same(t, denied, asX402LimitError(fmt.Errorf("limit: %w", denied)))
+	require.Equal(t, http.StatusTooManyRequests, asX402LimitError(errors.New("store failed")).Status())
+}
+
+func TestX402Limits_ClientIP(t *testing.T) {
+	limits, err := NewX402Limits(conf.X402LimitsConfig{TrustedProxies: []string{"10.0.0.0/8", "2001:db8::1"}})
+	require.NoError(t, err)
+	request := func(remoteAddr string, headers map[string]string) *http.Request {
+		req := httptest.NewRequest(http.MethodGet, "/x402/eth/", nil)
+		req.RemoteAddr = remoteAddr
+		for k, v := range headers {
+			req.Header.Set(k, v)
+		}
+		return req
+	}
+
+	// forwarded headers of untrusted clients are ignored
+	require.Equal(t, "198.51.100.7", limits.ClientIP(request("198.51.100.7:4242", map[string]string{xRealIPName: "1.2.3.4"})))
+	require.Equal(t, "2001:db8::7", limits.ClientIP(request("[2001:db8::7]:4242", map[string]string{forwardHeaderName: "1.2.3.4"})))
+	require.Equal(t, "198.51.100.7", (*X402Limits)(nil).ClientIP(request("198.51.100.7:4242", map[string]string{xRealIPName: "1.2.3.4"})))
+
+	// trusted proxies are believed, the chain is read back to the first
+	// address not of a trusted proxy
+	require.Equal(t, "1.2.3.4", limits.ClientIP(request("10.1.1.1:4242", map[string]string{xRealIPName: "1.2.3.4"})))
+	require.Equal(t, "5.6.7.8", limits.ClientIP(request("[2001:db8::1]:4242", map[string]string{forwardHeaderName: "1.2.3.4, 5.6.7.8, 10.2.2.2"})))
+	require.Equal(t, "10.1.1.1", limits.ClientIP(request("10.1.1.1:4242", map[string]string{forwardHeaderName: "garbage"})))
+
+	_, err = NewX402Limits(conf.X402LimitsConfig{TrustedProxies: []string{"proxy.local"}})
+	require.Error(t, err)
+}
+
+func TestX402Middleware_Limits(t *testing.T) {
+	paymentStore, err := NewX402PaymentStore("")
+	require.NoError(t, err)
+	defer paymentStore.Close()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	handler := NewX402Handler(testPayTo)
+	handler.Verifier = NewLocalVerifier()
+	handler.Store = paymentStore
+	handler.Accepts = []PaymentRequirements{testRequirements()}
+	handler.Limits, err = NewX402Limits(conf.X402LimitsConfig{PayerRequestsPerMinute: 1, Deny: []string{"10.0.0.1"}, TrustedProxies: []string{"192.0.2.1"}})
+	require.NoError(t, err)
+	proxy := &Proxy{x402: handler, logger: log.NewNopLogger(), proxies: map[string]*url.URL{"eth": {}}}
+
+	upstream := 0
+	serve := proxy.x402Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream++
+		w.WriteHeader(http.StatusOK)
+	}))
+	send := func(ip, payment string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/x402/eth/", nil)
+		req.Header.Set("X-Real-Ip", ip)
+		req.Header.Set("X-PAYMENT", payment)
+		rec := httptest.NewRecorder()
+		serve.ServeHTTP(rec, req)
+		return rec
+	}
+
+	first := signTestPayment(t, key, testRequirements(), testAuthorization(key, "1000", time.Now()))
+	second := signTestPayment(t, key, testRequirements(), testAuthorization(key, "1000", time.Now()))
+
+	// denied ips are refused before the payment is looked at
+	rec := send("10.0.0.1", first)
+	require.Equal(t, http.StatusForbidden, rec.Code)
+	require.Zero(t, upstream)
+
+	rec = send("1.2.3.4", first)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, 1, upstream)
+
+	// the payer is over its rate, the second payment is not charged
+	rec = send("1.2.3.4", second)
+	require.Equal(t, http.StatusTooManyRequests, rec.Code)
+	require.NotEmpty(t, rec.Header().Get("Retry-After"))
+	require.Equal(t, 1, upstream)
+	require.Len(t, paymentStore.List(), 1)
+}
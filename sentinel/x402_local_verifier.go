@@ -0,0 +1,359 @@
+package sentinel
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/common/math"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+)
+
+// x402SettlementBuffer is the minimum remaining lifetime an authorization
+// must have so it can still be submitted on chain after verification
+const x402SettlementBuffer = 6 * time.Second
+
+// eip3009Types are the EIP-712 types of an EIP-3009 TransferWithAuthorization
+var eip3009Types = apitypes.Types{
+	"EIP712Domain": []apitypes.Type{
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	},
+	"TransferWithAuthorization": []apitypes.Type{
+		{Name: "from", Type: "address"},
+		{Name: "to", Type: "address"},
+		{Name: "value", Type: "uint256"},
+		{Name: "validAfter", Type: "uint256"},
+		{Name: "validBefore", Type: "uint256"},
+		{Name: "nonce", Type: "bytes32"},
+	},
+}
+
+// eip2612Types are the EIP-712 types of an EIP-2612 Permit
+var eip2612Types = apitypes.Types{
+	"EIP712Domain": eip3009Types["EIP712Domain"],
+	"Permit": []apitypes.Type{
+		{Name: "owner", Type: "address"},
+		{Name: "spender", Type: "address"},
+		{Name: "value", Type: "uint256"},
+		{Name: "nonce", Type: "uint256"},
+		{Name: "deadline", Type: "uint256"},
+	},
+}
+
+// evmNetworkChainIDs maps legacy x402 network names to EIP-155 chain ids
+var evmNetworkChainIDs = map[string]int64{
+	"ethereum":     1,
+	"sepolia":      11155111,
+	"base":         8453,
+	"base-sepolia": 84532,
+}
+
+// LocalVerifier verifies EVM payments in process by checking the EIP-712
+// signature of the EIP-3009 authorization ("exact") or EIP-2612 permit
+// ("upto"), so no facilitator has to be reachable. It never broadcasts
+// anything: settled payments are reported uncollected, so the payment store
+// keeps their signed authorization until the provider exports it from the
+// admin routes and submits it on chain.
+type LocalVerifier struct {
+	// now is overridable for tests
+	now func() time.Time
+}
+
+// NewLocalVerifier creates a verifier that checks EIP-3009 authorizations locally
+func NewLocalVerifier() *LocalVerifier {
+	return &LocalVerifier{now: time.Now}
+}
+
+// Verify checks the signature, recipient, amount and validity window of the payment
+func (v *LocalVerifier) Verify(paymentPayload string, requirements PaymentRequirements) (*VerifyResponse, error) {
+	payload, err := DecodePaymentPayload(paymentPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	var payer string
+	var checkErr error
+	if payload.Scheme == X402SchemeUpto {
+		upto, err := payload.UptoEVM()
+		if err != nil {
+			return nil, err
+		}
+		payer = upto.Permit.Owner
+		checkErr = v.checkPermit(payload, upto, requirements)
+	} else {
+		evm, err := payload.ExactEVM()
+		if err != nil {
+			return nil, err
+		}
+		payer = evm.Authorization.From
+		checkErr = v.checkAuthorization(payload, evm, requirements)
+	}
+
+	if checkErr != nil {
+		return &VerifyResponse{Valid: false, Error: checkErr.Error(), InvalidReason: X402ErrorReason(checkErr), Payer: payer}, nil
+	}
+	return &VerifyResponse{Valid: true, Payer: payer}, nil
+}
+
+// Settle re-checks the payment and returns the authorization nonce as the
+// settlement id. The payment is uncollected: the authorization itself is
+// submitted on chain out of band.
+func (v *LocalVerifier) Settle(paymentPayload string, requirements PaymentRequirements) (*SettleResponse, error) {
+	verifyResp, err := v.Verify(paymentPayload, requirements)
+	if err != nil {
+		return nil, err
+	}
+	if !verifyResp.Valid {
+		return &SettleResponse{Success: false, Error: verifyResp.Error, ErrorReason: verifyResp.InvalidReason, Payer: verifyResp.Payer}, nil
+	}
+
+	payload, _ := DecodePaymentPayload(paymentPayload)
+	settlementID := ""
+	if payload.Scheme == X402SchemeUpto {
+		upto, _ := payload.UptoEVM()
+		settlementID = strings.ToLower(upto.Permit.Owner) + "/" + upto.Permit.Nonce
+	} else {
+		evm, _ := payload.ExactEVM()
+		settlementID = strings.ToLower(evm.Authorization.Nonce)
+	}
+	return &SettleResponse{
+		Success:      true,
+		SettlementID: settlementID,
+		Payer:        verifyResp.Payer,
+		Uncollected:  true,
+	}, nil
+}
+
+func (v *LocalVerifier) checkAuthorization(payload PaymentPayload, evm ExactEVMPayload, requirements PaymentRequirements) error {
+	if payload.Scheme != requirements.Scheme {
+		return newX402Error(X402ReasonUnsupportedScheme, "scheme %q does not match %q", payload.Scheme, requirements.Scheme)
+	}
+	if payload.Network != requirements.Network {
+		return newX402Error(X402ReasonWrongNetwork, "network %q does not match %q", payload.Network, requirements.Network)
+	}
+
+	auth := evm.Authorization
+	if !ethcommon.IsHexAddress(auth.From) || !ethcommon.IsHexAddress(auth.To) {
+		return newX402Error(X402ReasonInvalidPayload, "invalid authorization address")
+	}
+	if !strings.EqualFold(auth.To, requirements.PayTo) {
+		return newX402Error(X402ReasonWrongRecipient, "payment recipient %s does not match %s", auth.To, requirements.PayTo)
+	}
+
+	value, ok := new(big.Int).SetString(auth.Value, 10)
+	if !ok {
+		return newX402Error(X402ReasonInvalidPayload, "invalid authorization value %q", auth.Value)
+	}
+	required, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		return fmt.Errorf("invalid required amount %q", requirements.Amount)
+	}
+	if value.Cmp(required) < 0 {
+		return newX402Error(X402ReasonInsufficientFunds, "insufficient amount %s, %s required", value, required)
+	}
+
+	validAfter, err := strconv.ParseInt(auth.ValidAfter, 10, 64)
+	if err != nil {
+		return newX402Error(X402ReasonInvalidPayload, "invalid validAfter: %w", err)
+	}
+	validBefore, err := strconv.ParseInt(auth.ValidBefore, 10, 64)
+	if err != nil {
+		return newX402Error(X402ReasonInvalidPayload, "invalid validBefore: %w", err)
+	}
+	now := v.now()
+	if now.Unix() < validAfter {
+		return newX402Error(X402ReasonExpired, "authorization not valid until %d", validAfter)
+	}
+	if now.Add(x402SettlementBuffer).Unix() >= validBefore {
+		return newX402Error(X402ReasonExpired, "authorization expired at %d", validBefore)
+	}
+
+	signer, err := recoverEIP3009Signer(evm, requirements)
+	if err != nil {
+		return &X402Error{Reason: X402ReasonInvalidSignature, Err: err}
+	}
+	if !bytes.Equal(signer.Bytes(), ethcommon.HexToAddress(auth.From).Bytes()) {
+		return newX402Error(X402ReasonInvalidSignature, "signature does not match payer %s", auth.From)
+	}
+	return nil
+}
+
+func (v *LocalVerifier) checkPermit(payload PaymentPayload, upto UptoEVMPayload, requirements PaymentRequirements) error {
+	if payload.Scheme != requirements.Scheme {
+		return newX402Error(X402ReasonUnsupportedScheme, "scheme %q does not match %q", payload.Scheme, requirements.Scheme)
+	}
+	if payload.Network != requirements.Network {
+		return newX402Error(X402ReasonWrongNetwork, "network %q does not match %q", payload.Network, requirements.Network)
+	}
+
+	permit := upto.Permit
+	if !ethcommon.IsHexAddress(permit.Owner) || !ethcommon.IsHexAddress(permit.Spender) {
+		return newX402Error(X402ReasonInvalidPayload, "invalid permit address")
+	}
+	if !strings.EqualFold(permit.Spender, requirements.PayTo) {
+		return newX402Error(X402ReasonWrongRecipient, "permit spender %s does not match %s", permit.Spender, requirements.PayTo)
+	}
+
+	value, ok := new(big.Int).SetString(permit.Value, 10)
+	if !ok {
+		return newX402Error(X402ReasonInvalidPayload, "invalid permit value %q", permit.Value)
+	}
+	required, ok := new(big.Int).SetString(requirements.Amount, 10)
+	if !ok {
+		return fmt.Errorf("invalid required amount %q", requirements.Amount)
+	}
+	if value.Cmp(required) < 0 {
+		return newX402Error(X402ReasonInsufficientFunds, "insufficient amount %s, %s required", value, required)
+	}
+
+	deadline, err := strconv.ParseInt(permit.Deadline, 10, 64)
+	if err != nil {
+		return newX402Error(X402ReasonInvalidPayload, "invalid deadline: %w", err)
+	}
+	if v.now().Add(x402SettlementBuffer).Unix() >= deadline {
+		return newX402Error(X402ReasonExpired, "permit expired at %d", deadline)
+	}
+
+	hash, err := EIP2612TypedDataHash(permit, requirements)
+	if err != nil {
+		return err
+	}
+	signer, err := recoverSigner(hash, upto.Signature)
+	if err != nil {
+		return &X402Error{Reason: X402ReasonInvalidSignature, Err: err}
+	}
+	if !bytes.Equal(signer.Bytes(), ethcommon.HexToAddress(permit.Owner).Bytes()) {
+		return newX402Error(X402ReasonInvalidSignature, "signature does not match payer %s", permit.Owner)
+	}
+	return nil
+}
+
+// recoverEIP3009Signer returns the address that signed the authorization
+func recoverEIP3009Signer(evm ExactEVMPayload, requirements PaymentRequirements) (ethcommon.Address, error) {
+	hash, err := EIP3009TypedDataHash(evm.Authorization, requirements)
+	if err != nil {
+		return ethcommon.Address{}, err
+	}
+	return recoverSigner(hash, evm.Signature)
+}
+
+// recoverSigner returns the address that signed the EIP-712 digest
+func recoverSigner(hash []byte, signature string) (ethcommon.Address, error) {
+	sig, err := hexutil.Decode(signature)
+	if err != nil {
+		return ethcommon.Address{}, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+	if len(sig) != crypto.SignatureLength {
+		return ethcommon.Address{}, fmt.Errorf("signature must be %d bytes long", crypto.SignatureLength)
+	}
+	if sig[crypto.RecoveryIDOffset] == 27 || sig[crypto.RecoveryIDOffset] == 28 {
+		sig[crypto.RecoveryIDOffset] -= 27 // Transform yellow paper V from 27/28 to 0/1
+	}
+
+	pubKey, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		return ethcommon.Address{}, fmt.Errorf("failed to recover public key from signature: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pubKey), nil
+}
+
+// EIP3009TypedDataHash builds the EIP-712 digest of a TransferWithAuthorization.
+// The domain name and version come from the requirement extras, the chain id
+// from the network and the verifying contract is the token itself. Payers
+// sign it for "exact" payments on EVM networks.
+func EIP3009TypedDataHash(auth EIP3009Authorization, requirements PaymentRequirements) ([]byte, error) {
+	domain, err := tokenDomain(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	typedData := apitypes.TypedData{
+		Types:       eip3009Types,
+		PrimaryType: "TransferWithAuthorization",
+		Domain:      domain,
+		Message: apitypes.TypedDataMessage{
+			"from":        ethcommon.HexToAddress(auth.From).Hex(),
+			"to":          ethcommon.HexToAddress(auth.To).Hex(),
+			"value":       auth.Value,
+			"validAfter":  auth.ValidAfter,
+			"validBefore": auth.ValidBefore,
+			"nonce":       auth.Nonce,
+		},
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+	return hash, nil
+}
+
+// EIP2612TypedDataHash builds the EIP-712 digest of a Permit, in the same
+// token domain as EIP3009TypedDataHash. Payers sign it for "upto" payments.
+func EIP2612TypedDataHash(permit EIP2612Permit, requirements PaymentRequirements) ([]byte, error) {
+	domain, err := tokenDomain(requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	typedData := apitypes.TypedData{
+		Types:       eip2612Types,
+		PrimaryType: "Permit",
+		Domain:      domain,
+		Message: apitypes.TypedDataMessage{
+			"owner":    ethcommon.HexToAddress(permit.Owner).Hex(),
+			"spender":  ethcommon.HexToAddress(permit.Spender).Hex(),
+			"value":    permit.Value,
+			"nonce":    permit.Nonce,
+			"deadline": permit.Deadline,
+		},
+	}
+	hash, _, err := apitypes.TypedDataAndHash(typedData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash typed data: %w", err)
+	}
+	return hash, nil
+}
+
+// tokenDomain returns the EIP-712 domain of the token of the requirements
+func tokenDomain(requirements PaymentRequirements) (apitypes.TypedDataDomain, error) {
+	chainID, err := evmChainID(requirements.Network)
+	if err != nil {
+		return apitypes.TypedDataDomain{}, err
+	}
+	name, _ := requirements.Extra["name"].(string)
+	version, _ := requirements.Extra["version"].(string)
+	if name == "" || version == "" {
+		return apitypes.TypedDataDomain{}, fmt.Errorf("requirements are missing the eip712 name or version")
+	}
+	return apitypes.TypedDataDomain{
+		Name:              name,
+		Version:           version,
+		ChainId:           math.NewHexOrDecimal256(chainID),
+		VerifyingContract: ethcommon.HexToAddress(requirements.Asset).Hex(),
+	}, nil
+}
+
+// evmChainID parses the chain id of an "eip155:<id>" or legacy network name
+func evmChainID(network string) (int64, error) {
+	if id, ok := evmNetworkChainIDs[network]; ok {
+		return id, nil
+	}
+	raw, ok := strings.CutPrefix(network, "eip155:")
+	if !ok {
+		return 0, fmt.Errorf("network %q is not an evm network", network)
+	}
+	id, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid evm chain id %q: %w", raw, err)
+	}
+	return id, nil
+}
@@ -0,0 +1,181 @@
+package sentinel
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// X402PaymentResponse is the settlement receipt of a paid request, sent base64
+// encoded in the X-PAYMENT-RESPONSE header
+type X402PaymentResponse struct {
+	Success      bool   `json:"success"`
+	Status       string `json:"status,omitempty"`
+	ErrorReason  string `json:"errorReason,omitempty"`
+	TxHash       string `json:"txHash,omitempty"`
+	Network      string `json:"network,omitempty"`
+	Payer        string `json:"payer,omitempty"`
+	Amount       string `json:"amount,omitempty"`
+	ComputeUnits uint64 `json:"computeUnits,omitempty"`
+}
+
+// NewX402PaymentResponse builds the receipt of a payment settled for the given
+// compute units
+func NewX402PaymentResponse(payment *X402Payment, settleResp *SettleResponse, computeUnits uint64) X402PaymentResponse {
+	receipt := X402PaymentResponse{
+		Success:      true,
+		Status:       X402StatusSettled,
+		Network:      payment.Requirements.Network,
+		Payer:        payment.Payer,
+		Amount:       payment.Requirements.Amount,
+		ComputeUnits: computeUnits,
+	}
+	if payment.Metered() {
+		receipt.Amount = priceForUnits(payment.UnitPrice, computeUnits)
+	}
+	if settleResp != nil {
+		receipt.TxHash = settleResp.TxHash
+		if receipt.TxHash == "" {
+			receipt.TxHash = settleResp.SettlementID
+		}
+		if settleResp.Payer != "" {
+			receipt.Payer = settleResp.Payer
+		}
+	}
+	return receipt
+}
+
+// NewX402PaymentFailure builds the receipt of a payment that could not be settled
+func NewX402PaymentFailure(payment *X402Payment, err error) X402PaymentResponse {
+	return X402PaymentResponse{
+		Success:     false,
+		ErrorReason: X402ErrorReason(err),
+		Network:     payment.Requirements.Network,
+		Payer:       payment.Payer,
+	}
+}
+
+// Encode returns the base64 header value of the receipt
+func (r X402PaymentResponse) Encode() string {
+	buf, _ := json.Marshal(r)
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// DecodeX402PaymentResponse decodes an X-PAYMENT-RESPONSE header
+func DecodeX402PaymentResponse(header string) (X402PaymentResponse, error) {
+	var receipt X402PaymentResponse
+	raw, err := decodeBase64(strings.TrimSpace(header))
+	if err != nil {
+		return receipt, fmt.Errorf("payment response is not valid base64: %w", err)
+	}
+	if err := json.Unmarshal(raw, &receipt); err != nil {
+		return receipt, fmt.Errorf("payment response is not valid json: %w", err)
+	}
+	return receipt, nil
+}
+
+// x402MeterWriter buffers an upstream response so the metered charge can be
+// settled, and its receipt added to the headers, before anything is sent.
+// A response larger than limit is dropped and the upstream call cancelled.
+type x402MeterWriter struct {
+	header   http.Header
+	status   int
+	body     bytes.Buffer
+	limit    int64
+	tooLarge bool
+	cancel   context.CancelFunc
+}
+
+func newX402MeterWriter(limit int64, cancel context.CancelFunc) *x402MeterWriter {
+	return &x402MeterWriter{header: make(http.Header), limit: limit, cancel: cancel}
+}
+
+func (m *x402MeterWriter) Header() http.Header {
+	return m.header
+}
+
+func (m *x402MeterWriter) WriteHeader(status int) {
+	if m.status == 0 {
+		m.status = status
+	}
+}
+
+func (m *x402MeterWriter) Write(b []byte) (int, error) {
+	if m.status == 0 {
+		m.status = http.StatusOK
+	}
+	if m.tooLarge {
+		return len(b), nil
+	}
+	if int64(m.body.Len()+len(b)) > m.limit {
+		m.tooLarge = true
+		m.body = bytes.Buffer{}
+		m.cancel()
+		return len(b), nil
+	}
+	return m.body.Write(b)
+}
+
+// serve runs next into the meter. The reverse proxy aborts the handler once
+// a response too large is cancelled, that abort is recovered.
+func (m *x402MeterWriter) serve(next http.Handler, r *http.Request) {
+	defer func() {
+		if rec := recover(); rec != nil && !(rec == http.ErrAbortHandler && m.tooLarge) {
+			panic(rec)
+		}
+	}()
+	next.ServeHTTP(m, r)
+}
+
+// serveMetered runs the upstream call of an "upto" payment, then charges the
+// compute units metered from the response. Failed upstream calls are not
+// charged and the payment is voided, so are responses past the largest one
+// buffered.
+func (h *X402Handler) serveMetered(w http.ResponseWriter, r *http.Request, next http.Handler, service string, payment *X402Payment, spend *X402SpendReservation, computeUnits uint64) (X402PaymentResponse, error) {
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	meter := newX402MeterWriter(h.Pricing.MaxResponseBytes(service), cancel)
+	meter.serve(next, r.WithContext(ctx))
+
+	if meter.tooLarge {
+		receipt := h.VoidPayment(service, payment, computeUnits, http.StatusBadGateway)
+		w.Header().Set(X402PaymentResponseHeader, receipt.Encode())
+		h.SetModeHeader(w)
+		http.Error(w, "upstream response too large to meter", http.StatusBadGateway)
+		return receipt, nil
+	}
+
+	units := h.Pricing.MeteredUnits(service, computeUnits, meter.status, int64(meter.body.Len()))
+	var receipt X402PaymentResponse
+	if units == 0 {
+		receipt = h.VoidPayment(service, payment, computeUnits, meter.status)
+	} else {
+		settleResp, err := h.SettlePayment(payment, units)
+		if err != nil {
+			// the response is withheld, it was not paid for
+			receipt := NewX402PaymentFailure(payment, err)
+			w.Header().Set(X402PaymentResponseHeader, receipt.Encode())
+			h.WritePaymentError(w, service, r.URL.String(), computeUnits, err)
+			return receipt, err
+		}
+		receipt = NewX402PaymentResponse(payment, settleResp, units)
+		h.CreditRevenue(service, payment, receipt.Amount, 1)
+		spend.Settle(receipt.Amount)
+		h.RecordReceipt(service, payment, receipt, meter.status)
+	}
+
+	for k, v := range meter.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set(X402PaymentResponseHeader, receipt.Encode())
+	if meter.status == 0 {
+		meter.status = http.StatusOK
+	}
+	w.WriteHeader(meter.status)
+	_, _ = w.Write(meter.body.Bytes())
+	return receipt, nil
+}
@@ -0,0 +1,238 @@
+package sentinel
+
+import (
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+func testUptoRequirements() PaymentRequirements {
+	requirements := testRequirements()
+	requirements.Scheme = X402SchemeUpto
+	return requirements
+}
+
+func testPermit(key *ecdsa.PrivateKey, value string, now time.Time) EIP2612Permit {
+	return EIP2612Permit{
+		Owner:    crypto.PubkeyToAddress(key.PublicKey).Hex(),
+		Spender:  testPayTo,
+		Value:    value,
+		Nonce:    strconv.FormatInt(now.UnixNano(), 10),
+		Deadline: strconv.FormatInt(now.Add(time.Minute).Unix(), 10),
+	}
+}
+
+// signTestPermit builds a base64 X-PAYMENT header with a permit signed by key
+func signTestPermit(t *testing.T, key *ecdsa.PrivateKey, requirements PaymentRequirements, permit EIP2612Permit) string {
+	t.Helper()
+	hash, err := EIP2612TypedDataHash(permit, requirements)
+	require.NoError(t, err)
+	sig, err := crypto.Sign(hash, key)
+	require.NoError(t, err)
+	sig[crypto.RecoveryIDOffset] += 27
+
+	upto, err := json.Marshal(UptoEVMPayload{Signature: hexutil.Encode(sig), Permit: permit})
+	require.NoError(t, err)
+	raw, err := json.Marshal(PaymentPayload{
+		X402Version: X402Version,
+		Scheme:      X402SchemeUpto,
+		Network:     requirements.Network,
+		Payload:     upto,
+	})
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func TestLocalVerifier_Upto(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	now := time.Now()
+	verifier := NewLocalVerifier()
+	verifier.now = func() time.Time { return now }
+	requirements := testUptoRequirements()
+
+	payment := signTestPermit(t, key, requirements, testPermit(key, "1000", now))
+	resp, err := verifier.Verify(payment, requirements)
+	require.NoError(t, err)
+	require.True(t, resp.Valid, resp.Error)
+
+	// settling a smaller metered amount
+	metered := requirements
+	metered.Amount = "300"
+	settle, err := verifier.Settle(payment, metered)
+	require.NoError(t, err)
+	require.True(t, settle.Success, settle.Error)
+
+	// the permit does not cover the maximum
+	payment = signTestPermit(t, key, requirements, testPermit(key, "999", now))
+	resp, err = verifier.Verify(payment, requirements)
+	require.NoError(t, err)
+	require.False(t, resp.Valid)
+
+	// the permit lets someone else spend
+	wrongSpender := testPermit(key, "1000", now)
+	wrongSpender.Spender = "0x0000000000000000000000000000000000000001"
+	payment = signTestPermit(t, key, requirements, wrongSpender)
+	resp, err = verifier.Verify(payment, requirements)
+	require.NoError(t, err)
+	require.False(t, resp.Valid)
+
+	// expired permit
+	expired := testPermit(key, "1000", now)
+	expired.Deadline = strconv.FormatInt(now.Unix(), 10)
+	payment = signTestPermit(t, key, requirements, expired)
+	resp, err = verifier.Verify(payment, requirements)
+	require.NoError(t, err)
+	require.False(t, resp.Valid)
+}
+
+func TestX402Pricing_MeteredUnits(t *testing.T) {
+	pricing := NewX402Pricing(map[string]conf.X402ServicePricing{
+		"eth": {ComputeUnits: 2, BytesPerUnit: 100, MaxComputeUnits: 10},
+	})
+
+	require.Equal(t, uint64(10), pricing.MaxUnits("eth", 2))
+	require.Equal(t, uint64(20), pricing.MaxUnits("eth", 20))
+	require.Equal(t, uint64(30), pricing.MaxUnits("btc", 3))
+
+	require.Equal(t, uint64(2), pricing.MeteredUnits("eth", 2, http.StatusOK, 99))
+	require.Equal(t, uint64(5), pricing.MeteredUnits("eth", 2, http.StatusOK, 350))
+	require.Equal(t, uint64(10), pricing.MeteredUnits("eth", 2, http.StatusOK, 1<<20))
+	require.Equal(t, uint64(2), pricing.MeteredUnits("eth", 2, http.StatusNotFound, 0))
+	require.Equal(t, uint64(0), pricing.MeteredUnits("eth", 2, http.StatusBadGateway, 350))
+
+	require.Equal(t, int64(x402MeteredDefaultMaxResponseBytes), pricing.MaxResponseBytes("eth"))
+}
+
+func TestX402Middleware_Metered(t *testing.T) {
+	store, err := NewX402PaymentStore("")
+	require.NoError(t, err)
+	defer store.Close()
+
+	handler := NewX402Handler(testPayTo)
+	handler.Verifier = NewLocalVerifier()
+	handler.Store = store
+	handler.Pricing = NewX402Pricing(map[string]conf.X402ServicePricing{
+		"eth": {ComputeUnits: 2, BytesPerUnit: 10, MaxComputeUnits: 10},
+	})
+	unitPrice := testUptoRequirements()
+	unitPrice.Amount = "100"
+	handler.Accepts = []PaymentRequirements{unitPrice}
+	proxy := &Proxy{x402: handler, logger: log.NewNopLogger(), proxies: map[string]*url.URL{"eth": {}}}
+
+	status := http.StatusOK
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(strings.Repeat("x", 35)))
+	})
+	serve := proxy.x402Middleware(upstream)
+
+	// the quote asks for the maximum
+	rec := httptest.NewRecorder()
+	serve.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x402/eth/", nil))
+	require.Equal(t, http.StatusPaymentRequired, rec.Code)
+	var quote PaymentRequiredResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&quote))
+	require.Equal(t, "1000", quote.Accepts[0].Amount)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	receipt := func(rec *httptest.ResponseRecorder) X402PaymentResponse {
+		raw, err := base64.StdEncoding.DecodeString(rec.Header().Get(X402PaymentResponseHeader))
+		require.NoError(t, err)
+		var receipt X402PaymentResponse
+		require.NoError(t, json.Unmarshal(raw, &receipt))
+		return receipt
+	}
+
+	// a successful response is charged its units plus bytes
+	payment := signTestPermit(t, key, quote.Accepts[0], testPermit(key, "1000", time.Now()))
+	req := httptest.NewRequest(http.MethodGet, "/x402/eth/", nil)
+	req.Header.Set("X-PAYMENT", payment)
+	rec = httptest.NewRecorder()
+	serve.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Len(t, rec.Body.String(), 35)
+	charged := receipt(rec)
+	require.True(t, charged.Success)
+	require.Equal(t, uint64(5), charged.ComputeUnits)
+	require.Equal(t, "500", charged.Amount)
+
+	// an upstream failure is free and the permit can be used again
+	status = http.StatusBadGateway
+	payment = signTestPermit(t, key, quote.Accepts[0], testPermit(key, "1000", time.Now()))
+	req = httptest.NewRequest(http.MethodGet, "/x402/eth/", nil)
+	req.Header.Set("X-PAYMENT", payment)
+	rec = httptest.NewRecorder()
+	serve.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadGateway, rec.Code)
+	require.Equal(t, "0", receipt(rec).Amount)
+
+	status = http.StatusOK
+	req = httptest.NewRequest(http.MethodGet, "/x402/eth/", nil)
+	req.Header.Set("X-PAYMENT", payment)
+	rec = httptest.NewRecorder()
+	serve.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "500", receipt(rec).Amount)
+}
+
+func TestX402Middleware_MeteredTooLarge(t *testing.T) {
+	// the upstream streams far more than a metered response may hold
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		chunk := []byte(strings.Repeat("x", 1024))
+		for i := 0; i < 1024; i++ {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+		}
+	}))
+	defer upstream.Close()
+
+	store, err := NewX402PaymentStore("")
+	require.NoError(t, err)
+	defer store.Close()
+	handler := NewX402Handler(testPayTo)
+	handler.Verifier = NewLocalVerifier()
+	handler.Store = store
+	handler.Pricing = NewX402Pricing(map[string]conf.X402ServicePricing{
+		"eth": {ComputeUnits: 1, MaxResponseBytes: 4096},
+	})
+	unitPrice := testUptoRequirements()
+	unitPrice.Amount = "100"
+	handler.Accepts = []PaymentRequirements{unitPrice}
+	proxy := &Proxy{x402: handler, logger: log.NewNopLogger(), proxies: map[string]*url.URL{"eth": mustParseURL(t, upstream.URL)}}
+	server := httptest.NewServer(proxy.x402Middleware(http.HandlerFunc(proxy.handleX402Proxy)))
+	defer server.Close()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	quote := handler.BuildPaymentRequirements("eth", "", 1)
+	payment := signTestPermit(t, key, quote.Accepts[0], testPermit(key, quote.Accepts[0].Amount, time.Now()))
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/x402/eth/", nil)
+	require.NoError(t, err)
+	req.Header.Set("X-PAYMENT", payment)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	receipt, err := DecodeX402PaymentResponse(resp.Header.Get(X402PaymentResponseHeader))
+	require.NoError(t, err)
+	require.Equal(t, X402StatusVoided, receipt.Status)
+	require.Equal(t, "0", receipt.Amount)
+}
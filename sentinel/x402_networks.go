@@ -0,0 +1,144 @@
+package sentinel
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/arkeonetwork/arkeo/common/cosmos"
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+// x402DefaultTimeoutSeconds is how long a client has to use a payment quote
+const x402DefaultTimeoutSeconds = 60
+
+// x402AssetPreset is the default asset of a known x402 network
+type x402AssetPreset struct {
+	Asset    string
+	Decimals int
+	Extra    map[string]string
+}
+
+// x402NetworkPresets are the USDC deployments x402 clients pay with by default.
+// The extras are the EIP-712 domain of the token contract.
+var x402NetworkPresets = map[string]x402AssetPreset{
+	"eip155:1": {
+		Asset:    "0xA0b86991c6218b36c1d19D4a2e9Eb0cE3606eB48",
+		Decimals: 6,
+		Extra:    map[string]string{"name": "USD Coin", "version": "2"},
+	},
+	"eip155:11155111": {
+		Asset:    "0x1c7D4B196Cb0C7B01d743Fbc6116a902379C7238",
+		Decimals: 6,
+		Extra:    map[string]string{"name": "USDC", "version": "2"},
+	},
+	"eip155:8453": {
+		Asset:    "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913",
+		Decimals: 6,
+		Extra:    map[string]string{"name": "USD Coin", "version": "2"},
+	},
+	"eip155:84532": {
+		Asset:    "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		Decimals: 6,
+		Extra:    map[string]string{"name": "USDC", "version": "2"},
+	},
+}
+
+// arkeoAssetPreset is the native token of every "arkeo:<chain-id>" network
+var arkeoAssetPreset = x402AssetPreset{
+	Asset:    "uarkeo",
+	Decimals: cosmos.DefaultCoinDecimals,
+	Extra:    map[string]string{"name": "ARKEO"},
+}
+
+// normalizeX402Network converts legacy x402 network names such as
+// "base-sepolia" to CAIP-2 ids
+func normalizeX402Network(network string) string {
+	if id, ok := evmNetworkChainIDs[network]; ok {
+		return fmt.Sprintf("eip155:%d", id)
+	}
+	return network
+}
+
+// lookupX402Preset returns the default asset of a network
+func lookupX402Preset(network string) (x402AssetPreset, bool) {
+	if strings.HasPrefix(network, "arkeo:") {
+		return arkeoAssetPreset, true
+	}
+	preset, ok := x402NetworkPresets[network]
+	return preset, ok
+}
+
+// resolveX402Accepts turns the configured payment options into requirements
+// priced per compute unit, filling missing fields from the network presets.
+// Options without pay_to are paid to evmPayTo, or arkeoPayTo on arkeo networks.
+func resolveX402Accepts(accepts []conf.X402AcceptConfig, evmPayTo, arkeoPayTo string) ([]PaymentRequirements, error) {
+	requirements := make([]PaymentRequirements, 0, len(accepts))
+	for i, accept := range accepts {
+		network := normalizeX402Network(strings.TrimSpace(accept.Network))
+		if network == "" {
+			return nil, fmt.Errorf("x402 accept %d: network is required", i)
+		}
+
+		scheme := strings.ToLower(accept.Scheme)
+		switch scheme {
+		case "":
+			scheme = X402SchemeExact
+		case X402SchemeExact:
+		case X402SchemeUpto:
+			if _, err := evmChainID(network); err != nil {
+				return nil, fmt.Errorf("x402 accept %d: scheme upto is only supported on evm networks", i)
+			}
+		default:
+			return nil, fmt.Errorf("x402 accept %d: unknown scheme %q", i, accept.Scheme)
+		}
+
+		asset := accept.Asset
+		decimals := accept.Decimals
+		extra := make(map[string]interface{})
+		if preset, ok := lookupX402Preset(network); ok && (asset == "" || strings.EqualFold(asset, preset.Asset)) {
+			asset = preset.Asset
+			if decimals == 0 {
+				decimals = preset.Decimals
+			}
+			for k, v := range preset.Extra {
+				extra[k] = v
+			}
+		}
+		for k, v := range accept.Extra {
+			extra[k] = v
+		}
+		if asset == "" {
+			return nil, fmt.Errorf("x402 accept %d: asset is required on %s", i, network)
+		}
+		if decimals > 0 {
+			extra["decimals"] = decimals
+		}
+
+		payTo := accept.PayTo
+		if payTo == "" {
+			payTo = evmPayTo
+			if strings.HasPrefix(network, "arkeo:") {
+				payTo = arkeoPayTo
+			}
+		}
+		if payTo == "" {
+			return nil, fmt.Errorf("x402 accept %d: pay_to is required on %s", i, network)
+		}
+
+		if price, ok := new(big.Int).SetString(accept.Price, 10); !ok || price.Sign() < 0 {
+			return nil, fmt.Errorf("x402 accept %d: invalid price %q", i, accept.Price)
+		}
+
+		requirements = append(requirements, PaymentRequirements{
+			Scheme:            scheme,
+			Network:           network,
+			Amount:            accept.Price,
+			Asset:             asset,
+			PayTo:             payTo,
+			MaxTimeoutSeconds: x402DefaultTimeoutSeconds,
+			Extra:             extra,
+		})
+	}
+	return requirements, nil
+}
@@ -0,0 +1,69 @@
+package sentinel
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+func TestResolveX402Accepts(t *testing.T) {
+	accepts, err := resolveX402Accepts([]conf.X402AcceptConfig{
+		{Network: "base-sepolia", Price: "1000"},
+		{Network: "arkeo:arkeo-testnet-1", Price: "200000"},
+		{Network: "eip155:137", Asset: "0x3c499c542cEF5E3811e1192ce70d8cC03d5c3359", Decimals: 6, Price: "1000", Extra: map[string]string{"name": "USD Coin", "version": "2"}},
+	}, testPayTo, "tarkeo1provider")
+	require.NoError(t, err)
+	require.Len(t, accepts, 3)
+
+	require.Equal(t, "exact", accepts[0].Scheme)
+	require.Equal(t, "eip155:84532", accepts[0].Network)
+	require.Equal(t, "0x036CbD53842c5426634e7929541eC2318f3dCF7e", accepts[0].Asset)
+	require.Equal(t, testPayTo, accepts[0].PayTo)
+	require.Equal(t, "USDC", accepts[0].Extra["name"])
+	require.Equal(t, 6, accepts[0].Extra["decimals"])
+
+	require.Equal(t, "uarkeo", accepts[1].Asset)
+	require.Equal(t, "tarkeo1provider", accepts[1].PayTo)
+	require.Equal(t, 8, accepts[1].Extra["decimals"])
+
+	require.Equal(t, "USD Coin", accepts[2].Extra["name"])
+
+	_, err = resolveX402Accepts([]conf.X402AcceptConfig{{Network: "base-sepolia", Price: "1000"}}, "", "")
+	require.Error(t, err)
+	_, err = resolveX402Accepts([]conf.X402AcceptConfig{{Network: "base-sepolia", Price: "1.5"}}, testPayTo, "")
+	require.Error(t, err)
+	_, err = resolveX402Accepts([]conf.X402AcceptConfig{{Network: "eip155:137", Price: "1000"}}, testPayTo, "")
+	require.Error(t, err)
+}
+
+func TestX402Handler_SelectsSignedNetwork(t *testing.T) {
+	mainnet := testRequirements()
+	mainnet.Network = "eip155:8453"
+	mainnet.Asset = "0x833589fCD6eDb6E08f4c7C32D4f71b54bdA02913"
+	mainnet.Extra = map[string]interface{}{"name": "USD Coin", "version": "2"}
+
+	handler := NewX402Handler(testPayTo)
+	handler.Verifier = NewLocalVerifier()
+	handler.Accepts = []PaymentRequirements{mainnet, testRequirements()}
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+
+	// a testnet payment is verified against the testnet option, not the first one
+	payment := signTestPayment(t, key, testRequirements(), testAuthorization(key, "2000", time.Now()))
+	verified, _, err := handler.VerifyPayment(payment, 2)
+	require.NoError(t, err)
+	require.True(t, verified)
+
+	// a network the provider does not accept is rejected
+	sepolia := testRequirements()
+	sepolia.Network = "eip155:11155111"
+	payment = signTestPayment(t, key, sepolia, testAuthorization(key, "2000", time.Now()))
+	verified, _, err = handler.VerifyPayment(payment, 2)
+	require.Error(t, err)
+	require.False(t, verified)
+}
@@ -0,0 +1,305 @@
+package sentinel
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// X402PaymentGracePeriod is how long a payment is remembered after it can no
+// longer be submitted on chain
+const X402PaymentGracePeriod = 10 * time.Minute
+
+// ErrX402PaymentReplayed is returned when a payment has already been accepted
+var ErrX402PaymentReplayed = errors.New("x402 payment already used")
+
+// X402PaymentStore remembers accepted x402 payments so a payment payload or
+// EIP-3009 nonce cannot be replayed
+type X402PaymentStore struct {
+	logger zerolog.Logger
+	db     *leveldb.DB
+	mu     sync.Mutex
+}
+
+type X402PaymentRecord struct {
+	Key          string `json:"key"`
+	Nonce        string `json:"nonce"`
+	Payer        string `json:"payer"`
+	Amount       string `json:"amount"`
+	Network      string `json:"network"`
+	Asset        string `json:"asset"`
+	SettlementID string `json:"settlement_id"`
+	CreatedAt    int64  `json:"created_at"`
+	ExpiresAt    int64  `json:"expires_at"`
+
+	// Authorization is the signed payment payload of an uncollected payment,
+	// accepted by the local verifier without moving the funds. It is kept
+	// until the provider submits it on chain for CollectAmount.
+	Authorization json.RawMessage `json:"authorization,omitempty"`
+	CollectAmount string          `json:"collect_amount,omitempty"`
+	Collected     bool            `json:"collected,omitempty"`
+}
+
+// NewX402PaymentRecord builds the replay record of a payment. EVM payments are
+// keyed by payer and EIP-3009 or permit nonce, Arkeo payments by tx hash,
+// anything else by the payload hash. The record expires once the payment can
+// no longer be used plus a grace window.
+func NewX402PaymentRecord(paymentPayload string, requirements PaymentRequirements, now time.Time) X402PaymentRecord {
+	record := X402PaymentRecord{
+		Amount:    requirements.Amount,
+		Network:   requirements.Network,
+		Asset:     requirements.Asset,
+		CreatedAt: now.Unix(),
+	}
+	expiresAt := now.Add(time.Duration(requirements.MaxTimeoutSeconds) * time.Second)
+
+	payload, err := DecodePaymentPayload(paymentPayload)
+	if err == nil {
+		record.Network = payload.Network
+		if upto, err := payload.UptoEVM(); err == nil && payload.Scheme == X402SchemeUpto {
+			permit := upto.Permit
+			record.Nonce = permit.Nonce
+			record.Payer = strings.ToLower(permit.Owner)
+			record.Amount = permit.Value
+			record.Key = strings.Join([]string{"evm", payload.Network, strings.ToLower(requirements.Asset), record.Payer, "permit", record.Nonce}, "/")
+			if deadline, err := strconv.ParseInt(permit.Deadline, 10, 64); err == nil && deadline > expiresAt.Unix() {
+				expiresAt = time.Unix(deadline, 0)
+			}
+		} else if evm, err := payload.ExactEVM(); err == nil {
+			auth := evm.Authorization
+			record.Nonce = strings.ToLower(auth.Nonce)
+			record.Payer = strings.ToLower(auth.From)
+			record.Amount = auth.Value
+			record.Key = strings.Join([]string{"evm", payload.Network, strings.ToLower(requirements.Asset), record.Payer, record.Nonce}, "/")
+			if validBefore, err := strconv.ParseInt(auth.ValidBefore, 10, 64); err == nil && validBefore > expiresAt.Unix() {
+				expiresAt = time.Unix(validBefore, 0)
+			}
+		} else if tx, err := payload.ArkeoTx(); err == nil {
+			// a signed tx and its hash are the same payment
+			if hash, err := tx.Hash(); err == nil {
+				record.Nonce = hash
+				record.Key = strings.Join([]string{"arkeo", payload.Network, hash}, "/")
+				if txExpiry := now.Add(x402ArkeoTxMaxAge); txExpiry.After(expiresAt) {
+					expiresAt = txExpiry
+				}
+			}
+		}
+	}
+	if record.Key == "" {
+		digest := sha256.Sum256([]byte(paymentPayload))
+		record.Key = "payload/" + hex.EncodeToString(digest[:])
+	}
+
+	record.ExpiresAt = expiresAt.Add(X402PaymentGracePeriod).Unix()
+	return record
+}
+
+func NewX402PaymentStore(levelDbFolder string) (*X402PaymentStore, error) {
+	var db *leveldb.DB
+	var err error
+	if len(levelDbFolder) == 0 {
+		log.Warn().Msg("x402 payment store folder is empty, create in memory storage")
+		// no directory given, use in memory store
+		storage := storage.NewMemStorage()
+		db, err = leveldb.Open(storage, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fail to in memory open level db: %w", err)
+		}
+	} else {
+		db, err = leveldb.OpenFile(levelDbFolder, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fail to open level db %s: %w", levelDbFolder, err)
+		}
+	}
+	return &X402PaymentStore{
+		logger: log.With().Str("module", "x402-payment-storage").Logger(),
+		db:     db,
+	}, nil
+}
+
+// Reserve stores the record unless a payment with the same key was already
+// accepted, in which case ErrX402PaymentReplayed is returned. The check and
+// the write are atomic across concurrent requests.
+func (s *X402PaymentStore) Reserve(record X402PaymentRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	exist, err := s.db.Has([]byte(record.Key), nil)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("fail to check x402 payment existence")
+		return err
+	}
+	if exist {
+		return ErrX402PaymentReplayed
+	}
+	return s.set(record)
+}
+
+// SetSettlement records the settlement id of a reserved payment
+func (s *X402PaymentStore) SetSettlement(key, settlementID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.get(key)
+	if err != nil {
+		return err
+	}
+	record.SettlementID = settlementID
+	return s.set(record)
+}
+
+// SetUncollected records the settlement of a payment whose funds did not
+// move yet, with the signed authorization to submit on chain for amount
+func (s *X402PaymentStore) SetUncollected(key, settlementID string, authorization json.RawMessage, amount string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.get(key)
+	if err != nil {
+		return err
+	}
+	record.SettlementID = settlementID
+	record.Authorization = authorization
+	record.CollectAmount = amount
+	return s.set(record)
+}
+
+// Uncollected returns the payments whose authorization was not submitted on
+// chain yet, oldest first
+func (s *X402PaymentStore) Uncollected() []X402PaymentRecord {
+	var results []X402PaymentRecord
+	for _, record := range s.List() {
+		if len(record.Authorization) > 0 && !record.Collected {
+			results = append(results, record)
+		}
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].CreatedAt < results[j].CreatedAt })
+	return results
+}
+
+// MarkCollected records that the authorization of a payment was submitted on
+// chain
+func (s *X402PaymentStore) MarkCollected(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, err := s.get(key)
+	if err != nil {
+		return err
+	}
+	if len(record.Authorization) == 0 {
+		return fmt.Errorf("x402 payment %s has nothing to collect", key)
+	}
+	record.Collected = true
+	return s.set(record)
+}
+
+func (s *X402PaymentStore) Get(key string) (X402PaymentRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(key)
+}
+
+// Has check whether the given key exist in key value store
+func (s *X402PaymentStore) Has(key string) (ok bool) {
+	ok, _ = s.db.Has([]byte(key), nil)
+	return
+}
+
+// Remove remove the given item from key values store
+func (s *X402PaymentStore) Remove(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.db.Delete([]byte(key), nil)
+}
+
+// List returns every stored payment
+func (s *X402PaymentStore) List() []X402PaymentRecord {
+	iterator := s.db.NewIterator(util.BytesPrefix([]byte(nil)), nil)
+	defer iterator.Release()
+	var results []X402PaymentRecord
+	for iterator.Next() {
+		buf := iterator.Value()
+		if len(buf) == 0 {
+			continue
+		}
+
+		var item X402PaymentRecord
+		if err := json.Unmarshal(buf, &item); err != nil {
+			s.logger.Error().Err(err).Msg("fail to unmarshal x402 payment record")
+			continue
+		}
+
+		results = append(results, item)
+	}
+
+	return results
+}
+
+// Prune removes payments that expired before now and returns how many were removed
+func (s *X402PaymentStore) Prune(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch := new(leveldb.Batch)
+	for _, record := range s.List() {
+		if record.ExpiresAt < now.Unix() {
+			if len(record.Authorization) > 0 && !record.Collected {
+				s.logger.Warn().Str("key", record.Key).Str("amount", record.CollectAmount).Msg("x402 authorization expired before it was collected")
+			}
+			batch.Delete([]byte(record.Key))
+		}
+	}
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+	if err := s.db.Write(batch, nil); err != nil {
+		s.logger.Error().Err(err).Msg("fail to prune x402 payment records")
+		return 0, err
+	}
+	return batch.Len(), nil
+}
+
+// Close underlying db
+func (s *X402PaymentStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *X402PaymentStore) get(key string) (record X402PaymentRecord, err error) {
+	buf, err := s.db.Get([]byte(key), nil)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("fail to get x402 payment record")
+		return record, err
+	}
+	if err := json.Unmarshal(buf, &record); err != nil {
+		s.logger.Error().Err(err).Msg("fail to unmarshal x402 payment record")
+		return record, err
+	}
+	return record, nil
+}
+
+func (s *X402PaymentStore) set(record X402PaymentRecord) error {
+	buf, err := json.Marshal(record)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("fail to marshal x402 payment record")
+		return err
+	}
+	if err := s.db.Put([]byte(record.Key), buf, nil); err != nil {
+		s.logger.Error().Err(err).Msg("fail to set x402 payment record")
+		return err
+	}
+	return nil
+}
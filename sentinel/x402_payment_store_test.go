@@ -0,0 +1,184 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestX402PaymentStore_Replay(t *testing.T) {
+	store, err := NewX402PaymentStore("")
+	require.NoError(t, err)
+	defer store.Close()
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	now := time.Now()
+	requirements := testRequirements()
+	auth := testAuthorization(key, "1000", now)
+	payment := signTestPayment(t, key, requirements, auth)
+
+	record := NewX402PaymentRecord(payment, requirements, now)
+	assert.Equal(t, "1000", record.Amount)
+	assert.Equal(t, requirements.Network, record.Network)
+	assert.Contains(t, record.Key, record.Nonce)
+
+	require.NoError(t, store.Reserve(record))
+	require.ErrorIs(t, store.Reserve(record), ErrX402PaymentReplayed)
+
+	// the same authorization re-encoded is still a replay
+	again := NewX402PaymentRecord(payment+"  ", requirements, now.Add(time.Second))
+	require.ErrorIs(t, store.Reserve(again), ErrX402PaymentReplayed)
+
+	require.NoError(t, store.SetSettlement(record.Key, "0xsettled"))
+	stored, err := store.Get(record.Key)
+	require.NoError(t, err)
+	assert.Equal(t, "0xsettled", stored.SettlementID)
+
+	// removing the record allows the payment again
+	require.NoError(t, store.Remove(record.Key))
+	require.NoError(t, store.Reserve(record))
+
+	// non evm payloads are keyed by their hash
+	opaque := NewX402PaymentRecord("opaque-payload", requirements, now)
+	require.NoError(t, store.Reserve(opaque))
+	require.ErrorIs(t, store.Reserve(NewX402PaymentRecord("opaque-payload", requirements, now)), ErrX402PaymentReplayed)
+}
+
+func TestX402PaymentStore_ConcurrentReserve(t *testing.T) {
+	store, err := NewX402PaymentStore("")
+	require.NoError(t, err)
+	defer store.Close()
+
+	record := NewX402PaymentRecord("same-payload", testRequirements(), time.Now())
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	accepted := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := store.Reserve(record); err == nil {
+				mu.Lock()
+				accepted++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 1, accepted)
+}
+
+func TestX402PaymentStore_Prune(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "x402-payment-store-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(tmpDir)
+
+	store, err := NewX402PaymentStore(tmpDir)
+	require.NoError(t, err)
+
+	now := time.Now()
+	requirements := testRequirements()
+	old := NewX402PaymentRecord("old-payload", requirements, now.Add(-time.Hour))
+	fresh := NewX402PaymentRecord("fresh-payload", requirements, now)
+	require.NoError(t, store.Reserve(old))
+	require.NoError(t, store.Reserve(fresh))
+
+	// nothing expired yet within maxTimeoutSeconds + grace
+	pruned, err := store.Prune(now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 0, pruned)
+
+	pruned, err = store.Prune(now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, pruned)
+	assert.False(t, store.Has(old.Key))
+	assert.True(t, store.Has(fresh.Key))
+
+	// records survive a restart
+	require.NoError(t, store.Close())
+	store, err = NewX402PaymentStore(tmpDir)
+	require.NoError(t, err)
+	defer store.Close()
+	require.ErrorIs(t, store.Reserve(fresh), ErrX402PaymentReplayed)
+}
+
+func TestX402Handler_VerifyPaymentReplay(t *testing.T) {
+	store, err := NewX402PaymentStore("")
+	require.NoError(t, err)
+	defer store.Close()
+
+	handler := NewX402Handler(testPayTo)
+	handler.Verifier = NewLocalVerifier()
+	handler.Store = store
+	handler.Accepts = []PaymentRequirements{testRequirements()}
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	payment := signTestPayment(t, key, testRequirements(), testAuthorization(key, "1000", time.Now()))
+
+	verified, settlementID, err := handler.VerifyPayment(payment, 1)
+	require.NoError(t, err)
+	require.True(t, verified)
+	require.NotEmpty(t, settlementID)
+
+	verified, _, err = handler.VerifyPayment(payment, 1)
+	require.ErrorIs(t, err, ErrX402PaymentReplayed)
+	require.False(t, verified)
+}
+
+func TestX402PaymentStore_Uncollected(t *testing.T) {
+	store, err := NewX402PaymentStore("")
+	require.NoError(t, err)
+	defer store.Close()
+
+	handler := NewX402Handler(testPayTo)
+	handler.Verifier = NewLocalVerifier()
+	handler.Store = store
+	handler.Accepts = []PaymentRequirements{testRequirements()}
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	auth := testAuthorization(key, "1000", time.Now())
+	payment := signTestPayment(t, key, testRequirements(), auth)
+	verified, _, err := handler.VerifyPayment(payment, 1)
+	require.NoError(t, err)
+	require.True(t, verified)
+
+	// the local verifier moved no funds, the signed authorization is kept
+	router := (&Proxy{logger: log.NewNopLogger(), x402: handler}).getAdminRouter()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, RoutesAdminX402Uncollected, nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	var uncollected []X402PaymentRecord
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &uncollected))
+	require.Len(t, uncollected, 1)
+	require.Equal(t, "1000", uncollected[0].CollectAmount)
+	decoded, err := DecodePaymentPayload(string(uncollected[0].Authorization))
+	require.NoError(t, err)
+	evm, err := decoded.ExactEVM()
+	require.NoError(t, err)
+	require.Equal(t, auth.Nonce, evm.Authorization.Nonce)
+	require.NotEmpty(t, evm.Signature)
+
+	w = httptest.NewRecorder()
+	body := `{"keys":["` + uncollected[0].Key + `"]}`
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, RoutesAdminX402Collected, strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, store.Uncollected())
+
+	// the record still guards against replays
+	require.True(t, store.Has(uncollected[0].Key))
+	require.Error(t, store.MarkCollected("evm/unknown"))
+}
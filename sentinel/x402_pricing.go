@@ -0,0 +1,216 @@
+package sentinel
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+// maxPricedBodySize bounds how much of a request body is read to price it
+const maxPricedBodySize = 1 << 20
+
+// X402Pricing prices x402 requests in compute units from the pricing table of
+// the sentinel configuration. A nil pricing charges one unit per request.
+type X402Pricing struct {
+	mu       sync.RWMutex
+	services map[string]conf.X402ServicePricing
+}
+
+// NewX402Pricing creates the pricing from the configured table
+func NewX402Pricing(table map[string]conf.X402ServicePricing) *X402Pricing {
+	return &X402Pricing{services: x402PricingTable(table)}
+}
+
+// Update replaces the pricing table, as on a configuration reload
+func (p *X402Pricing) Update(table map[string]conf.X402ServicePricing) {
+	services := x402PricingTable(table)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.services = services
+}
+
+// x402PricingTable keys the pricing table by lower case service name
+func x402PricingTable(table map[string]conf.X402ServicePricing) map[string]conf.X402ServicePricing {
+	services := make(map[string]conf.X402ServicePricing, len(table))
+	for name, pricing := range table {
+		services[strings.ToLower(name)] = pricing
+	}
+	return services
+}
+
+// Service returns the pricing of a service and whether it is in the table
+func (p *X402Pricing) Service(service string) (conf.X402ServicePricing, bool) {
+	if p == nil {
+		return conf.X402ServicePricing{}, false
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	pricing, ok := p.services[strings.ToLower(service)]
+	return pricing, ok
+}
+
+// DefaultUnits returns the compute units of a request to the service that
+// matches no method or path
+func (p *X402Pricing) DefaultUnits(service string) uint64 {
+	pricing, _ := p.Service(service)
+	if pricing.ComputeUnits == 0 {
+		return 1
+	}
+	return pricing.ComputeUnits
+}
+
+// ComputeUnits returns the compute units charged for a request to service.
+// JSON-RPC bodies are priced per method (every batch element counts), other
+// requests by the first matching REST path pattern.
+func (p *X402Pricing) ComputeUnits(service string, r *http.Request) uint64 {
+	pricing, ok := p.Service(service)
+	if !ok {
+		if methods := jsonRPCMethods(r); len(methods) > 1 {
+			return uint64(len(methods))
+		}
+		return 1
+	}
+	units := p.DefaultUnits(service)
+
+	if len(pricing.Methods) > 0 {
+		if methods := jsonRPCMethods(r); len(methods) > 0 {
+			var total uint64
+			for _, method := range methods {
+				if mu, ok := pricing.Methods[method]; ok {
+					total += mu
+				} else {
+					total += units
+				}
+			}
+			return total
+		}
+	}
+
+	restPath := x402RestPath(r.URL.Path, service)
+	for _, pp := range pricing.Paths {
+		if matchPricePattern(pp.Pattern, restPath) {
+			return pp.ComputeUnits
+		}
+	}
+	return units
+}
+
+// x402UptoDefaultMultiplier bounds metered requests of services without
+// max_compute_units to this many times their quoted compute units
+const x402UptoDefaultMultiplier = 10
+
+// MaxUnits returns the most compute units a metered request quoted at units
+// can be charged
+func (p *X402Pricing) MaxUnits(service string, units uint64) uint64 {
+	pricing, _ := p.Service(service)
+	if pricing.MaxComputeUnits > units {
+		return pricing.MaxComputeUnits
+	}
+	if pricing.MaxComputeUnits > 0 {
+		return units
+	}
+	return units * x402UptoDefaultMultiplier
+}
+
+// x402MeteredDefaultMaxResponseBytes is the largest metered response of
+// services without max_response_bytes
+const x402MeteredDefaultMaxResponseBytes = 16 << 20
+
+// MaxResponseBytes returns the largest metered response of a service
+func (p *X402Pricing) MaxResponseBytes(service string) int64 {
+	pricing, _ := p.Service(service)
+	if pricing.MaxResponseBytes > 0 {
+		return pricing.MaxResponseBytes
+	}
+	return x402MeteredDefaultMaxResponseBytes
+}
+
+// MeteredUnits returns the compute units charged for a metered request once
+// the upstream answered. Failed requests are free, successful ones pay their
+// quoted units plus the response size, up to MaxUnits.
+func (p *X402Pricing) MeteredUnits(service string, units uint64, status int, responseBytes int64) uint64 {
+	if status == 0 || status >= http.StatusInternalServerError {
+		return 0
+	}
+	pricing, _ := p.Service(service)
+	charged := units
+	if pricing.BytesPerUnit > 0 && responseBytes > 0 {
+		charged += uint64(responseBytes) / pricing.BytesPerUnit
+	}
+	if maxUnits := p.MaxUnits(service, units); charged > maxUnits {
+		return maxUnits
+	}
+	return charged
+}
+
+// priceForUnits multiplies a per unit price in atomic units by the compute units
+func priceForUnits(price string, units uint64) string {
+	amount, ok := new(big.Int).SetString(price, 10)
+	if !ok {
+		return price
+	}
+	return amount.Mul(amount, new(big.Int).SetUint64(units)).String()
+}
+
+// jsonRPCMethods returns the methods of a single or batch JSON-RPC request.
+// The body is restored so it can still be proxied.
+func jsonRPCMethods(r *http.Request) []string {
+	if request, ok := JSONRPCFromContext(r.Context()); ok {
+		return request.Methods()
+	}
+	if r.Body == nil || r.Method != http.MethodPost {
+		return nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxPricedBodySize))
+	r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+	if err != nil {
+		return nil
+	}
+
+	type call struct {
+		Method string `json:"method"`
+	}
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' {
+		var batch []call
+		if err := json.Unmarshal(body, &batch); err != nil {
+			return nil
+		}
+		methods := make([]string, 0, len(batch))
+		for _, c := range batch {
+			methods = append(methods, c.Method)
+		}
+		return methods
+	}
+	var single call
+	if err := json.Unmarshal(body, &single); err != nil || single.Method == "" {
+		return nil
+	}
+	return []string{single.Method}
+}
+
+// x402RestPath strips the /x402/{service} prefix from a request path
+func x402RestPath(requestPath, service string) string {
+	rest := strings.TrimPrefix(requestPath, "/x402/"+service)
+	if !strings.HasPrefix(rest, "/") {
+		rest = "/" + rest
+	}
+	return rest
+}
+
+func matchPricePattern(pattern, restPath string) bool {
+	if ok, err := path.Match(pattern, restPath); err == nil && ok {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(restPath, prefix)
+	}
+	return false
+}
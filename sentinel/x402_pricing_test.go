@@ -0,0 +1,86 @@
+package sentinel
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+func testPricing() *X402Pricing {
+	return NewX402Pricing(map[string]conf.X402ServicePricing{
+		"eth-mainnet-fullnode": {
+			ComputeUnits: 2,
+			Methods: map[string]uint64{
+				"eth_chainId": 1,
+				"eth_getLogs": 50,
+			},
+		},
+		"gaia-mainnet-rpc": {
+			Paths: []conf.X402PathPricing{
+				{Pattern: "/cosmos/tx/v1beta1/txs/*", ComputeUnits: 5},
+				{Pattern: "/block_results*", ComputeUnits: 10},
+			},
+		},
+	})
+}
+
+func TestX402Pricing_ComputeUnits(t *testing.T) {
+	pricing := testPricing()
+
+	post := func(service, body string) *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/x402/"+service+"/", strings.NewReader(body))
+	}
+
+	// per method pricing
+	r := post("eth-mainnet-fullnode", `{"jsonrpc":"2.0","id":1,"method":"eth_getLogs","params":[]}`)
+	require.Equal(t, uint64(50), pricing.ComputeUnits("eth-mainnet-fullnode", r))
+
+	// the body can still be proxied after pricing
+	body, err := io.ReadAll(r.Body)
+	require.NoError(t, err)
+	require.Contains(t, string(body), "eth_getLogs")
+
+	// unlisted methods cost the service default
+	r = post("eth-mainnet-fullnode", `{"jsonrpc":"2.0","id":1,"method":"eth_blockNumber"}`)
+	require.Equal(t, uint64(2), pricing.ComputeUnits("eth-mainnet-fullnode", r))
+
+	// every batch element is charged
+	r = post("eth-mainnet-fullnode", `[{"method":"eth_chainId"},{"method":"eth_getLogs"},{"method":"eth_call"}]`)
+	require.Equal(t, uint64(53), pricing.ComputeUnits("eth-mainnet-fullnode", r))
+
+	// REST path patterns
+	r = httptest.NewRequest(http.MethodGet, "/x402/gaia-mainnet-rpc/cosmos/tx/v1beta1/txs/ABCDEF", nil)
+	require.Equal(t, uint64(5), pricing.ComputeUnits("gaia-mainnet-rpc", r))
+	r = httptest.NewRequest(http.MethodGet, "/x402/gaia-mainnet-rpc/block_results?height=10", nil)
+	require.Equal(t, uint64(10), pricing.ComputeUnits("gaia-mainnet-rpc", r))
+	r = httptest.NewRequest(http.MethodGet, "/x402/gaia-mainnet-rpc/status", nil)
+	require.Equal(t, uint64(1), pricing.ComputeUnits("gaia-mainnet-rpc", r))
+
+	// services not in the table and a nil pricing cost one unit
+	r = post("btc-mainnet-fullnode", `{"method":"getblockcount"}`)
+	require.Equal(t, uint64(1), pricing.ComputeUnits("btc-mainnet-fullnode", r))
+	var none *X402Pricing
+	require.Equal(t, uint64(1), none.ComputeUnits("eth-mainnet-fullnode", r))
+}
+
+func TestX402Handler_PricedRequirements(t *testing.T) {
+	handler := NewX402Handler(testPayTo)
+	handler.Pricing = testPricing()
+
+	quote := handler.BuildPaymentRequirements("eth-mainnet-fullnode", "/x402/eth-mainnet-fullnode/", 50)
+	for _, accept := range quote.Accepts {
+		switch accept.Asset {
+		case "uarkeo":
+			require.Equal(t, "42500000", accept.Amount)
+		default:
+			require.Equal(t, "50000", accept.Amount)
+		}
+	}
+	require.Equal(t, uint64(50), quote.Extensions["computeUnits"])
+}
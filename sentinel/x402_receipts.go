@@ -0,0 +1,230 @@
+package sentinel
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	// RouteX402Receipts lists the payment outcomes of a payer
+	RouteX402Receipts = "/x402/receipts/{payer}"
+
+	// X402ReceiptRetention is how long payment outcomes are kept
+	X402ReceiptRetention = 30 * 24 * time.Hour
+
+	x402ReceiptPrefix       = "receipt/"
+	defaultX402ReceiptLimit = 100
+	maxX402ReceiptLimit     = 1000
+)
+
+// x402 payment outcomes
+const (
+	X402StatusSettled  = "settled"  // the payment was charged
+	X402StatusVoided   = "voided"   // the request failed upstream and the payment was not charged
+	X402StatusCredited = "credited" // the request failed upstream after the funds moved, the payer is owed a refund
+)
+
+// X402ReceiptStore keeps the outcome of every x402 payment for its payer
+type X402ReceiptStore struct {
+	logger zerolog.Logger
+	db     *leveldb.DB
+	mu     sync.Mutex
+}
+
+// X402Receipt is the outcome of a payment
+type X402Receipt struct {
+	ID             string `json:"id"`
+	Payer          string `json:"payer"`
+	Service        string `json:"service"`
+	Status         string `json:"status"`
+	Network        string `json:"network,omitempty"`
+	Asset          string `json:"asset,omitempty"`
+	Amount         string `json:"amount,omitempty"`
+	ComputeUnits   uint64 `json:"computeUnits,omitempty"`
+	UpstreamStatus int    `json:"upstreamStatus,omitempty"`
+	TxHash         string `json:"txHash,omitempty"`
+	CreatedAt      int64  `json:"createdAt"`
+}
+
+// Key sorts the receipts of a payer by time
+func (r X402Receipt) Key() string {
+	return fmt.Sprintf("%s%s/%020d/%s", x402ReceiptPrefix, strings.ToLower(r.Payer), r.CreatedAt, r.ID)
+}
+
+func NewX402ReceiptStore(levelDbFolder string) (*X402ReceiptStore, error) {
+	var db *leveldb.DB
+	var err error
+	if len(levelDbFolder) == 0 {
+		log.Warn().Msg("x402 receipt store folder is empty, create in memory storage")
+		// no directory given, use in memory store
+		storage := storage.NewMemStorage()
+		db, err = leveldb.Open(storage, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fail to in memory open level db: %w", err)
+		}
+	} else {
+		db, err = leveldb.OpenFile(levelDbFolder, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fail to open level db %s: %w", levelDbFolder, err)
+		}
+	}
+	return &X402ReceiptStore{
+		logger: log.With().Str("module", "x402-receipt-storage").Logger(),
+		db:     db,
+	}, nil
+}
+
+func (s *X402ReceiptStore) Set(receipt X402Receipt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	buf, err := json.Marshal(receipt)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("fail to marshal x402 receipt")
+		return err
+	}
+	if err := s.db.Put([]byte(receipt.Key()), buf, nil); err != nil {
+		s.logger.Error().Err(err).Msg("fail to set x402 receipt")
+		return err
+	}
+	return nil
+}
+
+// List returns up to limit receipts of payer, most recent first
+func (s *X402ReceiptStore) List(payer string, limit int) []X402Receipt {
+	iterator := s.db.NewIterator(util.BytesPrefix([]byte(x402ReceiptPrefix+strings.ToLower(payer)+"/")), nil)
+	defer iterator.Release()
+	var results []X402Receipt
+	for ok := iterator.Last(); ok && len(results) < limit; ok = iterator.Prev() {
+		buf := iterator.Value()
+		if len(buf) == 0 {
+			continue
+		}
+
+		var item X402Receipt
+		if err := json.Unmarshal(buf, &item); err != nil {
+			s.logger.Error().Err(err).Msg("fail to unmarshal x402 receipt")
+			continue
+		}
+
+		results = append(results, item)
+	}
+
+	return results
+}
+
+// Prune removes receipts created before the given time and returns how many
+// were removed
+func (s *X402ReceiptStore) Prune(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	iterator := s.db.NewIterator(util.BytesPrefix([]byte(x402ReceiptPrefix)), nil)
+	batch := new(leveldb.Batch)
+	for iterator.Next() {
+		var item X402Receipt
+		if err := json.Unmarshal(iterator.Value(), &item); err != nil || item.CreatedAt < before.Unix() {
+			batch.Delete(append([]byte(nil), iterator.Key()...))
+		}
+	}
+	iterator.Release()
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+	if err := s.db.Write(batch, nil); err != nil {
+		s.logger.Error().Err(err).Msg("fail to prune x402 receipts")
+		return 0, err
+	}
+	return batch.Len(), nil
+}
+
+// Close underlying db
+func (s *X402ReceiptStore) Close() error {
+	return s.db.Close()
+}
+
+// RecordReceipt stores the outcome of a payment for its payer
+func (h *X402Handler) RecordReceipt(service string, payment *X402Payment, receipt X402PaymentResponse, upstreamStatus int) {
+	if h.Receipts == nil {
+		return
+	}
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return
+	}
+	record := X402Receipt{
+		ID:             hex.EncodeToString(id),
+		Payer:          receipt.Payer,
+		Service:        service,
+		Status:         receipt.Status,
+		Network:        receipt.Network,
+		Asset:          payment.Requirements.Asset,
+		Amount:         receipt.Amount,
+		ComputeUnits:   receipt.ComputeUnits,
+		UpstreamStatus: upstreamStatus,
+		TxHash:         receipt.TxHash,
+		CreatedAt:      time.Now().Unix(),
+	}
+	// failures are logged by the store
+	_ = h.Receipts.Set(record)
+}
+
+// VoidPayment gives up the charge of a request that failed upstream. The
+// payment is released so it can pay for a retry. Payments whose funds already
+// moved cannot be undone, they are recorded as credit owed to the payer.
+func (h *X402Handler) VoidPayment(service string, payment *X402Payment, computeUnits uint64, upstreamStatus int) X402PaymentResponse {
+	h.ReleasePayment(payment)
+	receipt := X402PaymentResponse{
+		Success:      false,
+		Status:       X402StatusVoided,
+		ErrorReason:  X402ReasonUpstreamFailed,
+		Network:      payment.Requirements.Network,
+		Payer:        payment.Payer,
+		Amount:       "0",
+		ComputeUnits: computeUnits,
+	}
+	if payment.Prepaid {
+		receipt.Status = X402StatusCredited
+		receipt.Amount = payment.Requirements.Amount
+	}
+	h.RecordReceipt(service, payment, receipt, upstreamStatus)
+	return receipt
+}
+
+// handleX402Receipts lists the payment outcomes of a payer, ?limit=N bounds
+// the number of receipts returned
+func (p *Proxy) handleX402Receipts(w http.ResponseWriter, r *http.Request) {
+	if p.x402 == nil || p.x402.Receipts == nil {
+		http.Error(w, "x402 receipts not enabled", http.StatusNotFound)
+		return
+	}
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultX402ReceiptLimit
+	}
+	if limit > maxX402ReceiptLimit {
+		limit = maxX402ReceiptLimit
+	}
+
+	receipts := p.x402.Receipts.List(mux.Vars(r)["payer"], limit)
+	if receipts == nil {
+		receipts = []X402Receipt{}
+	}
+	p.x402.SetModeHeader(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(receipts)
+}
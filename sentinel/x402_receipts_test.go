@@ -0,0 +1,118 @@
+package sentinel
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+)
+
+func TestX402Receipts(t *testing.T) {
+	paymentStore, err := NewX402PaymentStore("")
+	require.NoError(t, err)
+	defer paymentStore.Close()
+	receipts, err := NewX402ReceiptStore("")
+	require.NoError(t, err)
+	defer receipts.Close()
+
+	handler := NewX402Handler(testPayTo)
+	handler.Verifier = NewLocalVerifier()
+	handler.Store = paymentStore
+	handler.Receipts = receipts
+	handler.Accepts = []PaymentRequirements{testRequirements()}
+	proxy := &Proxy{x402: handler, logger: log.NewNopLogger(), proxies: map[string]*url.URL{"eth": {}}}
+
+	status := http.StatusBadGateway
+	upstream := 0
+	serve := proxy.x402Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream++
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte("upstream"))
+	}))
+	send := func(path, payment string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-PAYMENT", payment)
+		rec := httptest.NewRecorder()
+		serve.ServeHTTP(rec, req)
+		return rec
+	}
+	receipt := func(rec *httptest.ResponseRecorder) X402PaymentResponse {
+		raw, err := base64.StdEncoding.DecodeString(rec.Header().Get(X402PaymentResponseHeader))
+		require.NoError(t, err)
+		var receipt X402PaymentResponse
+		require.NoError(t, json.Unmarshal(raw, &receipt))
+		return receipt
+	}
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	payer := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	payment := signTestPayment(t, key, testRequirements(), testAuthorization(key, "1000", time.Now()))
+
+	// unknown services are refused before the payment is looked at
+	rec := send("/x402/btc/", payment)
+	require.Equal(t, http.StatusNotFound, rec.Code)
+	require.Zero(t, upstream)
+
+	// an upstream failure is voided and the payment can be used again
+	rec = send("/x402/eth/", payment)
+	require.Equal(t, http.StatusBadGateway, rec.Code)
+	voided := receipt(rec)
+	require.False(t, voided.Success)
+	require.Equal(t, X402StatusVoided, voided.Status)
+	require.Equal(t, X402ReasonUpstreamFailed, voided.ErrorReason)
+
+	status = http.StatusOK
+	rec = send("/x402/eth/", payment)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "upstream", rec.Body.String())
+	settled := receipt(rec)
+	require.True(t, settled.Success)
+	require.Equal(t, X402StatusSettled, settled.Status)
+
+	// the payer sees both outcomes, most recent first
+	router := mux.NewRouter()
+	router.HandleFunc(RouteX402Receipts, proxy.handleX402Receipts).Methods(http.MethodGet)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x402/receipts/"+payer, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var listed []X402Receipt
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&listed))
+	require.Len(t, listed, 2)
+	statuses := []string{listed[0].Status, listed[1].Status}
+	require.ElementsMatch(t, []string{X402StatusSettled, X402StatusVoided}, statuses)
+	for _, item := range listed {
+		require.Equal(t, "eth", item.Service)
+		if item.Status == X402StatusVoided {
+			require.Equal(t, http.StatusBadGateway, item.UpstreamStatus)
+		}
+	}
+
+	// receipts expire
+	pruned, err := receipts.Prune(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, pruned)
+	require.Empty(t, receipts.List(payer, 10))
+}
+
+func TestX402VoidPayment_Prepaid(t *testing.T) {
+	handler := NewX402Handler(testPayTo)
+	payment := &X402Payment{Requirements: testArkeoRequirements(), Payer: testArkeoPayer, Prepaid: true}
+
+	receipt := handler.VoidPayment("eth", payment, 1, http.StatusGatewayTimeout)
+	require.Equal(t, X402StatusCredited, receipt.Status)
+	require.Equal(t, "850000", receipt.Amount)
+
+	payment.Prepaid = false
+	receipt = handler.VoidPayment("eth", payment, 1, http.StatusGatewayTimeout)
+	require.Equal(t, X402StatusVoided, receipt.Status)
+	require.Equal(t, "0", receipt.Amount)
+}
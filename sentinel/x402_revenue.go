@@ -0,0 +1,287 @@
+package sentinel
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"cosmossdk.io/math"
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/cosmos/cosmos-sdk/client"
+	clienttx "github.com/cosmos/cosmos-sdk/client/tx"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	"github.com/cosmos/cosmos-sdk/std"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/types/tx/signing"
+	authsigning "github.com/cosmos/cosmos-sdk/x/auth/signing"
+
+	"github.com/arkeonetwork/arkeo/app/params"
+	"github.com/arkeonetwork/arkeo/common"
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+	arkeotypes "github.com/arkeonetwork/arkeo/x/arkeo/types"
+)
+
+const (
+	defaultX402RevenueInterval = 3600
+	defaultX402RevenueFee      = "200uarkeo"
+	defaultX402RevenueGas      = 200000
+)
+
+// X402RevenueReporter reports the revenue accrued in the ledger on chain with
+// MsgReportX402Revenue, so the reserve tax applies to it and the directory
+// counts agent traffic. Requests are reported for every network, revenue only
+// for uarkeo on Arkeo networks, the only revenue the provider holds on chain.
+type X402RevenueReporter struct {
+	ledger   *X402LedgerStore
+	chain    *ArkeoVerifier
+	provider common.PubKey
+	privKey  *secp256k1.PrivKey
+	txConfig client.TxConfig
+	fee      sdk.Coins
+	gas      uint64
+	interval time.Duration
+	logger   log.Logger
+
+	// now is overridable for tests
+	now func() time.Time
+}
+
+// NewX402RevenueReporter creates a reporter signing with the key of mnemonic,
+// which must be the key of the provider
+func NewX402RevenueReporter(config conf.X402RevenueConfig, provider common.PubKey, ledger *X402LedgerStore, chain *ArkeoVerifier, logger log.Logger) (*X402RevenueReporter, error) {
+	if config.Mnemonic == "" {
+		return nil, fmt.Errorf("x402 revenue reports need the provider mnemonic")
+	}
+	if config.ReportInterval == 0 {
+		config.ReportInterval = defaultX402RevenueInterval
+	}
+	if config.Fee == "" {
+		config.Fee = defaultX402RevenueFee
+	}
+	if config.Gas == 0 {
+		config.Gas = defaultX402RevenueGas
+	}
+	fee, err := sdk.ParseCoinsNormalized(config.Fee)
+	if err != nil {
+		return nil, fmt.Errorf("invalid x402 revenue fee %q: %w", config.Fee, err)
+	}
+
+	derivedPriv, err := hd.Secp256k1.Derive()(config.Mnemonic, "", hd.NewFundraiserParams(0, 118, 0).String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive private key: %w", err)
+	}
+	privKey := hd.Secp256k1.Generate()(derivedPriv).(*secp256k1.PrivKey)
+	pubKey, err := common.NewPubKeyFromCrypto(privKey.PubKey())
+	if err != nil {
+		return nil, err
+	}
+	if !pubKey.Equals(provider) {
+		return nil, fmt.Errorf("x402 revenue mnemonic is not the key of provider %s", provider)
+	}
+
+	encodingConfig := params.MakeEncodingConfig()
+	std.RegisterInterfaces(encodingConfig.InterfaceRegistry)
+	arkeotypes.RegisterInterfaces(encodingConfig.InterfaceRegistry)
+
+	return &X402RevenueReporter{
+		ledger:   ledger,
+		chain:    chain,
+		provider: provider,
+		privKey:  privKey,
+		txConfig: encodingConfig.TxConfig,
+		fee:      fee,
+		gas:      config.Gas,
+		interval: time.Duration(config.ReportInterval) * time.Second,
+		logger:   logger,
+		now:      time.Now,
+	}, nil
+}
+
+// Run reports the ledger every interval until ctx is done
+func (r *X402RevenueReporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			hash, reported, err := r.Report(ctx)
+			if err != nil {
+				r.logger.Error("failed to report x402 revenue", "error", err)
+			} else if reported > 0 {
+				r.logger.Info("reported x402 revenue", "services", reported, "txhash", hash)
+			}
+		}
+	}
+}
+
+// Report sends the ledger on chain in one tx and debits it once the tx is
+// included. It returns the tx hash and the number of services reported.
+func (r *X402RevenueReporter) Report(ctx context.Context) (string, int, error) {
+	entries := r.ledger.List()
+	msgs, reported := r.buildMsgs(entries, r.now().Unix())
+	if len(msgs) == 0 {
+		return "", 0, nil
+	}
+
+	txBytes, err := r.signTx(ctx, msgs)
+	if err != nil {
+		return "", 0, err
+	}
+	result, err := r.chain.broadcastTx(txBytes)
+	if err != nil {
+		return "", 0, err
+	}
+	if result.Code != 0 {
+		return result.TxHash, 0, fmt.Errorf("report tx rejected with code %d: %s", result.Code, result.RawLog)
+	}
+	result, err = r.chain.waitForTx(result.TxHash)
+	if err != nil {
+		return result.TxHash, 0, err
+	}
+	if result.Code != 0 {
+		return result.TxHash, 0, fmt.Errorf("report tx %s failed with code %d: %s", result.TxHash, result.Code, result.RawLog)
+	}
+
+	if err := r.ledger.Debit(reported); err != nil {
+		return result.TxHash, 0, fmt.Errorf("failed to debit reported x402 revenue: %w", err)
+	}
+	return result.TxHash, len(msgs), nil
+}
+
+// buildMsgs aggregates the ledger per service. It returns the messages and
+// the entries they cover.
+func (r *X402RevenueReporter) buildMsgs(entries []X402LedgerEntry, periodEnd int64) ([]sdk.Msg, []X402LedgerEntry) {
+	addr, err := r.provider.GetMyAddress()
+	if err != nil {
+		r.logger.Error("failed to get provider address", "error", err)
+		return nil, nil
+	}
+
+	type serviceRevenue struct {
+		requests int64
+		revenue  *big.Int
+		entries  []X402LedgerEntry
+	}
+	var services []string
+	byService := make(map[string]*serviceRevenue)
+	for _, entry := range entries {
+		if entry.Service == "" {
+			continue
+		}
+		sr, ok := byService[entry.Service]
+		if !ok {
+			sr = &serviceRevenue{revenue: new(big.Int)}
+			byService[entry.Service] = sr
+			services = append(services, entry.Service)
+		}
+		sr.requests += entry.Requests
+		sr.entries = append(sr.entries, entry)
+		if strings.HasPrefix(entry.Network, "arkeo:") && entry.Asset == arkeoAssetPreset.Asset {
+			if amount, ok := new(big.Int).SetString(entry.Amount, 10); ok {
+				sr.revenue.Add(sr.revenue, amount)
+			}
+		}
+	}
+
+	var msgs []sdk.Msg
+	var reported []X402LedgerEntry
+	for _, service := range services {
+		sr := byService[service]
+		if sr.requests <= 0 && sr.revenue.Sign() == 0 {
+			continue
+		}
+		revenue := sdk.NewCoin(arkeoAssetPreset.Asset, math.NewIntFromBigInt(sr.revenue))
+		msgs = append(msgs, arkeotypes.NewMsgReportX402Revenue(addr, r.provider, service, sr.requests, revenue, periodEnd))
+		reported = append(reported, sr.entries...)
+	}
+	return msgs, reported
+}
+
+// signTx builds and signs a tx of msgs with the provider key, returning the
+// base64 tx bytes
+func (r *X402RevenueReporter) signTx(ctx context.Context, msgs []sdk.Msg) (string, error) {
+	chainID, err := r.chain.getChainID()
+	if err != nil {
+		return "", err
+	}
+	accountNumber, sequence, err := r.getAccount()
+	if err != nil {
+		return "", err
+	}
+
+	builder := r.txConfig.NewTxBuilder()
+	if err := builder.SetMsgs(msgs...); err != nil {
+		return "", fmt.Errorf("failed to set report msgs: %w", err)
+	}
+	builder.SetGasLimit(r.gas)
+	builder.SetFeeAmount(r.fee)
+
+	// the signer info must be set before the sign bytes are built
+	signMode := signing.SignMode_SIGN_MODE_DIRECT
+	sig := signing.SignatureV2{
+		PubKey:   r.privKey.PubKey(),
+		Data:     &signing.SingleSignatureData{SignMode: signMode},
+		Sequence: sequence,
+	}
+	if err := builder.SetSignatures(sig); err != nil {
+		return "", fmt.Errorf("failed to set signer info: %w", err)
+	}
+	signerData := authsigning.SignerData{
+		Address:       sdk.AccAddress(r.privKey.PubKey().Address()).String(),
+		ChainID:       chainID,
+		AccountNumber: accountNumber,
+		Sequence:      sequence,
+		PubKey:        r.privKey.PubKey(),
+	}
+	sig, err = clienttx.SignWithPrivKey(ctx, signMode, signerData, builder, r.privKey, r.txConfig, sequence)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign report tx: %w", err)
+	}
+	if err := builder.SetSignatures(sig); err != nil {
+		return "", fmt.Errorf("failed to set signature: %w", err)
+	}
+
+	txBytes, err := r.txConfig.TxEncoder()(builder.GetTx())
+	if err != nil {
+		return "", fmt.Errorf("failed to encode report tx: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(txBytes), nil
+}
+
+// getAccount returns the account number and sequence of the provider
+func (r *X402RevenueReporter) getAccount() (uint64, uint64, error) {
+	addr, err := r.provider.GetMyAddress()
+	if err != nil {
+		return 0, 0, err
+	}
+	var res struct {
+		Info struct {
+			AccountNumber string `json:"account_number"`
+			Sequence      string `json:"sequence"`
+		} `json:"info"`
+	}
+	status, err := r.chain.do(http.MethodGet, "/cosmos/auth/v1beta1/account_info/"+addr.String(), nil, &res)
+	if err != nil {
+		return 0, 0, err
+	}
+	if status != http.StatusOK {
+		return 0, 0, fmt.Errorf("fail to get account %s, status %d", addr, status)
+	}
+	accountNumber, err := strconv.ParseUint(res.Info.AccountNumber, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid account number %q: %w", res.Info.AccountNumber, err)
+	}
+	sequence, err := strconv.ParseUint(defaultString(res.Info.Sequence, "0"), 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid account sequence %q: %w", res.Info.Sequence, err)
+	}
+	return accountNumber, sequence, nil
+}
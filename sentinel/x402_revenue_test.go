@@ -0,0 +1,87 @@
+package sentinel
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keys/secp256k1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/common"
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+	arkeotypes "github.com/arkeonetwork/arkeo/x/arkeo/types"
+)
+
+func testRevenueProvider(t *testing.T) common.PubKey {
+	t.Helper()
+	derivedPriv, err := hd.Secp256k1.Derive()(testMnemonic, "", hd.NewFundraiserParams(0, 118, 0).String())
+	require.NoError(t, err)
+	privKey := hd.Secp256k1.Generate()(derivedPriv).(*secp256k1.PrivKey)
+	pubKey, err := common.NewPubKeyFromCrypto(privKey.PubKey())
+	require.NoError(t, err)
+	return pubKey
+}
+
+func TestX402RevenueReporter(t *testing.T) {
+	node := &testArkeoNode{txs: map[string]map[string]interface{}{}}
+	server := httptest.NewServer(node)
+	defer server.Close()
+	chain := NewArkeoVerifier(server.URL, nil)
+	chain.pollInterval = time.Millisecond
+
+	ledger, err := NewX402LedgerStore("")
+	require.NoError(t, err)
+	defer ledger.Close()
+
+	provider := testRevenueProvider(t)
+	config := conf.X402RevenueConfig{Enabled: true, Mnemonic: testMnemonic}
+	// the mnemonic must be the key of the provider
+	otherKey := conf.X402RevenueConfig{Enabled: true, Mnemonic: strings.Repeat("abandon ", 11) + "about"}
+	_, err = NewX402RevenueReporter(otherKey, provider, ledger, chain, log.NewNopLogger())
+	require.Error(t, err)
+	reporter, err := NewX402RevenueReporter(config, provider, ledger, chain, log.NewNopLogger())
+	require.NoError(t, err)
+
+	// nothing to report
+	_, reported, err := reporter.Report(context.Background())
+	require.NoError(t, err)
+	require.Zero(t, reported)
+	require.Zero(t, node.broadcast)
+
+	require.NoError(t, ledger.Credit("eth-mainnet-fullnode", "arkeo:arkeo-testnet-1", "uarkeo", "1000", 2))
+	require.NoError(t, ledger.Credit("eth-mainnet-fullnode", "eip155:8453", "0xusdc", "5000", 3))
+	require.NoError(t, ledger.Credit("btc-mainnet-fullnode", "eip155:8453", "0xusdc", "5000", 1))
+
+	hash, reported, err := reporter.Report(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 2, reported)
+	require.NotEmpty(t, hash)
+	require.Equal(t, 1, node.broadcast)
+	require.Empty(t, ledger.List())
+
+	// requests count on every network, revenue only in uarkeo
+	txBytes, err := base64.StdEncoding.DecodeString(node.lastTx)
+	require.NoError(t, err)
+	tx, err := reporter.txConfig.TxDecoder()(txBytes)
+	require.NoError(t, err)
+	msgs := tx.GetMsgs()
+	require.Len(t, msgs, 2)
+	btc := msgs[0].(*arkeotypes.MsgReportX402Revenue)
+	require.Equal(t, "btc-mainnet-fullnode", btc.Service)
+	require.Equal(t, int64(1), btc.Requests)
+	require.True(t, btc.Revenue.IsZero())
+	eth := msgs[1].(*arkeotypes.MsgReportX402Revenue)
+	require.Equal(t, "eth-mainnet-fullnode", eth.Service)
+	require.Equal(t, int64(5), eth.Requests)
+	require.Equal(t, "1000uarkeo", eth.Revenue.String())
+	require.True(t, eth.Provider.Equals(provider))
+	for _, msg := range msgs {
+		require.NoError(t, msg.(*arkeotypes.MsgReportX402Revenue).ValidateBasic())
+	}
+}
@@ -1,13 +1,19 @@
 package sentinel
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
 )
 
 // X402 Route Constants
@@ -19,13 +25,173 @@ const (
 	RouteX402RPC = "/x402/{service}/{path:.*}"
 )
 
-// x402Handler is the handler instance for the proxy
-var x402Handler *X402Handler
+// x402 payment verifier names
+const (
+	X402VerifierFacilitator = "facilitator"
+	X402VerifierLocal       = "local"
+)
+
+// InitX402 initializes the x402 payment handler for the configured mode. In
+// production mode a missing verifier or payment address is a fatal error.
+func (p *Proxy) InitX402() error {
+	mode := p.Config.GetX402Mode()
+	handler := NewX402Handler(p.Config.X402ProviderAddress)
+	if !p.Config.ProviderPubKey.IsEmpty() {
+		addr, err := p.Config.ProviderPubKey.GetMyAddress()
+		if err != nil {
+			return fmt.Errorf("failed to get provider address: %w", err)
+		}
+		handler.ArkeoAddress = addr.String()
+	}
+
+	switch mode {
+	case conf.X402ModeOff:
+		return nil
+	case conf.X402ModeDev:
+		handler.Verifier = DevVerifier{}
+		handler.DevMode = true
+		p.logger.Error("x402 DEV MODE: payments are NOT verified, never expose this sentinel publicly")
+	case conf.X402ModeProduction:
+		if p.Config.X402ProviderAddress == "" && len(p.Config.X402Accepts) == 0 {
+			return fmt.Errorf("x402 production mode requires x402_provider_address")
+		}
+		verifier, err := p.newX402Verifier()
+		if err != nil {
+			return fmt.Errorf("x402 production mode requires a payment verifier: %w", err)
+		}
+		handler.Verifier = NetworkVerifier{EVM: verifier, Arkeo: p.newX402ArkeoVerifier()}
+	default:
+		return fmt.Errorf("unknown x402 mode %q", mode)
+	}
+
+	if p.Config.X402PriceUSDC != "" {
+		handler.PricePerRequestUSDC = p.Config.X402PriceUSDC
+	}
+	if p.Config.X402PriceARKEO != "" {
+		handler.PricePerRequestARKEO = p.Config.X402PriceARKEO
+	}
+	handler.Pricing = NewX402Pricing(p.Config.X402Pricing)
+	handler.WebSocket = p.Config.X402WebSocket
+	limits, err := NewX402Limits(p.Config.X402Limits)
+	if err != nil {
+		return err
+	}
+	handler.Limits = limits
+	if len(p.Config.X402Accepts) > 0 {
+		accepts, err := resolveX402Accepts(p.Config.X402Accepts, p.Config.X402ProviderAddress, handler.ArkeoAddress)
+		if err != nil {
+			return err
+		}
+		handler.Accepts = accepts
+	}
+
+	// tx hashes stay valid for a while, a replay record lost in a restart
+	// would let the same hash pay again
+	if network, ok := handler.arkeoNetwork(); ok && p.Config.X402PaymentStoreLocation == "" {
+		return fmt.Errorf("x402 payments on %s require x402_payment_store_location", network)
+	}
+	store, err := NewX402PaymentStore(p.Config.X402PaymentStoreLocation)
+	if err != nil {
+		return fmt.Errorf("failed to create x402 payment store: %w", err)
+	}
+	handler.Store = store
+	if strings.EqualFold(p.Config.X402Verifier, X402VerifierLocal) && p.Config.X402PaymentStoreLocation == "" {
+		p.logger.Error("x402 local verifier without x402_payment_store_location, uncollected payments are lost on restart")
+	}
+
+	receipts, err := NewX402ReceiptStore(p.Config.X402ReceiptStoreLocation)
+	if err != nil {
+		return fmt.Errorf("failed to create x402 receipt store: %w", err)
+	}
+	handler.Receipts = receipts
+
+	spend, err := NewX402SpendStore(p.Config.GetX402SpendStoreLocation())
+	if err != nil {
+		return fmt.Errorf("failed to create x402 spend store: %w", err)
+	}
+	handler.Spend = spend
+	handler.SpendCaps = p.Config.X402Spend.Caps
 
-// InitX402 initializes the x402 payment handler
-func (p *Proxy) InitX402(providerAddress string) {
-	x402Handler = NewX402Handler(providerAddress)
-	p.logger.Info("x402 payment handler initialized")
+	if p.Config.X402Sessions.Enabled {
+		sessionStore, err := NewX402SessionStore(p.Config.X402Sessions.StoreLocation)
+		if err != nil {
+			return fmt.Errorf("failed to create x402 session store: %w", err)
+		}
+		handler.Sessions, err = NewX402Sessions(p.Config.X402Sessions, sessionStore)
+		if err != nil {
+			return fmt.Errorf("failed to create x402 sessions: %w", err)
+		}
+	}
+
+	if p.Config.X402Revenue.Enabled {
+		if handler.DevMode {
+			return fmt.Errorf("x402 revenue cannot be reported in dev mode")
+		}
+		if err := p.initX402Revenue(handler); err != nil {
+			return err
+		}
+	}
+
+	p.x402 = handler
+	p.logger.Info("x402 payment handler initialized", "mode", mode, "verifier", p.Config.X402Verifier)
+	return nil
+}
+
+// newX402Verifier creates the payment verifier selected in the configuration
+func (p *Proxy) newX402Verifier() (PaymentVerifier, error) {
+	switch strings.ToLower(p.Config.X402Verifier) {
+	case "", X402VerifierFacilitator:
+		facilitator, err := NewX402Facilitator()
+		if err != nil {
+			return nil, err
+		}
+		if p.Config.X402FacilitatorURL != "" {
+			facilitator.FacilitatorURL = p.Config.X402FacilitatorURL
+		}
+		return facilitator, nil
+	case X402VerifierLocal:
+		return NewLocalVerifier(), nil
+	default:
+		return nil, fmt.Errorf("unknown x402 verifier %q", p.Config.X402Verifier)
+	}
+}
+
+// newX402ArkeoVerifier creates the verifier of native ARKEO payments, it
+// queries the same chain REST API as the contract store
+func (p *Proxy) newX402ArkeoVerifier() PaymentVerifier {
+	baseURL := p.Config.HubProviderURI
+	if baseURL == "" {
+		baseURL = p.Config.SourceChain
+	}
+	if baseURL == "" {
+		p.logger.Info("no arkeo REST endpoint configured, native ARKEO x402 payments are disabled")
+		return nil
+	}
+	return NewArkeoVerifier(baseURL, p.authManager)
+}
+
+// initX402Revenue credits settled payments to the ledger and creates the
+// reporter sending it on chain
+func (p *Proxy) initX402Revenue(handler *X402Handler) error {
+	baseURL := p.Config.HubProviderURI
+	if baseURL == "" {
+		baseURL = p.Config.SourceChain
+	}
+	if baseURL == "" {
+		return fmt.Errorf("x402 revenue reports need an arkeo REST endpoint")
+	}
+	ledger, err := NewX402LedgerStore(p.Config.X402Revenue.StoreLocation)
+	if err != nil {
+		return fmt.Errorf("failed to create x402 ledger store: %w", err)
+	}
+	reporter, err := NewX402RevenueReporter(p.Config.X402Revenue, p.Config.ProviderPubKey, ledger, NewArkeoVerifier(baseURL, p.authManager), p.logger.With("module", LogModuleX402))
+	if err != nil {
+		_ = ledger.Close()
+		return fmt.Errorf("failed to create x402 revenue reporter: %w", err)
+	}
+	handler.Ledger = ledger
+	p.x402Revenue = reporter
+	return nil
 }
 
 // RegisterX402Routes adds x402-specific routes to the router
@@ -33,12 +199,76 @@ func (p *Proxy) RegisterX402Routes(router *mux.Router) {
 	// Payment requirements endpoint - agents query this to know how to pay
 	router.HandleFunc(RouteX402Requirements, p.handleX402Requirements).Methods(http.MethodGet)
 	
+	// Prepaid sessions - agents pay once for a bundle of requests or time
+	router.HandleFunc(RouteX402Session, p.handleX402Session).Methods(http.MethodPost)
+
+	// Discovery catalog of every paid service
+	router.HandleFunc(RouteX402WellKnown, p.handleX402Catalog).Methods(http.MethodGet)
+	router.HandleFunc(RouteX402Catalog, p.handleX402Catalog).Methods(http.MethodGet)
+	router.HandleFunc(RouteX402OpenAPI, p.handleX402OpenAPI).Methods(http.MethodGet)
+
+	// Payment outcomes of a payer, including voided and credited payments
+	router.HandleFunc(RouteX402Receipts, p.handleX402Receipts).Methods(http.MethodGet)
+
+	// Spend of a payer today and this month
+	router.HandleFunc(RouteX402Spend, p.handleX402Spend).Methods(http.MethodGet)
+
 	// x402-enabled RPC proxy - checks payment before routing
-	router.PathPrefix("/x402/").Handler(p.x402Middleware(http.HandlerFunc(p.handleX402Proxy)))
+	router.PathPrefix("/x402/").Handler(p.instrument(TierX402, x402ServiceName,
+		p.inspectJSONRPC(x402ServiceName, p.x402Middleware(http.HandlerFunc(p.handleX402Proxy)))))
 	
 	p.logger.Info("x402 routes registered")
 }
 
+// pruneX402Payments periodically drops expired payments from the replay store,
+// old receipts and ended sessions
+func (p *Proxy) pruneX402Payments(ctx context.Context) {
+	if p.x402 == nil || p.x402.Store == nil {
+		return
+	}
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pruned, err := p.x402.Store.Prune(time.Now())
+			if err != nil {
+				p.logger.Error("failed to prune x402 payments", "error", err)
+			} else if pruned > 0 {
+				p.logger.Info("pruned expired x402 payments", "count", pruned)
+			}
+			if p.x402.Receipts != nil {
+				pruned, err = p.x402.Receipts.Prune(time.Now().Add(-X402ReceiptRetention))
+				if err != nil {
+					p.logger.Error("failed to prune x402 receipts", "error", err)
+				} else if pruned > 0 {
+					p.logger.Info("pruned old x402 receipts", "count", pruned)
+				}
+			}
+			if p.x402.Spend != nil {
+				pruned, err = p.x402.Spend.Prune(time.Now().Add(-X402ReceiptRetention))
+				if err != nil {
+					p.logger.Error("failed to prune x402 spend", "error", err)
+				} else if pruned > 0 {
+					p.logger.Info("pruned old x402 spend", "count", pruned)
+				}
+			}
+			p.x402.Limits.Prune(time.Now())
+			if p.x402.Sessions == nil {
+				continue
+			}
+			pruned, err = p.x402.Sessions.Prune(time.Now())
+			if err != nil {
+				p.logger.Error("failed to prune x402 sessions", "error", err)
+			} else if pruned > 0 {
+				p.logger.Info("pruned ended x402 sessions", "count", pruned)
+			}
+		}
+	}
+}
+
 // handleX402Requirements returns the payment requirements for a service
 func (p *Proxy) handleX402Requirements(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
@@ -60,13 +290,17 @@ func (p *Proxy) handleX402Requirements(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Return payment requirements
-	if x402Handler == nil {
+	if p.x402 == nil {
 		http.Error(w, "x402 not initialized", http.StatusInternalServerError)
 		return
 	}
 	
-	requirements := x402Handler.BuildPaymentRequirements(service, r.URL.String())
+	requirements := p.x402.BuildPaymentRequirements(service, r.URL.String(), p.x402.Pricing.DefaultUnits(service))
+	if pricing, ok := p.x402.Pricing.Service(service); ok {
+		requirements.Extensions["pricing"] = pricing
+	}
 	
+	p.x402.SetModeHeader(w)
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("X-X402-Version", "2")
 	json.NewEncoder(w).Encode(requirements)
@@ -75,10 +309,11 @@ func (p *Proxy) handleX402Requirements(w http.ResponseWriter, r *http.Request) {
 // x402Middleware checks for valid payment before allowing access
 func (p *Proxy) x402Middleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if x402Handler == nil {
+		if p.x402 == nil {
 			http.Error(w, "x402 not initialized", http.StatusInternalServerError)
 			return
 		}
+		p.x402.SetModeHeader(w)
 		
 		// Extract service from path: /x402/{service}/...
 		pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
@@ -87,49 +322,83 @@ func (p *Proxy) x402Middleware(next http.Handler) http.Handler {
 			service = pathParts[1]
 		}
 		
+		// Unknown services are refused before any payment is taken
+		p.proxyMu.RLock()
+		target, exists := p.proxies[service]
+		p.proxyMu.RUnlock()
+		if !exists || target == nil {
+			http.Error(w, "service not found", http.StatusNotFound)
+			return
+		}
+
+		// Limits apply before any work, 402 quotes included
+		if err := p.x402.Limits.AllowIP(p.x402.Limits.ClientIP(r)); err != nil {
+			p.logger.Info("x402 request refused", "service", service, "error", err)
+			p.x402.WriteLimitError(w, asX402LimitError(err))
+			return
+		}
+
+		// WebSocket upgrades buy a budget of frames or time
+		if websocket.IsWebSocketUpgrade(r) {
+			p.serveX402WebSocket(w, r, service)
+			return
+		}
+
+		// Price the request before anything else so the quote matches the charge
+		computeUnits := p.x402.Pricing.ComputeUnits(service, r)
+
+		// Prepaid sessions are spent locally, without settlement
+		if p.x402.Sessions != nil {
+			if token := p.x402.Sessions.TokenFromRequest(r); token != "" {
+				session, err := p.x402.ServeSession(w, r, next, service, token, computeUnits)
+				if err != nil {
+					p.logger.Error("x402 session rejected", "service", service, "reason", X402ErrorReason(err), "error", err)
+				} else {
+					p.logger.Debug("x402 session spent", "service", service, "session", session.ID, "compute_units", computeUnits, "remaining", session.Remaining)
+				}
+				return
+			}
+		}
+
 		// Check for payment header
-		hasPayment, paymentPayload := x402Handler.CheckPaymentHeader(r)
+		hasPayment, paymentPayload := p.x402.CheckPaymentHeader(r)
 		
 		if !hasPayment {
 			// Return 402 Payment Required
-			x402Handler.WritePaymentRequired(w, service, r.URL.String())
+			p.x402.WritePaymentRequired(w, service, r.URL.String(), computeUnits)
 			return
 		}
 		
-		// Verify payment
-		verified, settlementID, err := x402Handler.VerifyPayment(paymentPayload)
+		// Verify, serve and settle the payment
+		receipt, err := p.x402.ServePayment(w, r, next, service, paymentPayload, computeUnits)
 		if err != nil {
-			p.logger.Error("x402 payment verification failed", "error", err)
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusUnauthorized)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error":   "payment verification failed",
-				"details": err.Error(),
-			})
+			p.logger.Error("x402 payment failed",
+				"service", service,
+				"reason", receipt.ErrorReason,
+				"error", err,
+			)
 			return
 		}
 		
-		if !verified {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusPaymentRequired)
-			json.NewEncoder(w).Encode(map[string]string{
-				"error": "payment not verified",
-			})
+		if receipt.Status != X402StatusSettled {
+			p.logger.Info("x402 payment not charged, upstream failed",
+				"service", service,
+				"network", receipt.Network,
+				"payer", receipt.Payer,
+				"status", receipt.Status,
+			)
 			return
 		}
 		
-		// Payment verified - add settlement ID to response headers
-		w.Header().Set("X-Settlement-ID", settlementID)
-		w.Header().Set("X-Payment-Status", "verified")
-		
 		// Log successful payment
-		p.logger.Info("x402 payment verified", 
+		p.logger.Info("x402 payment settled",
 			"service", service,
-			"settlement_id", settlementID,
+			"network", receipt.Network,
+			"payer", receipt.Payer,
+			"compute_units", receipt.ComputeUnits,
+			"amount", receipt.Amount,
+			"tx_hash", receipt.TxHash,
 		)
-		
-		// Proceed to actual handler
-		next.ServeHTTP(w, r)
 	})
 }
 
@@ -151,47 +420,80 @@ func (p *Proxy) handleX402Proxy(w http.ResponseWriter, r *http.Request) {
 		remainingPath = "/" + strings.Join(pathParts[2:], "/")
 	}
 	
-	// Look up the proxy for this service
-	p.proxyMu.RLock()
-	targetURL, exists := p.proxies[service]
-	p.proxyMu.RUnlock()
-	
-	if !exists || targetURL == nil {
+	// Look up the upstreams of this service
+	pool := p.upstreamPool(service)
+	if pool == nil {
 		http.Error(w, "service not found", http.StatusNotFound)
 		return
 	}
 	
 	// Modify the request to proxy to the actual service
 	r.URL.Path = remainingPath
-	r.URL.Host = targetURL.Host
-	r.URL.Scheme = targetURL.Scheme
-	r.Host = targetURL.Host
 	
 	// Use the existing proxy logic
-	p.handleHTTPWithURL(w, r, targetURL)
+	p.handleHTTPWithUpstreams(w, r, service, pool)
 }
 
-// handleHTTPWithURL proxies the request to the given URL
-// This wraps the existing proxy functionality
-func (p *Proxy) handleHTTPWithURL(w http.ResponseWriter, r *http.Request, target *url.URL) {
-	// Create a reverse proxy
+// handleHTTPWithUpstreams proxies the request to the upstreams of a service,
+// the request path is appended to the upstream path
+func (p *Proxy) handleHTTPWithUpstreams(w http.ResponseWriter, r *http.Request, service string, pool *UpstreamPool) {
+	// Limit the size and duration of the request, as on the free tier
+	svc, _ := p.serviceConfig(service)
+	r, cancel := p.limitRequest(w, r, svc)
+	defer cancel()
+
+	// Create a reverse proxy, streamed answers are flushed as they come
 	proxy := &httputil.ReverseProxy{
-		Director: func(req *http.Request) {
-			req.URL.Scheme = target.Scheme
-			req.URL.Host = target.Host
+		Director: func(req *http.Request) {},
+		Transport: pool.Transport(func(req *http.Request, target *url.URL) {
+			setUpstreamURL(req, target)
+			req.URL.Path = joinUpstreamPath(target.Path, r.URL.Path)
+			req.URL.RawPath = ""
 			req.Host = target.Host
-			
-			// Preserve the path from the original request
-			if r.URL.Path != "" {
-				req.URL.Path = r.URL.Path
-			}
-			
-			// Copy headers
-			if r.Header.Get("Content-Type") != "" {
-				req.Header.Set("Content-Type", r.Header.Get("Content-Type"))
-			}
-		},
+		}),
+		FlushInterval: flushInterval(svc),
+	}
+	proxy.ErrorHandler = func(rw http.ResponseWriter, req *http.Request, err error) {
+		p.logger.Error("proxy error", "service", service, "error", err)
+		http.Error(rw, "Proxy error: "+err.Error(), proxyErrorStatus(err))
 	}
 	
-	proxy.ServeHTTP(w, r)
+	p.serveCached(w, r, pool, r.URL.Path, proxy)
+}
+
+// handleX402Uncollected lists the payments accepted by the local verifier
+// whose signed authorization the provider still has to submit on chain
+func (p *Proxy) handleX402Uncollected(w http.ResponseWriter, r *http.Request) {
+	if p.x402 == nil || p.x402.Store == nil {
+		respondWithError(w, "x402 is disabled", http.StatusNotFound)
+		return
+	}
+	uncollected := p.x402.Store.Uncollected()
+	if uncollected == nil {
+		uncollected = []X402PaymentRecord{}
+	}
+	respondWithJSON(w, http.StatusOK, uncollected)
+}
+
+// handleX402Collected marks the payments of {"keys": [...]} as submitted on
+// chain, so they are no longer listed as uncollected
+func (p *Proxy) handleX402Collected(w http.ResponseWriter, r *http.Request) {
+	if p.x402 == nil || p.x402.Store == nil {
+		respondWithError(w, "x402 is disabled", http.StatusNotFound)
+		return
+	}
+	var request struct {
+		Keys []string `json:"keys"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, defaultMaxBodySize)).Decode(&request); err != nil || len(request.Keys) == 0 {
+		respondWithError(w, "expected {\"keys\": [...]}", http.StatusBadRequest)
+		return
+	}
+	for _, key := range request.Keys {
+		if err := p.x402.Store.MarkCollected(key); err != nil {
+			respondWithError(w, fmt.Sprintf("failed to mark %s collected: %s", key, err), http.StatusBadRequest)
+			return
+		}
+	}
+	respondWithJSON(w, http.StatusOK, map[string]int{"collected": len(request.Keys)})
 }
@@ -0,0 +1,213 @@
+package sentinel
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+const (
+	x402SessionPrefix    = "session/"
+	x402SessionSecretKey = "secret"
+)
+
+var (
+	// ErrX402SessionExpired is returned when a session is used after it ended
+	ErrX402SessionExpired = errors.New("x402 session expired")
+
+	// ErrX402SessionExhausted is returned when a session has too few compute
+	// units left for a request
+	ErrX402SessionExhausted = errors.New("x402 session exhausted")
+)
+
+// X402SessionStore keeps the balance of prepaid x402 sessions
+type X402SessionStore struct {
+	logger zerolog.Logger
+	db     *leveldb.DB
+	mu     sync.Mutex
+}
+
+// X402Session is a prepaid bundle of compute units, or of time when TimeBased
+type X402Session struct {
+	ID           string `json:"id"`
+	Service      string `json:"service"`
+	Payer        string `json:"payer"`
+	ComputeUnits uint64 `json:"compute_units"`
+	Remaining    uint64 `json:"remaining"`
+	TimeBased    bool   `json:"time_based"`
+	SettlementID string `json:"settlement_id"`
+	CreatedAt    int64  `json:"created_at"`
+	ExpiresAt    int64  `json:"expires_at"`
+}
+
+func (s X402Session) Key() string {
+	return x402SessionPrefix + s.ID
+}
+
+func NewX402SessionStore(levelDbFolder string) (*X402SessionStore, error) {
+	var db *leveldb.DB
+	var err error
+	if len(levelDbFolder) == 0 {
+		log.Warn().Msg("x402 session store folder is empty, create in memory storage")
+		// no directory given, use in memory store
+		storage := storage.NewMemStorage()
+		db, err = leveldb.Open(storage, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fail to in memory open level db: %w", err)
+		}
+	} else {
+		db, err = leveldb.OpenFile(levelDbFolder, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fail to open level db %s: %w", levelDbFolder, err)
+		}
+	}
+	return &X402SessionStore{
+		logger: log.With().Str("module", "x402-session-storage").Logger(),
+		db:     db,
+	}, nil
+}
+
+// Secret returns the token signing key kept in the store, it is generated on
+// first use so tokens stay valid across restarts
+func (s *X402SessionStore) Secret() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, err := s.db.Get([]byte(x402SessionSecretKey), nil)
+	if err == nil {
+		return secret, nil
+	}
+	if !errors.Is(err, leveldb.ErrNotFound) {
+		s.logger.Error().Err(err).Msg("fail to get x402 session secret")
+		return nil, err
+	}
+
+	secret = make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("fail to generate x402 session secret: %w", err)
+	}
+	if err := s.db.Put([]byte(x402SessionSecretKey), secret, nil); err != nil {
+		s.logger.Error().Err(err).Msg("fail to set x402 session secret")
+		return nil, err
+	}
+	return secret, nil
+}
+
+func (s *X402SessionStore) Set(session X402Session) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.set(session)
+}
+
+func (s *X402SessionStore) Get(id string) (X402Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(id)
+}
+
+// Spend takes the compute units of a request from the session. Time based
+// sessions only check that the session has not ended.
+func (s *X402SessionStore) Spend(id string, units uint64, now time.Time) (X402Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, err := s.get(id)
+	if err != nil {
+		return session, err
+	}
+	if now.Unix() >= session.ExpiresAt {
+		return session, ErrX402SessionExpired
+	}
+	if session.TimeBased {
+		return session, nil
+	}
+	if session.Remaining < units {
+		return session, ErrX402SessionExhausted
+	}
+	session.Remaining -= units
+	return session, s.set(session)
+}
+
+// List returns every stored session
+func (s *X402SessionStore) List() []X402Session {
+	iterator := s.db.NewIterator(util.BytesPrefix([]byte(x402SessionPrefix)), nil)
+	defer iterator.Release()
+	var results []X402Session
+	for iterator.Next() {
+		buf := iterator.Value()
+		if len(buf) == 0 {
+			continue
+		}
+
+		var item X402Session
+		if err := json.Unmarshal(buf, &item); err != nil {
+			s.logger.Error().Err(err).Msg("fail to unmarshal x402 session")
+			continue
+		}
+
+		results = append(results, item)
+	}
+
+	return results
+}
+
+// Prune removes sessions that ended before now and returns how many were removed
+func (s *X402SessionStore) Prune(now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batch := new(leveldb.Batch)
+	for _, session := range s.List() {
+		if session.ExpiresAt < now.Unix() {
+			batch.Delete([]byte(session.Key()))
+		}
+	}
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+	if err := s.db.Write(batch, nil); err != nil {
+		s.logger.Error().Err(err).Msg("fail to prune x402 sessions")
+		return 0, err
+	}
+	return batch.Len(), nil
+}
+
+// Close underlying db
+func (s *X402SessionStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *X402SessionStore) get(id string) (session X402Session, err error) {
+	buf, err := s.db.Get([]byte(x402SessionPrefix+id), nil)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("fail to get x402 session")
+		return session, err
+	}
+	if err := json.Unmarshal(buf, &session); err != nil {
+		s.logger.Error().Err(err).Msg("fail to unmarshal x402 session")
+		return session, err
+	}
+	return session, nil
+}
+
+func (s *X402SessionStore) set(session X402Session) error {
+	buf, err := json.Marshal(session)
+	if err != nil {
+		s.logger.Error().Err(err).Msg("fail to marshal x402 session")
+		return err
+	}
+	if err := s.db.Put([]byte(session.Key()), buf, nil); err != nil {
+		s.logger.Error().Err(err).Msg("fail to set x402 session")
+		return err
+	}
+	return nil
+}
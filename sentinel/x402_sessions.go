@@ -0,0 +1,349 @@
+package sentinel
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/syndtr/goleveldb/leveldb"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+const (
+	// RouteX402Session sells prepaid sessions for a service
+	RouteX402Session = "/x402/sessions/{service}"
+
+	// X402SessionHeader carries a session token, "Authorization: Bearer" works too
+	X402SessionHeader = "X-PAYMENT-SESSION"
+
+	// X402SessionRemainingHeader reports the compute units left in a session
+	X402SessionRemainingHeader = "X-PAYMENT-SESSION-REMAINING"
+
+	x402SessionTokenPrefix = "x402s_"
+
+	defaultX402SessionMaxRequests    = 100000
+	defaultX402SessionMaxSeconds     = 86400
+	defaultX402SessionUnitsPerSecond = 1
+)
+
+// X402Sessions sells prepaid bundles of requests or time and spends them with
+// signed bearer tokens, so agents pay once instead of settling every request.
+// The token only identifies the session, its balance lives in the store.
+type X402Sessions struct {
+	store  *X402SessionStore
+	secret []byte
+	config conf.X402SessionConfig
+
+	// now is overridable for tests
+	now func() time.Time
+}
+
+// Close closes the session store
+func (s *X402Sessions) Close() error {
+	return s.store.Close()
+}
+
+// x402SessionClaims is the signed content of a session token
+type x402SessionClaims struct {
+	ID        string `json:"sid"`
+	Service   string `json:"svc"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// NewX402Sessions creates the session manager, filling unset limits with defaults
+func NewX402Sessions(config conf.X402SessionConfig, store *X402SessionStore) (*X402Sessions, error) {
+	if config.MaxRequests == 0 {
+		config.MaxRequests = defaultX402SessionMaxRequests
+	}
+	if config.MaxSeconds == 0 {
+		config.MaxSeconds = defaultX402SessionMaxSeconds
+	}
+	if config.UnitsPerSecond == 0 {
+		config.UnitsPerSecond = defaultX402SessionUnitsPerSecond
+	}
+
+	secret := []byte(config.Secret)
+	if len(secret) == 0 {
+		var err error
+		secret, err = store.Secret()
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &X402Sessions{
+		store:  store,
+		secret: secret,
+		config: config,
+		now:    time.Now,
+	}, nil
+}
+
+// Quote returns the compute units of a session of the given requests or
+// seconds (exactly one must be set) and how long it lasts
+func (s *X402Sessions) Quote(pricing *X402Pricing, service string, requests, seconds uint64) (uint64, time.Duration, error) {
+	switch {
+	case requests > 0 && seconds > 0, requests == 0 && seconds == 0:
+		return 0, 0, fmt.Errorf("a session is either a number of requests or of seconds")
+	case requests > 0:
+		if requests > s.config.MaxRequests {
+			return 0, 0, fmt.Errorf("at most %d requests per session", s.config.MaxRequests)
+		}
+		return requests * pricing.DefaultUnits(service), time.Duration(s.config.MaxSeconds) * time.Second, nil
+	default:
+		if seconds > s.config.MaxSeconds {
+			return 0, 0, fmt.Errorf("at most %d seconds per session", s.config.MaxSeconds)
+		}
+		return seconds * s.config.UnitsPerSecond, time.Duration(seconds) * time.Second, nil
+	}
+}
+
+// Issue stores a new session and returns its token
+func (s *X402Sessions) Issue(service, payer, settlementID string, units uint64, ttl time.Duration, timeBased bool) (string, X402Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", X402Session{}, fmt.Errorf("fail to generate session id: %w", err)
+	}
+	now := s.now()
+	session := X402Session{
+		ID:           hex.EncodeToString(id),
+		Service:      service,
+		Payer:        payer,
+		ComputeUnits: units,
+		Remaining:    units,
+		TimeBased:    timeBased,
+		SettlementID: settlementID,
+		CreatedAt:    now.Unix(),
+		ExpiresAt:    now.Add(ttl).Unix(),
+	}
+	if err := s.store.Set(session); err != nil {
+		return "", session, err
+	}
+	token, err := s.sign(x402SessionClaims{ID: session.ID, Service: service, ExpiresAt: session.ExpiresAt})
+	if err != nil {
+		return "", session, err
+	}
+	return token, session, nil
+}
+
+// Spend checks the token was issued for service and takes the compute units
+// of a request from its session
+func (s *X402Sessions) Spend(token, service string, units uint64) (X402Session, error) {
+	claims, err := s.verify(token)
+	if err != nil {
+		return X402Session{}, err
+	}
+	if claims.Service != service {
+		return X402Session{}, newX402Error(X402ReasonWrongRecipient, "session is for service %s", claims.Service)
+	}
+
+	session, err := s.store.Spend(claims.ID, units, s.now())
+	switch {
+	case errors.Is(err, ErrX402SessionExpired):
+		return session, &X402Error{Reason: X402ReasonExpired, Err: err}
+	case errors.Is(err, ErrX402SessionExhausted):
+		return session, &X402Error{Reason: X402ReasonInsufficientFunds, Err: err}
+	case errors.Is(err, leveldb.ErrNotFound):
+		// a signed token whose session was pruned
+		return session, &X402Error{Reason: X402ReasonExpired, Err: err}
+	case err != nil:
+		return session, fmt.Errorf("fail to spend session %s: %w", claims.ID, err)
+	}
+	return session, nil
+}
+
+// Payer returns the payer of the session of a valid token
+func (s *X402Sessions) Payer(token string) (string, error) {
+	claims, err := s.verify(token)
+	if err != nil {
+		return "", err
+	}
+	session, err := s.store.Get(claims.ID)
+	if err != nil {
+		return "", err
+	}
+	return session.Payer, nil
+}
+
+// TokenFromRequest returns the session token of a request, if any
+func (s *X402Sessions) TokenFromRequest(r *http.Request) string {
+	if token := r.Header.Get(X402SessionHeader); token != "" {
+		return token
+	}
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && strings.HasPrefix(token, x402SessionTokenPrefix) {
+		return token
+	}
+	return ""
+}
+
+// Prune removes ended sessions
+func (s *X402Sessions) Prune(now time.Time) (int, error) {
+	return s.store.Prune(now)
+}
+
+func (s *X402Sessions) sign(claims x402SessionClaims) (string, error) {
+	buf, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("fail to marshal session claims: %w", err)
+	}
+	payload := base64.RawURLEncoding.EncodeToString(buf)
+	return x402SessionTokenPrefix + payload + "." + s.mac(payload), nil
+}
+
+func (s *X402Sessions) verify(token string) (x402SessionClaims, error) {
+	var claims x402SessionClaims
+	payload, mac, ok := strings.Cut(strings.TrimPrefix(token, x402SessionTokenPrefix), ".")
+	if !ok || !hmac.Equal([]byte(mac), []byte(s.mac(payload))) {
+		return claims, newX402Error(X402ReasonInvalidSignature, "invalid session token")
+	}
+	buf, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return claims, newX402Error(X402ReasonInvalidPayload, "invalid session token encoding: %w", err)
+	}
+	if err := json.Unmarshal(buf, &claims); err != nil {
+		return claims, newX402Error(X402ReasonInvalidPayload, "invalid session token claims: %w", err)
+	}
+	if s.now().Unix() >= claims.ExpiresAt {
+		return claims, newX402Error(X402ReasonExpired, "session expired at %d", claims.ExpiresAt)
+	}
+	return claims, nil
+}
+
+func (s *X402Sessions) mac(payload string) string {
+	h := hmac.New(sha256.New, s.secret)
+	h.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(h.Sum(nil))
+}
+
+// ServeSession serves a request paid from a session. Failures are written to
+// w as x402 errors and returned for logging.
+func (h *X402Handler) ServeSession(w http.ResponseWriter, r *http.Request, next http.Handler, service, token string, computeUnits uint64) (X402Session, error) {
+	// limits are checked before the session is spent, invalid tokens are
+	// refused by Spend
+	if payer, err := h.Sessions.Payer(token); err == nil {
+		release, err := h.Limits.AcquirePayer(payer)
+		if err != nil {
+			h.WriteLimitError(w, asX402LimitError(err))
+			return X402Session{Payer: payer}, err
+		}
+		defer release()
+	}
+
+	session, err := h.Sessions.Spend(token, service, computeUnits)
+	if err != nil {
+		h.WritePaymentError(w, service, r.URL.String(), computeUnits, err)
+		return session, err
+	}
+
+	// the token is ours, it must not reach the upstream
+	r.Header.Del(X402SessionHeader)
+	if strings.HasPrefix(r.Header.Get("Authorization"), "Bearer "+x402SessionTokenPrefix) {
+		r.Header.Del("Authorization")
+	}
+	if !session.TimeBased {
+		w.Header().Set(X402SessionRemainingHeader, strconv.FormatUint(session.Remaining, 10))
+	}
+	// the session was credited when it was sold, only the request is counted
+	h.CreditRevenue(service, nil, "0", 1)
+	next.ServeHTTP(w, r)
+	return session, nil
+}
+
+// X402SessionResponse is returned when a session is bought
+type X402SessionResponse struct {
+	Token        string `json:"token"`
+	Service      string `json:"service"`
+	ComputeUnits uint64 `json:"computeUnits,omitempty"`
+	ExpiresAt    int64  `json:"expiresAt"`
+}
+
+// handleX402Session sells a session of ?requests=N or ?seconds=T for a
+// service. Without payment it answers with the 402 quote of the session.
+func (p *Proxy) handleX402Session(w http.ResponseWriter, r *http.Request) {
+	if p.x402 == nil || p.x402.Sessions == nil {
+		http.Error(w, "x402 sessions not enabled", http.StatusNotFound)
+		return
+	}
+	p.x402.SetModeHeader(w)
+
+	service := mux.Vars(r)["service"]
+	p.serviceMu.RLock()
+	_, exists := p.serviceIDs[service]
+	p.serviceMu.RUnlock()
+	if !exists {
+		http.Error(w, "service not found", http.StatusNotFound)
+		return
+	}
+
+	requests, _ := strconv.ParseUint(r.URL.Query().Get("requests"), 10, 64)
+	seconds, _ := strconv.ParseUint(r.URL.Query().Get("seconds"), 10, 64)
+	units, ttl, err := p.x402.Sessions.Quote(p.x402.Pricing, service, requests, seconds)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hasPayment, paymentPayload := p.x402.CheckPaymentHeader(r)
+	if !hasPayment {
+		p.x402.WritePaymentRequired(w, service, r.URL.String(), units)
+		return
+	}
+
+	payment, err := p.x402.AuthorizePayment(service, paymentPayload, units)
+	if err != nil {
+		p.logger.Error("x402 session payment failed", "service", service, "reason", X402ErrorReason(err), "error", err)
+		p.x402.WritePaymentError(w, service, r.URL.String(), units, err)
+		return
+	}
+	spend, err := p.x402.ReserveSpend(r, payment)
+	if err != nil {
+		p.x402.ReleasePayment(payment)
+		p.logger.Info("x402 session refused", "service", service, "payer", payment.Payer, "error", err)
+		p.x402.WritePaymentError(w, service, r.URL.String(), units, err)
+		return
+	}
+	defer spend.Release()
+	settleResp, err := p.x402.SettlePayment(payment, units)
+	if err != nil {
+		p.logger.Error("x402 session settlement failed", "service", service, "reason", X402ErrorReason(err), "error", err)
+		w.Header().Set(X402PaymentResponseHeader, NewX402PaymentFailure(payment, err).Encode())
+		p.x402.WritePaymentError(w, service, r.URL.String(), units, err)
+		return
+	}
+	receipt := NewX402PaymentResponse(payment, settleResp, units)
+	p.x402.CreditRevenue(service, payment, receipt.Amount, 0)
+	spend.Settle(receipt.Amount)
+	p.x402.RecordReceipt(service, payment, receipt, http.StatusCreated)
+
+	token, session, err := p.x402.Sessions.Issue(service, receipt.Payer, settleResp.SettlementID, units, ttl, seconds > 0)
+	if err != nil {
+		p.logger.Error("failed to issue x402 session", "service", service, "settlement_id", settleResp.SettlementID, "error", err)
+		http.Error(w, "failed to issue session", http.StatusInternalServerError)
+		return
+	}
+	p.logger.Info("x402 session sold",
+		"service", service,
+		"payer", receipt.Payer,
+		"compute_units", units,
+		"expires_at", session.ExpiresAt,
+	)
+
+	response := X402SessionResponse{Token: token, Service: service, ExpiresAt: session.ExpiresAt}
+	if !session.TimeBased {
+		response.ComputeUnits = session.ComputeUnits
+	}
+	w.Header().Set(X402PaymentResponseHeader, receipt.Encode())
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(response)
+}
@@ -0,0 +1,164 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+func testX402Sessions(t *testing.T) *X402Sessions {
+	t.Helper()
+	store, err := NewX402SessionStore("")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	sessions, err := NewX402Sessions(conf.X402SessionConfig{Enabled: true, MaxRequests: 100, MaxSeconds: 3600}, store)
+	require.NoError(t, err)
+	return sessions
+}
+
+func TestX402Sessions_Spend(t *testing.T) {
+	sessions := testX402Sessions(t)
+	now := time.Now()
+	sessions.now = func() time.Time { return now }
+
+	token, session, err := sessions.Issue("eth", "0xpayer", "settlement", 5, time.Minute, false)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), session.Remaining)
+
+	session, err = sessions.Spend(token, "eth", 2)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), session.Remaining)
+
+	_, err = sessions.Spend(token, "btc", 1)
+	require.Equal(t, X402ReasonWrongRecipient, X402ErrorReason(err))
+
+	_, err = sessions.Spend(token[:len(token)-2]+"xx", "eth", 1)
+	require.Equal(t, X402ReasonInvalidSignature, X402ErrorReason(err))
+
+	_, err = sessions.Spend(token, "eth", 4)
+	require.Equal(t, X402ReasonInsufficientFunds, X402ErrorReason(err))
+	session, err = sessions.Spend(token, "eth", 3)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), session.Remaining)
+
+	// time based sessions are not drawn down, only bounded in time
+	timeToken, _, err := sessions.Issue("eth", "0xpayer", "settlement", 60, time.Minute, true)
+	require.NoError(t, err)
+	for i := 0; i < 100; i++ {
+		_, err = sessions.Spend(timeToken, "eth", 1)
+		require.NoError(t, err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	_, err = sessions.Spend(timeToken, "eth", 1)
+	require.Equal(t, X402ReasonExpired, X402ErrorReason(err))
+
+	removed, err := sessions.Prune(now)
+	require.NoError(t, err)
+	require.Equal(t, 2, removed)
+}
+
+func TestX402Sessions_Quote(t *testing.T) {
+	sessions := testX402Sessions(t)
+	pricing := NewX402Pricing(map[string]conf.X402ServicePricing{"eth": {ComputeUnits: 3}})
+
+	units, ttl, err := sessions.Quote(pricing, "eth", 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(30), units)
+	require.Equal(t, time.Hour, ttl)
+
+	units, ttl, err = sessions.Quote(pricing, "eth", 0, 600)
+	require.NoError(t, err)
+	require.Equal(t, uint64(600), units)
+	require.Equal(t, 10*time.Minute, ttl)
+
+	_, _, err = sessions.Quote(pricing, "eth", 0, 0)
+	require.Error(t, err)
+	_, _, err = sessions.Quote(pricing, "eth", 10, 10)
+	require.Error(t, err)
+	_, _, err = sessions.Quote(pricing, "eth", 101, 0)
+	require.Error(t, err)
+	_, _, err = sessions.Quote(pricing, "eth", 0, 3601)
+	require.Error(t, err)
+}
+
+func TestX402Sessions_BuyAndSpend(t *testing.T) {
+	paymentStore, err := NewX402PaymentStore("")
+	require.NoError(t, err)
+	defer paymentStore.Close()
+
+	handler := NewX402Handler(testPayTo)
+	handler.Verifier = NewLocalVerifier()
+	handler.Store = paymentStore
+	handler.Sessions = testX402Sessions(t)
+	unitPrice := testRequirements()
+	unitPrice.Amount = "100"
+	handler.Accepts = []PaymentRequirements{unitPrice}
+	proxy := &Proxy{x402: handler, logger: log.NewNopLogger(), serviceIDs: map[string]int32{"eth": 1}, proxies: map[string]*url.URL{"eth": {}}}
+
+	router := mux.NewRouter()
+	router.HandleFunc(RouteX402Session, proxy.handleX402Session).Methods(http.MethodPost)
+	upstream := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Empty(t, r.Header.Get(X402SessionHeader))
+		_, _ = w.Write([]byte("ok"))
+	})
+	serve := proxy.x402Middleware(upstream)
+
+	// without payment the session is quoted
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/x402/sessions/eth?requests=2", nil))
+	require.Equal(t, http.StatusPaymentRequired, rec.Code)
+	var quote PaymentRequiredResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&quote))
+	require.Equal(t, "200", quote.Accepts[0].Amount)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/x402/sessions/btc?requests=2", nil))
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPost, "/x402/sessions/eth?requests=2", nil)
+	req.Header.Set("X-PAYMENT", signTestPayment(t, key, quote.Accepts[0], testAuthorization(key, "200", time.Now())))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+	require.NotEmpty(t, rec.Header().Get(X402PaymentResponseHeader))
+	var bought X402SessionResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&bought))
+	require.Equal(t, uint64(2), bought.ComputeUnits)
+
+	// the session pays for two requests, by header or bearer token
+	req = httptest.NewRequest(http.MethodGet, "/x402/eth/", nil)
+	req.Header.Set(X402SessionHeader, bought.Token)
+	rec = httptest.NewRecorder()
+	serve.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "1", rec.Header().Get(X402SessionRemainingHeader))
+
+	req = httptest.NewRequest(http.MethodGet, "/x402/eth/", nil)
+	req.Header.Set("Authorization", "Bearer "+bought.Token)
+	rec = httptest.NewRecorder()
+	serve.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "0", rec.Header().Get(X402SessionRemainingHeader))
+
+	req = httptest.NewRequest(http.MethodGet, "/x402/eth/", nil)
+	req.Header.Set(X402SessionHeader, bought.Token)
+	rec = httptest.NewRecorder()
+	serve.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusPaymentRequired, rec.Code)
+	var exhausted PaymentRequiredResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&exhausted))
+	require.Equal(t, X402ReasonInsufficientFunds, exhausted.Error)
+}
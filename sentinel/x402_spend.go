@@ -0,0 +1,431 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/storage"
+	"github.com/syndtr/goleveldb/leveldb/util"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+const (
+	// RouteX402Spend reports what a payer spent today and this month
+	RouteX402Spend = "/x402/spend/{payer}"
+
+	// X402MaxSpendHeader is the budget of the agent: "<amount>" caps the
+	// request, "<amount>/day" and "<amount>/month" what the payer spends in
+	// the current UTC day or month, in atomic units of the chosen asset
+	X402MaxSpendHeader = "X-PAYMENT-MAX-SPEND"
+
+	// X402ReasonSpendLimit refuses a payment over a spend limit
+	X402ReasonSpendLimit = "spend_limit_exceeded"
+
+	x402SpendPrefix = "spend/"
+)
+
+// x402 spend periods
+const (
+	X402SpendRequest = "request"
+	X402SpendDay     = "day"
+	X402SpendMonth   = "month"
+)
+
+// X402SpendStore keeps what every payer spent per UTC day and month, and
+// what payments being served hold against it
+type X402SpendStore struct {
+	logger   zerolog.Logger
+	db       *leveldb.DB
+	mu       sync.Mutex
+	reserved map[string]*big.Int
+}
+
+// X402Spend is what a payer spent in an asset during a period
+type X402Spend struct {
+	Payer    string `json:"payer"`
+	Network  string `json:"network"`
+	Asset    string `json:"asset"`
+	Period   string `json:"period"`
+	Start    string `json:"start"`
+	Amount   string `json:"amount"`
+	Requests int64  `json:"requests"`
+	End      int64  `json:"end"`
+}
+
+// Key groups the spend of a payer by asset and period
+func (s X402Spend) Key() string {
+	return x402SpendKey(s.Payer, s.Network, s.Asset, s.Period, s.Start)
+}
+
+func x402SpendKey(payer, network, asset, period, start string) string {
+	return fmt.Sprintf("%s%s/%s/%s/%s/%s", x402SpendPrefix, strings.ToLower(payer), network, strings.ToLower(asset), period, start)
+}
+
+// x402SpendPeriod returns the start and end of the UTC day or month of now
+func x402SpendPeriod(period string, now time.Time) (string, time.Time) {
+	now = now.UTC()
+	if period == X402SpendMonth {
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start.Format("2006-01"), start.AddDate(0, 1, 0)
+	}
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return start.Format("2006-01-02"), start.AddDate(0, 0, 1)
+}
+
+func NewX402SpendStore(levelDbFolder string) (*X402SpendStore, error) {
+	var db *leveldb.DB
+	var err error
+	if len(levelDbFolder) == 0 {
+		log.Warn().Msg("x402 spend store folder is empty, create in memory storage")
+		// no directory given, use in memory store
+		storage := storage.NewMemStorage()
+		db, err = leveldb.Open(storage, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fail to in memory open level db: %w", err)
+		}
+	} else {
+		db, err = leveldb.OpenFile(levelDbFolder, nil)
+		if err != nil {
+			return nil, fmt.Errorf("fail to open level db %s: %w", levelDbFolder, err)
+		}
+	}
+	return &X402SpendStore{
+		logger:   log.With().Str("module", "x402-spend-storage").Logger(),
+		db:       db,
+		reserved: make(map[string]*big.Int),
+	}, nil
+}
+
+// Add counts a settled amount in the day and month of now
+func (s *X402SpendStore) Add(payer, network, asset, amount string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.add(payer, network, asset, amount, now)
+}
+
+// x402SpendLimit is a day or month limit on the spend of a payer
+type x402SpendLimit struct {
+	Source string
+	Limit  string
+	Period string
+}
+
+// X402SpendReservation holds the amount of a payment being served against
+// the day and month spend of its payer, so concurrent payments cannot all
+// pass a limit only one of them fits in. It is settled with the amount
+// charged, or released when the payment is voided.
+type X402SpendReservation struct {
+	store   *X402SpendStore
+	payer   string
+	network string
+	asset   string
+	amount  *big.Int
+	keys    []string
+	done    bool
+}
+
+// Reserve checks amount against limits, counting what was spent and what is
+// reserved, and reserves it in the day and month of now
+func (s *X402SpendStore) Reserve(payer, network, asset, amount string, limits []x402SpendLimit, now time.Time) (*X402SpendReservation, error) {
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok {
+		return nil, fmt.Errorf("invalid spend amount %q", amount)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, limit := range limits {
+		max, ok := new(big.Int).SetString(limit.Limit, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s spend limit %q", limit.Source, limit.Limit)
+		}
+		spend, err := s.get(payer, network, asset, limit.Period, now)
+		if err != nil {
+			return nil, err
+		}
+		spent, ok := new(big.Int).SetString(spend.Amount, 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid x402 spend %q", spend.Amount)
+		}
+		if reserved := s.reserved[spend.Key()]; reserved != nil {
+			spent.Add(spent, reserved)
+		}
+		if new(big.Int).Add(spent, value).Cmp(max) > 0 {
+			return nil, &X402Error{Reason: X402ReasonSpendLimit, Err: &X402SpendLimitError{
+				Source: limit.Source, Period: limit.Period, Limit: limit.Limit, Spent: spent.String(), Amount: amount,
+			}}
+		}
+	}
+
+	reservation := &X402SpendReservation{store: s, payer: payer, network: network, asset: asset, amount: value}
+	for _, period := range []string{X402SpendDay, X402SpendMonth} {
+		start, _ := x402SpendPeriod(period, now)
+		key := x402SpendKey(payer, network, asset, period, start)
+		if s.reserved[key] == nil {
+			s.reserved[key] = new(big.Int)
+		}
+		s.reserved[key].Add(s.reserved[key], value)
+		reservation.keys = append(reservation.keys, key)
+	}
+	return reservation, nil
+}
+
+// unreserve drops a reservation, s.mu must be held
+func (s *X402SpendStore) unreserve(reservation *X402SpendReservation) {
+	for _, key := range reservation.keys {
+		reserved := s.reserved[key]
+		if reserved == nil {
+			continue
+		}
+		reserved.Sub(reserved, reservation.amount)
+		if reserved.Sign() <= 0 {
+			delete(s.reserved, key)
+		}
+	}
+}
+
+// Settle replaces the reservation with the amount charged
+func (r *X402SpendReservation) Settle(amount string) {
+	if r == nil || r.done {
+		return
+	}
+	r.done = true
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.unreserve(r)
+	if r.payer == "" {
+		return
+	}
+	// failures are logged by the store, the request was paid either way
+	_ = r.store.add(r.payer, r.network, r.asset, amount, time.Now())
+}
+
+// Release drops the reservation of a payment that was not charged, it does
+// nothing once settled
+func (r *X402SpendReservation) Release() {
+	if r == nil || r.done {
+		return
+	}
+	r.done = true
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	r.store.unreserve(r)
+}
+
+func (s *X402SpendStore) add(payer, network, asset, amount string, now time.Time) error {
+	batch := new(leveldb.Batch)
+	for _, period := range []string{X402SpendDay, X402SpendMonth} {
+		spend, err := s.get(payer, network, asset, period, now)
+		if err != nil {
+			return err
+		}
+		spend.Amount, err = addX402Amounts(spend.Amount, amount, 1)
+		if err != nil {
+			s.logger.Error().Err(err).Msg("fail to add x402 spend")
+			return err
+		}
+		spend.Requests++
+		buf, err := json.Marshal(spend)
+		if err != nil {
+			s.logger.Error().Err(err).Msg("fail to marshal x402 spend")
+			return err
+		}
+		batch.Put([]byte(spend.Key()), buf)
+	}
+	if err := s.db.Write(batch, nil); err != nil {
+		s.logger.Error().Err(err).Msg("fail to set x402 spend")
+		return err
+	}
+	return nil
+}
+
+// Get returns what a payer spent in the day or month of now
+func (s *X402SpendStore) Get(payer, network, asset, period string, now time.Time) (X402Spend, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(payer, network, asset, period, now)
+}
+
+// List returns the spend of payer in the day and month of now
+func (s *X402SpendStore) List(payer string, now time.Time) []X402Spend {
+	day, _ := x402SpendPeriod(X402SpendDay, now)
+	month, _ := x402SpendPeriod(X402SpendMonth, now)
+	iterator := s.db.NewIterator(util.BytesPrefix([]byte(x402SpendPrefix+strings.ToLower(payer)+"/")), nil)
+	defer iterator.Release()
+	results := []X402Spend{}
+	for iterator.Next() {
+		var item X402Spend
+		if err := json.Unmarshal(iterator.Value(), &item); err != nil {
+			s.logger.Error().Err(err).Msg("fail to unmarshal x402 spend")
+			continue
+		}
+		if item.Start == day || item.Start == month {
+			results = append(results, item)
+		}
+	}
+	return results
+}
+
+// Prune removes the spend of periods that ended before the given time and
+// returns how many were removed
+func (s *X402SpendStore) Prune(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	iterator := s.db.NewIterator(util.BytesPrefix([]byte(x402SpendPrefix)), nil)
+	batch := new(leveldb.Batch)
+	for iterator.Next() {
+		var item X402Spend
+		if err := json.Unmarshal(iterator.Value(), &item); err != nil || item.End < before.Unix() {
+			batch.Delete(append([]byte(nil), iterator.Key()...))
+		}
+	}
+	iterator.Release()
+	if batch.Len() == 0 {
+		return 0, nil
+	}
+	if err := s.db.Write(batch, nil); err != nil {
+		s.logger.Error().Err(err).Msg("fail to prune x402 spend")
+		return 0, err
+	}
+	return batch.Len(), nil
+}
+
+// Close underlying db
+func (s *X402SpendStore) Close() error {
+	return s.db.Close()
+}
+
+func (s *X402SpendStore) get(payer, network, asset, period string, now time.Time) (X402Spend, error) {
+	start, end := x402SpendPeriod(period, now)
+	spend := X402Spend{
+		Payer:   strings.ToLower(payer),
+		Network: network,
+		Asset:   strings.ToLower(asset),
+		Period:  period,
+		Start:   start,
+		Amount:  "0",
+		End:     end.Unix(),
+	}
+	buf, err := s.db.Get([]byte(spend.Key()), nil)
+	if err == leveldb.ErrNotFound {
+		return spend, nil
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Msg("fail to get x402 spend")
+		return spend, err
+	}
+	if err := json.Unmarshal(buf, &spend); err != nil {
+		s.logger.Error().Err(err).Msg("fail to unmarshal x402 spend")
+		return spend, err
+	}
+	return spend, nil
+}
+
+// X402SpendLimitError is a payment refused for going over a spend limit. It
+// is sent to the client in the spendLimit extension of the 402.
+type X402SpendLimitError struct {
+	Source string `json:"source"` // "payer" for X-PAYMENT-MAX-SPEND, "provider" for the configured caps
+	Period string `json:"period"`
+	Limit  string `json:"limit"`
+	Spent  string `json:"spent"`
+	Amount string `json:"amount"`
+}
+
+func (e *X402SpendLimitError) Error() string {
+	return fmt.Sprintf("%s spend limit of %s per %s exceeded: spent %s, payment of %s", e.Source, e.Limit, e.Period, e.Spent, e.Amount)
+}
+
+// parseX402MaxSpend parses an X-PAYMENT-MAX-SPEND header
+func parseX402MaxSpend(value string) (string, string, error) {
+	amount, period, found := strings.Cut(strings.TrimSpace(value), "/")
+	if !found {
+		period = X402SpendRequest
+	}
+	switch period {
+	case X402SpendRequest, X402SpendDay, X402SpendMonth:
+	default:
+		return "", "", fmt.Errorf("unknown spend period %q", period)
+	}
+	if _, ok := new(big.Int).SetString(amount, 10); !ok {
+		return "", "", fmt.Errorf("invalid spend amount %q", amount)
+	}
+	return amount, period, nil
+}
+
+// ReserveSpend refuses a payment that would take its payer over the budget
+// in the X-PAYMENT-MAX-SPEND header of r or over the caps of its network, and
+// otherwise reserves its quoted amount until it is settled or released. The
+// payment is charged at most its quoted amount.
+func (h *X402Handler) ReserveSpend(r *http.Request, payment *X402Payment) (*X402SpendReservation, error) {
+	amount := payment.Requirements.Amount
+	var limits []x402SpendLimit
+	if value := r.Header.Get(X402MaxSpendHeader); value != "" {
+		limit, period, err := parseX402MaxSpend(value)
+		if err != nil {
+			return nil, &X402Error{Reason: X402ReasonInvalidPayload, Err: fmt.Errorf("invalid %s: %w", X402MaxSpendHeader, err)}
+		}
+		if period == X402SpendRequest {
+			over, _ := new(big.Int).SetString(amount, 10)
+			max, _ := new(big.Int).SetString(limit, 10)
+			if over == nil || over.Cmp(max) > 0 {
+				return nil, &X402Error{Reason: X402ReasonSpendLimit, Err: &X402SpendLimitError{
+					Source: "payer", Period: period, Limit: limit, Spent: "0", Amount: amount,
+				}}
+			}
+		} else {
+			limits = append(limits, x402SpendLimit{Source: "payer", Limit: limit, Period: period})
+		}
+	}
+	caps := h.SpendCaps[payment.Requirements.Network]
+	if caps.Daily != "" {
+		limits = append(limits, x402SpendLimit{Source: "provider", Limit: caps.Daily, Period: X402SpendDay})
+	}
+	if caps.Monthly != "" {
+		limits = append(limits, x402SpendLimit{Source: "provider", Limit: caps.Monthly, Period: X402SpendMonth})
+	}
+	if h.Spend == nil {
+		return nil, nil
+	}
+	return h.Spend.Reserve(payment.Payer, payment.Requirements.Network, payment.Requirements.Asset, amount, limits, time.Now())
+}
+
+// X402SpendSummary is what a payer spent in the current UTC day and month,
+// with the caps of the provider
+type X402SpendSummary struct {
+	Payer string                       `json:"payer"`
+	Day   string                       `json:"day"`
+	Month string                       `json:"month"`
+	Spend []X402Spend                  `json:"spend"`
+	Caps  map[string]conf.X402SpendCap `json:"caps,omitempty"`
+}
+
+// handleX402Spend reports what a payer spent today and this month
+func (p *Proxy) handleX402Spend(w http.ResponseWriter, r *http.Request) {
+	if p.x402 == nil || p.x402.Spend == nil {
+		http.Error(w, "x402 spend tracking not enabled", http.StatusNotFound)
+		return
+	}
+	now := time.Now()
+	payer := mux.Vars(r)["payer"]
+	summary := X402SpendSummary{
+		Payer: strings.ToLower(payer),
+		Spend: p.x402.Spend.List(payer, now),
+		Caps:  p.x402.SpendCaps,
+	}
+	summary.Day, _ = x402SpendPeriod(X402SpendDay, now)
+	summary.Month, _ = x402SpendPeriod(X402SpendMonth, now)
+	p.x402.SetModeHeader(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(summary)
+}
@@ -0,0 +1,196 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+func TestX402SpendStore(t *testing.T) {
+	store, err := NewX402SpendStore("")
+	require.NoError(t, err)
+	defer store.Close()
+
+	now := time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC)
+	require.NoError(t, store.Add("0xABC", "eip155:8453", "0xUSDC", "100", now))
+	require.NoError(t, store.Add("0xabc", "eip155:8453", "0xusdc", "50", now))
+	require.NoError(t, store.Add("0xabc", "eip155:8453", "0xusdc", "25", now.Add(2*time.Hour)))
+
+	day, err := store.Get("0xabc", "eip155:8453", "0xUSDC", X402SpendDay, now)
+	require.NoError(t, err)
+	require.Equal(t, "150", day.Amount)
+	require.Equal(t, int64(2), day.Requests)
+	require.Equal(t, "2026-01-31", day.Start)
+
+	// a new day and a new month
+	month, err := store.Get("0xabc", "eip155:8453", "0xusdc", X402SpendMonth, now.Add(2*time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, "25", month.Amount)
+	require.Equal(t, "2026-02", month.Start)
+	require.Len(t, store.List("0xABC", now), 2)
+
+	pruned, err := store.Prune(time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	require.Equal(t, 2, pruned)
+	require.Len(t, store.List("0xabc", now.Add(2*time.Hour)), 2)
+}
+
+func TestX402SpendStore_Reserve(t *testing.T) {
+	store, err := NewX402SpendStore("")
+	require.NoError(t, err)
+	defer store.Close()
+
+	now := time.Now()
+	limits := []x402SpendLimit{{Source: "provider", Limit: "2500", Period: X402SpendDay}}
+
+	// concurrent payments only reserve what fits the limit
+	var mu sync.Mutex
+	var reserved []*X402SpendReservation
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			spend, err := store.Reserve("0xabc", "eip155:8453", "0xusdc", "1000", limits, now)
+			if err != nil {
+				var limitErr *X402SpendLimitError
+				require.ErrorAs(t, err, &limitErr)
+				return
+			}
+			mu.Lock()
+			reserved = append(reserved, spend)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	require.Len(t, reserved, 2)
+
+	// a release frees its reservation, a settle replaces it with the charge
+	reserved[0].Release()
+	reserved[1].Settle("400")
+	reserved[1].Release()
+	day, err := store.Get("0xabc", "eip155:8453", "0xusdc", X402SpendDay, now)
+	require.NoError(t, err)
+	require.Equal(t, "400", day.Amount)
+	require.Empty(t, store.reserved)
+
+	spend, err := store.Reserve("0xabc", "eip155:8453", "0xusdc", "2000", limits, now)
+	require.NoError(t, err)
+	_, err = store.Reserve("0xabc", "eip155:8453", "0xusdc", "200", limits, now)
+	var limitErr *X402SpendLimitError
+	require.ErrorAs(t, err, &limitErr)
+	require.Equal(t, "2400", limitErr.Spent)
+	spend.Release()
+}
+
+func TestParseX402MaxSpend(t *testing.T) {
+	amount, period, err := parseX402MaxSpend("1000")
+	require.NoError(t, err)
+	require.Equal(t, "1000", amount)
+	require.Equal(t, X402SpendRequest, period)
+
+	_, period, err = parseX402MaxSpend(" 1000/month ")
+	require.NoError(t, err)
+	require.Equal(t, X402SpendMonth, period)
+
+	_, _, err = parseX402MaxSpend("1000/week")
+	require.Error(t, err)
+	_, _, err = parseX402MaxSpend("ten/day")
+	require.Error(t, err)
+}
+
+func TestX402Spend(t *testing.T) {
+	paymentStore, err := NewX402PaymentStore("")
+	require.NoError(t, err)
+	defer paymentStore.Close()
+	spend, err := NewX402SpendStore("")
+	require.NoError(t, err)
+	defer spend.Close()
+
+	handler := NewX402Handler(testPayTo)
+	handler.Verifier = NewLocalVerifier()
+	handler.Store = paymentStore
+	handler.Accepts = []PaymentRequirements{testRequirements()}
+	handler.Spend = spend
+	handler.SpendCaps = map[string]conf.X402SpendCap{testRequirements().Network: {Daily: "2500"}}
+	proxy := &Proxy{x402: handler, logger: log.NewNopLogger(), proxies: map[string]*url.URL{"eth": {}}}
+
+	upstream := 0
+	serve := proxy.x402Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstream++
+		w.WriteHeader(http.StatusOK)
+	}))
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	payer := crypto.PubkeyToAddress(key.PublicKey).Hex()
+	send := func(maxSpend string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/x402/eth/", nil)
+		req.Header.Set("X-PAYMENT", signTestPayment(t, key, testRequirements(), testAuthorization(key, "1000", time.Now())))
+		if maxSpend != "" {
+			req.Header.Set(X402MaxSpendHeader, maxSpend)
+		}
+		rec := httptest.NewRecorder()
+		serve.ServeHTTP(rec, req)
+		return rec
+	}
+	refused := func(rec *httptest.ResponseRecorder) X402SpendLimitError {
+		require.Equal(t, http.StatusPaymentRequired, rec.Code)
+		var body struct {
+			Error      string `json:"error"`
+			Extensions struct {
+				SpendLimit X402SpendLimitError `json:"spendLimit"`
+			} `json:"extensions"`
+		}
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+		require.Equal(t, X402ReasonSpendLimit, body.Error)
+		return body.Extensions.SpendLimit
+	}
+
+	// the request costs more than the agent allows
+	limit := refused(send("999"))
+	require.Equal(t, "payer", limit.Source)
+	require.Equal(t, X402SpendRequest, limit.Period)
+	require.Zero(t, upstream)
+	require.Empty(t, paymentStore.List())
+
+	require.Equal(t, http.StatusOK, send("1000/day").Code)
+	require.Equal(t, http.StatusOK, send("").Code)
+
+	// the agent budget of the day is spent
+	limit = refused(send("2000/day"))
+	require.Equal(t, "2000", limit.Spent)
+
+	// the provider cap of the day is spent
+	limit = refused(send(""))
+	require.Equal(t, "provider", limit.Source)
+	require.Equal(t, "2500", limit.Limit)
+	require.Equal(t, 2, upstream)
+
+	// malformed budgets are refused
+	require.Equal(t, http.StatusPaymentRequired, send("lots").Code)
+
+	router := mux.NewRouter()
+	router.HandleFunc(RouteX402Spend, proxy.handleX402Spend).Methods(http.MethodGet)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/x402/spend/"+payer, nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	var summary X402SpendSummary
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&summary))
+	require.Len(t, summary.Spend, 2)
+	for _, item := range summary.Spend {
+		require.Equal(t, "2000", item.Amount)
+		require.Equal(t, int64(2), item.Requests)
+	}
+	require.Equal(t, "2500", summary.Caps[testRequirements().Network].Daily)
+}
@@ -4,13 +4,19 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
 )
 
 func TestX402Handler_BuildPaymentRequirements(t *testing.T) {
 	handler := NewX402Handler("0x1234567890abcdef1234567890abcdef12345678")
 	
-	requirements := handler.BuildPaymentRequirements("eth", "/eth/blockNumber")
+	requirements := handler.BuildPaymentRequirements("eth", "/eth/blockNumber", 1)
 	
 	// Check version
 	if requirements.X402Version != 2 {
@@ -26,7 +32,7 @@ func TestX402Handler_BuildPaymentRequirements(t *testing.T) {
 	hasUSDC := false
 	for _, opt := range requirements.Accepts {
 		if opt.Extra != nil {
-			if name, ok := opt.Extra["name"].(string); ok && name == "USDC" {
+			if name, ok := opt.Extra["name"].(string); ok && name == "USD Coin" {
 				hasUSDC = true
 				break
 			}
@@ -64,7 +70,7 @@ func TestX402Handler_WritePaymentRequired(t *testing.T) {
 	rec := httptest.NewRecorder()
 	
 	// Write payment required response
-	handler.WritePaymentRequired(rec, "eth", req.URL.String())
+	handler.WritePaymentRequired(rec, "eth", req.URL.String(), 1)
 	
 	// Check status code
 	if rec.Code != http.StatusPaymentRequired {
@@ -148,3 +154,66 @@ func TestExtractService(t *testing.T) {
 	
 	t.Log("Service extraction working correctly")
 }
+
+func TestInitX402Modes(t *testing.T) {
+	logger := log.NewNopLogger()
+
+	// off: no handler, no routes
+	proxy := &Proxy{Config: conf.Configuration{X402Mode: conf.X402ModeOff}, logger: logger}
+	require.NoError(t, proxy.InitX402())
+	require.Nil(t, proxy.x402)
+
+	// production without a payment address refuses to start
+	proxy = &Proxy{Config: conf.Configuration{X402Mode: conf.X402ModeProduction, X402Verifier: X402VerifierLocal}, logger: logger}
+	require.Error(t, proxy.InitX402())
+
+	// production with an unknown verifier refuses to start
+	proxy = &Proxy{Config: conf.Configuration{X402Mode: conf.X402ModeProduction, X402ProviderAddress: testPayTo, X402Verifier: "bogus"}, logger: logger}
+	require.Error(t, proxy.InitX402())
+
+	// production with the local verifier
+	proxy = &Proxy{Config: conf.Configuration{X402Mode: conf.X402ModeProduction, X402ProviderAddress: testPayTo, X402Verifier: X402VerifierLocal}, logger: logger}
+	require.NoError(t, proxy.InitX402())
+	require.NotNil(t, proxy.x402)
+	require.False(t, proxy.x402.DevMode)
+
+	// native ARKEO payments need a persistent replay store
+	proxy = &Proxy{Config: conf.Configuration{X402Mode: conf.X402ModeProduction, X402ProviderAddress: testPayTo, X402Verifier: X402VerifierLocal, SourceChain: "http://localhost:1317"}, logger: logger}
+	require.Error(t, proxy.InitX402())
+	proxy.Config.X402PaymentStoreLocation = t.TempDir()
+	require.NoError(t, proxy.InitX402())
+	require.NoError(t, proxy.x402.Store.Close())
+	proxy = &Proxy{Config: conf.Configuration{X402Mode: conf.X402ModeProduction, X402ProviderAddress: testPayTo, X402Verifier: X402VerifierLocal}, logger: logger}
+	require.NoError(t, proxy.InitX402())
+
+	// a garbage payment is rejected in production
+	verified, _, err := proxy.x402.VerifyPayment("garbage", 1)
+	require.Error(t, err)
+	require.False(t, verified)
+
+	// dev mode accepts anything and flags responses
+	proxy = &Proxy{Config: conf.Configuration{X402Mode: conf.X402ModeDev}, logger: logger}
+	require.NoError(t, proxy.InitX402())
+	require.True(t, proxy.x402.DevMode)
+	verified, settlementID, err := proxy.x402.VerifyPayment("garbage", 1)
+	require.NoError(t, err)
+	require.True(t, verified)
+	require.True(t, strings.HasPrefix(settlementID, "dev-"))
+
+	rec := httptest.NewRecorder()
+	proxy.x402.WritePaymentRequired(rec, "eth", "/x402/eth/", 1)
+	require.Equal(t, "dev", rec.Header().Get(X402ModeHeader))
+
+	// unknown mode
+	proxy = &Proxy{Config: conf.Configuration{X402Mode: "yolo"}, logger: logger}
+	require.Error(t, proxy.InitX402())
+}
+
+func TestX402Handler_NoVerifier(t *testing.T) {
+	handler := NewX402Handler("0x1234567890abcdef1234567890abcdef12345678")
+
+	// without a verifier no payment is ever accepted
+	verified, _, err := handler.VerifyPayment("test-payment-payload", 1)
+	require.Error(t, err)
+	require.False(t, verified)
+}
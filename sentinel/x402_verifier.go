@@ -0,0 +1,245 @@
+package sentinel
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// PaymentVerifier verifies and settles x402 payment payloads against the
+// requirements the sentinel advertised. Implementations may call out to a
+// remote facilitator or check the payload locally.
+type PaymentVerifier interface {
+	// Verify checks the payment payload without moving any funds.
+	Verify(paymentPayload string, requirements PaymentRequirements) (*VerifyResponse, error)
+
+	// Settle executes a payment that has already been verified. For "upto"
+	// payments requirements.Amount is the metered amount to charge, at most the
+	// amount that was verified.
+	Settle(paymentPayload string, requirements PaymentRequirements) (*SettleResponse, error)
+}
+
+// NetworkVerifier routes a payment to the verifier of its network: "arkeo:"
+// networks go to Arkeo, everything else to EVM
+type NetworkVerifier struct {
+	EVM   PaymentVerifier
+	Arkeo PaymentVerifier
+}
+
+func (v NetworkVerifier) Verify(paymentPayload string, requirements PaymentRequirements) (*VerifyResponse, error) {
+	verifier, err := v.verifierFor(requirements.Network)
+	if err != nil {
+		return nil, err
+	}
+	return verifier.Verify(paymentPayload, requirements)
+}
+
+func (v NetworkVerifier) Settle(paymentPayload string, requirements PaymentRequirements) (*SettleResponse, error) {
+	verifier, err := v.verifierFor(requirements.Network)
+	if err != nil {
+		return nil, err
+	}
+	return verifier.Settle(paymentPayload, requirements)
+}
+
+func (v NetworkVerifier) verifierFor(network string) (PaymentVerifier, error) {
+	verifier := v.EVM
+	if strings.HasPrefix(network, "arkeo:") {
+		verifier = v.Arkeo
+	}
+	if verifier == nil {
+		return nil, newX402Error(X402ReasonWrongNetwork, "no payment verifier for network %s", network)
+	}
+	return verifier, nil
+}
+
+// DevVerifier accepts every payment without checking it. It is only used in
+// x402 dev mode and must never serve a public sentinel.
+type DevVerifier struct{}
+
+// Verify accepts any payload
+func (DevVerifier) Verify(paymentPayload string, requirements PaymentRequirements) (*VerifyResponse, error) {
+	return &VerifyResponse{Valid: true, Payer: devPayer(paymentPayload)}, nil
+}
+
+// Settle pretends to settle the payment
+func (DevVerifier) Settle(paymentPayload string, requirements PaymentRequirements) (*SettleResponse, error) {
+	return &SettleResponse{
+		Success:      true,
+		SettlementID: "dev-" + time.Now().Format("20060102150405"),
+		Payer:        devPayer(paymentPayload),
+	}, nil
+}
+
+// devPayer best effort extracts the payer of an unverified payload
+func devPayer(paymentPayload string) string {
+	payload, err := DecodePaymentPayload(paymentPayload)
+	if err != nil {
+		return ""
+	}
+	evm, err := payload.ExactEVM()
+	if err != nil {
+		return ""
+	}
+	return evm.Authorization.From
+}
+
+// PaymentPayload is the decoded content of the X-PAYMENT header
+type PaymentPayload struct {
+	X402Version int    `json:"x402Version"`
+	Scheme      string `json:"scheme,omitempty"`
+	Network     string `json:"network,omitempty"`
+
+	// Accepted echoes the requirement the client signed for (x402 v2)
+	Accepted *PaymentRequirements `json:"accepted,omitempty"`
+
+	// Payload is the scheme specific part of the payment
+	Payload json.RawMessage `json:"payload"`
+}
+
+// ExactEVMPayload is the scheme specific payload of an "exact" payment on an
+// EVM network, an EIP-3009 TransferWithAuthorization signed by the payer
+type ExactEVMPayload struct {
+	Signature     string               `json:"signature"`
+	Authorization EIP3009Authorization `json:"authorization"`
+}
+
+// EIP3009Authorization is the TransferWithAuthorization message signed by the payer
+type EIP3009Authorization struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Value       string `json:"value"`
+	ValidAfter  string `json:"validAfter"`
+	ValidBefore string `json:"validBefore"`
+	Nonce       string `json:"nonce"`
+}
+
+// UptoEVMPayload is the scheme specific payload of an "upto" payment on an EVM
+// network, an EIP-2612 permit letting the provider pull at most Value from the
+// payer once the metered amount is known
+type UptoEVMPayload struct {
+	Signature string        `json:"signature"`
+	Permit    EIP2612Permit `json:"permit"`
+}
+
+// EIP2612Permit is the Permit message signed by the payer
+type EIP2612Permit struct {
+	Owner    string `json:"owner"`
+	Spender  string `json:"spender"`
+	Value    string `json:"value"`
+	Nonce    string `json:"nonce"`
+	Deadline string `json:"deadline"`
+}
+
+// DecodePaymentPayload decodes an X-PAYMENT header. The header is expected to
+// be base64 encoded JSON, plain JSON is accepted as well.
+func DecodePaymentPayload(header string) (PaymentPayload, error) {
+	var payload PaymentPayload
+
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return payload, newX402Error(X402ReasonInvalidPayload, "empty payment payload")
+	}
+
+	raw := []byte(header)
+	if !strings.HasPrefix(header, "{") {
+		var err error
+		raw, err = decodeBase64(header)
+		if err != nil {
+			return payload, newX402Error(X402ReasonInvalidPayload, "payment payload is not valid base64: %w", err)
+		}
+	}
+
+	if err := json.Unmarshal(raw, &payload); err != nil {
+		return payload, newX402Error(X402ReasonInvalidPayload, "payment payload is not valid json: %w", err)
+	}
+	if payload.Scheme == "" && payload.Accepted != nil {
+		payload.Scheme = payload.Accepted.Scheme
+	}
+	if payload.Network == "" && payload.Accepted != nil {
+		payload.Network = payload.Accepted.Network
+	}
+	payload.Network = normalizeX402Network(payload.Network)
+	if payload.Scheme == "" || payload.Network == "" {
+		return payload, newX402Error(X402ReasonInvalidPayload, "payment payload is missing scheme or network")
+	}
+	return payload, nil
+}
+
+// ExactEVM returns the payload as an EIP-3009 authorization
+func (p PaymentPayload) ExactEVM() (ExactEVMPayload, error) {
+	var evm ExactEVMPayload
+	if err := json.Unmarshal(p.Payload, &evm); err != nil {
+		return evm, newX402Error(X402ReasonInvalidPayload, "invalid exact evm payload: %w", err)
+	}
+	if evm.Signature == "" || evm.Authorization.From == "" {
+		return evm, newX402Error(X402ReasonInvalidPayload, "exact evm payload is missing signature or authorization")
+	}
+	return evm, nil
+}
+
+// UptoEVM returns the payload as an EIP-2612 permit
+func (p PaymentPayload) UptoEVM() (UptoEVMPayload, error) {
+	var evm UptoEVMPayload
+	if err := json.Unmarshal(p.Payload, &evm); err != nil {
+		return evm, newX402Error(X402ReasonInvalidPayload, "invalid upto evm payload: %w", err)
+	}
+	if evm.Signature == "" || evm.Permit.Owner == "" {
+		return evm, newX402Error(X402ReasonInvalidPayload, "upto evm payload is missing signature or permit")
+	}
+	return evm, nil
+}
+
+// ArkeoTxPayload is the scheme specific payload of an "exact" payment on an
+// "arkeo:<chain-id>" network. It carries either a signed Cosmos tx with a bank
+// MsgSend to the provider, which the sentinel broadcasts, or the hash of such a
+// tx the agent already broadcast.
+type ArkeoTxPayload struct {
+	// Tx is the base64 encoded signed TxRaw
+	Tx string `json:"tx,omitempty"`
+	// TxHash is the hash of a broadcast tx
+	TxHash string `json:"txHash,omitempty"`
+	// PubKey is the bech32 account public key of the sender of a broadcast
+	// tx, and Signature its hex signature of "<network>:<tx hash>". Tx hashes
+	// are public, the signature proves the requester paid.
+	PubKey    string `json:"pubKey,omitempty"`
+	Signature string `json:"signature,omitempty"`
+}
+
+// ArkeoTx returns the payload as a Cosmos tx or tx hash
+func (p PaymentPayload) ArkeoTx() (ArkeoTxPayload, error) {
+	var tx ArkeoTxPayload
+	if err := json.Unmarshal(p.Payload, &tx); err != nil {
+		return tx, newX402Error(X402ReasonInvalidPayload, "invalid arkeo payload: %w", err)
+	}
+	if tx.Tx == "" && tx.TxHash == "" {
+		return tx, newX402Error(X402ReasonInvalidPayload, "arkeo payload is missing tx or txHash")
+	}
+	return tx, nil
+}
+
+// Hash returns the upper case hex hash of the tx, as indexed by the chain
+func (t ArkeoTxPayload) Hash() (string, error) {
+	if t.Tx == "" {
+		return strings.ToUpper(t.TxHash), nil
+	}
+	txBytes, err := decodeBase64(t.Tx)
+	if err != nil {
+		return "", newX402Error(X402ReasonInvalidPayload, "invalid tx encoding: %w", err)
+	}
+	digest := sha256.Sum256(txBytes)
+	return strings.ToUpper(hex.EncodeToString(digest[:])), nil
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported base64 encoding")
+}
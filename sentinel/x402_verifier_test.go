@@ -0,0 +1,191 @@
+package sentinel
+
+import (
+	"crypto/ecdsa"
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+const testPayTo = "0x1234567890abcdef1234567890abcdef12345678"
+
+func testRequirements() PaymentRequirements {
+	return PaymentRequirements{
+		Scheme:            "exact",
+		Network:           "eip155:84532",
+		Amount:            "1000",
+		Asset:             "0x036CbD53842c5426634e7929541eC2318f3dCF7e",
+		PayTo:             testPayTo,
+		MaxTimeoutSeconds: 60,
+		Extra: map[string]interface{}{
+			"name":    "USDC",
+			"version": "2",
+		},
+	}
+}
+
+// signTestPayment builds a base64 X-PAYMENT header signed by key
+func signTestPayment(t *testing.T, key *ecdsa.PrivateKey, requirements PaymentRequirements, auth EIP3009Authorization) string {
+	t.Helper()
+	hash, err := EIP3009TypedDataHash(auth, requirements)
+	require.NoError(t, err)
+	sig, err := crypto.Sign(hash, key)
+	require.NoError(t, err)
+	sig[crypto.RecoveryIDOffset] += 27
+
+	evm, err := json.Marshal(ExactEVMPayload{Signature: hexutil.Encode(sig), Authorization: auth})
+	require.NoError(t, err)
+	raw, err := json.Marshal(PaymentPayload{
+		X402Version: X402Version,
+		Scheme:      requirements.Scheme,
+		Network:     requirements.Network,
+		Payload:     evm,
+	})
+	require.NoError(t, err)
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func testAuthorization(key *ecdsa.PrivateKey, value string, now time.Time) EIP3009Authorization {
+	return EIP3009Authorization{
+		From:        crypto.PubkeyToAddress(key.PublicKey).Hex(),
+		To:          testPayTo,
+		Value:       value,
+		ValidAfter:  strconv.FormatInt(now.Add(-time.Minute).Unix(), 10),
+		ValidBefore: strconv.FormatInt(now.Add(time.Minute).Unix(), 10),
+		Nonce:       hexutil.Encode(crypto.Keccak256([]byte(value + now.String()))),
+	}
+}
+
+func TestLocalVerifier(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	now := time.Now()
+	verifier := NewLocalVerifier()
+	verifier.now = func() time.Time { return now }
+	requirements := testRequirements()
+
+	// valid payment
+	auth := testAuthorization(key, "1000", now)
+	payment := signTestPayment(t, key, requirements, auth)
+	resp, err := verifier.Verify(payment, requirements)
+	require.NoError(t, err)
+	require.True(t, resp.Valid, resp.Error)
+	require.Equal(t, auth.From, resp.Payer)
+
+	settle, err := verifier.Settle(payment, requirements)
+	require.NoError(t, err)
+	require.True(t, settle.Success)
+	require.Equal(t, auth.Nonce, settle.SettlementID)
+	require.True(t, settle.Uncollected)
+
+	// insufficient amount
+	payment = signTestPayment(t, key, requirements, testAuthorization(key, "999", now))
+	resp, err = verifier.Verify(payment, requirements)
+	require.NoError(t, err)
+	require.False(t, resp.Valid)
+
+	// expired authorization
+	expired := testAuthorization(key, "1000", now)
+	expired.ValidBefore = strconv.FormatInt(now.Add(time.Second).Unix(), 10)
+	payment = signTestPayment(t, key, requirements, expired)
+	resp, err = verifier.Verify(payment, requirements)
+	require.NoError(t, err)
+	require.False(t, resp.Valid)
+
+	// not yet valid
+	early := testAuthorization(key, "1000", now)
+	early.ValidAfter = strconv.FormatInt(now.Add(time.Minute).Unix(), 10)
+	payment = signTestPayment(t, key, requirements, early)
+	resp, err = verifier.Verify(payment, requirements)
+	require.NoError(t, err)
+	require.False(t, resp.Valid)
+
+	// wrong recipient
+	wrongTo := testAuthorization(key, "1000", now)
+	wrongTo.To = "0x0000000000000000000000000000000000000001"
+	payment = signTestPayment(t, key, requirements, wrongTo)
+	resp, err = verifier.Verify(payment, requirements)
+	require.NoError(t, err)
+	require.False(t, resp.Valid)
+
+	// signed by someone other than the payer
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	forged := testAuthorization(key, "1000", now)
+	payment = signTestPayment(t, other, requirements, forged)
+	resp, err = verifier.Verify(payment, requirements)
+	require.NoError(t, err)
+	require.False(t, resp.Valid)
+
+	// signed for another network
+	otherNetwork := requirements
+	otherNetwork.Network = "eip155:8453"
+	payment = signTestPayment(t, key, otherNetwork, testAuthorization(key, "1000", now))
+	resp, err = verifier.Verify(payment, requirements)
+	require.NoError(t, err)
+	require.False(t, resp.Valid)
+
+	// garbage payload
+	_, err = verifier.Verify("not-a-payment", requirements)
+	require.Error(t, err)
+}
+
+func TestDecodePaymentPayload(t *testing.T) {
+	raw := `{"x402Version":2,"accepted":{"scheme":"exact","network":"eip155:8453"},"payload":{}}`
+
+	payload, err := DecodePaymentPayload(raw)
+	require.NoError(t, err)
+	require.Equal(t, "exact", payload.Scheme)
+	require.Equal(t, "eip155:8453", payload.Network)
+
+	payload, err = DecodePaymentPayload(base64.StdEncoding.EncodeToString([]byte(raw)))
+	require.NoError(t, err)
+	require.Equal(t, "eip155:8453", payload.Network)
+
+	_, err = DecodePaymentPayload(`{"x402Version":2,"payload":{}}`)
+	require.Error(t, err)
+}
+
+func TestX402Handler_AuthorizePaymentDuringReload(t *testing.T) {
+	handler := NewX402Handler(testPayTo)
+	handler.Verifier = NewLocalVerifier()
+	requirements := testRequirements()
+	other := testRequirements()
+	other.Network = "eip155:8453"
+	other.Amount = "5"
+	handler.SetPrices("", "", []PaymentRequirements{requirements})
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	payment := signTestPayment(t, key, requirements, testAuthorization(key, "1000", time.Now()))
+
+	// the options are reloaded in another order while payments are authorized
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if i%2 == 0 {
+				handler.SetPrices("", "", []PaymentRequirements{other, requirements})
+			} else {
+				handler.SetPrices("", "", []PaymentRequirements{requirements})
+			}
+		}
+	}()
+	for i := 0; i < 2000; i++ {
+		authorized, err := handler.AuthorizePayment("eth-mainnet-fullnode", payment, 1)
+		require.NoError(t, err)
+		require.Equal(t, requirements.Network, authorized.Requirements.Network)
+		require.Equal(t, "1000", authorized.UnitPrice)
+	}
+}
@@ -0,0 +1,326 @@
+package sentinel
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/arkeonetwork/arkeo/sentinel/conf"
+)
+
+const (
+	// X402CloseCodePaymentRequired closes a WebSocket whose paid budget is
+	// spent, it sits in the private range of close codes
+	X402CloseCodePaymentRequired = 4402
+
+	// X402WebSocketBudgetHeader reports the budget bought with the upgrade,
+	// "frames=N" or "seconds=T"
+	X402WebSocketBudgetHeader = "X-PAYMENT-WS-BUDGET"
+
+	defaultX402WebSocketFrames         = 1000
+	defaultX402WebSocketMaxFrames      = 1000000
+	defaultX402WebSocketMaxSeconds     = 86400
+	defaultX402WebSocketUnitsPerSecond = 1
+
+	x402WebSocketCloseTimeout = 5 * time.Second
+	x402WebSocketCloseReason  = "payment required"
+)
+
+// x402WebSocketUpgrader accepts upgrades from any origin, x402 clients are
+// agents rather than browsers and every connection is paid for
+var x402WebSocketUpgrader = websocket.Upgrader{
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// withX402WebSocketDefaults fills unset WebSocket limits with defaults
+func withX402WebSocketDefaults(config conf.X402WebSocketConfig) conf.X402WebSocketConfig {
+	if config.DefaultFrames == 0 {
+		config.DefaultFrames = defaultX402WebSocketFrames
+	}
+	if config.MaxFrames == 0 {
+		config.MaxFrames = defaultX402WebSocketMaxFrames
+	}
+	if config.MaxSeconds == 0 {
+		config.MaxSeconds = defaultX402WebSocketMaxSeconds
+	}
+	if config.UnitsPerSecond == 0 {
+		config.UnitsPerSecond = defaultX402WebSocketUnitsPerSecond
+	}
+	return config
+}
+
+// X402WebSocketBudget is what the payment of a WebSocket upgrade buys: a
+// number of frames in either direction, or unlimited frames for a duration
+type X402WebSocketBudget struct {
+	Frames  uint64
+	Seconds uint64
+
+	mu        sync.Mutex
+	remaining uint64
+}
+
+// String returns the budget as sent in X-PAYMENT-WS-BUDGET
+func (b *X402WebSocketBudget) String() string {
+	if b.Seconds > 0 {
+		return "seconds=" + strconv.FormatUint(b.Seconds, 10)
+	}
+	return "frames=" + strconv.FormatUint(b.Frames, 10)
+}
+
+// Spend takes one frame from the budget, it reports false once the budget is
+// spent. Time budgets are enforced by the connection deadline instead.
+func (b *X402WebSocketBudget) Spend() bool {
+	if b.Seconds > 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.remaining == 0 {
+		return false
+	}
+	b.remaining--
+	return true
+}
+
+// QuoteWebSocket returns the budget asked for in the ?frames=N or ?seconds=T
+// query of an upgrade and its compute units. Each frame costs the compute
+// units of a request to the service.
+func (h *X402Handler) QuoteWebSocket(service string, query url.Values) (*X402WebSocketBudget, uint64, error) {
+	config := withX402WebSocketDefaults(h.WebSocket)
+	frames, _ := strconv.ParseUint(query.Get("frames"), 10, 64)
+	seconds, _ := strconv.ParseUint(query.Get("seconds"), 10, 64)
+	switch {
+	case frames > 0 && seconds > 0:
+		return nil, 0, fmt.Errorf("a WebSocket budget is either a number of frames or of seconds")
+	case seconds > 0:
+		if seconds > config.MaxSeconds {
+			return nil, 0, fmt.Errorf("at most %d seconds per WebSocket", config.MaxSeconds)
+		}
+		return &X402WebSocketBudget{Seconds: seconds}, seconds * config.UnitsPerSecond, nil
+	default:
+		if frames == 0 {
+			frames = config.DefaultFrames
+		}
+		if frames > config.MaxFrames {
+			return nil, 0, fmt.Errorf("at most %d frames per WebSocket", config.MaxFrames)
+		}
+		return &X402WebSocketBudget{Frames: frames, remaining: frames}, frames * h.Pricing.DefaultUnits(service), nil
+	}
+}
+
+// serveX402WebSocket sells a WebSocket to service. The payment is authorized,
+// the upstream dialed and only then the payment settled and the client
+// upgraded, so a failed upstream costs nothing. Frames are relayed until the
+// budget is spent, then the client gets a payment required close frame.
+func (p *Proxy) serveX402WebSocket(w http.ResponseWriter, r *http.Request, service string) {
+	budget, units, err := p.x402.QuoteWebSocket(service, r.URL.Query())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	hasPayment, paymentPayload := p.x402.CheckPaymentHeader(r)
+	if !hasPayment {
+		p.x402.WritePaymentRequired(w, service, r.URL.String(), units)
+		return
+	}
+
+	target, err := p.x402WebSocketTarget(r, service)
+	if errors.Is(err, errNoUpstream) {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	payment, err := p.x402.AuthorizePayment(service, paymentPayload, units)
+	if err != nil {
+		p.logger.Error("x402 websocket payment failed", "service", service, "reason", X402ErrorReason(err), "error", err)
+		p.x402.WritePaymentError(w, service, r.URL.String(), units, err)
+		return
+	}
+
+	// the connection holds a slot of the payer until it closes
+	release, err := p.x402.Limits.AcquirePayer(payment.Payer)
+	if err != nil {
+		p.x402.ReleasePayment(payment)
+		p.logger.Info("x402 websocket refused", "service", service, "payer", payment.Payer, "error", err)
+		p.x402.WriteLimitError(w, asX402LimitError(err))
+		return
+	}
+	defer release()
+
+	spend, err := p.x402.ReserveSpend(r, payment)
+	if err != nil {
+		p.x402.ReleasePayment(payment)
+		p.logger.Info("x402 websocket refused", "service", service, "payer", payment.Payer, "error", err)
+		p.x402.WritePaymentError(w, service, r.URL.String(), units, err)
+		return
+	}
+	defer spend.Release()
+
+	upstream, _, err := websocket.DefaultDialer.Dial(target.String(), x402WebSocketHeaders(r.Header))
+	if err != nil {
+		p.x402.ReleasePayment(payment)
+		p.logger.Error("failed to dial x402 websocket upstream", "service", service, "error", err)
+		http.Error(w, "upstream unavailable", http.StatusBadGateway)
+		return
+	}
+	defer upstream.Close()
+
+	settleResp, err := p.x402.SettlePayment(payment, units)
+	if err != nil {
+		p.logger.Error("x402 websocket settlement failed", "service", service, "reason", X402ErrorReason(err), "error", err)
+		w.Header().Set(X402PaymentResponseHeader, NewX402PaymentFailure(payment, err).Encode())
+		p.x402.WritePaymentError(w, service, r.URL.String(), units, err)
+		return
+	}
+	receipt := NewX402PaymentResponse(payment, settleResp, units)
+	p.x402.CreditRevenue(service, payment, receipt.Amount, 1)
+	spend.Settle(receipt.Amount)
+	p.x402.RecordReceipt(service, payment, receipt, http.StatusSwitchingProtocols)
+
+	header := http.Header{}
+	header.Set(X402PaymentResponseHeader, receipt.Encode())
+	header.Set(X402WebSocketBudgetHeader, budget.String())
+	if p.x402.DevMode {
+		header.Set(X402ModeHeader, "dev")
+	}
+	if protocol := upstream.Subprotocol(); protocol != "" {
+		header.Set("Sec-WebSocket-Protocol", protocol)
+	}
+	client, err := x402WebSocketUpgrader.Upgrade(w, r, header)
+	if err != nil {
+		// the upgrader already answered the client
+		p.logger.Error("failed to upgrade x402 websocket", "service", service, "error", err)
+		return
+	}
+	defer client.Close()
+	// the budget replaces the other WebSocket limits of the service, but not
+	// its message size
+	svc, _ := p.serviceConfig(service)
+	maxMessageSize := withWebSocketDefaults(svc.WebSocket).MaxMessageSize
+	client.SetReadLimit(maxMessageSize)
+	upstream.SetReadLimit(maxMessageSize)
+
+	p.logger.Info("x402 websocket opened",
+		"service", service,
+		"payer", receipt.Payer,
+		"budget", budget.String(),
+		"tx_hash", receipt.TxHash,
+	)
+	reason := relayX402WebSocket(client, upstream, budget)
+	p.logger.Info("x402 websocket closed", "service", service, "payer", receipt.Payer, "reason", reason)
+}
+
+// x402WebSocketTarget returns the upstream WebSocket URL of an
+// /x402/{service}/... request, without the budget query
+func (p *Proxy) x402WebSocketTarget(r *http.Request, service string) (*url.URL, error) {
+	pool := p.upstreamPool(service)
+	if pool == nil {
+		return nil, fmt.Errorf("service not found")
+	}
+
+	upstream := pool.Pick(nil)
+	if upstream == nil {
+		return nil, errNoUpstream
+	}
+	target := *upstream.URL
+	switch target.Scheme {
+	case "https", "wss":
+		target.Scheme = "wss"
+	default:
+		target.Scheme = "ws"
+	}
+	pathParts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	rest := ""
+	if len(pathParts) > 2 {
+		rest = "/" + strings.Join(pathParts[2:], "/")
+	}
+	target.Path = joinUpstreamPath(upstream.URL.Path, rest)
+	query := r.URL.Query()
+	query.Del("frames")
+	query.Del("seconds")
+	target.RawQuery = query.Encode()
+	return &target, nil
+}
+
+// x402WebSocketHeaders returns the request headers to forward upstream. The
+// handshake headers are set by the dialer and the payment is ours.
+func x402WebSocketHeaders(in http.Header) http.Header {
+	out := http.Header{}
+	for k, v := range in {
+		switch http.CanonicalHeaderKey(k) {
+		case "Upgrade", "Connection", "Sec-Websocket-Key", "Sec-Websocket-Version",
+			"Sec-Websocket-Extensions", "X-Payment", X402SessionHeader:
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// relayX402WebSocket copies frames between client and upstream until either
+// side closes or the budget is spent, and returns why the relay ended
+func relayX402WebSocket(client, upstream *websocket.Conn, budget *X402WebSocketBudget) string {
+	done := make(chan string, 2)
+	var once sync.Once
+	closeClient := func(code int, text string) {
+		once.Do(func() {
+			msg := websocket.FormatCloseMessage(code, text)
+			_ = client.WriteControl(websocket.CloseMessage, msg, time.Now().Add(x402WebSocketCloseTimeout))
+		})
+	}
+
+	relay := func(from, to *websocket.Conn, toClient bool) {
+		for {
+			messageType, data, err := from.ReadMessage()
+			if err != nil {
+				code, text := websocket.CloseNormalClosure, ""
+				if closeErr, ok := err.(*websocket.CloseError); ok {
+					code, text = closeErr.Code, closeErr.Text
+				}
+				if toClient {
+					closeClient(code, text)
+				} else {
+					_ = upstream.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, text), time.Now().Add(x402WebSocketCloseTimeout))
+				}
+				done <- fmt.Sprintf("closed: %d", code)
+				return
+			}
+			if !budget.Spend() {
+				closeClient(X402CloseCodePaymentRequired, x402WebSocketCloseReason)
+				done <- "budget spent"
+				return
+			}
+			if err := to.WriteMessage(messageType, data); err != nil {
+				done <- fmt.Sprintf("write failed: %s", err)
+				return
+			}
+		}
+	}
+	go relay(client, upstream, false)
+	go relay(upstream, client, true)
+
+	var expired <-chan time.Time
+	if budget.Seconds > 0 {
+		timer := time.NewTimer(time.Duration(budget.Seconds) * time.Second)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case reason := <-done:
+		return reason
+	case <-expired:
+		closeClient(X402CloseCodePaymentRequired, x402WebSocketCloseReason)
+		return "budget spent"
+	}
+}
@@ -0,0 +1,110 @@
+package sentinel
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/libs/log"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+func TestX402WebSocket(t *testing.T) {
+	// the upstream echoes every message
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Empty(t, r.Header.Get("X-PAYMENT"))
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if err := conn.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	}))
+	defer upstream.Close()
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	paymentStore, err := NewX402PaymentStore("")
+	require.NoError(t, err)
+	defer paymentStore.Close()
+	handler := NewX402Handler(testPayTo)
+	handler.Verifier = NewLocalVerifier()
+	handler.Store = paymentStore
+	unitPrice := testRequirements()
+	unitPrice.Amount = "100"
+	handler.Accepts = []PaymentRequirements{unitPrice}
+	proxy := &Proxy{
+		x402:       handler,
+		logger:     log.NewNopLogger(),
+		proxies:    map[string]*url.URL{"eth": upstreamURL},
+		serviceIDs: map[string]int32{"eth": 1},
+	}
+	server := httptest.NewServer(proxy.x402Middleware(http.HandlerFunc(proxy.handleX402Proxy)))
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/x402/eth/?frames=3"
+
+	// without payment the budget is quoted
+	_, resp, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	require.ErrorIs(t, err, websocket.ErrBadHandshake)
+	require.Equal(t, http.StatusPaymentRequired, resp.StatusCode)
+	var quote PaymentRequiredResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&quote))
+	require.Equal(t, "300", quote.Accepts[0].Amount)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	header := http.Header{}
+	header.Set("X-PAYMENT", signTestPayment(t, key, quote.Accepts[0], testAuthorization(key, "300", time.Now())))
+	conn, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+	require.NoError(t, err)
+	defer conn.Close()
+	require.NotEmpty(t, resp.Header.Get(X402PaymentResponseHeader))
+	require.Equal(t, "frames=3", resp.Header.Get(X402WebSocketBudgetHeader))
+
+	// the request and its echo spend two frames
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(`{"method":"eth_subscribe"}`)))
+	_, data, err := conn.ReadMessage()
+	require.NoError(t, err)
+	require.Equal(t, `{"method":"eth_subscribe"}`, string(data))
+
+	// the third frame reaches the upstream, its echo is over budget
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("ping")))
+	_, _, err = conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.ErrorAs(t, err, &closeErr)
+	require.Equal(t, X402CloseCodePaymentRequired, closeErr.Code)
+}
+
+func TestX402WebSocket_Quote(t *testing.T) {
+	handler := NewX402Handler(testPayTo)
+
+	budget, units, err := handler.QuoteWebSocket("eth", url.Values{})
+	require.NoError(t, err)
+	require.Equal(t, "frames=1000", budget.String())
+	require.Equal(t, uint64(1000), units)
+
+	budget, units, err = handler.QuoteWebSocket("eth", url.Values{"seconds": {"60"}})
+	require.NoError(t, err)
+	require.Equal(t, "seconds=60", budget.String())
+	require.Equal(t, uint64(60), units)
+	require.True(t, budget.Spend())
+
+	_, _, err = handler.QuoteWebSocket("eth", url.Values{"seconds": {"60"}, "frames": {"10"}})
+	require.Error(t, err)
+	_, _, err = handler.QuoteWebSocket("eth", url.Values{"frames": {"1000001"}})
+	require.Error(t, err)
+}
@@ -40,6 +40,7 @@ func GetTxCmd() *cobra.Command {
 	cmd.AddCommand(CmdRegisterService())
 	cmd.AddCommand(CmdUpdateService())
 	cmd.AddCommand(CmdRemoveService())
+	cmd.AddCommand(CmdReportX402Revenue())
 	// this line is used by starport scaffolding # 1
 
 	return cmd
@@ -0,0 +1,66 @@
+package cli
+
+import (
+	"strings"
+
+	"github.com/arkeonetwork/arkeo/common"
+	"github.com/arkeonetwork/arkeo/common/cosmos"
+	"github.com/arkeonetwork/arkeo/x/arkeo/types"
+
+	"github.com/cosmos/cosmos-sdk/client"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/client/tx"
+	"github.com/spf13/cast"
+	"github.com/spf13/cobra"
+)
+
+func CmdReportX402Revenue() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "report-x402-revenue [pubkey] [service] [requests] [revenue] [period-end]",
+		Short: "Broadcast message reportX402Revenue",
+		Args:  cobra.ExactArgs(5),
+		RunE: func(cmd *cobra.Command, args []string) (err error) {
+			clientCtx, err := client.GetClientTxContext(cmd)
+			if err != nil {
+				return err
+			}
+
+			pubkey, err := common.NewPubKey(args[0])
+			if err != nil {
+				return err
+			}
+
+			argRequests, err := cast.ToInt64E(args[2])
+			if err != nil {
+				return err
+			}
+
+			revenue, err := cosmos.ParseCoin(args[3])
+			if err != nil {
+				return err
+			}
+
+			argPeriodEnd, err := cast.ToInt64E(args[4])
+			if err != nil {
+				return err
+			}
+
+			msg := types.NewMsgReportX402Revenue(
+				clientCtx.GetFromAddress(),
+				pubkey,
+				strings.ToLower(args[1]),
+				argRequests,
+				revenue,
+				argPeriodEnd,
+			)
+			if err := msg.ValidateBasic(); err != nil {
+				return err
+			}
+			return tx.GenerateOrBroadcastTxCLI(clientCtx, cmd.Flags(), msg)
+		},
+	}
+
+	flags.AddTxFlagsToCmd(cmd)
+
+	return cmd
+}
@@ -21,6 +21,7 @@ func NewConfigValue010() *ConfigVals {
 			EmissionCurve:              10,                         // rate in which the reserve is depleted to pay validators
 			ValidatorPayoutCycle:       1,                          // how often validators are paid out rewards
 			VersionConsensus:           90,                         // out of 100, percentage of nodes on a specific version before it is accepted
+			HandlerReportX402Revenue:   0,                          // enable/disable report x402 revenue handler
 		},
 		boolValues:   map[ConfigName]bool{},
 		stringValues: map[ConfigName]string{},
@@ -23,6 +23,7 @@ const (
 	EmissionCurve
 	ValidatorPayoutCycle
 	VersionConsensus
+	HandlerReportX402Revenue
 )
 
 var nameToString = map[ConfigName]string{
@@ -41,6 +42,7 @@ var nameToString = map[ConfigName]string{
 	EmissionCurve:              "EmissionCurve",
 	ValidatorPayoutCycle:       "ValidatorPayoutCycle",
 	VersionConsensus:           "VersionConsensus",
+	HandlerReportX402Revenue:   "HandlerReportX402Revenue",
 }
 
 // String implement fmt.stringer
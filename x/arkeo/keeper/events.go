@@ -102,3 +102,17 @@ func (mgr Manager) EmitValidatorPayoutEvent(ctx cosmos.Context, acc cosmos.AccAd
 		},
 	)
 }
+
+func (k msgServer) EmitX402RevenueEvent(ctx cosmos.Context, reserve cosmos.Int, msg *types.MsgReportX402Revenue) error {
+	return ctx.EventManager().EmitTypedEvent(
+		&types.EventX402Revenue{
+			Provider:  msg.Provider,
+			Service:   msg.Service,
+			Requests:  msg.Requests,
+			Revenue:   msg.Revenue,
+			Reserve:   reserve,
+			PeriodEnd: msg.PeriodEnd,
+			Height:    ctx.BlockHeight(),
+		},
+	)
+}
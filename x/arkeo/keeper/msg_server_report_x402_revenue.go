@@ -0,0 +1,80 @@
+package keeper
+
+import (
+	"context"
+
+	"cosmossdk.io/errors"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/arkeonetwork/arkeo/common"
+	"github.com/arkeonetwork/arkeo/common/cosmos"
+	"github.com/arkeonetwork/arkeo/x/arkeo/configs"
+	"github.com/arkeonetwork/arkeo/x/arkeo/types"
+)
+
+func (k msgServer) ReportX402Revenue(goCtx context.Context, msg *types.MsgReportX402Revenue) (*types.MsgReportX402RevenueResponse, error) {
+	ctx := sdk.UnwrapSDKContext(goCtx)
+
+	ctx.Logger().Info(
+		"receive MsgReportX402Revenue",
+		"provider", msg.Provider,
+		"service", msg.Service,
+		"requests", msg.Requests,
+		"revenue", msg.Revenue,
+		"period end", msg.PeriodEnd,
+	)
+
+	cacheCtx, commit := ctx.CacheContext()
+	if err := k.ReportX402RevenueValidate(cacheCtx, msg); err != nil {
+		ctx.Logger().Error("failed report x402 revenue validation", "err", err)
+		return nil, err
+	}
+
+	if err := k.ReportX402RevenueHandle(cacheCtx, msg); err != nil {
+		ctx.Logger().Error("failed report x402 revenue handle", "err", err)
+		return nil, err
+	}
+
+	commit()
+
+	return &types.MsgReportX402RevenueResponse{}, nil
+}
+
+func (k msgServer) ReportX402RevenueValidate(ctx cosmos.Context, msg *types.MsgReportX402Revenue) error {
+	if k.FetchConfig(ctx, configs.HandlerReportX402Revenue) > 0 {
+		return errors.Wrapf(types.ErrDisabledHandler, "report x402 revenue")
+	}
+
+	service, _, err := k.ResolveServiceEnum(ctx, msg.Service)
+	if err != nil {
+		return err
+	}
+	provider, err := k.GetProvider(ctx, msg.Provider, service)
+	if err != nil {
+		return err
+	}
+	if provider.Bond.IsZero() {
+		return errors.Wrapf(types.ErrProviderNotFound, "provider is not bonded for %s", msg.Service)
+	}
+
+	return nil
+}
+
+// ReportX402RevenueHandle takes the reserve tax of the reported revenue from
+// the provider, x402 income is paid to the provider directly so there is no
+// module deposit to take it from like contract settlements do
+func (k msgServer) ReportX402RevenueHandle(ctx cosmos.Context, msg *types.MsgReportX402Revenue) error {
+	reserve := common.GetSafeShare(cosmos.NewDec(k.FetchConfig(ctx, configs.ReserveTax)), cosmos.NewDec(configs.MaxBasisPoints), msg.Revenue.Amount.ToLegacyDec()).RoundInt()
+
+	if !reserve.IsZero() {
+		provider, err := msg.Provider.GetMyAddress()
+		if err != nil {
+			return err
+		}
+		if err := k.SendFromAccountToModule(ctx, provider, types.ReserveName, cosmos.NewCoins(cosmos.NewCoin(msg.Revenue.Denom, reserve))); err != nil {
+			return err
+		}
+	}
+
+	return k.EmitX402RevenueEvent(ctx, reserve, msg)
+}
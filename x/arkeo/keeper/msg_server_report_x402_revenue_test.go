@@ -0,0 +1,76 @@
+package keeper
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/common"
+	"github.com/arkeonetwork/arkeo/common/cosmos"
+	"github.com/arkeonetwork/arkeo/x/arkeo/configs"
+	"github.com/arkeonetwork/arkeo/x/arkeo/types"
+)
+
+func TestReportX402RevenueValidate(t *testing.T) {
+	ctx, k, sk := SetupKeeperWithStaking(t)
+	s := newMsgServer(k, sk)
+
+	pubkey := types.GetRandomPubKey()
+	acct, err := pubkey.GetMyAddress()
+	require.NoError(t, err)
+
+	msg := types.NewMsgReportX402Revenue(acct, pubkey, common.BTCService.String(), 10, getCoin(1000), 1700000000)
+
+	// not bonded
+	err = s.ReportX402RevenueValidate(ctx, msg)
+	require.ErrorIs(t, err, types.ErrProviderNotFound)
+
+	provider := types.NewProvider(pubkey, common.BTCService)
+	provider.Bond = cosmos.NewInt(500)
+	require.NoError(t, k.SetProvider(ctx, provider))
+
+	// happy path
+	require.NoError(t, s.ReportX402RevenueValidate(ctx, msg))
+
+	// unknown service
+	msg.Service = "not-a-service"
+	err = s.ReportX402RevenueValidate(ctx, msg)
+	require.Error(t, err)
+}
+
+func TestReportX402RevenueHandle(t *testing.T) {
+	ctx, k, sk := SetupKeeperWithStaking(t)
+	s := newMsgServer(k, sk)
+
+	pubkey := types.GetRandomPubKey()
+	acct, err := pubkey.GetMyAddress()
+	require.NoError(t, err)
+	require.NoError(t, k.MintAndSendToAccount(ctx, acct, getCoin(common.Tokens(10))))
+
+	msg := types.NewMsgReportX402Revenue(acct, pubkey, common.BTCService.String(), 10, getCoin(1000), 1700000000)
+	require.NoError(t, s.ReportX402RevenueHandle(ctx, msg))
+
+	// 10% reserve tax is taken from the provider
+	require.Equal(t, int64(100), k.GetBalanceOfModule(ctx, types.ReserveName, configs.Denom).Int64())
+	require.Equal(t, common.Tokens(10)-100, k.GetBalance(ctx, acct).AmountOf(configs.Denom).Int64())
+
+	var found bool
+	for _, event := range ctx.EventManager().Events() {
+		if event.Type == "arkeo.arkeo.EventX402Revenue" {
+			found = true
+		}
+	}
+	require.True(t, found)
+
+	// requests without revenue are not taxed
+	msg.Revenue = getCoin(0)
+	require.NoError(t, s.ReportX402RevenueHandle(ctx, msg))
+	require.Equal(t, int64(100), k.GetBalanceOfModule(ctx, types.ReserveName, configs.Denom).Int64())
+
+	// a provider without the funds cannot pay the tax
+	poor := types.GetRandomPubKey()
+	poorAcct, err := poor.GetMyAddress()
+	require.NoError(t, err)
+	msg = types.NewMsgReportX402Revenue(poorAcct, poor, common.BTCService.String(), 10, getCoin(1000), 1700000000)
+	require.Error(t, s.ReportX402RevenueHandle(ctx, msg))
+}
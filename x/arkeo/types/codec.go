@@ -18,6 +18,7 @@ func RegisterLegacyAminoCodec(cdc *codec.LegacyAmino) {
 	cdc.RegisterConcrete(&MsgRegisterService{}, "arkeo/RegisterService", nil)
 	cdc.RegisterConcrete(&MsgUpdateService{}, "arkeo/UpdateService", nil)
 	cdc.RegisterConcrete(&MsgRemoveService{}, "arkeo/RemoveService", nil)
+	cdc.RegisterConcrete(&MsgReportX402Revenue{}, "arkeo/ReportX402Revenue", nil)
 	// this line is used by starport scaffolding # 2
 }
 
@@ -32,6 +33,7 @@ func RegisterInterfaces(registry cdctypes.InterfaceRegistry) {
 		&MsgRegisterService{},
 		&MsgUpdateService{},
 		&MsgRemoveService{},
+		&MsgReportX402Revenue{},
 	)
 	// this line is used by starport scaffolding # 3
 
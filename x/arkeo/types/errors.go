@@ -43,4 +43,5 @@ var (
 	ErrInvalidVersion                         = errors.Register(ModuleName, 34, "version cannot be zero or lower")
 	ErrInvalidBlocksPerYear                   = errors.Register(ModuleName, 37, "blocks per year cannot be zero or lower")
 	ErrInvalidEmissionCurve                   = errors.Register(ModuleName, 38, "emissionCurve set is invalid")
+	ErrInvalidX402Revenue                     = errors.Register(ModuleName, 39, "invalid x402 revenue")
 )
@@ -15,6 +15,7 @@ const (
 	EventTypeRegisterService = "arkeo.arkeo.EventRegisterService"
 	EventTypeUpdateService   = "arkeo.arkeo.EventUpdateService"
 	EventTypeRemoveService   = "arkeo.arkeo.EventRemoveService"
+	EventTypeX402Revenue     = "arkeo.arkeo.EventX402Revenue"
 )
 
 func NewOpenContractEvent(openCost int64, contract *Contract) EventOpenContract {
@@ -585,6 +585,92 @@ func (m *EventValidatorPayout) GetValidator() github_com_cosmos_cosmos_sdk_types
 	return nil
 }
 
+// EventX402Revenue is emitted when a provider reports x402 revenue.
+type EventX402Revenue struct {
+	Provider  github_com_arkeonetwork_arkeo_common.PubKey `protobuf:"bytes,1,opt,name=provider,proto3,casttype=github.com/arkeonetwork/arkeo/common.PubKey" json:"provider,omitempty"`
+	Service   string                                      `protobuf:"bytes,2,opt,name=service,proto3" json:"service,omitempty"`
+	Requests  int64                                       `protobuf:"varint,3,opt,name=requests,proto3" json:"requests,omitempty"`
+	Revenue   types.Coin                                  `protobuf:"bytes,4,opt,name=revenue,proto3" json:"revenue"`
+	Reserve   cosmossdk_io_math.Int                       `protobuf:"bytes,5,opt,name=reserve,proto3,customtype=cosmossdk.io/math.Int" json:"reserve"`
+	PeriodEnd int64                                       `protobuf:"varint,6,opt,name=period_end,json=periodEnd,proto3" json:"period_end,omitempty"`
+	Height    int64                                       `protobuf:"varint,7,opt,name=height,proto3" json:"height,omitempty"`
+}
+
+func (m *EventX402Revenue) Reset()         { *m = EventX402Revenue{} }
+func (m *EventX402Revenue) String() string { return proto.CompactTextString(m) }
+func (*EventX402Revenue) ProtoMessage()    {}
+func (*EventX402Revenue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_39b4417094f69f41, []int{6}
+}
+func (m *EventX402Revenue) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *EventX402Revenue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_EventX402Revenue.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *EventX402Revenue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EventX402Revenue.Merge(m, src)
+}
+func (m *EventX402Revenue) XXX_Size() int {
+	return m.Size()
+}
+func (m *EventX402Revenue) XXX_DiscardUnknown() {
+	xxx_messageInfo_EventX402Revenue.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EventX402Revenue proto.InternalMessageInfo
+
+func (m *EventX402Revenue) GetProvider() github_com_arkeonetwork_arkeo_common.PubKey {
+	if m != nil {
+		return m.Provider
+	}
+	return nil
+}
+
+func (m *EventX402Revenue) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *EventX402Revenue) GetRequests() int64 {
+	if m != nil {
+		return m.Requests
+	}
+	return 0
+}
+
+func (m *EventX402Revenue) GetRevenue() types.Coin {
+	if m != nil {
+		return m.Revenue
+	}
+	return types.Coin{}
+}
+
+func (m *EventX402Revenue) GetPeriodEnd() int64 {
+	if m != nil {
+		return m.PeriodEnd
+	}
+	return 0
+}
+
+func (m *EventX402Revenue) GetHeight() int64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterType((*EventBondProvider)(nil), "arkeo.arkeo.EventBondProvider")
 	proto.RegisterType((*EventModProvider)(nil), "arkeo.arkeo.EventModProvider")
@@ -592,71 +678,76 @@ func init() {
 	proto.RegisterType((*EventSettleContract)(nil), "arkeo.arkeo.EventSettleContract")
 	proto.RegisterType((*EventCloseContract)(nil), "arkeo.arkeo.EventCloseContract")
 	proto.RegisterType((*EventValidatorPayout)(nil), "arkeo.arkeo.EventValidatorPayout")
+	proto.RegisterType((*EventX402Revenue)(nil), "arkeo.arkeo.EventX402Revenue")
 }
 
 func init() { proto.RegisterFile("arkeo/arkeo/events.proto", fileDescriptor_39b4417094f69f41) }
 
 var fileDescriptor_39b4417094f69f41 = []byte{
-	// 940 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xec, 0x56, 0xc1, 0x6e, 0xdb, 0x46,
-	0x13, 0x36, 0x6d, 0x59, 0x92, 0x47, 0xb6, 0x7f, 0x7b, 0xed, 0xfc, 0xa0, 0x1d, 0x40, 0x52, 0x05,
-	0x14, 0x10, 0xe0, 0x9a, 0x82, 0xed, 0x07, 0x08, 0x24, 0xd7, 0x4d, 0x0c, 0x37, 0x8d, 0xc1, 0xb4,
-	0x05, 0xda, 0x0b, 0xb1, 0x22, 0x07, 0xd2, 0x42, 0x22, 0x97, 0xdd, 0x5d, 0x2a, 0x56, 0xaf, 0xbd,
-	0xf5, 0xd4, 0x63, 0x1f, 0xa2, 0xa7, 0xa2, 0xa7, 0x3e, 0x41, 0x8e, 0x41, 0x4f, 0x45, 0x0f, 0x46,
-	0x61, 0xbf, 0x45, 0x4e, 0x05, 0x97, 0x4b, 0x59, 0x4a, 0x82, 0x36, 0x12, 0xd2, 0xa2, 0x87, 0x5e,
-	0x44, 0xee, 0xcc, 0x7c, 0xc3, 0xdd, 0x6f, 0xbe, 0x4f, 0x24, 0xd8, 0x54, 0x0c, 0x90, 0xb7, 0xb2,
-	0x5f, 0x1c, 0x61, 0xa4, 0xa4, 0x13, 0x0b, 0xae, 0x38, 0xa9, 0xe8, 0x98, 0xa3, 0x7f, 0xf7, 0x77,
-	0x7b, 0xbc, 0xc7, 0x75, 0xbc, 0x95, 0xde, 0x65, 0x25, 0xfb, 0x7b, 0x3e, 0x97, 0x21, 0x97, 0x5e,
-	0x96, 0xc8, 0x16, 0x26, 0x55, 0xcd, 0x56, 0xad, 0x2e, 0x95, 0xd8, 0x1a, 0x1d, 0x75, 0x51, 0xd1,
-	0xa3, 0x96, 0xcf, 0x59, 0x64, 0xf2, 0x33, 0xcf, 0x1d, 0x20, 0xc6, 0x28, 0xb2, 0x4c, 0xe3, 0xdb,
-	0x65, 0xd8, 0x3e, 0x4b, 0x37, 0xd2, 0xe1, 0x51, 0x70, 0x29, 0xf8, 0x88, 0x05, 0x28, 0xc8, 0x05,
-	0x94, 0x63, 0x73, 0x6f, 0x5b, 0x75, 0xab, 0xb9, 0xde, 0x69, 0xbd, 0xbc, 0xae, 0x1d, 0xf4, 0x98,
-	0xea, 0x27, 0x5d, 0xc7, 0xe7, 0x61, 0xd6, 0x2a, 0x42, 0xf5, 0x8c, 0x8b, 0x81, 0xe9, 0xeb, 0xf3,
-	0x30, 0xe4, 0x91, 0x73, 0x99, 0x74, 0x2f, 0x70, 0xec, 0x4e, 0x1a, 0x10, 0x1b, 0x4a, 0x12, 0xc5,
-	0x88, 0xf9, 0x68, 0x2f, 0xd7, 0xad, 0xe6, 0x9a, 0x9b, 0x2f, 0xc9, 0x47, 0x50, 0xee, 0xf2, 0x28,
-	0xf0, 0x04, 0x0e, 0xed, 0x95, 0x34, 0xd5, 0x39, 0x78, 0x7e, 0x5d, 0x5b, 0xfa, 0xed, 0xba, 0x76,
-	0x2f, 0x3b, 0x90, 0x0c, 0x06, 0x0e, 0xe3, 0xad, 0x90, 0xaa, 0xbe, 0x73, 0x1e, 0xa9, 0x5f, 0x7e,
-	0x3a, 0x04, 0x73, 0xee, 0xf3, 0x48, 0xb9, 0xa5, 0x14, 0xec, 0xe2, 0x70, 0xd2, 0x87, 0x76, 0xa5,
-	0x5d, 0x58, 0xb0, 0x4f, 0xbb, 0x2b, 0x1b, 0x3f, 0xaf, 0xc2, 0x96, 0x26, 0xe3, 0x31, 0x9f, 0xe6,
-	0xa2, 0xe4, 0x0b, 0xa4, 0x8a, 0xe7, 0x54, 0x1c, 0xbd, 0xbc, 0xae, 0x1d, 0x4e, 0x51, 0x61, 0xb8,
-	0xcf, 0x2e, 0x87, 0x32, 0x18, 0xb4, 0xd4, 0x38, 0x46, 0xe9, 0xb4, 0x7d, 0xbf, 0x1d, 0x04, 0x02,
-	0xa5, 0x74, 0xf3, 0x0e, 0x33, 0xc4, 0x2e, 0xbf, 0x43, 0x62, 0x57, 0x66, 0x89, 0x7d, 0x0f, 0xd6,
-	0x43, 0x54, 0x34, 0xa0, 0x8a, 0x7a, 0x89, 0x60, 0x19, 0x29, 0x6e, 0x25, 0x8f, 0x7d, 0x26, 0x18,
-	0x79, 0x1f, 0x36, 0x27, 0x25, 0x11, 0x8f, 0x7c, 0xb4, 0x57, 0xeb, 0x56, 0xb3, 0xe0, 0x6e, 0xe4,
-	0xd1, 0x4f, 0xd2, 0x20, 0x39, 0x81, 0xa2, 0x54, 0x54, 0x25, 0xd2, 0x2e, 0xd6, 0xad, 0xe6, 0xe6,
-	0xf1, 0x7d, 0x67, 0x4a, 0xa8, 0x4e, 0x4e, 0xd2, 0x53, 0x5d, 0xe2, 0x9a, 0x52, 0x72, 0x0c, 0xf7,
-	0x42, 0x16, 0x79, 0x3e, 0x8f, 0x94, 0xa0, 0xbe, 0xf2, 0x82, 0x44, 0x50, 0xc5, 0x78, 0x64, 0x97,
-	0xea, 0x56, 0x73, 0xc5, 0xdd, 0x09, 0x59, 0x74, 0x6a, 0x72, 0x1f, 0x9a, 0x94, 0xc6, 0xd0, 0xab,
-	0x37, 0x60, 0xca, 0x06, 0x43, 0xaf, 0x5e, 0xc3, 0x7c, 0x0c, 0xdb, 0x32, 0xe9, 0x4a, 0x5f, 0xb0,
-	0x38, 0x5d, 0x7b, 0x82, 0x2a, 0xb4, 0xd7, 0xea, 0x2b, 0xcd, 0xca, 0xf1, 0x9e, 0x63, 0x06, 0x9c,
-	0x5a, 0xc2, 0x31, 0x96, 0x70, 0x4e, 0x39, 0x8b, 0x3a, 0x85, 0x54, 0x1b, 0xee, 0xd6, 0x34, 0xd2,
-	0xa5, 0x0a, 0xc9, 0x05, 0x90, 0x98, 0x8e, 0x3d, 0x2a, 0xbd, 0x31, 0x4f, 0xbc, 0x1e, 0xcf, 0xda,
-	0xc1, 0xdb, 0xb5, 0xdb, 0x8c, 0xe9, 0xb8, 0x2d, 0xbf, 0xe0, 0xc9, 0x43, 0xae, 0x9b, 0x3d, 0x80,
-	0x42, 0xaa, 0x2a, 0xbb, 0x32, 0xbf, 0x1c, 0x35, 0x90, 0xb4, 0x60, 0x47, 0xa2, 0x52, 0x43, 0x0c,
-	0x31, 0x9a, 0x62, 0x63, 0x5d, 0xb3, 0x41, 0xee, 0x52, 0x39, 0x19, 0x8d, 0x6f, 0x8a, 0xc6, 0xc9,
-	0x4f, 0x62, 0x9c, 0xd0, 0xfb, 0x6e, 0x9d, 0x5c, 0x83, 0xca, 0x64, 0x3e, 0x2c, 0xd0, 0x02, 0x2e,
-	0xb8, 0x90, 0x87, 0xce, 0x83, 0x3f, 0x51, 0xe4, 0x43, 0x28, 0xfa, 0x43, 0x86, 0x91, 0xd2, 0x5a,
-	0x5c, 0x60, 0x17, 0x06, 0x9e, 0x1e, 0x28, 0xc0, 0x21, 0xf6, 0xd2, 0xd9, 0xac, 0x2e, 0x78, 0xa0,
-	0xbc, 0x01, 0x39, 0x84, 0x42, 0xea, 0x55, 0xa3, 0xed, 0xbd, 0x19, 0x6d, 0xe7, 0x14, 0x7e, 0x3a,
-	0x8e, 0xd1, 0xd5, 0x65, 0xe4, 0xff, 0x50, 0xec, 0x23, 0xeb, 0xf5, 0x95, 0x11, 0xb2, 0x59, 0x91,
-	0x7d, 0x28, 0xbf, 0x22, 0xd7, 0xc9, 0x9a, 0x9c, 0x40, 0xc1, 0xc8, 0xd2, 0x7a, 0x1b, 0x1d, 0xe9,
-	0x62, 0x72, 0x1f, 0xd6, 0x78, 0x8c, 0xa9, 0x83, 0xa4, 0xb2, 0x21, 0xeb, 0xc8, 0xf5, 0x58, 0xa5,
-	0x22, 0x67, 0x50, 0x0a, 0x30, 0xe6, 0x92, 0xa9, 0x45, 0xd4, 0x95, 0x63, 0xe7, 0x16, 0x18, 0x79,
-	0x04, 0x1b, 0x34, 0x51, 0x7d, 0x2e, 0xd8, 0xd7, 0x59, 0xe9, 0x86, 0x66, 0xad, 0xf1, 0x46, 0xd6,
-	0xda, 0xd3, 0x95, 0xee, 0x2c, 0x90, 0x7c, 0x00, 0xe4, 0xab, 0x04, 0x05, 0x43, 0xe9, 0xc5, 0x28,
-	0xbc, 0x90, 0x45, 0x89, 0x42, 0x7b, 0x53, 0x3f, 0x79, 0xcb, 0x64, 0x2e, 0x51, 0x3c, 0xd6, 0x71,
-	0x72, 0x00, 0xdb, 0x53, 0x1b, 0x35, 0x03, 0xf8, 0x5f, 0x56, 0x7c, 0x97, 0x78, 0xa4, 0xe3, 0x8d,
-	0xef, 0x0b, 0xb0, 0xa3, 0x5d, 0xf0, 0x54, 0x67, 0xfe, 0xf3, 0xc1, 0xdf, 0xe1, 0x83, 0x5d, 0x58,
-	0xcd, 0x5e, 0x19, 0x99, 0x0d, 0xb2, 0xc5, 0x94, 0x3b, 0xca, 0x33, 0xee, 0x78, 0x00, 0x85, 0x98,
-	0xb2, 0x40, 0x3b, 0x60, 0xde, 0xbf, 0xc2, 0x14, 0x98, 0x0a, 0x5e, 0x60, 0x4a, 0x20, 0x6a, 0x2f,
-	0xcc, 0x2b, 0x78, 0x83, 0x6d, 0xfc, 0xb8, 0x0c, 0x44, 0x4b, 0xe3, 0x74, 0xc8, 0xe5, 0x9d, 0x32,
-	0x5e, 0x19, 0xa6, 0xf5, 0xda, 0x30, 0xff, 0xa1, 0x77, 0xf6, 0xbf, 0x52, 0x19, 0x8d, 0x1f, 0x2c,
-	0xd8, 0xd5, 0xa4, 0x7d, 0x4e, 0x87, 0x2c, 0x48, 0xbf, 0x61, 0x2e, 0xe9, 0x98, 0x27, 0x8a, 0x3c,
-	0x81, 0xb5, 0x51, 0x1e, 0x5a, 0xfc, 0xc3, 0xe8, 0xae, 0x07, 0x39, 0x85, 0xa2, 0xc0, 0x67, 0x54,
-	0x64, 0x7e, 0x9a, 0x73, 0xc8, 0x06, 0xda, 0x39, 0x7b, 0x7e, 0x53, 0xb5, 0x5e, 0xdc, 0x54, 0xad,
-	0xdf, 0x6f, 0xaa, 0xd6, 0x77, 0xb7, 0xd5, 0xa5, 0x17, 0xb7, 0xd5, 0xa5, 0x5f, 0x6f, 0xab, 0x4b,
-	0x5f, 0xfe, 0xc5, 0xf9, 0xaf, 0xcc, 0x55, 0xef, 0xb0, 0x5b, 0xd4, 0x1f, 0xc7, 0x27, 0x7f, 0x04,
-	0x00, 0x00, 0xff, 0xff, 0x16, 0x2f, 0xaf, 0x13, 0xb0, 0x0b, 0x00, 0x00,
+	// 1006 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xec, 0x57, 0x4d, 0x6f, 0x1b, 0x45,
+	0x18, 0xce, 0xc6, 0x1b, 0xc7, 0x7e, 0xf3, 0x41, 0x32, 0x49, 0xd1, 0x26, 0x15, 0x8e, 0xb1, 0x84,
+	0x64, 0x29, 0x64, 0x4d, 0x12, 0x2e, 0x9c, 0xaa, 0x24, 0x84, 0x36, 0x0a, 0xa5, 0xd1, 0x16, 0x10,
+	0x70, 0x59, 0x8d, 0x77, 0x5f, 0x39, 0xa3, 0xd8, 0x3b, 0xdb, 0x99, 0xd9, 0x34, 0xe6, 0xca, 0x8d,
+	0x13, 0x47, 0x7e, 0x04, 0x27, 0xc4, 0x89, 0x5f, 0xd0, 0x13, 0xaa, 0x38, 0x21, 0x0e, 0x11, 0x4a,
+	0xfe, 0x45, 0x4f, 0x68, 0x67, 0x66, 0x1d, 0xbb, 0x2d, 0x90, 0x58, 0xa5, 0xe2, 0xc0, 0xc5, 0xbb,
+	0xef, 0xe7, 0xce, 0x3c, 0xef, 0xf3, 0x68, 0xc6, 0xe0, 0x51, 0x71, 0x82, 0xbc, 0x65, 0x7e, 0xf1,
+	0x14, 0x13, 0x25, 0xfd, 0x54, 0x70, 0xc5, 0xc9, 0x8c, 0xf6, 0xf9, 0xfa, 0x77, 0x75, 0xb9, 0xc3,
+	0x3b, 0x5c, 0xfb, 0x5b, 0xf9, 0x9b, 0x49, 0x59, 0x5d, 0x89, 0xb8, 0xec, 0x71, 0x19, 0x9a, 0x80,
+	0x31, 0x6c, 0xa8, 0x66, 0xac, 0x56, 0x9b, 0x4a, 0x6c, 0x9d, 0x6e, 0xb6, 0x51, 0xd1, 0xcd, 0x56,
+	0xc4, 0x59, 0x62, 0xe3, 0x23, 0xdf, 0x3d, 0x41, 0x4c, 0x51, 0x98, 0x48, 0xe3, 0xdb, 0x49, 0x58,
+	0xdc, 0xcf, 0x17, 0xb2, 0xcb, 0x93, 0xf8, 0x48, 0xf0, 0x53, 0x16, 0xa3, 0x20, 0x87, 0x50, 0x49,
+	0xed, 0xbb, 0xe7, 0xd4, 0x9d, 0xe6, 0xec, 0x6e, 0xeb, 0xd9, 0xf9, 0xda, 0x7a, 0x87, 0xa9, 0xe3,
+	0xac, 0xed, 0x47, 0xbc, 0x67, 0x5a, 0x25, 0xa8, 0x1e, 0x73, 0x71, 0x62, 0xfb, 0x46, 0xbc, 0xd7,
+	0xe3, 0x89, 0x7f, 0x94, 0xb5, 0x0f, 0xb1, 0x1f, 0x0c, 0x1a, 0x10, 0x0f, 0xa6, 0x25, 0x8a, 0x53,
+	0x16, 0xa1, 0x37, 0x59, 0x77, 0x9a, 0xd5, 0xa0, 0x30, 0xc9, 0x47, 0x50, 0x69, 0xf3, 0x24, 0x0e,
+	0x05, 0x76, 0xbd, 0x52, 0x1e, 0xda, 0x5d, 0x7f, 0x72, 0xbe, 0x36, 0xf1, 0xfb, 0xf9, 0xda, 0x2d,
+	0xb3, 0x21, 0x19, 0x9f, 0xf8, 0x8c, 0xb7, 0x7a, 0x54, 0x1d, 0xfb, 0x07, 0x89, 0xfa, 0xf5, 0xa7,
+	0x0d, 0xb0, 0xfb, 0x3e, 0x48, 0x54, 0x30, 0x9d, 0x17, 0x07, 0xd8, 0x1d, 0xf4, 0xa1, 0x6d, 0xe9,
+	0xb9, 0x63, 0xf6, 0xd9, 0x69, 0xcb, 0xc6, 0xcf, 0x53, 0xb0, 0xa0, 0xc1, 0xb8, 0xcf, 0x87, 0xb1,
+	0x98, 0x8e, 0x04, 0x52, 0xc5, 0x0b, 0x28, 0x36, 0x9f, 0x9d, 0xaf, 0x6d, 0x0c, 0x41, 0x61, 0xb1,
+	0x37, 0x8f, 0x0d, 0x19, 0x9f, 0xb4, 0x54, 0x3f, 0x45, 0xe9, 0xef, 0x44, 0xd1, 0x4e, 0x1c, 0x0b,
+	0x94, 0x32, 0x28, 0x3a, 0x8c, 0x00, 0x3b, 0xf9, 0x0a, 0x81, 0x2d, 0x8d, 0x02, 0xfb, 0x36, 0xcc,
+	0xf6, 0x50, 0xd1, 0x98, 0x2a, 0x1a, 0x66, 0x82, 0x19, 0x50, 0x82, 0x99, 0xc2, 0xf7, 0x99, 0x60,
+	0xe4, 0x1d, 0x98, 0x1f, 0xa4, 0x24, 0x3c, 0x89, 0xd0, 0x9b, 0xaa, 0x3b, 0x4d, 0x37, 0x98, 0x2b,
+	0xbc, 0x9f, 0xe4, 0x4e, 0xb2, 0x0d, 0x65, 0xa9, 0xa8, 0xca, 0xa4, 0x57, 0xae, 0x3b, 0xcd, 0xf9,
+	0xad, 0xdb, 0xfe, 0x10, 0x51, 0xfd, 0x02, 0xa4, 0x87, 0x3a, 0x25, 0xb0, 0xa9, 0x64, 0x0b, 0x6e,
+	0xf5, 0x58, 0x12, 0x46, 0x3c, 0x51, 0x82, 0x46, 0x2a, 0x8c, 0x33, 0x41, 0x15, 0xe3, 0x89, 0x37,
+	0x5d, 0x77, 0x9a, 0xa5, 0x60, 0xa9, 0xc7, 0x92, 0x3d, 0x1b, 0xfb, 0xd0, 0x86, 0x74, 0x0d, 0x3d,
+	0x7b, 0x49, 0x4d, 0xc5, 0xd6, 0xd0, 0xb3, 0x17, 0x6a, 0x3e, 0x86, 0x45, 0x99, 0xb5, 0x65, 0x24,
+	0x58, 0x9a, 0xdb, 0xa1, 0xa0, 0x0a, 0xbd, 0x6a, 0xbd, 0xd4, 0x9c, 0xd9, 0x5a, 0xf1, 0xed, 0x80,
+	0x73, 0x49, 0xf8, 0x56, 0x12, 0xfe, 0x1e, 0x67, 0xc9, 0xae, 0x9b, 0x73, 0x23, 0x58, 0x18, 0xae,
+	0x0c, 0xa8, 0x42, 0x72, 0x08, 0x24, 0xa5, 0xfd, 0x90, 0xca, 0xb0, 0xcf, 0xb3, 0xb0, 0xc3, 0x4d,
+	0x3b, 0xb8, 0x5e, 0xbb, 0xf9, 0x94, 0xf6, 0x77, 0xe4, 0x97, 0x3c, 0xbb, 0xcb, 0x75, 0xb3, 0x3b,
+	0xe0, 0xe6, 0xac, 0xf2, 0x66, 0x6e, 0x4e, 0x47, 0x5d, 0x48, 0x5a, 0xb0, 0x24, 0x51, 0xa9, 0x2e,
+	0xf6, 0x30, 0x19, 0x42, 0x63, 0x56, 0xa3, 0x41, 0xae, 0x42, 0x05, 0x18, 0x8d, 0x6f, 0xca, 0x56,
+	0xc9, 0x0f, 0x52, 0x1c, 0xc0, 0xfb, 0x6a, 0x95, 0xbc, 0x06, 0x33, 0x83, 0xf9, 0xb0, 0x58, 0x13,
+	0xd8, 0x0d, 0xa0, 0x70, 0x1d, 0xc4, 0x7f, 0xc3, 0xc8, 0xbb, 0x50, 0x8e, 0xba, 0x0c, 0x13, 0xe5,
+	0xb9, 0xe3, 0xad, 0xc2, 0x96, 0xe7, 0x1b, 0x8a, 0xb1, 0x8b, 0x1d, 0xaa, 0x0c, 0x63, 0xc7, 0xd9,
+	0x50, 0xd1, 0x80, 0x6c, 0x80, 0x9b, 0x6b, 0xd5, 0x72, 0x7b, 0x65, 0x84, 0xdb, 0x05, 0x84, 0x9f,
+	0xf6, 0x53, 0x0c, 0x74, 0x1a, 0x79, 0x13, 0xca, 0xc7, 0xc8, 0x3a, 0xc7, 0xca, 0x12, 0xd9, 0x5a,
+	0x64, 0x15, 0x2a, 0xcf, 0xd1, 0x75, 0x60, 0x93, 0x6d, 0x70, 0x2d, 0x2d, 0x9d, 0xeb, 0xf0, 0x48,
+	0x27, 0x93, 0xdb, 0x50, 0xe5, 0x29, 0xe6, 0x0a, 0x92, 0xca, 0x03, 0xd3, 0x91, 0xeb, 0xb1, 0x4a,
+	0x45, 0xf6, 0x61, 0x3a, 0xc6, 0x94, 0x4b, 0xa6, 0xc6, 0x61, 0x57, 0x51, 0x7b, 0x63, 0x82, 0x91,
+	0x7b, 0x30, 0x47, 0x33, 0x75, 0xcc, 0x05, 0xfb, 0xda, 0xa4, 0xce, 0x69, 0xd4, 0x1a, 0x2f, 0x45,
+	0x6d, 0x67, 0x38, 0x33, 0x18, 0x2d, 0x24, 0xef, 0x02, 0x79, 0x94, 0xa1, 0x60, 0x28, 0xc3, 0x14,
+	0x45, 0xd8, 0x63, 0x49, 0xa6, 0xd0, 0x9b, 0xd7, 0x5f, 0x5e, 0xb0, 0x91, 0x23, 0x14, 0xf7, 0xb5,
+	0x9f, 0xac, 0xc3, 0xe2, 0xd0, 0x42, 0xed, 0x00, 0xde, 0x30, 0xc9, 0x57, 0x81, 0x7b, 0xda, 0xdf,
+	0xf8, 0xde, 0x85, 0x25, 0xad, 0x82, 0x87, 0x3a, 0xf2, 0xbf, 0x0e, 0xfe, 0x0d, 0x1d, 0x2c, 0xc3,
+	0x94, 0x39, 0x32, 0x8c, 0x0c, 0x8c, 0x31, 0xa4, 0x8e, 0xca, 0x88, 0x3a, 0xee, 0x80, 0x9b, 0x52,
+	0x16, 0x7b, 0xd5, 0x9b, 0x93, 0x55, 0x17, 0xe6, 0x84, 0x17, 0x98, 0x03, 0x88, 0x1e, 0xdc, 0xbc,
+	0x47, 0x51, 0xdb, 0xf8, 0x71, 0x12, 0x88, 0xa6, 0xc6, 0x5e, 0x97, 0xcb, 0x2b, 0x66, 0x3c, 0x37,
+	0x4c, 0xe7, 0x85, 0x61, 0xbe, 0xa6, 0x33, 0xfb, 0x3f, 0xc9, 0x8c, 0xc6, 0x0f, 0x0e, 0x2c, 0x6b,
+	0xd0, 0x3e, 0xa7, 0x5d, 0x16, 0x53, 0xc5, 0xc5, 0x11, 0xed, 0xf3, 0x4c, 0x91, 0x07, 0x50, 0x3d,
+	0x2d, 0x5c, 0xe3, 0x5f, 0x8c, 0xae, 0x7a, 0x90, 0x3d, 0x28, 0x0b, 0x7c, 0x4c, 0x85, 0xd1, 0xd3,
+	0x0d, 0x87, 0x6c, 0x4b, 0x1b, 0xbf, 0x4c, 0xda, 0x1b, 0xdc, 0x17, 0xef, 0xbf, 0xb7, 0x15, 0xe4,
+	0x37, 0xec, 0x0c, 0x5f, 0xd7, 0x6d, 0x76, 0x15, 0x2a, 0x02, 0x1f, 0x65, 0x28, 0x95, 0xd4, 0xb3,
+	0x2d, 0x05, 0x03, 0x9b, 0x7c, 0x90, 0x53, 0x58, 0xaf, 0xc6, 0x73, 0xaf, 0x77, 0x10, 0x14, 0xf9,
+	0xc3, 0xec, 0x9f, 0x1a, 0x9f, 0xfd, 0xe4, 0x2d, 0x80, 0x14, 0x05, 0xe3, 0x71, 0x88, 0x49, 0xac,
+	0x85, 0x5e, 0x0a, 0xaa, 0xc6, 0xb3, 0x9f, 0xc4, 0x7f, 0x75, 0xb4, 0xed, 0xee, 0x3f, 0xb9, 0xa8,
+	0x39, 0x4f, 0x2f, 0x6a, 0xce, 0x1f, 0x17, 0x35, 0xe7, 0xbb, 0xcb, 0xda, 0xc4, 0xd3, 0xcb, 0xda,
+	0xc4, 0x6f, 0x97, 0xb5, 0x89, 0xaf, 0xfe, 0x01, 0xbf, 0x33, 0xfb, 0xd4, 0x23, 0x6f, 0x97, 0xf5,
+	0xbf, 0x8d, 0xed, 0x3f, 0x07, 0x00, 0xac, 0xcc, 0x6f, 0x9d, 0x01, 0x0d, 0x00, 0x00,
 }
 
 func (m *EventBondProvider) Marshal() (dAtA []byte, err error) {
@@ -1133,6 +1224,78 @@ func (m *EventValidatorPayout) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	return len(dAtA) - i, nil
 }
 
+func (m *EventX402Revenue) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *EventX402Revenue) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *EventX402Revenue) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Height != 0 {
+		i = encodeVarintEvents(dAtA, i, uint64(m.Height))
+		i--
+		dAtA[i] = 0x38
+	}
+	if m.PeriodEnd != 0 {
+		i = encodeVarintEvents(dAtA, i, uint64(m.PeriodEnd))
+		i--
+		dAtA[i] = 0x30
+	}
+	{
+		size := m.Reserve.Size()
+		i -= size
+		if _, err := m.Reserve.MarshalTo(dAtA[i:]); err != nil {
+			return 0, err
+		}
+		i = encodeVarintEvents(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0x2a
+	{
+		size, err := m.Revenue.MarshalToSizedBuffer(dAtA[:i])
+		if err != nil {
+			return 0, err
+		}
+		i -= size
+		i = encodeVarintEvents(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0x22
+	if m.Requests != 0 {
+		i = encodeVarintEvents(dAtA, i, uint64(m.Requests))
+		i--
+		dAtA[i] = 0x18
+	}
+	if len(m.Service) > 0 {
+		i -= len(m.Service)
+		copy(dAtA[i:], m.Service)
+		i = encodeVarintEvents(dAtA, i, uint64(len(m.Service)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Provider) > 0 {
+		i -= len(m.Provider)
+		copy(dAtA[i:], m.Provider)
+		i = encodeVarintEvents(dAtA, i, uint64(len(m.Provider)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
 func encodeVarintEvents(dAtA []byte, offset int, v uint64) int {
 	offset -= sovEvents(v)
 	base := offset
@@ -1359,6 +1522,36 @@ func (m *EventValidatorPayout) Size() (n int) {
 	return n
 }
 
+func (m *EventX402Revenue) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Provider)
+	if l > 0 {
+		n += 1 + l + sovEvents(uint64(l))
+	}
+	l = len(m.Service)
+	if l > 0 {
+		n += 1 + l + sovEvents(uint64(l))
+	}
+	if m.Requests != 0 {
+		n += 1 + sovEvents(uint64(m.Requests))
+	}
+	l = m.Revenue.Size()
+	n += 1 + l + sovEvents(uint64(l))
+	l = m.Reserve.Size()
+	n += 1 + l + sovEvents(uint64(l))
+	if m.PeriodEnd != 0 {
+		n += 1 + sovEvents(uint64(m.PeriodEnd))
+	}
+	if m.Height != 0 {
+		n += 1 + sovEvents(uint64(m.Height))
+	}
+	return n
+}
+
 func sovEvents(x uint64) (n int) {
 	return (math_bits.Len64(x|1) + 6) / 7
 }
@@ -2999,6 +3192,246 @@ func (m *EventValidatorPayout) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *EventX402Revenue) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowEvents
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: EventX402Revenue: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: EventX402Revenue: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Provider", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEvents
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthEvents
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEvents
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Provider = append(m.Provider[:0], dAtA[iNdEx:postIndex]...)
+			if m.Provider == nil {
+				m.Provider = []byte{}
+			}
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Service", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEvents
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthEvents
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEvents
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Service = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Requests", wireType)
+			}
+			m.Requests = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEvents
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Requests |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Revenue", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEvents
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthEvents
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthEvents
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Revenue.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Reserve", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEvents
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthEvents
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthEvents
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Reserve.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PeriodEnd", wireType)
+			}
+			m.PeriodEnd = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEvents
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.PeriodEnd |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 7:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Height", wireType)
+			}
+			m.Height = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowEvents
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Height |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipEvents(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthEvents
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func skipEvents(dAtA []byte) (n int, err error) {
 	l := len(dAtA)
 	iNdEx := 0
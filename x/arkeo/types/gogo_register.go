@@ -33,10 +33,13 @@ func init() {
 	cosmosproto.RegisterType((*MsgUpdateServiceResponse)(nil), "arkeo.arkeo.MsgUpdateServiceResponse")
 	cosmosproto.RegisterType((*MsgRemoveService)(nil), "arkeo.arkeo.MsgRemoveService")
 	cosmosproto.RegisterType((*MsgRemoveServiceResponse)(nil), "arkeo.arkeo.MsgRemoveServiceResponse")
+	cosmosproto.RegisterType((*MsgReportX402Revenue)(nil), "arkeo.arkeo.MsgReportX402Revenue")
+	cosmosproto.RegisterType((*MsgReportX402RevenueResponse)(nil), "arkeo.arkeo.MsgReportX402RevenueResponse")
 	cosmosproto.RegisterType((*EventBondProvider)(nil), "arkeo.arkeo.EventBondProvider")
 	cosmosproto.RegisterType((*EventModProvider)(nil), "arkeo.arkeo.EventModProvider")
 	cosmosproto.RegisterType((*EventOpenContract)(nil), "arkeo.arkeo.EventOpenContract")
 	cosmosproto.RegisterType((*EventSettleContract)(nil), "arkeo.arkeo.EventSettleContract")
 	cosmosproto.RegisterType((*EventCloseContract)(nil), "arkeo.arkeo.EventCloseContract")
 	cosmosproto.RegisterType((*EventValidatorPayout)(nil), "arkeo.arkeo.EventValidatorPayout")
+	cosmosproto.RegisterType((*EventX402Revenue)(nil), "arkeo.arkeo.EventX402Revenue")
 }
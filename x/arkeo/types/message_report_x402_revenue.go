@@ -0,0 +1,90 @@
+package types
+
+import (
+	"strings"
+
+	"cosmossdk.io/errors"
+
+	"github.com/arkeonetwork/arkeo/common"
+	"github.com/arkeonetwork/arkeo/common/cosmos"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+const TypeMsgReportX402Revenue = "report_x402_revenue"
+
+var _ sdk.Msg = &MsgReportX402Revenue{}
+
+func NewMsgReportX402Revenue(creator cosmos.AccAddress, provider common.PubKey, service string, requests int64, revenue cosmos.Coin, periodEnd int64) *MsgReportX402Revenue {
+	return &MsgReportX402Revenue{
+		Creator:   creator.String(),
+		Provider:  provider,
+		Service:   service,
+		Requests:  requests,
+		Revenue:   revenue,
+		PeriodEnd: periodEnd,
+	}
+}
+
+func (msg *MsgReportX402Revenue) Route() string {
+	return RouterKey
+}
+
+func (msg *MsgReportX402Revenue) Type() string {
+	return TypeMsgReportX402Revenue
+}
+
+func (msg *MsgReportX402Revenue) GetSigners() []sdk.AccAddress {
+	return []sdk.AccAddress{sdk.MustAccAddressFromBech32(msg.Creator)}
+}
+
+func (msg *MsgReportX402Revenue) MustGetSigner() sdk.AccAddress {
+	return sdk.MustAccAddressFromBech32(msg.Creator)
+}
+
+func (msg *MsgReportX402Revenue) GetSignBytes() []byte {
+	bz := ModuleCdc.MustMarshalJSON(msg)
+	return sdk.MustSortJSON(bz)
+}
+
+func (msg *MsgReportX402Revenue) ValidateBasic() error {
+	signer, err := sdk.AccAddressFromBech32(msg.Creator)
+	if err != nil {
+		return errors.Wrapf(ErrProviderBadSigner, "invalid creator address (%s): %s", msg.Creator, err)
+	}
+
+	// verify pubkey
+	_, err = common.NewPubKey(msg.Provider.String())
+	if err != nil {
+		return errors.Wrapf(ErrInvalidPubKey, "invalid provider pubkey (%s): %s", msg.Provider, err)
+	}
+
+	// only the provider can report its revenue, and pays the reserve tax on it
+	provider, err := msg.Provider.GetMyAddress()
+	if err != nil {
+		return err
+	}
+	if !signer.Equals(provider) {
+		return errors.Wrapf(ErrProviderBadSigner, "Signer: %s, Provider Address: %s", msg.GetSigners(), provider)
+	}
+
+	// verify service
+	if strings.TrimSpace(msg.Service) == "" {
+		return errors.Wrapf(ErrInvalidService, "service cannot be empty")
+	}
+
+	if msg.Requests < 0 {
+		return errors.Wrapf(ErrInvalidX402Revenue, "requests cannot be negative")
+	}
+	if err := msg.Revenue.Validate(); err != nil {
+		return errors.Wrapf(ErrInvalidX402Revenue, "invalid revenue: %s", err)
+	}
+	if msg.Requests == 0 && msg.Revenue.IsZero() {
+		return errors.Wrapf(ErrInvalidX402Revenue, "nothing to report")
+	}
+	if msg.PeriodEnd <= 0 {
+		return errors.Wrapf(ErrInvalidX402Revenue, "period end must be set")
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package types
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/arkeonetwork/arkeo/common"
+	"github.com/arkeonetwork/arkeo/common/cosmos"
+	"github.com/arkeonetwork/arkeo/testutil/sample"
+)
+
+func TestMsgReportX402Revenue_ValidateBasic(t *testing.T) {
+	pubkey := GetRandomPubKey()
+	acct, err := pubkey.GetMyAddress()
+	require.NoError(t, err)
+
+	valid := func() MsgReportX402Revenue {
+		return *NewMsgReportX402Revenue(acct, pubkey, common.BTCService.String(), 10, cosmos.NewInt64Coin("uarkeo", 500), 1700000000)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(msg *MsgReportX402Revenue)
+		err    error
+	}{
+		{
+			name:   "valid",
+			modify: func(msg *MsgReportX402Revenue) {},
+		},
+		{
+			name: "requests only",
+			modify: func(msg *MsgReportX402Revenue) {
+				msg.Revenue = cosmos.NewInt64Coin("uarkeo", 0)
+			},
+		},
+		{
+			name: "not the provider",
+			modify: func(msg *MsgReportX402Revenue) {
+				msg.Creator = sample.AccAddress().String()
+			},
+			err: ErrProviderBadSigner,
+		},
+		{
+			name: "empty service",
+			modify: func(msg *MsgReportX402Revenue) {
+				msg.Service = " "
+			},
+			err: ErrInvalidService,
+		},
+		{
+			name: "negative requests",
+			modify: func(msg *MsgReportX402Revenue) {
+				msg.Requests = -1
+			},
+			err: ErrInvalidX402Revenue,
+		},
+		{
+			name: "nothing to report",
+			modify: func(msg *MsgReportX402Revenue) {
+				msg.Requests = 0
+				msg.Revenue = cosmos.NewInt64Coin("uarkeo", 0)
+			},
+			err: ErrInvalidX402Revenue,
+		},
+		{
+			name: "no period",
+			modify: func(msg *MsgReportX402Revenue) {
+				msg.PeriodEnd = 0
+			},
+			err: ErrInvalidX402Revenue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := valid()
+			tt.modify(&msg)
+			err := msg.ValidateBasic()
+			if tt.err != nil {
+				require.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
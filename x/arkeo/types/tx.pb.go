@@ -1091,6 +1091,132 @@ func (m *MsgRemoveServiceResponse) XXX_DiscardUnknown() {
 
 var xxx_messageInfo_MsgRemoveServiceResponse proto.InternalMessageInfo
 
+// MsgReportX402Revenue reports the x402 agent payments a provider settled for
+// a service, the reserve tax is paid on the revenue.
+type MsgReportX402Revenue struct {
+	Creator  string                                      `protobuf:"bytes,1,opt,name=creator,proto3" json:"creator,omitempty"`
+	Provider github_com_arkeonetwork_arkeo_common.PubKey `protobuf:"bytes,2,opt,name=provider,proto3,casttype=github.com/arkeonetwork/arkeo/common.PubKey" json:"provider,omitempty"`
+	Service  string                                      `protobuf:"bytes,3,opt,name=service,proto3" json:"service,omitempty"`
+	// paid requests in the reported period
+	Requests int64 `protobuf:"varint,4,opt,name=requests,proto3" json:"requests,omitempty"`
+	// revenue settled in the reported period, taxed in the same denom
+	Revenue types.Coin `protobuf:"bytes,5,opt,name=revenue,proto3" json:"revenue"`
+	// end of the reported period (unix seconds)
+	PeriodEnd int64 `protobuf:"varint,6,opt,name=period_end,json=periodEnd,proto3" json:"period_end,omitempty"`
+}
+
+func (m *MsgReportX402Revenue) Reset()         { *m = MsgReportX402Revenue{} }
+func (m *MsgReportX402Revenue) String() string { return proto.CompactTextString(m) }
+func (*MsgReportX402Revenue) ProtoMessage()    {}
+func (*MsgReportX402Revenue) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a12700967a3e4015, []int{18}
+}
+func (m *MsgReportX402Revenue) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MsgReportX402Revenue) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MsgReportX402Revenue.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MsgReportX402Revenue) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MsgReportX402Revenue.Merge(m, src)
+}
+func (m *MsgReportX402Revenue) XXX_Size() int {
+	return m.Size()
+}
+func (m *MsgReportX402Revenue) XXX_DiscardUnknown() {
+	xxx_messageInfo_MsgReportX402Revenue.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MsgReportX402Revenue proto.InternalMessageInfo
+
+func (m *MsgReportX402Revenue) GetCreator() string {
+	if m != nil {
+		return m.Creator
+	}
+	return ""
+}
+
+func (m *MsgReportX402Revenue) GetProvider() github_com_arkeonetwork_arkeo_common.PubKey {
+	if m != nil {
+		return m.Provider
+	}
+	return nil
+}
+
+func (m *MsgReportX402Revenue) GetService() string {
+	if m != nil {
+		return m.Service
+	}
+	return ""
+}
+
+func (m *MsgReportX402Revenue) GetRequests() int64 {
+	if m != nil {
+		return m.Requests
+	}
+	return 0
+}
+
+func (m *MsgReportX402Revenue) GetRevenue() types.Coin {
+	if m != nil {
+		return m.Revenue
+	}
+	return types.Coin{}
+}
+
+func (m *MsgReportX402Revenue) GetPeriodEnd() int64 {
+	if m != nil {
+		return m.PeriodEnd
+	}
+	return 0
+}
+
+// MsgReportX402RevenueResponse is the response for MsgReportX402Revenue.
+type MsgReportX402RevenueResponse struct {
+}
+
+func (m *MsgReportX402RevenueResponse) Reset()         { *m = MsgReportX402RevenueResponse{} }
+func (m *MsgReportX402RevenueResponse) String() string { return proto.CompactTextString(m) }
+func (*MsgReportX402RevenueResponse) ProtoMessage()    {}
+func (*MsgReportX402RevenueResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_a12700967a3e4015, []int{19}
+}
+func (m *MsgReportX402RevenueResponse) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *MsgReportX402RevenueResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_MsgReportX402RevenueResponse.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *MsgReportX402RevenueResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_MsgReportX402RevenueResponse.Merge(m, src)
+}
+func (m *MsgReportX402RevenueResponse) XXX_Size() int {
+	return m.Size()
+}
+func (m *MsgReportX402RevenueResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_MsgReportX402RevenueResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_MsgReportX402RevenueResponse proto.InternalMessageInfo
+
 func init() {
 	proto.RegisterType((*MsgBondProvider)(nil), "arkeo.arkeo.MsgBondProvider")
 	proto.RegisterType((*MsgBondProviderResponse)(nil), "arkeo.arkeo.MsgBondProviderResponse")
@@ -1110,91 +1236,99 @@ func init() {
 	proto.RegisterType((*MsgUpdateServiceResponse)(nil), "arkeo.arkeo.MsgUpdateServiceResponse")
 	proto.RegisterType((*MsgRemoveService)(nil), "arkeo.arkeo.MsgRemoveService")
 	proto.RegisterType((*MsgRemoveServiceResponse)(nil), "arkeo.arkeo.MsgRemoveServiceResponse")
+	proto.RegisterType((*MsgReportX402Revenue)(nil), "arkeo.arkeo.MsgReportX402Revenue")
+	proto.RegisterType((*MsgReportX402RevenueResponse)(nil), "arkeo.arkeo.MsgReportX402RevenueResponse")
 }
 
 func init() { proto.RegisterFile("arkeo/arkeo/tx.proto", fileDescriptor_a12700967a3e4015) }
 
 var fileDescriptor_a12700967a3e4015 = []byte{
-	// 1255 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xd4, 0x57, 0xcd, 0x6e, 0x14, 0x47,
-	0x10, 0xf6, 0x78, 0xd7, 0x36, 0xae, 0xb5, 0x8d, 0x69, 0x0c, 0x8c, 0x07, 0xb3, 0xde, 0x2c, 0xa0,
-	0x58, 0xfc, 0xec, 0xca, 0x46, 0xb9, 0xf8, 0x90, 0x08, 0x3b, 0x88, 0x58, 0x66, 0x03, 0x1a, 0x87,
-	0x48, 0x49, 0x0e, 0xab, 0xde, 0x99, 0xd6, 0xd0, 0xb2, 0x67, 0x7a, 0xd2, 0xdd, 0xeb, 0x78, 0x73,
-	0x8a, 0x72, 0x4c, 0x14, 0x29, 0x4f, 0x90, 0x47, 0x88, 0x38, 0xf0, 0x10, 0x1c, 0x11, 0x27, 0x94,
-	0x03, 0x42, 0x10, 0x89, 0x4b, 0x9e, 0x20, 0xa7, 0x68, 0x7a, 0x7a, 0x66, 0xe7, 0x67, 0x81, 0x05,
-	0x94, 0x48, 0xb9, 0x8c, 0xb7, 0xea, 0xab, 0xaa, 0xae, 0xaa, 0xf9, 0xaa, 0x7a, 0x0c, 0x4b, 0x98,
-	0xef, 0x13, 0xd6, 0x8e, 0x9f, 0xf2, 0xa8, 0x15, 0x72, 0x26, 0x19, 0xaa, 0x29, 0xb9, 0xa5, 0x9e,
-	0xd6, 0x92, 0xc7, 0x3c, 0xa6, 0xf4, 0xed, 0xe8, 0x57, 0x6c, 0x62, 0x2d, 0x3b, 0x4c, 0xf8, 0x4c,
-	0x74, 0x63, 0x20, 0x16, 0x34, 0x54, 0x8f, 0xa5, 0x76, 0x0f, 0x0b, 0xd2, 0x3e, 0x5c, 0xef, 0x11,
-	0x89, 0xd7, 0xdb, 0x0e, 0xa3, 0x81, 0xc6, 0xcd, 0xec, 0x99, 0xfb, 0x84, 0x84, 0x84, 0x6b, 0xe4,
-	0x8c, 0xf6, 0xf4, 0x85, 0xd7, 0x3e, 0x5c, 0x8f, 0xfe, 0x68, 0xe0, 0x04, 0xf6, 0x69, 0xc0, 0xda,
-	0xea, 0x19, 0xab, 0x9a, 0x7f, 0x19, 0x70, 0xbc, 0x23, 0xbc, 0x2d, 0x16, 0xb8, 0x77, 0x38, 0x3b,
-	0xa4, 0x2e, 0xe1, 0x68, 0x03, 0x66, 0x1c, 0x4e, 0xb0, 0x64, 0xdc, 0x34, 0x1a, 0xc6, 0xda, 0xec,
-	0x96, 0xf9, 0xf8, 0xc1, 0xd5, 0x25, 0x9d, 0xdc, 0x75, 0xd7, 0xe5, 0x44, 0x88, 0x3d, 0xc9, 0x69,
-	0xe0, 0xd9, 0x89, 0x21, 0xb2, 0xe0, 0x58, 0xa8, 0xfd, 0xcd, 0xc9, 0xc8, 0xc9, 0x4e, 0x65, 0x64,
-	0xc2, 0x8c, 0x20, 0xfc, 0x90, 0x3a, 0xc4, 0xac, 0x28, 0x28, 0x11, 0xd1, 0x27, 0x50, 0xed, 0xb1,
-	0xc0, 0x35, 0xab, 0xea, 0x98, 0xcb, 0x0f, 0x9f, 0xae, 0x4e, 0xfc, 0xf1, 0x74, 0xf5, 0x54, 0x7c,
-	0x94, 0x70, 0xf7, 0x5b, 0x94, 0xb5, 0x7d, 0x2c, 0xef, 0xb5, 0x76, 0x02, 0xf9, 0xf8, 0xc1, 0x55,
-	0xd0, 0x39, 0xec, 0x04, 0xd2, 0x56, 0x8e, 0x9b, 0xad, 0x1f, 0x5f, 0xde, 0xbf, 0x94, 0x24, 0xf1,
-	0xd3, 0xcb, 0xfb, 0x97, 0xce, 0xc5, 0xfd, 0x38, 0xd2, 0x7d, 0x29, 0x94, 0xd6, 0x5c, 0x86, 0x33,
-	0x05, 0x95, 0x4d, 0x44, 0xc8, 0x02, 0x41, 0x9a, 0xbf, 0x4c, 0xc1, 0x42, 0x47, 0x78, 0x1d, 0xf6,
-	0x7e, 0x8d, 0xd8, 0x2d, 0x34, 0x62, 0x6e, 0xab, 0xfd, 0xf7, 0xd3, 0xd5, 0xcb, 0x1e, 0x95, 0xf7,
-	0xfa, 0xbd, 0x96, 0xc3, 0xfc, 0x38, 0xb3, 0x80, 0xc8, 0xef, 0x18, 0xdf, 0xd7, 0x69, 0x3a, 0xcc,
-	0xf7, 0x59, 0xd0, 0xba, 0xd3, 0xef, 0xed, 0x92, 0xc1, 0x58, 0x9d, 0xfb, 0x00, 0xe6, 0x7c, 0x22,
-	0xb1, 0x8b, 0x25, 0xee, 0xf6, 0x39, 0x8d, 0x3b, 0x68, 0xd7, 0x12, 0xdd, 0x5d, 0x4e, 0xd1, 0x45,
-	0x58, 0x48, 0x4d, 0x02, 0x16, 0x38, 0xc4, 0x9c, 0x6a, 0x18, 0x6b, 0x55, 0x7b, 0x3e, 0xd1, 0x7e,
-	0x1e, 0x29, 0xd1, 0x35, 0x98, 0x16, 0x12, 0xcb, 0xbe, 0x30, 0xa7, 0x1b, 0xc6, 0xda, 0xc2, 0xc6,
-	0xd9, 0x56, 0x86, 0xb6, 0xad, 0xa4, 0x17, 0x7b, 0xca, 0xc4, 0xd6, 0xa6, 0x68, 0x03, 0x4e, 0xf9,
-	0x34, 0xe8, 0x3a, 0x2c, 0x90, 0x1c, 0x3b, 0xb2, 0xeb, 0xf6, 0x39, 0x96, 0x94, 0x05, 0xe6, 0x4c,
-	0xc3, 0x58, 0xab, 0xd8, 0x27, 0x7d, 0x1a, 0x6c, 0x6b, 0xec, 0x53, 0x0d, 0x29, 0x1f, 0x7c, 0x34,
-	0xc2, 0xe7, 0x98, 0xf6, 0xc1, 0x47, 0x25, 0x9f, 0x5b, 0x70, 0x42, 0xf4, 0x7b, 0xc2, 0xe1, 0x34,
-	0x8c, 0xe4, 0x2e, 0xc7, 0x92, 0x98, 0xb3, 0x8d, 0xca, 0x5a, 0x6d, 0x63, 0xb9, 0xa5, 0x5f, 0x44,
-	0x34, 0x20, 0x2d, 0x3d, 0x20, 0xad, 0x6d, 0x46, 0x83, 0xad, 0x6a, 0x44, 0x24, 0x7b, 0x31, 0xeb,
-	0x69, 0x63, 0x49, 0xd0, 0x2e, 0xa0, 0x10, 0x0f, 0xba, 0x58, 0x74, 0x07, 0xac, 0xdf, 0xf5, 0x58,
-	0x1c, 0x0e, 0xc6, 0x0b, 0xb7, 0x10, 0xe2, 0xc1, 0x75, 0xf1, 0x15, 0xeb, 0xdf, 0x64, 0x2a, 0x58,
-	0x1b, 0x4e, 0x0a, 0x22, 0xe5, 0x01, 0xf1, 0x49, 0x90, 0x29, 0xa6, 0xa6, 0x8a, 0x41, 0x43, 0x28,
-	0xa9, 0x65, 0xf3, 0x6a, 0x91, 0xab, 0x2b, 0x25, 0xae, 0x66, 0xc8, 0xd7, 0x34, 0xe1, 0x74, 0x5e,
-	0x93, 0x32, 0xf5, 0x59, 0x55, 0xcd, 0xec, 0xed, 0x90, 0xa4, 0x4d, 0xfe, 0x0f, 0x67, 0xf6, 0x34,
-	0x4c, 0x3b, 0x07, 0x94, 0x04, 0x52, 0x73, 0x4e, 0x4b, 0x51, 0x34, 0x97, 0x1c, 0x10, 0x2f, 0x6a,
-	0xe9, 0x54, 0x1c, 0x2d, 0x91, 0xd1, 0xc7, 0x30, 0x9f, 0xbe, 0x76, 0x39, 0x08, 0x89, 0xa6, 0xda,
-	0x72, 0x8e, 0x6a, 0x49, 0x2d, 0x5f, 0x0c, 0x42, 0x62, 0xcf, 0x39, 0x19, 0x49, 0xc5, 0xce, 0x33,
-	0x2c, 0x95, 0xd1, 0x35, 0xa8, 0xaa, 0xd7, 0x18, 0xb1, 0x68, 0x8c, 0xd7, 0xa8, 0x8c, 0xd1, 0x0d,
-	0x98, 0x71, 0x49, 0xc8, 0x04, 0x95, 0xe6, 0xec, 0xdb, 0xef, 0x9e, 0xc4, 0xf7, 0x55, 0x1c, 0x80,
-	0x57, 0x71, 0x00, 0x7d, 0x06, 0xf3, 0xb8, 0x2f, 0xef, 0x31, 0x4e, 0xbf, 0x1f, 0xd2, 0x65, 0x61,
-	0xa3, 0x39, 0xb2, 0x11, 0xd7, 0xb3, 0x96, 0x76, 0xde, 0x11, 0x5d, 0x01, 0xf4, 0x6d, 0x9f, 0x70,
-	0x4a, 0x44, 0x37, 0x24, 0xbc, 0xeb, 0xd3, 0xa0, 0x2f, 0x89, 0x39, 0xa7, 0x4e, 0x5e, 0xd4, 0xc8,
-	0x1d, 0xc2, 0x3b, 0x4a, 0x3f, 0xce, 0x9e, 0xcc, 0xd2, 0x49, 0xef, 0xc9, 0xac, 0x2a, 0x65, 0xdf,
-	0xef, 0x93, 0xb0, 0xd8, 0x11, 0xde, 0xf6, 0x01, 0x13, 0xe4, 0xbd, 0xe8, 0xb7, 0x0a, 0xb5, 0x94,
-	0x14, 0xd4, 0x55, 0x0c, 0xac, 0xda, 0x90, 0xa8, 0x76, 0x5c, 0x74, 0x33, 0x65, 0x5a, 0xe5, 0xdd,
-	0x16, 0x69, 0x42, 0xcd, 0xdd, 0x0c, 0x35, 0xab, 0xef, 0xb8, 0x93, 0x93, 0x00, 0x9b, 0xed, 0x62,
-	0x2b, 0xeb, 0xa5, 0x56, 0xe6, 0x7a, 0xd3, 0xb4, 0xc0, 0x2c, 0xea, 0xd2, 0x66, 0x3e, 0x31, 0xd4,
-	0x94, 0x6f, 0x1f, 0x60, 0xea, 0x27, 0xe0, 0x4e, 0xe0, 0x30, 0x9f, 0xfc, 0x3b, 0x2d, 0x5d, 0x81,
-	0x59, 0x41, 0xbd, 0x00, 0xcb, 0x3e, 0xd7, 0xad, 0xb0, 0x87, 0x0a, 0xb4, 0x04, 0x53, 0xc3, 0x8b,
-	0xa2, 0x62, 0xc7, 0xc2, 0xe6, 0x47, 0xc5, 0x82, 0x2f, 0x8c, 0x28, 0xb8, 0x94, 0x7f, 0xb3, 0x01,
-	0xf5, 0xd1, 0x48, 0x5a, 0xfc, 0xcf, 0x06, 0xcc, 0x77, 0x84, 0xb7, 0x47, 0xe4, 0x97, 0x84, 0x8b,
-	0xf8, 0x8a, 0x78, 0xfb, 0x9a, 0x4d, 0x98, 0x39, 0x8c, 0xdd, 0x55, 0xbd, 0x15, 0x3b, 0x11, 0x37,
-	0xaf, 0x14, 0x13, 0x3f, 0x5b, 0x4a, 0x7c, 0x78, 0x76, 0xf3, 0x0c, 0x9c, 0xca, 0x29, 0xd2, 0x34,
-	0xff, 0x34, 0x00, 0x75, 0x84, 0x67, 0x13, 0x8f, 0x0a, 0x49, 0xf8, 0x9e, 0xde, 0x83, 0xef, 0x92,
-	0xeb, 0x02, 0x4c, 0xa6, 0xaf, 0x65, 0x92, 0xba, 0x08, 0x41, 0x35, 0xc0, 0x7e, 0xb2, 0x62, 0xd5,
-	0x6f, 0xd4, 0x80, 0x9a, 0x4b, 0xd2, 0x7b, 0x2b, 0xb9, 0xd8, 0x33, 0xaa, 0xe8, 0xee, 0xd7, 0xcb,
-	0x38, 0x5e, 0xa6, 0xf1, 0xb6, 0xad, 0x69, 0x5d, 0xb4, 0x30, 0x37, 0xd7, 0x8b, 0xa5, 0x37, 0x4a,
-	0xa5, 0x17, 0xea, 0x69, 0xae, 0x80, 0x55, 0xd6, 0x0e, 0xef, 0x1c, 0x43, 0x4d, 0xfd, 0xdd, 0xd0,
-	0xc5, 0x92, 0xfc, 0x2f, 0x5a, 0x30, 0xc6, 0x9c, 0xe6, 0xaa, 0xd1, 0x73, 0x9a, 0xd3, 0x65, 0xa9,
-	0xba, 0xa8, 0xba, 0xe3, 0xb3, 0xc3, 0xf7, 0x2a, 0x3f, 0x29, 0x77, 0x72, 0x58, 0xee, 0x38, 0x99,
-	0xe6, 0x0e, 0xd6, 0x99, 0xe6, 0x74, 0x49, 0xa6, 0x1b, 0xbf, 0x4d, 0x43, 0xa5, 0x23, 0x3c, 0x64,
-	0xc3, 0x5c, 0xee, 0xa3, 0x7e, 0x25, 0x77, 0xc5, 0x14, 0x3e, 0x82, 0xad, 0x0b, 0xaf, 0x43, 0x93,
-	0xd8, 0xe8, 0x36, 0xd4, 0xb2, 0x9f, 0xc7, 0x67, 0x8b, 0x4e, 0x19, 0xd0, 0x3a, 0xff, 0x1a, 0x30,
-	0x0d, 0x68, 0xc3, 0x5c, 0xee, 0x2b, 0xa6, 0x94, 0x64, 0x16, 0x2d, 0x27, 0x39, 0xea, 0x7e, 0x42,
-	0x77, 0x61, 0x3e, 0x7f, 0x37, 0x9d, 0x2b, 0xba, 0xe5, 0x60, 0xeb, 0xe2, 0x6b, 0xe1, 0x34, 0xac,
-	0x07, 0x27, 0x47, 0x6d, 0xe9, 0xf3, 0x65, 0xef, 0x92, 0x91, 0x75, 0x79, 0x0c, 0xa3, 0xf4, 0xa0,
-	0x5b, 0x00, 0x99, 0x8d, 0x68, 0x15, 0x5d, 0x87, 0x98, 0xd5, 0x7c, 0x35, 0x96, 0x46, 0xfb, 0x06,
-	0x8e, 0x17, 0x17, 0xd7, 0x6a, 0xd1, 0xad, 0x60, 0x60, 0x7d, 0xf8, 0x06, 0x83, 0x6c, 0xab, 0xf3,
-	0x0b, 0xa1, 0xd4, 0xea, 0x1c, 0x5c, 0x6e, 0xf5, 0xc8, 0x61, 0x8b, 0xc2, 0xe6, 0x07, 0xed, 0x5c,
-	0x39, 0xa1, 0x0c, 0x5c, 0x0e, 0x3b, 0x72, 0x32, 0xac, 0xa9, 0x1f, 0x5e, 0xde, 0xbf, 0x64, 0x6c,
-	0xdd, 0x78, 0xf8, 0xbc, 0x6e, 0x3c, 0x7a, 0x5e, 0x37, 0x9e, 0x3d, 0xaf, 0x1b, 0xbf, 0xbe, 0xa8,
-	0x4f, 0x3c, 0x7a, 0x51, 0x9f, 0x78, 0xf2, 0xa2, 0x3e, 0xf1, 0xf5, 0x1b, 0x3e, 0x08, 0x92, 0x71,
-	0x8c, 0x16, 0x8e, 0xe8, 0x4d, 0xab, 0xff, 0x9f, 0xaf, 0xfd, 0x13, 0x00, 0x00, 0xff, 0xff, 0x31,
-	0x2d, 0xa2, 0x21, 0xfb, 0x0f, 0x00, 0x00,
+	// 1355 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0xd4, 0x58, 0x4d, 0x6f, 0xdb, 0x46,
+	0x13, 0x36, 0x2d, 0xd9, 0x8e, 0x47, 0xb6, 0xe3, 0x6c, 0x9c, 0x84, 0x66, 0x6c, 0x59, 0x51, 0x62,
+	0xbc, 0x7e, 0xf3, 0x21, 0xd5, 0x72, 0x7b, 0xa8, 0x0e, 0x2d, 0x62, 0x37, 0x48, 0x0d, 0x47, 0x4d,
+	0x40, 0x37, 0x45, 0x3f, 0x0e, 0x02, 0x25, 0x2e, 0x18, 0xc2, 0xe6, 0x2e, 0xb3, 0xbb, 0x54, 0xed,
+	0x9e, 0xda, 0x1e, 0x5b, 0x14, 0xe8, 0x2f, 0x29, 0x72, 0xc8, 0x8f, 0xc8, 0x31, 0xc8, 0x29, 0x28,
+	0x8a, 0x20, 0x48, 0x0a, 0xe4, 0xd2, 0x5f, 0xd0, 0x53, 0xa1, 0xe5, 0x92, 0xe2, 0x87, 0xe2, 0x28,
+	0x09, 0x5a, 0xb4, 0x17, 0x59, 0x33, 0xcf, 0xcc, 0xec, 0xcc, 0xf0, 0xd9, 0x19, 0xca, 0xb0, 0x60,
+	0xb1, 0x3d, 0x4c, 0xeb, 0xe1, 0xa7, 0x38, 0xa8, 0xf9, 0x8c, 0x0a, 0x8a, 0x4a, 0x52, 0xae, 0xc9,
+	0x4f, 0x63, 0xc1, 0xa1, 0x0e, 0x95, 0xfa, 0x7a, 0xff, 0x5b, 0x68, 0x62, 0x2c, 0x76, 0x29, 0xf7,
+	0x28, 0x6f, 0x87, 0x40, 0x28, 0x28, 0xa8, 0x1c, 0x4a, 0xf5, 0x8e, 0xc5, 0x71, 0xbd, 0xb7, 0xde,
+	0xc1, 0xc2, 0x5a, 0xaf, 0x77, 0xa9, 0x4b, 0x14, 0xae, 0x27, 0xcf, 0xdc, 0xc3, 0xd8, 0xc7, 0x4c,
+	0x21, 0x67, 0x94, 0xa7, 0xc7, 0x9d, 0x7a, 0x6f, 0xbd, 0xff, 0x47, 0x01, 0x27, 0x2c, 0xcf, 0x25,
+	0xb4, 0x2e, 0x3f, 0x43, 0x55, 0xf5, 0x0f, 0x0d, 0x8e, 0xb7, 0xb8, 0xb3, 0x49, 0x89, 0x7d, 0x8b,
+	0xd1, 0x9e, 0x6b, 0x63, 0x86, 0x1a, 0x30, 0xd5, 0x65, 0xd8, 0x12, 0x94, 0xe9, 0x5a, 0x45, 0x5b,
+	0x9b, 0xde, 0xd4, 0x1f, 0xdd, 0xbf, 0xb2, 0xa0, 0x92, 0xbb, 0x6a, 0xdb, 0x0c, 0x73, 0xbe, 0x2b,
+	0x98, 0x4b, 0x1c, 0x33, 0x32, 0x44, 0x06, 0x1c, 0xf3, 0x95, 0xbf, 0x3e, 0xde, 0x77, 0x32, 0x63,
+	0x19, 0xe9, 0x30, 0xc5, 0x31, 0xeb, 0xb9, 0x5d, 0xac, 0x17, 0x24, 0x14, 0x89, 0xe8, 0x43, 0x28,
+	0x76, 0x28, 0xb1, 0xf5, 0xa2, 0x3c, 0xe6, 0xd2, 0x83, 0x27, 0x2b, 0x63, 0xbf, 0x3e, 0x59, 0x39,
+	0x15, 0x1e, 0xc5, 0xed, 0xbd, 0x9a, 0x4b, 0xeb, 0x9e, 0x25, 0xee, 0xd4, 0xb6, 0x89, 0x78, 0x74,
+	0xff, 0x0a, 0xa8, 0x1c, 0xb6, 0x89, 0x30, 0xa5, 0x63, 0xb3, 0xf6, 0xfd, 0x8b, 0x7b, 0x17, 0xa3,
+	0x24, 0x7e, 0x78, 0x71, 0xef, 0xe2, 0x72, 0xd8, 0x8f, 0x03, 0xd5, 0x97, 0x4c, 0x69, 0xd5, 0x45,
+	0x38, 0x93, 0x51, 0x99, 0x98, 0xfb, 0x94, 0x70, 0x5c, 0xfd, 0x69, 0x02, 0xe6, 0x5a, 0xdc, 0x69,
+	0xd1, 0xb7, 0x6b, 0xc4, 0x4e, 0xa6, 0x11, 0x33, 0x9b, 0xf5, 0x3f, 0x9f, 0xac, 0x5c, 0x72, 0x5c,
+	0x71, 0x27, 0xe8, 0xd4, 0xba, 0xd4, 0x0b, 0x33, 0x23, 0x58, 0x7c, 0x4d, 0xd9, 0x9e, 0x4a, 0xb3,
+	0x4b, 0x3d, 0x8f, 0x92, 0xda, 0xad, 0xa0, 0xb3, 0x83, 0x0f, 0x47, 0xea, 0xdc, 0x39, 0x98, 0xf1,
+	0xb0, 0xb0, 0x6c, 0x4b, 0x58, 0xed, 0x80, 0xb9, 0x61, 0x07, 0xcd, 0x52, 0xa4, 0xbb, 0xcd, 0x5c,
+	0xb4, 0x0a, 0x73, 0xb1, 0x09, 0xa1, 0xa4, 0x8b, 0xf5, 0x89, 0x8a, 0xb6, 0x56, 0x34, 0x67, 0x23,
+	0xed, 0x27, 0x7d, 0x25, 0xda, 0x80, 0x49, 0x2e, 0x2c, 0x11, 0x70, 0x7d, 0xb2, 0xa2, 0xad, 0xcd,
+	0x35, 0xce, 0xd6, 0x12, 0xb4, 0xad, 0x45, 0xbd, 0xd8, 0x95, 0x26, 0xa6, 0x32, 0x45, 0x0d, 0x38,
+	0xe5, 0xb9, 0xa4, 0xdd, 0xa5, 0x44, 0x30, 0xab, 0x2b, 0xda, 0x76, 0xc0, 0x2c, 0xe1, 0x52, 0xa2,
+	0x4f, 0x55, 0xb4, 0xb5, 0x82, 0x79, 0xd2, 0x73, 0xc9, 0x96, 0xc2, 0x3e, 0x52, 0x90, 0xf4, 0xb1,
+	0x0e, 0x86, 0xf8, 0x1c, 0x53, 0x3e, 0xd6, 0x41, 0xce, 0xe7, 0x06, 0x9c, 0xe0, 0x41, 0x87, 0x77,
+	0x99, 0xeb, 0xf7, 0xe5, 0x36, 0xb3, 0x04, 0xd6, 0xa7, 0x2b, 0x85, 0xb5, 0x52, 0x63, 0xb1, 0xa6,
+	0x1e, 0x44, 0xff, 0x82, 0xd4, 0xd4, 0x05, 0xa9, 0x6d, 0x51, 0x97, 0x6c, 0x16, 0xfb, 0x44, 0x32,
+	0xe7, 0x93, 0x9e, 0xa6, 0x25, 0x30, 0xda, 0x01, 0xe4, 0x5b, 0x87, 0x6d, 0x8b, 0xb7, 0x0f, 0x69,
+	0xd0, 0x76, 0x68, 0x18, 0x0e, 0x46, 0x0b, 0x37, 0xe7, 0x5b, 0x87, 0x57, 0xf9, 0x17, 0x34, 0xb8,
+	0x4e, 0x65, 0xb0, 0x3a, 0x9c, 0xe4, 0x58, 0x88, 0x7d, 0xec, 0x61, 0x92, 0x28, 0xa6, 0x24, 0x8b,
+	0x41, 0x03, 0x28, 0xaa, 0xa5, 0x79, 0x25, 0xcb, 0xd5, 0xa5, 0x1c, 0x57, 0x13, 0xe4, 0xab, 0xea,
+	0x70, 0x3a, 0xad, 0x89, 0x99, 0xfa, 0xb4, 0x28, 0xef, 0xec, 0x4d, 0x1f, 0xc7, 0x4d, 0xfe, 0x07,
+	0xef, 0xec, 0x69, 0x98, 0xec, 0xee, 0xbb, 0x98, 0x08, 0xc5, 0x39, 0x25, 0xf5, 0xa3, 0xd9, 0x78,
+	0x1f, 0x3b, 0x96, 0x08, 0x89, 0x36, 0x6d, 0xc6, 0x32, 0xfa, 0x00, 0x66, 0xe3, 0xc7, 0x2e, 0x0e,
+	0x7d, 0xac, 0xa8, 0xb6, 0x98, 0xa2, 0x5a, 0x54, 0xcb, 0xa7, 0x87, 0x3e, 0x36, 0x67, 0xba, 0x09,
+	0x49, 0xc6, 0x4e, 0x33, 0x2c, 0x96, 0xd1, 0x06, 0x14, 0xe5, 0x63, 0xec, 0xb3, 0x68, 0x84, 0xc7,
+	0x28, 0x8d, 0xd1, 0x35, 0x98, 0xb2, 0xb1, 0x4f, 0xb9, 0x2b, 0xf4, 0xe9, 0xd7, 0x9f, 0x3d, 0x91,
+	0xef, 0xcb, 0x38, 0x00, 0x2f, 0xe3, 0x00, 0xfa, 0x18, 0x66, 0xad, 0x40, 0xdc, 0xa1, 0xcc, 0xfd,
+	0x66, 0x40, 0x97, 0xb9, 0x46, 0x75, 0x68, 0x23, 0xae, 0x26, 0x2d, 0xcd, 0xb4, 0x23, 0xba, 0x0c,
+	0xe8, 0x6e, 0x80, 0x99, 0x8b, 0x79, 0xdb, 0xc7, 0xac, 0xed, 0xb9, 0x24, 0x10, 0x58, 0x9f, 0x91,
+	0x27, 0xcf, 0x2b, 0xe4, 0x16, 0x66, 0x2d, 0xa9, 0x1f, 0x65, 0x4e, 0x26, 0xe9, 0xa4, 0xe6, 0x64,
+	0x52, 0x15, 0xb3, 0xef, 0x97, 0x71, 0x98, 0x6f, 0x71, 0x67, 0x6b, 0x9f, 0x72, 0xfc, 0x56, 0xf4,
+	0x5b, 0x81, 0x52, 0x4c, 0x0a, 0xd7, 0x96, 0x0c, 0x2c, 0x9a, 0x10, 0xa9, 0xb6, 0x6d, 0x74, 0x3d,
+	0x66, 0x5a, 0xe1, 0xcd, 0x06, 0x69, 0x44, 0xcd, 0x9d, 0x04, 0x35, 0x8b, 0x6f, 0x38, 0x93, 0xa3,
+	0x00, 0xcd, 0x7a, 0xb6, 0x95, 0xe5, 0x5c, 0x2b, 0x53, 0xbd, 0xa9, 0x1a, 0xa0, 0x67, 0x75, 0x71,
+	0x33, 0x1f, 0x6b, 0xf2, 0x96, 0x6f, 0xed, 0x5b, 0xae, 0x17, 0x81, 0xdb, 0xa4, 0x4b, 0x3d, 0xfc,
+	0xf7, 0xb4, 0x74, 0x09, 0xa6, 0xb9, 0xeb, 0x10, 0x4b, 0x04, 0x4c, 0xb5, 0xc2, 0x1c, 0x28, 0xd0,
+	0x02, 0x4c, 0x0c, 0x16, 0x45, 0xc1, 0x0c, 0x85, 0xe6, 0x7b, 0xd9, 0x82, 0x2f, 0x0c, 0x29, 0x38,
+	0x97, 0x7f, 0xb5, 0x02, 0xe5, 0xe1, 0x48, 0x5c, 0xfc, 0x8f, 0x1a, 0xcc, 0xb6, 0xb8, 0xb3, 0x8b,
+	0xc5, 0x67, 0x98, 0xf1, 0x70, 0x45, 0xbc, 0x7e, 0xcd, 0x3a, 0x4c, 0xf5, 0x42, 0x77, 0x59, 0x6f,
+	0xc1, 0x8c, 0xc4, 0xe6, 0xe5, 0x6c, 0xe2, 0x67, 0x73, 0x89, 0x0f, 0xce, 0xae, 0x9e, 0x81, 0x53,
+	0x29, 0x45, 0x9c, 0xe6, 0xef, 0x1a, 0xa0, 0x16, 0x77, 0x4c, 0xec, 0xb8, 0x5c, 0x60, 0xb6, 0xab,
+	0xe6, 0xe0, 0x9b, 0xe4, 0x3a, 0x07, 0xe3, 0xf1, 0x63, 0x19, 0x77, 0x6d, 0x84, 0xa0, 0x48, 0x2c,
+	0x2f, 0x1a, 0xb1, 0xf2, 0x3b, 0xaa, 0x40, 0xc9, 0xc6, 0xf1, 0xde, 0x8a, 0x16, 0x7b, 0x42, 0xd5,
+	0xdf, 0xfd, 0x6a, 0x18, 0x87, 0xc3, 0x34, 0x9c, 0xb6, 0x25, 0xa5, 0xeb, 0x0f, 0xcc, 0xe6, 0x7a,
+	0xb6, 0xf4, 0x4a, 0xae, 0xf4, 0x4c, 0x3d, 0xd5, 0x25, 0x30, 0xf2, 0xda, 0xc1, 0xce, 0xd1, 0xe4,
+	0xad, 0xbf, 0xed, 0xdb, 0x96, 0xc0, 0xff, 0x89, 0x16, 0x8c, 0x70, 0x4f, 0x53, 0xd5, 0xa8, 0x7b,
+	0x9a, 0xd2, 0x25, 0xa9, 0x3a, 0x2f, 0xbb, 0xe3, 0xd1, 0xde, 0x5b, 0x95, 0x1f, 0x95, 0x3b, 0x3e,
+	0x28, 0x77, 0x94, 0x4c, 0x53, 0x07, 0xab, 0x4c, 0x53, 0xba, 0x38, 0xd3, 0xdf, 0xc6, 0x61, 0x41,
+	0x82, 0x3e, 0x65, 0xe2, 0xf3, 0x77, 0xdf, 0x69, 0x98, 0xb8, 0x87, 0x49, 0x80, 0xff, 0xcd, 0x2f,
+	0xb3, 0x06, 0x1c, 0x63, 0xf8, 0x6e, 0x80, 0xb9, 0xe0, 0xf2, 0x61, 0x17, 0xcc, 0x58, 0x46, 0xef,
+	0xc3, 0x14, 0x0b, 0x2b, 0xd0, 0x27, 0x46, 0xdb, 0xf0, 0x91, 0x3d, 0x5a, 0x06, 0xf0, 0x31, 0x73,
+	0xa9, 0xdd, 0xc6, 0xc4, 0x96, 0xaf, 0x1c, 0x05, 0x73, 0x3a, 0xd4, 0x5c, 0x23, 0x76, 0x73, 0x23,
+	0xdb, 0xf6, 0xea, 0x90, 0xb6, 0x67, 0xba, 0x58, 0x2d, 0xc3, 0xd2, 0x30, 0x7d, 0xd4, 0xfe, 0xc6,
+	0x77, 0x53, 0x50, 0x68, 0x71, 0x07, 0x99, 0x30, 0x93, 0xfa, 0x4d, 0xb5, 0x94, 0xda, 0xf0, 0x99,
+	0xdf, 0x20, 0xc6, 0x85, 0xa3, 0xd0, 0x28, 0x36, 0xba, 0x09, 0xa5, 0xe4, 0xaf, 0x93, 0xb3, 0x59,
+	0xa7, 0x04, 0x68, 0x9c, 0x3f, 0x02, 0x8c, 0x03, 0x9a, 0x30, 0x93, 0x7a, 0x89, 0xcc, 0x25, 0x99,
+	0x44, 0x8d, 0x0b, 0x47, 0xa1, 0x71, 0xcc, 0xdb, 0x30, 0x9b, 0x7e, 0x35, 0x58, 0xce, 0xba, 0xa5,
+	0x60, 0x63, 0xf5, 0x48, 0x38, 0x0e, 0xeb, 0xc0, 0xc9, 0x61, 0x4b, 0xf2, 0x7c, 0xde, 0x3b, 0x67,
+	0x64, 0x5c, 0x1a, 0xc1, 0x28, 0x3e, 0xe8, 0x06, 0x40, 0x62, 0x21, 0x19, 0x59, 0xd7, 0x01, 0x66,
+	0x54, 0x5f, 0x8e, 0xc5, 0xd1, 0xbe, 0x82, 0xe3, 0xd9, 0xbd, 0xb1, 0x92, 0x75, 0xcb, 0x18, 0x18,
+	0xff, 0x7b, 0x85, 0x41, 0xb2, 0xd5, 0xe9, 0x79, 0x9c, 0x6b, 0x75, 0x0a, 0x36, 0x56, 0x8f, 0x84,
+	0x93, 0x61, 0xd3, 0x73, 0x6e, 0x39, 0x9f, 0x50, 0x02, 0x36, 0x56, 0x8f, 0x84, 0xe3, 0xb0, 0x16,
+	0x9c, 0xc8, 0x0f, 0xa5, 0x73, 0x79, 0xdf, 0x8c, 0x89, 0xf1, 0xff, 0x57, 0x9a, 0x44, 0x47, 0x18,
+	0x13, 0xdf, 0xbe, 0xb8, 0x77, 0x51, 0xdb, 0xbc, 0xf6, 0xe0, 0x59, 0x59, 0x7b, 0xf8, 0xac, 0xac,
+	0x3d, 0x7d, 0x56, 0xd6, 0x7e, 0x7e, 0x5e, 0x1e, 0x7b, 0xf8, 0xbc, 0x3c, 0xf6, 0xf8, 0x79, 0x79,
+	0xec, 0xcb, 0x57, 0x4c, 0xae, 0xe8, 0xe6, 0xf7, 0x57, 0x0a, 0xef, 0x4c, 0xca, 0xff, 0x90, 0x6c,
+	0xfc, 0x35, 0x00, 0x67, 0xae, 0x8f, 0x2c, 0xdd, 0x11, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -1228,6 +1362,8 @@ type MsgClient interface {
 	UpdateService(ctx context.Context, in *MsgUpdateService, opts ...grpc.CallOption) (*MsgUpdateServiceResponse, error)
 	// RemoveService removes an existing service from the registry.
 	RemoveService(ctx context.Context, in *MsgRemoveService, opts ...grpc.CallOption) (*MsgRemoveServiceResponse, error)
+	// ReportX402Revenue reports provider income from x402 agent payments.
+	ReportX402Revenue(ctx context.Context, in *MsgReportX402Revenue, opts ...grpc.CallOption) (*MsgReportX402RevenueResponse, error)
 }
 
 type msgClient struct {
@@ -1319,6 +1455,15 @@ func (c *msgClient) RemoveService(ctx context.Context, in *MsgRemoveService, opt
 	return out, nil
 }
 
+func (c *msgClient) ReportX402Revenue(ctx context.Context, in *MsgReportX402Revenue, opts ...grpc.CallOption) (*MsgReportX402RevenueResponse, error) {
+	out := new(MsgReportX402RevenueResponse)
+	err := c.cc.Invoke(ctx, "/arkeo.arkeo.Msg/ReportX402Revenue", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MsgServer is the server API for Msg service.
 type MsgServer interface {
 	// BondProvider creates or updates a provider bond.
@@ -1340,6 +1485,8 @@ type MsgServer interface {
 	UpdateService(context.Context, *MsgUpdateService) (*MsgUpdateServiceResponse, error)
 	// RemoveService removes an existing service from the registry.
 	RemoveService(context.Context, *MsgRemoveService) (*MsgRemoveServiceResponse, error)
+	// ReportX402Revenue reports provider income from x402 agent payments.
+	ReportX402Revenue(context.Context, *MsgReportX402Revenue) (*MsgReportX402RevenueResponse, error)
 }
 
 // UnimplementedMsgServer can be embedded to have forward compatible implementations.
@@ -1373,6 +1520,9 @@ func (*UnimplementedMsgServer) UpdateService(ctx context.Context, req *MsgUpdate
 func (*UnimplementedMsgServer) RemoveService(ctx context.Context, req *MsgRemoveService) (*MsgRemoveServiceResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RemoveService not implemented")
 }
+func (*UnimplementedMsgServer) ReportX402Revenue(ctx context.Context, req *MsgReportX402Revenue) (*MsgReportX402RevenueResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReportX402Revenue not implemented")
+}
 
 func RegisterMsgServer(s grpc1.Server, srv MsgServer) {
 	s.RegisterService(&_Msg_serviceDesc, srv)
@@ -1540,6 +1690,24 @@ func _Msg_RemoveService_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Msg_ReportX402Revenue_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MsgReportX402Revenue)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MsgServer).ReportX402Revenue(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/arkeo.arkeo.Msg/ReportX402Revenue",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MsgServer).ReportX402Revenue(ctx, req.(*MsgReportX402Revenue))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Msg_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "arkeo.arkeo.Msg",
 	HandlerType: (*MsgServer)(nil),
@@ -1580,6 +1748,10 @@ var _Msg_serviceDesc = grpc.ServiceDesc{
 			MethodName: "RemoveService",
 			Handler:    _Msg_RemoveService_Handler,
 		},
+		{
+			MethodName: "ReportX402Revenue",
+			Handler:    _Msg_ReportX402Revenue_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "arkeo/arkeo/tx.proto",
@@ -2333,6 +2505,93 @@ func (m *MsgRemoveServiceResponse) MarshalToSizedBuffer(dAtA []byte) (int, error
 	return len(dAtA) - i, nil
 }
 
+func (m *MsgReportX402Revenue) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MsgReportX402Revenue) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MsgReportX402Revenue) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.PeriodEnd != 0 {
+		i = encodeVarintTx(dAtA, i, uint64(m.PeriodEnd))
+		i--
+		dAtA[i] = 0x30
+	}
+	{
+		size, err := m.Revenue.MarshalToSizedBuffer(dAtA[:i])
+		if err != nil {
+			return 0, err
+		}
+		i -= size
+		i = encodeVarintTx(dAtA, i, uint64(size))
+	}
+	i--
+	dAtA[i] = 0x2a
+	if m.Requests != 0 {
+		i = encodeVarintTx(dAtA, i, uint64(m.Requests))
+		i--
+		dAtA[i] = 0x20
+	}
+	if len(m.Service) > 0 {
+		i -= len(m.Service)
+		copy(dAtA[i:], m.Service)
+		i = encodeVarintTx(dAtA, i, uint64(len(m.Service)))
+		i--
+		dAtA[i] = 0x1a
+	}
+	if len(m.Provider) > 0 {
+		i -= len(m.Provider)
+		copy(dAtA[i:], m.Provider)
+		i = encodeVarintTx(dAtA, i, uint64(len(m.Provider)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if len(m.Creator) > 0 {
+		i -= len(m.Creator)
+		copy(dAtA[i:], m.Creator)
+		i = encodeVarintTx(dAtA, i, uint64(len(m.Creator)))
+		i--
+		dAtA[i] = 0xa
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *MsgReportX402RevenueResponse) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *MsgReportX402RevenueResponse) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *MsgReportX402RevenueResponse) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	return len(dAtA) - i, nil
+}
+
 func encodeVarintTx(dAtA []byte, offset int, v uint64) int {
 	offset -= sovTx(v)
 	base := offset
@@ -2684,6 +2943,44 @@ func (m *MsgRemoveServiceResponse) Size() (n int) {
 	return n
 }
 
+func (m *MsgReportX402Revenue) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	l = len(m.Creator)
+	if l > 0 {
+		n += 1 + l + sovTx(uint64(l))
+	}
+	l = len(m.Provider)
+	if l > 0 {
+		n += 1 + l + sovTx(uint64(l))
+	}
+	l = len(m.Service)
+	if l > 0 {
+		n += 1 + l + sovTx(uint64(l))
+	}
+	if m.Requests != 0 {
+		n += 1 + sovTx(uint64(m.Requests))
+	}
+	l = m.Revenue.Size()
+	n += 1 + l + sovTx(uint64(l))
+	if m.PeriodEnd != 0 {
+		n += 1 + sovTx(uint64(m.PeriodEnd))
+	}
+	return n
+}
+
+func (m *MsgReportX402RevenueResponse) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	return n
+}
+
 func sovTx(x uint64) (n int) {
 	return (math_bits.Len64(x|1) + 6) / 7
 }
@@ -4967,6 +5264,275 @@ func (m *MsgRemoveServiceResponse) Unmarshal(dAtA []byte) error {
 	}
 	return nil
 }
+func (m *MsgReportX402Revenue) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTx
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MsgReportX402Revenue: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MsgReportX402Revenue: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Creator", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTx
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTx
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Creator = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Provider", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthTx
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTx
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Provider = append(m.Provider[:0], dAtA[iNdEx:postIndex]...)
+			if m.Provider == nil {
+				m.Provider = []byte{}
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Service", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTx
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTx
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Service = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Requests", wireType)
+			}
+			m.Requests = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Requests |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Revenue", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTx
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTx
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if err := m.Revenue.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 6:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field PeriodEnd", wireType)
+			}
+			m.PeriodEnd = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTx
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.PeriodEnd |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTx(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTx
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *MsgReportX402RevenueResponse) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTx
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: MsgReportX402RevenueResponse: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: MsgReportX402RevenueResponse: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTx(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTx
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
 func skipTx(dAtA []byte) (n int, err error) {
 	l := len(dAtA)
 	iNdEx := 0